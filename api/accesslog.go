@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/users"
+)
+
+// AccessLog is a middleware which logs every request with its route, status and duration. The
+// pattern of the route is logged instead of the path, which may have secrets. The line has the
+// fields of the request context, like the request and trace IDs, so it should be added before
+// the RequestID and Tracing middlewares.
+func (h *Handler) AccessLog(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	req, route := withRoute(req)
+	w := &statusWriter{ResponseWriter: rw, code: http.StatusOK}
+	next(w, req)
+
+	logger.With(
+		logger.FromContext(req.Context(), h.Logger),
+		"method", req.Method,
+		"path", routePattern(req),
+		"route", route.name,
+		"status", w.code,
+		"duration", time.Since(start).String(),
+		"remoteAddr", users.TokenSalt(req),
+	).Info("request")
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+// adminTokenHeader is the header with the token of the admin API
+const adminTokenHeader = "X-Admin-Token"
+
+var errNoLevels = errs.NotFound("Sorry, the log levels of the app can't be changed")
+
+// logLevels is the payload to change the log levels, and the response with the current ones
+type logLevels struct {
+	// Levels is the default level followed by the levels of the packages, e.g.
+	// info,users=debug,mongo=warn
+	Levels string `json:"levels"`
+}
+
+// mwareAdmin lets through only the requests with the admin token. The admin API does not exist
+// if the token is not configured.
+func (h *Handler) mwareAdmin(rw http.ResponseWriter, req *http.Request) {
+	if h.AdminToken == "" {
+		h.sendError(rw, req, errNoRoute)
+		return
+	}
+	token := strings.TrimSpace(req.Header.Get(adminTokenHeader))
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		h.sendError(rw, req, errNotAuthorized)
+		return
+	}
+}
+
+// adminLogLevels returns the current log levels
+func (h *Handler) adminLogLevels(rw http.ResponseWriter, req *http.Request) {
+	levels := logger.LevelsOf(h.Logger)
+	if levels == nil {
+		h.sendError(rw, req, errNoLevels)
+		return
+	}
+	webgo.R200(rw, logLevels{Levels: levels.String()})
+}
+
+// adminSetLogLevels replaces the log levels, until the app is restarted or the levels file is
+// reloaded
+func (h *Handler) adminSetLogLevels(rw http.ResponseWriter, req *http.Request) {
+	levels := logger.LevelsOf(h.Logger)
+	if levels == nil {
+		h.sendError(rw, req, errNoLevels)
+		return
+	}
+
+	input := logLevels{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	err = levels.Set(input.Levels)
+	if err != nil {
+		h.sendError(rw, req, errs.Field("levels", err.Error()))
+		return
+	}
+
+	logger.FromContext(req.Context(), h.Logger).Info("log levels", levels.String())
+	webgo.R200(rw, logLevels{Levels: levels.String()})
+}
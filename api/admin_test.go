@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdminLogLevels(t *testing.T) {
+	server, _ := newServer(t)
+	request := func(method, token, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+"/v1/admin/logs", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		req.Header.Set("X-Admin-Token", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer resp.Body.Close()
+		out := struct {
+			Data struct {
+				Levels string `json:"levels"`
+			} `json:"data"`
+		}{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out.Data.Levels
+	}
+
+	code, _ := request(http.MethodGet, "wrong", "")
+	if code != http.StatusUnauthorized {
+		t.Fatalf("Expected %d without the admin token, got %d", http.StatusUnauthorized, code)
+	}
+	code, levels := request(http.MethodGet, adminToken, "")
+	if code != http.StatusOK || levels != "info" {
+		t.Fatalf("Expected the levels to be info, got %d %q", code, levels)
+	}
+	code, levels = request(http.MethodPut, adminToken, `{"levels":"warn,users=debug,api=info"}`)
+	if code != http.StatusOK || levels != "warn,api=info,users=debug" {
+		t.Fatalf("Expected the new levels, got %d %q", code, levels)
+	}
+	code, _ = request(http.MethodPut, adminToken, `{"levels":"users=verbose"}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("Expected %d for an invalid level, got %d", http.StatusBadRequest, code)
+	}
+	code, levels = request(http.MethodGet, adminToken, "")
+	if code != http.StatusOK || levels != "warn,api=info,users=debug" {
+		t.Fatalf("Expected the levels to be kept, got %d %q", code, levels)
+	}
+}
@@ -1,16 +1,24 @@
 // Package api serves all the API endpoints of the app
 package api
 
-import "github.com/bnkamalesh/notes/pkg/services"
+import (
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/services"
+)
 
 // Handler holds all the services which will serve the endpoints
 type Handler struct {
 	Services services.Handler
+	// Logger logs the internal errors, which are not sent in responses
+	Logger logger.Service
+	// AdminToken is the token required by the admin API, which is disabled if it's empty
+	AdminToken string
 }
 
 // NewHandler returns a handler instance with all the services initialized
-func NewHandler(s services.Handler) Handler {
+func NewHandler(s services.Handler, l logger.Service) Handler {
 	return Handler{
 		Services: s,
+		Logger:   l,
 	}
 }
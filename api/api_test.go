@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/services/servicestest"
+	"github.com/bnkamalesh/notes/pkg/testenv"
+)
+
+// adminToken is the token of the admin API of the test servers
+const adminToken = "admin-token"
+
+// newServer returns a server running the API, with the data in memory, and the buffer of its
+// logs
+func newServer(t *testing.T) (*httptest.Server, *bytes.Buffer) {
+	t.Helper()
+	env := testenv.New(t)
+	logs := &bytes.Buffer{}
+	l, err := logger.NewLog(logger.Config{Level: "info", Output: logs})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	env.Logger = l
+	h := NewHandler(servicestest.New(env), l)
+	h.AdminToken = adminToken
+
+	server := httptest.NewServer(NewRouter(&h, &webgo.Config{}))
+	t.Cleanup(server.Close)
+	return server, logs
+}
+
+// call sends a JSON request with the token, and decodes the data of the response into result
+func call(t *testing.T, method, url, token string, body, result interface{}) *http.Response {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	if result != nil {
+		err = json.NewDecoder(resp.Body).Decode(&struct {
+			Data interface{} `json:"data"`
+		}{Data: result})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	return resp
+}
+
+// login signs up a user and returns its token
+func login(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	input := map[string]string{"name": "Jane", "email": "jane@example.com", "password": "password"}
+	resp := call(t, http.MethodPost, server.URL+"/signup", "", input, nil)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the user to sign up, got %d", resp.StatusCode)
+	}
+	user := struct {
+		AuthToken string `json:"authToken"`
+	}{}
+	call(t, http.MethodPost, server.URL+"/login", "", input, &user)
+	if user.AuthToken == "" {
+		t.Fatal("Expected a token")
+	}
+	return user.AuthToken
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+// errNoFile is returned when a multipart upload does not have a file
+var errNoFile = errs.Field("file", "Sorry, no file provided in the 'file' field")
+
+// attachmentUpload returns the name, content type and content of the file being uploaded. The
+// file is either the 'file' field of a multipart form, or the raw body of the request with the
+// name in the query or the Content-Disposition header. The content is streamed in both cases.
+func attachmentUpload(req *http.Request) (string, string, io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		mr, err := req.MultipartReader()
+		if err != nil {
+			return "", "", nil, errs.Invalid(err.Error())
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return "", "", nil, errNoFile
+			}
+			if err != nil {
+				return "", "", nil, errs.Invalid(err.Error())
+			}
+			if part.FormName() == "file" {
+				return part.FileName(), part.Header.Get("Content-Type"), part, nil
+			}
+		}
+	}
+
+	name := req.URL.Query().Get("name")
+	if name == "" {
+		_, params, err := mime.ParseMediaType(req.Header.Get("Content-Disposition"))
+		if err == nil {
+			name = params["filename"]
+		}
+	}
+	return name, req.Header.Get("Content-Type"), req.Body, nil
+}
+
+// userAddAttachment attaches a file to an item of the user
+func (h *Handler) userAddAttachment(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	name, contentType, body, err := attachmentUpload(req)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	a, err := h.users(req).AddAttachment(user, wctx.Params["id"], name, contentType, body)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R201(rw, a)
+}
+
+// userItemAttachments returns all the attachments of an item of the user
+func (h *Handler) userItemAttachments(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	aa, err := h.users(req).Attachments(user, wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, aa)
+}
+
+// userDownloadAttachment streams a decrypted attachment of an item of the user. Range requests
+// are supported.
+func (h *Handler) userDownloadAttachment(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	a, r, err := h.users(req).OpenAttachment(user, wctx.Params["id"], wctx.Params["attachmentID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	defer r.Close()
+
+	rw = streamWriter(rw, req)
+	rw.Header().Set("Content-Type", a.ContentType)
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(rw, req, a.Name, *a.CreatedAt, r)
+}
+
+// userDeleteAttachment deletes an attachment of an item of the user
+func (h *Handler) userDeleteAttachment(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	a, err := h.users(req).DeleteAttachment(user, wctx.Params["id"], wctx.Params["attachmentID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, a)
+}
+
+// userAttachmentUsage returns the storage used by the attachments of the user
+func (h *Handler) userAttachmentUsage(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	u, err := h.users(req).AttachmentUsage(user)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, u)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bnkamalesh/webgo"
+)
+
+// checklistEntryInput is the payload to add or update a checklist entry
+type checklistEntryInput struct {
+	Text     *string `json:"text"`
+	Checked  *bool   `json:"checked"`
+	Position *int    `json:"position"`
+}
+
+// reorderInput is the payload to reorder the checklist of an item
+type reorderInput struct {
+	// Order is the IDs of all the entries in the new order
+	Order []string `json:"order"`
+}
+
+// userAddChecklistEntry adds a new entry to the checklist of an item
+func (h *Handler) userAddChecklistEntry(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := checklistEntryInput{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	text := ""
+	if input.Text != nil {
+		text = *input.Text
+	}
+	position := -1
+	if input.Position != nil {
+		position = *input.Position
+	}
+
+	wctx := webgo.Context(req)
+	item, err := h.users(req).AddChecklistEntry(user, wctx.Params["id"], text, position)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, item)
+}
+
+// userUpdateChecklistEntry updates the text and/or checked state of a checklist entry
+func (h *Handler) userUpdateChecklistEntry(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := checklistEntryInput{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	item, err := h.users(req).UpdateChecklistEntry(
+		user,
+		wctx.Params["id"],
+		wctx.Params["entryID"],
+		input.Text,
+		input.Checked,
+	)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, item)
+}
+
+// userToggleChecklistEntry flips the checked state of a checklist entry
+func (h *Handler) userToggleChecklistEntry(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	item, err := h.users(req).ToggleChecklistEntry(user, wctx.Params["id"], wctx.Params["entryID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, item)
+}
+
+// userRemoveChecklistEntry removes an entry from the checklist of an item
+func (h *Handler) userRemoveChecklistEntry(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	item, err := h.users(req).RemoveChecklistEntry(user, wctx.Params["id"], wctx.Params["entryID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, item)
+}
+
+// userReorderChecklist sets the order of the checklist entries of an item
+func (h *Handler) userReorderChecklist(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := reorderInput{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	item, err := h.users(req).ReorderChecklist(user, wctx.Params["id"], input.Order)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, item)
+}
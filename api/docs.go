@@ -0,0 +1,299 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/health"
+	"github.com/bnkamalesh/notes/pkg/importer"
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/metrics"
+	"github.com/bnkamalesh/notes/pkg/publications"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+	"github.com/bnkamalesh/notes/pkg/secrets"
+	"github.com/bnkamalesh/notes/pkg/shares"
+	"github.com/bnkamalesh/notes/pkg/users"
+	"github.com/bnkamalesh/notes/pkg/webhooks"
+)
+
+// signupInput documents the payload to sign up
+type signupInput struct {
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// loginInput documents the payload to login
+type loginInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// itemInput documents the payload to create or replace an item
+type itemInput struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// DueAt is an RFC3339 timestamp
+	DueAt string `json:"dueAt,omitempty"`
+	// Tags is a comma separated list of tags
+	Tags string `json:"tags,omitempty"`
+}
+
+// shareInput documents the payload to share an item
+type shareInput struct {
+	Email string `json:"email"`
+	// Permission is either read or write
+	Permission string `json:"permission"`
+}
+
+// passwordInput documents the form to open a password protected publication
+type passwordInput struct {
+	Key      string `json:"key"`
+	Password string `json:"password"`
+}
+
+var (
+	itemQuery = []queryParam{
+		{Name: "sort", Description: "created, modified or title, prefixed with - for descending order"},
+		{Name: "after", Description: "cursor of the next page, from the Link header of the previous page"},
+		{Name: "start", Description: "offset of the first item, cursors should be preferred", Type: "integer"},
+		{Name: "limit", Description: "maximum number of items in the page", Type: "integer"},
+		{Name: "total", Description: "include the total number of items", Type: "boolean"},
+		{Name: "status", Description: "open or done"},
+		{Name: "shared", Description: "list the items shared with the user instead", Type: "boolean"},
+	}
+
+	// routeDocs documents every route in the OpenAPI document, by the name of the route
+	routeDocs = map[string]routeDoc{
+		"home": {
+			Summary: "Version of the app", Tag: "meta", Public: true,
+			Response: map[string]string{},
+		},
+		"openAPI": {
+			Summary: "OpenAPI document of the API", Tag: "meta", Public: true,
+		},
+		"appMetrics": {
+			Summary: "Metrics of the app in the Prometheus text format", Tag: "meta", Admin: true,
+			ContentType: metrics.ContentType,
+		},
+		"appLiveness": {
+			Summary: "Liveness of the app, it's alive if it responds", Tag: "meta", Public: true,
+			Response: liveness{},
+		},
+		"appReadiness": {
+			Summary: "Readiness of the app with the checks of its dependencies, 503 if it's not ready", Tag: "meta", Public: true,
+			Response: health.Report{},
+		},
+		"adminLogLevels": {
+			Summary: "Log levels of the app", Tag: "admin", Admin: true,
+			Response: logLevels{},
+		},
+		"adminSetLogLevels": {
+			Summary: "Change the log levels of the app, until it's restarted", Tag: "admin", Admin: true,
+			Body: logLevels{}, Response: logLevels{},
+		},
+		"userSignup": {
+			Summary: "Sign up", Tag: "users", Public: true,
+			Body: signupInput{}, Response: users.User{},
+		},
+		"userLogin": {
+			Summary: "Login, the auth token is in the response", Tag: "users", Public: true,
+			Body: loginInput{}, Response: users.User{},
+		},
+		"userRefreshToken": {
+			Summary: "Replace the auth token with a new one, the old token stops working", Tag: "users",
+			Response: users.User{},
+		},
+		"userItems": {
+			Summary: "List the items of the user", Tag: "items",
+			Query: itemQuery, Response: items.Page{},
+		},
+		"userCreateItem": {
+			Summary: "Create an item", Tag: "items",
+			Body: itemInput{}, Response: items.Item{},
+		},
+		"userSearchItems": {
+			Summary: "Search the items of the user", Tag: "items",
+			Query: []queryParam{
+				{Name: "q", Description: "search query"},
+				{Name: "limit", Description: "maximum number of items", Type: "integer"},
+			},
+			Response: []items.Item{},
+		},
+		"userReadItem": {
+			Summary: "Read an item", Tag: "items",
+			Response: items.Item{},
+		},
+		"userUpdateItem": {
+			Summary: "Replace an item", Tag: "items",
+			Body: itemInput{}, Response: items.Item{},
+		},
+		"userPatchItem": {
+			Summary: "Update the fields of an item with a JSON Merge Patch", Tag: "items",
+			Body: map[string]interface{}{}, Response: items.Item{},
+		},
+		"userDeleteItem": {
+			Summary: "Delete an item", Tag: "items",
+			Response: items.Item{},
+		},
+		"userAddChecklistEntry": {
+			Summary: "Add a checklist entry", Tag: "checklist",
+			Body: checklistEntryInput{}, Response: items.Item{},
+		},
+		"userReorderChecklist": {
+			Summary: "Reorder the checklist", Tag: "checklist",
+			Body: reorderInput{}, Response: items.Item{},
+		},
+		"userUpdateChecklistEntry": {
+			Summary: "Update a checklist entry", Tag: "checklist",
+			Body: checklistEntryInput{}, Response: items.Item{},
+		},
+		"userToggleChecklistEntry": {
+			Summary: "Check or uncheck a checklist entry", Tag: "checklist",
+			Response: items.Item{},
+		},
+		"userRemoveChecklistEntry": {
+			Summary: "Remove a checklist entry", Tag: "checklist",
+			Response: items.Item{},
+		},
+		"userAddReminder": {
+			Summary: "Add a reminder to an item", Tag: "reminders",
+			Body: reminderInput{}, Response: reminders.Reminder{}, Status: http.StatusCreated,
+		},
+		"userItemReminders": {
+			Summary: "List the reminders of an item", Tag: "reminders",
+			Response: []reminders.Reminder{},
+		},
+		"userDeleteReminder": {
+			Summary: "Delete a reminder", Tag: "reminders",
+			Response: reminders.Reminder{},
+		},
+		"userShareItem": {
+			Summary: "Share an item with another user", Tag: "shares",
+			Body: shareInput{}, Response: shares.Share{}, Status: http.StatusCreated,
+		},
+		"userItemShares": {
+			Summary: "List the shares of an item", Tag: "shares",
+			Response: []shares.Share{},
+		},
+		"userRevokeShare": {
+			Summary: "Revoke a share", Tag: "shares",
+			Response: shares.Share{},
+		},
+		"userAddAttachment": {
+			Summary: "Attach a file to an item, as the 'file' field of a form or as the body", Tag: "attachments",
+			Query:  []queryParam{{Name: "name", Description: "name of the file, if the body is the file"}},
+			Upload: true, Response: attachments.Attachment{}, Status: http.StatusCreated,
+		},
+		"userItemAttachments": {
+			Summary: "List the attachments of an item", Tag: "attachments",
+			Response: []attachments.Attachment{},
+		},
+		"userDownloadAttachment": {
+			Summary: "Download an attachment, range requests are supported", Tag: "attachments",
+			ContentType: "application/octet-stream",
+		},
+		"userDeleteAttachment": {
+			Summary: "Delete an attachment", Tag: "attachments",
+			Response: attachments.Attachment{},
+		},
+		"userAttachmentUsage": {
+			Summary: "Storage used by the attachments of the user", Tag: "attachments",
+			Response: attachments.Usage{},
+		},
+		"userPublishItem": {
+			Summary: "Publish an item at a public link", Tag: "publications",
+			Body: publishInput{}, Response: publications.Published{}, Status: http.StatusCreated,
+		},
+		"userItemPublications": {
+			Summary: "List the publications of an item", Tag: "publications",
+			Response: []publications.Publication{},
+		},
+		"userUnpublishItem": {
+			Summary: "Delete a publication", Tag: "publications",
+			Response: publications.Publication{},
+		},
+		"userExport": {
+			Summary: "Export all the items as a zip archive", Tag: "export",
+			ContentType: "application/zip",
+		},
+		"userExportEncrypted": {
+			Summary: "Export all the items as an archive encrypted with a passphrase", Tag: "export",
+			Body: exportInput{}, ContentType: "application/octet-stream",
+		},
+		"userImport": {
+			Summary: "Import notes from Evernote, Google Keep or Markdown in the background, an encrypted archive is decrypted with the X-Import-Passphrase header", Tag: "import",
+			Query: []queryParam{
+				{Name: "format", Description: "enex, keep or markdown, detected if empty"},
+				{Name: "dryRun", Description: "only parse and validate the notes", Type: "boolean"},
+				{Name: "name", Description: "name of the file, if the body is the file"},
+			},
+			Upload: true, Response: importer.Job{}, Status: http.StatusAccepted,
+		},
+		"userImportJob": {
+			Summary: "Status and report of an import", Tag: "import",
+			Response: importer.Job{},
+		},
+		"userAddWebhook": {
+			Summary: "Register a webhook for the events of the items, the response has the signing secret", Tag: "webhooks",
+			Body: webhookInput{}, Response: webhooks.Webhook{}, Status: http.StatusCreated,
+		},
+		"userWebhooks": {
+			Summary: "List the webhooks", Tag: "webhooks",
+			Response: []webhooks.Webhook{},
+		},
+		"userDeleteWebhook": {
+			Summary: "Delete a webhook and its deliveries", Tag: "webhooks",
+			Response: webhooks.Webhook{},
+		},
+		"userWebhookDeliveries": {
+			Summary: "List the latest deliveries of a webhook, with the response of every attempt", Tag: "webhooks",
+			Response: []webhooks.Delivery{},
+		},
+		"userReplayDelivery": {
+			Summary: "Deliver the payload of a delivery again, without the content of the item", Tag: "webhooks",
+			Response: webhooks.Delivery{}, Status: http.StatusAccepted,
+		},
+		"userSyncChanges": {
+			Summary: "Changes of the items after a sequence, including the deleted items", Tag: "sync",
+			Query: []queryParam{
+				{Name: "since", Description: "sequence of the last change synced, 0 to fetch all the items", Type: "integer"},
+				{Name: "limit", Description: "maximum number of changes", Type: "integer"},
+			},
+			Response: items.Changes{},
+		},
+		"userSync": {
+			Summary: "Save the changes made by a client, conflicting updates are saved as copies", Tag: "sync",
+			Body: syncInput{}, Response: []users.SyncResult{},
+		},
+		"userEvents": {
+			Summary: "Stream the changes of the items as server-sent events", Tag: "events",
+			Query: []queryParam{
+				{Name: "lastEventId", Description: "ID of the last event received, to resume from if the Last-Event-ID header can't be sent", Type: "integer"},
+			},
+			ContentType: "text/event-stream",
+		},
+		"createSecret": {
+			Summary: "Create a one time secret", Tag: "secrets",
+			Body: secretInput{}, Response: secrets.Created{}, Status: http.StatusCreated,
+		},
+		"readSecret": {
+			Summary: "Read a secret, it's burnt on its last view", Tag: "secrets", Public: true,
+			Response: secrets.Secret{},
+		},
+		"readPublication": {
+			Summary: "Read a publication, as HTML or JSON, the key is in the X-Publication-Key header", Tag: "publications", Public: true,
+			Query:    []queryParam{{Name: "format", Description: "json for a JSON response"}},
+			Response: publications.Snapshot{},
+		},
+		"openPublication": {
+			Summary: "Read a publication, the key and the password are in the form", Tag: "publications", Public: true,
+			Body: passwordInput{}, BodyType: "application/x-www-form-urlencoded", Response: publications.Snapshot{},
+		},
+		"legacyPublication": {
+			Summary: "Redirect a link with the key in the path to the link with the key in the fragment", Tag: "publications", Public: true,
+			Query:  []queryParam{{Name: "format", Description: "json to read the publication instead"}},
+			Status: http.StatusMovedPermanently,
+		},
+	}
+)
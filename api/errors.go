@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+type requestIDKey string
+
+const (
+	requestIDCtxKey = requestIDKey("requestID")
+	// requestIDHeader is the header with the ID of the request, a valid ID sent by the client is
+	// used as is, so that requests can be traced across services
+	requestIDHeader = "X-Request-ID"
+
+	// internalMessage is sent instead of the message of internal errors, which may leak details
+	// of the implementation
+	internalMessage = "Sorry, something went wrong"
+)
+
+var (
+	errUnidentified  = errs.Unauthorized("Unidentified user")
+	errNotAuthorized = errs.Unauthorized("Sorry, you're not authorized to access this API")
+	errNoRoute       = errs.NotFound("Sorry, the requested resource does not exist")
+
+	validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+	// errStatus is the HTTP status of each kind of error
+	errStatus = map[errs.Kind]int{
+		errs.KindValidation:   http.StatusBadRequest,
+		errs.KindNotFound:     http.StatusNotFound,
+		errs.KindConflict:     http.StatusConflict,
+		errs.KindUnauthorized: http.StatusUnauthorized,
+		errs.KindForbidden:    http.StatusForbidden,
+		errs.KindTooLarge:     http.StatusRequestEntityTooLarge,
+		errs.KindInternal:     http.StatusInternalServerError,
+	}
+)
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Code    errs.Kind `json:"code"`
+	Message string    `json:"message"`
+	// Details lists the invalid fields of a validation error
+	Details   []errs.FieldError `json:"details"`
+	RequestID string            `json:"requestId"`
+}
+
+// RequestID is a middleware which sets the ID of the request in its context and in the
+// response header. The ID is generated if the client did not send a valid one.
+func RequestID(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	id := req.Header.Get(requestIDHeader)
+	if !validRequestID.MatchString(id) {
+		b := make([]byte, 16)
+		_, _ = io.ReadFull(rand.Reader, b)
+		id = hex.EncodeToString(b)
+	}
+
+	rw.Header().Set(requestIDHeader, id)
+	ctx := logger.ContextWith(req.Context(), "requestId", id)
+	next(rw, req.WithContext(context.WithValue(ctx, requestIDCtxKey, id)))
+}
+
+func requestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDCtxKey).(string)
+	return id
+}
+
+// errorStatus returns the kind and the HTTP status of the error
+func errorStatus(err error) (errs.Kind, int) {
+	kind := errs.KindOf(err)
+	status, ok := errStatus[kind]
+	if !ok {
+		return errs.KindInternal, http.StatusInternalServerError
+	}
+	return kind, status
+}
+
+// sendError responds with the error, and the status of its kind. Internal errors are logged
+// and their message is not sent.
+func (h *Handler) sendError(rw http.ResponseWriter, req *http.Request, err error) {
+	kind, status := errorStatus(err)
+	body := errorResponse{
+		Code:      kind,
+		Message:   err.Error(),
+		Details:   errs.FieldsOf(err),
+		RequestID: requestID(req),
+	}
+	if body.Details == nil {
+		body.Details = []errs.FieldError{}
+	}
+	if kind == errs.KindInternal {
+		logger.FromContext(req.Context(), h.Logger).Error(req.Method, routePattern(req), err.Error())
+		body.Message = internalMessage
+	}
+
+	rw.Header().Set(webgo.HeaderContentType, webgo.JSONContentType)
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(body)
+}
+
+// NotFound responds to requests which do not match any route
+func (h *Handler) NotFound(rw http.ResponseWriter, req *http.Request) {
+	h.sendError(rw, req, errNoRoute)
+}
+
+// decodeJSON decodes the JSON body of a request into v
+func decodeJSON(r io.Reader, v interface{}) error {
+	err := json.NewDecoder(r).Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return errs.TooLarge("Sorry, the request body is too large")
+	}
+	return errs.Invalid("Sorry, the request body should be valid JSON: " + err.Error())
+}
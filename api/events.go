@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/events"
+)
+
+const (
+	// lastEventIDHeader is the header sent by the clients reconnecting to the events. Clients
+	// which can't set headers can use the lastEventId query parameter instead.
+	lastEventIDHeader = "Last-Event-ID"
+	// eventsKeepAlive is the interval of the comments sent to keep the connection open through
+	// the proxies
+	eventsKeepAlive = 30 * time.Second
+)
+
+// userEvents streams the changes of the items owned by, or shared with, the user as
+// server-sent events. Events have only the IDs and versions of the items, the client fetches the
+// items it needs. A reset event means some events were missed, and the client should fetch all
+// the items again.
+func (h *Handler) userEvents(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	lastID := strings.TrimSpace(req.Header.Get(lastEventIDHeader))
+	if lastID == "" {
+		lastID = strings.TrimSpace(req.URL.Query().Get("lastEventId"))
+	}
+	lastIDInt, _ := strconv.ParseInt(lastID, 10, 64)
+
+	evs, err := h.users(req).Events(req.Context(), user, lastIDInt)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	rw = streamWriter(rw, req)
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	flush(rw)
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-req.Context().Done():
+			return
+		case e, ok := <-evs:
+			if !ok {
+				// the client fell behind, it reconnects and resumes from its last event
+				return
+			}
+			err = writeEvent(rw, e)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(rw, ": keep-alive\n\n")
+		}
+		if err != nil {
+			return
+		}
+		flush(rw)
+	}
+}
+
+// writeEvent writes the event in the format of server-sent events
+func writeEvent(rw http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+func flush(rw http.ResponseWriter) {
+	f, ok := rw.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	// passphraseHeader is the header used to send the passphrase of an encrypted export
+	passphraseHeader = "X-Export-Passphrase"
+)
+
+// exportInput is the payload to export an encrypted archive
+type exportInput struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// userExport streams an archive of all the items of the user. The archive is encrypted if a
+// passphrase is provided, in the passphrase header or in the JSON body of a POST request.
+func (h *Handler) userExport(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	passphrase := req.Header.Get(passphraseHeader)
+	if req.Method == http.MethodPost && req.ContentLength != 0 {
+		input := exportInput{}
+		err := decodeJSON(req.Body, &input)
+		if err != nil {
+			h.sendError(rw, req, err)
+			return
+		}
+		passphrase = input.Passphrase
+	}
+
+	name := fmt.Sprintf("notes-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	contentType := "application/zip"
+	if passphrase != "" {
+		name += ".enc"
+		contentType = "application/octet-stream"
+	}
+
+	rw = streamWriter(rw, req)
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	rw.Header().Set("Cache-Control", "no-store")
+
+	// errors are not sent since the response has already started. An incomplete archive is
+	// detected as corrupt by the client, as the zip directory is written at the end.
+	h.users(req).Export(user, rw, passphrase)
+}
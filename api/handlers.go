@@ -1,18 +1,29 @@
 package api
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/items"
 	"github.com/bnkamalesh/notes/pkg/users"
 	"github.com/bnkamalesh/webgo"
 )
 
+const (
+	// version is the version of the app
+	version               = "0.5.0"
+	mergePatchContentType = "application/merge-patch+json"
+)
+
+var errContentType = errs.Field("Content-Type", "Unsupported content type, expected "+mergePatchContentType)
+
 func paginationParams(req *http.Request) (int, int) {
 	start := strings.TrimSpace(req.URL.Query().Get("start"))
-	limit := strings.TrimSpace(req.URL.Query().Get("start"))
+	limit := strings.TrimSpace(req.URL.Query().Get("limit"))
 	startInt := 0
 	limitInt := 0
 	if start != "" {
@@ -24,37 +35,57 @@ func paginationParams(req *http.Request) (int, int) {
 	return startInt, limitInt
 }
 
+// listOptions returns the item listing options from the request query parameters
+func listOptions(req *http.Request) items.ListOptions {
+	query := req.URL.Query()
+	start, limit := paginationParams(req)
+	total, _ := strconv.ParseBool(strings.TrimSpace(query.Get("total")))
+	return items.ListOptions{
+		Sort:   strings.TrimSpace(query.Get("sort")),
+		After:  strings.TrimSpace(query.Get("after")),
+		Start:  start,
+		Limit:  limit,
+		Total:  total,
+		Status: strings.TrimSpace(query.Get("status")),
+	}
+}
+
+// nextPageURL returns the URL of the next page, with all the query parameters of the current
+// request retained
+func nextPageURL(req *http.Request, next string) string {
+	query := req.URL.Query()
+	query.Del("start")
+	query.Set("after", next)
+	u := url.URL{
+		Path:     req.URL.Path,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
 // Home is the home page handler
 func (h *Handler) Home(rw http.ResponseWriter, req *http.Request) {
 	webgo.R200(rw, map[string]string{
-		"version": "0.5.0",
+		"version": version,
 	})
 }
 
 func (h *Handler) userSignup(rw http.ResponseWriter, req *http.Request) {
 	input := make(map[string]string, 3)
-	err := json.NewDecoder(req.Body).Decode(&input)
+	err := decodeJSON(req.Body, &input)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	user, err := users.New(input)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 
-	services := h.Services
-	user, err = services.Users.Create(*user)
+	user, err = h.users(req).Create(*user)
 	if err != nil {
-		switch err {
-		case users.ErrUsrExists:
-			{
-				webgo.SendResponse(rw, err.Error(), 409)
-			}
-		default:
-			webgo.R400(rw, err.Error())
-		}
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, user)
@@ -62,22 +93,30 @@ func (h *Handler) userSignup(rw http.ResponseWriter, req *http.Request) {
 
 func (h *Handler) userLogin(rw http.ResponseWriter, req *http.Request) {
 	input := make(map[string]string, 2)
-	err := json.NewDecoder(req.Body).Decode(&input)
+	err := decodeJSON(req.Body, &input)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
-	services := h.Services
-	user, err := services.Users.Authenticate(input["email"], input["password"], req.RemoteAddr)
+	user, err := h.users(req).Authenticate(input["email"], input["password"], users.TokenSalt(req))
 	if err != nil {
-		switch err {
-		case users.ErrUsrNotExists:
-			{
-				webgo.R404(rw, err.Error())
-				return
-			}
-		}
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, user)
+}
+
+// userRefreshToken issues a new auth token in place of the one in the request
+func (h *Handler) userRefreshToken(rw http.ResponseWriter, req *http.Request) {
+	authToken := strings.TrimSpace(req.Header.Get("Authorization"))
+	if authToken == "" {
+		h.sendError(rw, req, errNotAuthorized)
+		return
+	}
+
+	user, err := h.users(req).RefreshToken(authToken, users.TokenSalt(req))
+	if err != nil {
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, user)
@@ -87,15 +126,48 @@ func (h *Handler) userLogin(rw http.ResponseWriter, req *http.Request) {
 func (h *Handler) userItems(rw http.ResponseWriter, req *http.Request) {
 	user := getUser(req)
 	if user == nil {
-		webgo.R403(rw, "Unidentified user")
+		h.sendError(rw, req, errUnidentified)
 		return
 	}
 
-	services := h.Services
-	start, limit := paginationParams(req)
-	items, err := services.Users.Items(user, start, limit)
+	shared, _ := strconv.ParseBool(strings.TrimSpace(req.URL.Query().Get("shared")))
+	if shared {
+		ii, err := h.users(req).SharedItems(user)
+		if err != nil {
+			h.sendError(rw, req, err)
+			return
+		}
+		webgo.R200(rw, items.Page{Items: ii})
+		return
+	}
+
+	opts := listOptions(req)
+	page, err := h.users(req).Items(user, opts)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	if page.Next != "" {
+		rw.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextPageURL(req, page.Next)))
+	}
+	webgo.R200(rw, page)
+}
+
+// userSearchItems returns the items owned by the logged in user matching the search query
+func (h *Handler) userSearchItems(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	q := strings.TrimSpace(req.URL.Query().Get("q"))
+	_, limit := paginationParams(req)
+
+	items, err := h.users(req).SearchItems(user, q, limit)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, items)
@@ -105,19 +177,18 @@ func (h *Handler) userItems(rw http.ResponseWriter, req *http.Request) {
 func (h *Handler) userCreateItem(rw http.ResponseWriter, req *http.Request) {
 	user := getUser(req)
 	if user == nil {
-		webgo.R403(rw, "Unidentified user")
+		h.sendError(rw, req, errUnidentified)
 		return
 	}
 	input := make(map[string]string, 0)
-	err := json.NewDecoder(req.Body).Decode(&input)
+	err := decodeJSON(req.Body, &input)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
-	services := h.Services
-	item, err := services.Users.CreateItem(user, input)
+	item, err := h.users(req).CreateItem(user, input)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, item)
@@ -127,15 +198,14 @@ func (h *Handler) userCreateItem(rw http.ResponseWriter, req *http.Request) {
 func (h *Handler) userReadItem(rw http.ResponseWriter, req *http.Request) {
 	user := getUser(req)
 	if user == nil {
-		webgo.R403(rw, "Unidentified user")
+		h.sendError(rw, req, errUnidentified)
 		return
 	}
 	wctx := webgo.Context(req)
 	id := wctx.Params["id"]
-	services := h.Services
-	item, err := services.Users.Item(user, id)
+	item, err := h.users(req).Item(user, id)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, item)
@@ -145,21 +215,51 @@ func (h *Handler) userReadItem(rw http.ResponseWriter, req *http.Request) {
 func (h *Handler) userUpdateItem(rw http.ResponseWriter, req *http.Request) {
 	user := getUser(req)
 	if user == nil {
-		webgo.R403(rw, "Unidentified user")
+		h.sendError(rw, req, errUnidentified)
 		return
 	}
 	input := make(map[string]string, 0)
-	err := json.NewDecoder(req.Body).Decode(&input)
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	wctx := webgo.Context(req)
+	id := wctx.Params["id"]
+	item, err := h.users(req).UpdateItem(user, id, input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, item)
+}
+
+// userPatchItem partially updates an existing item for the user, the request body is a
+// JSON Merge Patch
+func (h *Handler) userPatchItem(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	contentType := strings.TrimSpace(strings.Split(req.Header.Get(webgo.HeaderContentType), ";")[0])
+	if contentType != "" && contentType != mergePatchContentType && contentType != webgo.JSONContentType {
+		h.sendError(rw, req, errContentType)
+		return
+	}
+
+	patch := make(map[string]interface{}, 0)
+	err := decodeJSON(req.Body, &patch)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	wctx := webgo.Context(req)
 	id := wctx.Params["id"]
-	services := h.Services
-	item, err := services.Users.UpdateItem(user, id, input)
+	item, err := h.users(req).PatchItem(user, id, patch)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, item)
@@ -169,16 +269,15 @@ func (h *Handler) userUpdateItem(rw http.ResponseWriter, req *http.Request) {
 func (h *Handler) userDeleteItem(rw http.ResponseWriter, req *http.Request) {
 	user := getUser(req)
 	if user == nil {
-		webgo.R403(rw, "Unidentified user")
+		h.sendError(rw, req, errUnidentified)
 		return
 	}
 	wctx := webgo.Context(req)
 	id := wctx.Params["id"]
-	services := h.Services
 
-	item, err := services.Users.DeleteItem(user, id)
+	item, err := h.users(req).DeleteItem(user, id)
 	if err != nil {
-		webgo.R400(rw, err.Error())
+		h.sendError(rw, req, err)
 		return
 	}
 	webgo.R200(rw, item)
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bnkamalesh/webgo"
+)
+
+// liveness is the response of the liveness probe
+type liveness struct {
+	Status string `json:"status"`
+}
+
+// appLiveness responds as long as the app is running, it does not check the dependencies so
+// that the app is not restarted when one of them is down
+func (h *Handler) appLiveness(rw http.ResponseWriter, req *http.Request) {
+	webgo.R200(rw, liveness{Status: "ok"})
+}
+
+// appReadiness responds with the checks of the dependencies, with the status 503 if the app
+// should not get requests: a dependency is down or the app is shutting down
+func (h *Handler) appReadiness(rw http.ResponseWriter, req *http.Request) {
+	report := h.Services.Health.Ready()
+	code := http.StatusOK
+	if !report.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	webgo.SendResponse(rw, report, code)
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	server, _ := newServer(t)
+	for path, expected := range map[string]string{
+		"/healthz": `"status":"ok"`,
+		"/readyz":  `"ready":true,"checks":[{"name":"cache","up":true`,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), expected) {
+			t.Errorf("Expected %s in the response of %s, got %d %s", expected, path, resp.StatusCode, body)
+		}
+	}
+}
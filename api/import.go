@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/archive"
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/importer"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+)
+
+// importPassphraseHeader is the header used to send the passphrase of an encrypted archive
+const importPassphraseHeader = "X-Import-Passphrase"
+
+var errDryRun = errs.Field("dryRun", "Sorry, dryRun should be true or false")
+
+// decryptUpload returns the zip file of the upload if it's an encrypted archive, otherwise the
+// upload as is
+func decryptUpload(body io.Reader, passphrase string) (io.Reader, error) {
+	br := bufio.NewReader(body)
+	// the upload may be shorter than the header, it's imported as is
+	head, _ := br.Peek(64)
+	if !archive.IsEncrypted(head) {
+		return br, nil
+	}
+	return archive.Decrypt(br, passphrase)
+}
+
+// userImport starts importing the uploaded file as items of the user, in the background. The
+// format is in the 'format' query parameter, it's detected if not provided. If 'dryRun' is
+// true, the notes are only parsed and validated. An encrypted archive is decrypted with the
+// passphrase in the passphrase header. The job returned can be polled for its status and report.
+func (h *Handler) userImport(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	query := req.URL.Query()
+	dryRun := false
+	if v := query.Get("dryRun"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			h.sendError(rw, req, errDryRun)
+			return
+		}
+	}
+
+	name, _, body, err := attachmentUpload(req)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	body, err = decryptUpload(body, req.Header.Get(importPassphraseHeader))
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	// the import runs after the response is sent, so it's not canceled with the request, and is
+	// traced in a span of its own
+	jobCtx := context.WithoutCancel(req.Context())
+	services := h.Services
+	job, err := services.Importer.Start(
+		user.ID,
+		name,
+		body,
+		query.Get("format"),
+		dryRun,
+		func(src importer.Source, format string, progress func(*importer.Report)) (*importer.Report, error) {
+			ctx, span := tracing.Start(jobCtx, "importer.Job", tracing.KindInternal)
+			defer span.End()
+			us := services.Users.WithContext(ctx)
+			report, err := us.Import(user, src, format, dryRun, progress)
+			if err != nil {
+				span.SetError(err)
+			}
+			return report, err
+		},
+	)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.SendResponse(rw, job, http.StatusAccepted)
+}
+
+// userImportJob returns the status of an import of the user
+func (h *Handler) userImportJob(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	services := h.Services
+	job, err := services.Importer.Job(wctx.Params["jobID"])
+	if err == nil && job.UserID != user.ID {
+		err = importer.ErrJobNotFound
+	}
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, job)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/metrics"
+)
+
+type routeKey string
+
+const (
+	routeCtxKey = routeKey("route")
+	// notFoundRoute is the route name of the requests which did not match any route
+	notFoundRoute = "notFound"
+)
+
+var (
+	httpRequests = metrics.NewCounter(
+		"notes_http_requests_total",
+		"Number of HTTP requests, by route and status code",
+		"route", "code",
+	)
+	httpDuration = metrics.NewHistogram(
+		"notes_http_request_duration_seconds",
+		"Duration of the HTTP requests, by route",
+		metrics.DefaultBuckets,
+		"route",
+	)
+)
+
+// requestRoute is the route which matched the request
+type requestRoute struct {
+	name string
+	// pattern is logged instead of the path of the request, which may have secrets like the
+	// keys of the publications
+	pattern string
+}
+
+// withRoute returns the request with a holder of the name of its route in the context, set by
+// the first handler of the route. The holder is shared by the middlewares.
+func withRoute(req *http.Request) (*http.Request, *requestRoute) {
+	route, ok := req.Context().Value(routeCtxKey).(*requestRoute)
+	if ok {
+		return req, route
+	}
+	route = &requestRoute{name: notFoundRoute}
+	return req.WithContext(context.WithValue(req.Context(), routeCtxKey, route)), route
+}
+
+// routePattern returns the pattern of the route of the request, to be logged instead of its
+// path. The name of the route is returned if it has no pattern, e.g. if no route matched.
+func routePattern(req *http.Request) string {
+	route, ok := req.Context().Value(routeCtxKey).(*requestRoute)
+	if !ok {
+		return notFoundRoute
+	}
+	if route.pattern == "" {
+		return route.name
+	}
+	return route.pattern
+}
+
+// statusWriter keeps the status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Metrics is a middleware which counts the requests and observes their duration, by the name of
+// the route. It should be added before the Streams middleware.
+func Metrics(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	req, route := withRoute(req)
+	w := &statusWriter{ResponseWriter: rw, code: http.StatusOK}
+	next(w, req)
+
+	httpRequests.Inc(route.name, strconv.Itoa(w.code))
+	httpDuration.Since(start, route.name)
+}
+
+// namedRoute returns the handler of the route, which sets the name and the pattern of the route
+// for the middlewares and the logs of the request, and then runs the handlers of the route with
+// the route in the context of the request
+func namedRoute(name, pattern string, handlers []http.HandlerFunc) []http.HandlerFunc {
+	next := chain(handlers)
+	named := func(rw http.ResponseWriter, req *http.Request) {
+		route, ok := req.Context().Value(routeCtxKey).(*requestRoute)
+		if ok {
+			route.name = name
+			route.pattern = pattern
+		}
+		next(rw, req.WithContext(logger.ContextWith(req.Context(), "route", name)))
+	}
+	return []http.HandlerFunc{named}
+}
+
+// writeTracker keeps whether the response was written
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeTracker) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// chain returns a handler which runs the handlers in order on the same request, until one of
+// them writes the response, like the router does with the handlers of a route
+func chain(handlers []http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		w := &writeTracker{ResponseWriter: rw}
+		for _, handler := range handlers {
+			handler(w, req)
+			if w.written {
+				return
+			}
+		}
+	}
+}
+
+// appMetrics responds with the metrics of the app in the Prometheus text format. It's an admin
+// route, the metrics are not public.
+func (h *Handler) appMetrics(rw http.ResponseWriter, req *http.Request) {
+	buf := &bytes.Buffer{}
+	_, err := metrics.Default.WriteTo(buf)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	rw.Header().Set(webgo.HeaderContentType, metrics.ContentType)
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(buf.Bytes())
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	server, _ := newServer(t)
+	token := login(t, server)
+	resp := call(t, http.MethodPost, server.URL+"/v1/login", "", map[string]string{"email": "jane@example.com", "password": "wrong"}, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	call(t, http.MethodPost, server.URL+"/v1/items", token, map[string]string{"title": "Groceries", "description": "Milk"}, nil)
+
+	// the metrics are only served to the admin
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected %d without the admin token, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/metrics", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	req.Header.Set("X-Admin-Token", adminToken)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("Expected the metrics, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for _, m := range []string{
+		`notes_http_requests_total{route="v1.userCreateItem",code="200"}`,
+		`notes_http_requests_total{route="v1.userLogin",code="401"}`,
+		`notes_http_request_duration_seconds_count{route="userSignup"}`,
+		`notes_login_failures_total{reason="wrongPassword"}`,
+		`notes_crypto_duration_seconds_count{operation="encrypt"}`,
+		`notes_sessions_active `,
+	} {
+		if !strings.Contains(string(body), "\n"+m) {
+			t.Errorf("Expected the metric %s, got:\n%s", m, body)
+		}
+	}
+}
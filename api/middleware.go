@@ -5,14 +5,17 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/users"
-	"github.com/bnkamalesh/webgo"
 )
 
 type userKey string
 
+type streamKey string
+
 const (
-	userCtxKey = userKey("user")
+	userCtxKey   = userKey("user")
+	streamCtxKey = streamKey("stream")
 )
 
 func getUser(req *http.Request) *users.User {
@@ -22,19 +25,55 @@ func getUser(req *http.Request) *users.User {
 
 func (h *Handler) mwareAuthenticate(rw http.ResponseWriter, req *http.Request) {
 	authToken := strings.TrimSpace(req.Header.Get("Authorization"))
-	services := h.Services
-	user, err := services.Users.AuthUser(authToken, req.RemoteAddr)
+	user, err := h.users(req).AuthUser(authToken, users.TokenSalt(req))
 	if err != nil || authToken == "" {
-		webgo.R403(rw, "Sorry, you're not authorized to access this API")
+		h.sendError(rw, req, errNotAuthorized)
 		return
 	}
 
 	reqwc := req.WithContext(
 		context.WithValue(
-			req.Context(),
+			logger.ContextWith(req.Context(), "userId", user.ID),
 			userCtxKey,
 			user,
 		),
 	)
 	*req = *reqwc
 }
+
+// Streams is a middleware which keeps the response writer of the server in the request context,
+// for the handlers streaming their response. The router lets handlers write only once to the
+// response, so streams are written to the writer of the server instead. It should be the last
+// middleware added to the router, so that it gets the writer of the server.
+func Streams(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	next(rw, req.WithContext(context.WithValue(req.Context(), streamCtxKey, rw)))
+}
+
+// streamResponseWriter writes the body to the writer of the server, while the status goes
+// through the router and the middlewares
+type streamResponseWriter struct {
+	http.ResponseWriter
+	server http.ResponseWriter
+}
+
+func (w *streamResponseWriter) Write(b []byte) (int, error) {
+	return w.server.Write(b)
+}
+
+// Flush sends the buffered data to the client
+func (w *streamResponseWriter) Flush() {
+	f, ok := w.server.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
+
+// streamWriter returns a response writer which can be written to several times. It's the
+// response writer of the handler if the Streams middleware is not used.
+func streamWriter(rw http.ResponseWriter, req *http.Request) http.ResponseWriter {
+	server, ok := req.Context().Value(streamCtxKey).(http.ResponseWriter)
+	if !ok {
+		return rw
+	}
+	return &streamResponseWriter{ResponseWriter: rw, server: server}
+}
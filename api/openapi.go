@@ -0,0 +1,366 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document conforms to
+const openAPIVersion = "3.0.3"
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	pathParam = regexp.MustCompile(`:(\w+)`)
+
+	// openAPIDoc is the JSON of the document, it's built once since the routes do not change
+	openAPIDoc  []byte
+	openAPIOnce sync.Once
+)
+
+// schemas builds JSON schemas of Go types. Named structs are added to the components and
+// referenced, so that each schema is defined once.
+type schemas struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+	types      map[string]reflect.Type
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: map[string]interface{}{},
+		names:      map[reflect.Type]string{},
+		types:      map[string]reflect.Type{},
+	}
+}
+
+// name returns the name of the component of a struct. The package is added to the name only
+// if two packages have a struct with the same name.
+func (s *schemas) name(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := exportedName(t.Name())
+	if _, ok := s.types[name]; ok {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = exportedName(pkg) + name
+	}
+	s.names[t] = name
+	s.types[name] = t
+	return name
+}
+
+// exportedName returns the name with its first letter in upper case
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// schema returns the schema of a Go type, as encoded by encoding/json
+func (s *schemas) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := s.name(t)
+		if _, ok := s.components[name]; !ok {
+			// set before building the object, for types which refer to themselves
+			s.components[name] = nil
+			s.components[name] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object returns the schema of a struct, fields of embedded structs are inlined
+func (s *schemas) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	s.fields(t, properties, &required)
+
+	obj := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func (s *schemas) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			s.fields(ft, properties, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = s.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// queryParam is a query parameter of a route
+type queryParam struct {
+	Name        string
+	Description string
+	// Type is the JSON schema type of the parameter, string if empty
+	Type string
+}
+
+// routeDoc documents a route in the OpenAPI document
+type routeDoc struct {
+	Summary string
+	Tag     string
+	// Public is true if the route does not require authentication
+	Public bool
+	// Admin is true if the route requires the admin token instead of the auth token of a user
+	Admin bool
+	Query []queryParam
+	// Body is a value of the type of the request body, nil if there's no body
+	Body interface{}
+	// BodyType is the content type of the body if it's not JSON, e.g. a form
+	BodyType string
+	// Upload is true if the body is a file, either the 'file' field of a multipart form or
+	// the raw body
+	Upload bool
+	// Response is a value of the type of the data in the response, nil if there's no data
+	Response interface{}
+	// Status is the status of a successful response, 200 if it's 0
+	Status int
+	// ContentType is the content type of a response which is not JSON, e.g. a download
+	ContentType string
+}
+
+// operation returns the OpenAPI operation of the route
+func (d routeDoc) operation(s *schemas, r *webgo.Route) map[string]interface{} {
+	params := []interface{}{}
+	for _, m := range pathParam.FindAllStringSubmatch(r.Pattern, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range d.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		params = append(params, map[string]interface{}{
+			"name":        q.Name,
+			"in":          "query",
+			"description": q.Description,
+			"schema":      map[string]interface{}{"type": typ},
+		})
+	}
+
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case d.ContentType != "":
+		success["content"] = map[string]interface{}{
+			d.ContentType: map[string]interface{}{
+				"schema": map[string]interface{}{"type": "string", "format": "binary"},
+			},
+		}
+	case d.Response != nil:
+		// webgo wraps the data of every JSON response
+		success["content"] = map[string]interface{}{
+			webgo.JSONContentType: map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"data":   s.schema(reflect.TypeOf(d.Response)),
+						"status": map[string]interface{}{"type": "integer"},
+					},
+				},
+			},
+		}
+	}
+
+	op := map[string]interface{}{
+		"operationId": r.Name,
+		"summary":     d.Summary,
+		"tags":        []string{d.Tag},
+		"parameters":  params,
+		"responses": map[string]interface{}{
+			strconv.Itoa(status): success,
+			"default":            map[string]interface{}{"$ref": "#/components/responses/Error"},
+		},
+	}
+
+	binary := map[string]interface{}{"type": "string", "format": "binary"}
+	switch {
+	case d.Upload:
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"file": binary},
+						"required":   []string{"file"},
+					},
+				},
+				"application/octet-stream": map[string]interface{}{"schema": binary},
+			},
+		}
+	case d.Body != nil:
+		bodyType := d.BodyType
+		if bodyType == "" {
+			bodyType = webgo.JSONContentType
+		}
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				bodyType: map[string]interface{}{
+					"schema": s.schema(reflect.TypeOf(d.Body)),
+				},
+			},
+		}
+	}
+
+	switch {
+	case d.Public:
+		// overrides the default security of the document
+		op["security"] = []interface{}{}
+	case d.Admin:
+		op["security"] = []interface{}{
+			map[string]interface{}{"adminToken": []string{}},
+		}
+	}
+	return op
+}
+
+// openAPI returns the OpenAPI document of the routes. The routes are documented without the
+// version, which is in the URL of the server.
+func openAPI(routes []*webgo.Route) map[string]interface{} {
+	s := newSchemas()
+	paths := map[string]interface{}{}
+	for _, r := range routes {
+		doc, ok := routeDocs[r.Name]
+		if !ok {
+			continue
+		}
+
+		path := pathParam.ReplaceAllString(r.Pattern, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = doc.operation(s, r)
+	}
+
+	errSchema := s.schema(reflect.TypeOf(errorResponse{}))
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Notes API",
+			"version": version,
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": apiVersion},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"token": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": s.components,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error",
+					"content": map[string]interface{}{
+						webgo.JSONContentType: map[string]interface{}{"schema": errSchema},
+					},
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "The auth token returned on login",
+				},
+				"adminToken": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        adminTokenHeader,
+					"description": "The token of the admin API, configured on the server",
+				},
+			},
+		},
+	}
+}
+
+// openAPISpec serves the OpenAPI document of the API
+func (h *Handler) openAPISpec(rw http.ResponseWriter, req *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIDoc, _ = json.Marshal(openAPI(h.routes()))
+	})
+	rw.Header().Set(webgo.HeaderContentType, webgo.JSONContentType)
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(openAPIDoc)
+}
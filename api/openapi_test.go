@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteDocs(t *testing.T) {
+	h := Handler{}
+	names := map[string]bool{}
+	for _, r := range h.routes() {
+		names[r.Name] = true
+		if _, ok := routeDocs[r.Name]; !ok {
+			t.Errorf("Route '%s' (%s %s) is not documented in routeDocs", r.Name, r.Method, r.Pattern)
+		}
+	}
+	for name := range routeDocs {
+		if !names[name] {
+			t.Errorf("routeDocs documents '%s', which is not a route", name)
+		}
+	}
+}
+
+// refs returns all the $ref in the JSON document
+func refs(v interface{}, found map[string]bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if ref, ok := child.(string); ok && k == "$ref" {
+				found[ref] = true
+				continue
+			}
+			refs(child, found)
+		}
+	case []interface{}:
+		for _, child := range val {
+			refs(child, found)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	h := Handler{}
+	rw := httptest.NewRecorder()
+	h.openAPISpec(rw, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rw.Code)
+	}
+
+	doc := map[string]interface{}{}
+	err := json.Unmarshal(rw.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	paths := doc["paths"].(map[string]interface{})
+	item, ok := paths["/items/{id}"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected the path '/items/{id}'")
+	}
+	for _, method := range []string{"get", "put", "patch", "delete"} {
+		if _, ok := item[method]; !ok {
+			t.Errorf("Expected the method '%s' of '/items/{id}'", method)
+		}
+	}
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"User", "Item", "Entry", "Page"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("Expected the schema '%s'", name)
+		}
+	}
+	user := schemas["User"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := user["password"]; ok {
+		t.Error("Fields not encoded in JSON should not be in the schema")
+	}
+
+	found := map[string]bool{}
+	refs(doc, found)
+	for ref := range found {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if name == ref {
+			continue
+		}
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("Unresolved reference '%s'", ref)
+		}
+	}
+}
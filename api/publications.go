@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/publications"
+)
+
+const (
+	// keyHeader is the header used to send the key of a publication. The key is in the fragment
+	// of the public link, so it's never part of the path of the request.
+	keyHeader = "X-Publication-Key"
+	// passwordHeader is the header used to send the password of a protected publication
+	passwordHeader = "X-Publication-Password"
+)
+
+var publicationTpl = template.Must(template.New("publication").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Snapshot}}{{.Snapshot.Title}}{{else}}Notes{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+.description { white-space: pre-wrap; }
+.checklist { list-style: none; padding: 0; }
+.meta, .error { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+{{if .Snapshot}}
+<h1>{{.Snapshot.Title}}</h1>
+<p class="meta">Published {{.Snapshot.PublishedAt.Format "Jan 2, 2006"}}{{if .Snapshot.DueAt}}, due {{.Snapshot.DueAt.Format "Jan 2, 2006 15:04 MST"}}{{end}}</p>
+<div class="description">{{.Snapshot.Description}}</div>
+{{if .Snapshot.Checklist}}
+<ul class="checklist">
+{{range .Snapshot.Checklist}}<li><input type="checkbox" disabled{{if .Checked}} checked{{end}}> {{.Text}}</li>
+{{end}}</ul>
+{{end}}
+{{else if .Form}}
+<form method="post" id="open">
+<input type="hidden" name="key" value="{{.Key}}">
+{{if .Protected}}
+<p>This note is password protected.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+{{else}}
+<noscript><p class="error">Sorry, JavaScript is required to open this note.</p></noscript>
+{{end}}
+</form>
+<script>
+var form = document.getElementById("open");
+if (!form.elements.key.value) {
+	form.elements.key.value = decodeURIComponent(location.hash.slice(1));
+}
+{{if not .Protected}}if (form.elements.key.value) {
+	form.submit();
+}{{end}}
+</script>
+{{else}}
+<p class="error">{{.Error}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// publicationPage is the data used to render a publication as HTML
+type publicationPage struct {
+	Snapshot  *publications.Snapshot
+	Protected bool
+	// Form is true if the page posts the key from the fragment of the link, and the password
+	// of a protected publication
+	Form  bool
+	Key   string
+	Error string
+}
+
+// publishInput is the payload to publish an item
+type publishInput struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// userPublishItem publishes an item of the user at a public link
+func (h *Handler) userPublishItem(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := publishInput{}
+	if req.ContentLength != 0 {
+		err := decodeJSON(req.Body, &input)
+		if err != nil {
+			h.sendError(rw, req, err)
+			return
+		}
+	}
+
+	wctx := webgo.Context(req)
+	published, err := h.users(req).PublishItem(user, wctx.Params["id"], input.Password, input.ExpiresAt)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R201(rw, published)
+}
+
+// userItemPublications returns all the publications of an item of the user
+func (h *Handler) userItemPublications(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	pp, err := h.users(req).Publications(user, wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, pp)
+}
+
+// userUnpublishItem removes a publication of an item of the user
+func (h *Handler) userUnpublishItem(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	p, err := h.users(req).UnpublishItem(user, wctx.Params["id"], wctx.Params["publicationID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, p)
+}
+
+// wantsJSON returns true if the client prefers JSON over HTML
+func wantsJSON(req *http.Request) bool {
+	if req.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(req.Header.Get("Accept"), "application/json")
+}
+
+// readPublication serves a publication publicly, as JSON or HTML. The key and the password of a
+// protected publication are read from the headers, or from the form for HTML. Browsers opening
+// the link get a page which posts the key from the fragment of the link.
+func (h *Handler) readPublication(rw http.ResponseWriter, req *http.Request) {
+	// the key is in the form of the page, it should not leak to other sites or caches
+	rw.Header().Set("Referrer-Policy", "no-referrer")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.Header().Set("X-Robots-Tag", "noindex")
+
+	key := req.Header.Get(keyHeader)
+	password := req.Header.Get(passwordHeader)
+	if req.Method == http.MethodPost {
+		key = req.PostFormValue("key")
+		password = req.PostFormValue("password")
+	}
+
+	wctx := webgo.Context(req)
+	services := h.Services
+	id := wctx.Params["id"]
+
+	if wantsJSON(req) {
+		snapshot, _, err := openPublication(services.Publications, id, key, password)
+		if err != nil {
+			h.sendError(rw, req, err)
+			return
+		}
+		webgo.R200(rw, snapshot)
+		return
+	}
+
+	page := publicationPage{}
+	var err error
+	if key == "" {
+		var p *publications.Publication
+		p, err = services.Publications.Read(id)
+		if err == nil {
+			page.Form = true
+			page.Protected = p.Protected
+		}
+	} else {
+		page.Snapshot, page.Protected, err = openPublication(services.Publications, id, key, password)
+		page.Form = err != nil && page.Protected
+		page.Key = key
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		var kind errs.Kind
+		kind, status = errorStatus(err)
+		switch {
+		case err == publications.ErrPasswordRequired:
+			// the form is shown without an error
+		case kind == errs.KindInternal:
+			logger.FromContext(req.Context(), h.Logger).Error(req.Method, routePattern(req), err.Error())
+			page.Error = internalMessage
+		default:
+			page.Error = err.Error()
+		}
+	}
+	h.renderPublication(rw, req, page, status)
+}
+
+// renderPublication renders the page in a buffer first, the router allows a single write of
+// the response and the template is written in parts
+func (h *Handler) renderPublication(rw http.ResponseWriter, req *http.Request, page publicationPage, status int) {
+	buf := &bytes.Buffer{}
+	err := publicationTpl.Execute(buf, page)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	rw.Header().Set(webgo.HeaderContentType, webgo.HTMLContentType)
+	rw.WriteHeader(status)
+	rw.Write(buf.Bytes())
+}
+
+// legacyPublication serves the links created before the key was moved to the fragment. Browsers
+// are redirected to the current link, so that the key is not sent again, the other clients are
+// served as is.
+func (h *Handler) legacyPublication(rw http.ResponseWriter, req *http.Request) {
+	wctx := webgo.Context(req)
+	if wantsJSON(req) {
+		req.Header.Set(keyHeader, wctx.Params["key"])
+		h.readPublication(rw, req)
+		return
+	}
+
+	rw.Header().Set("Referrer-Policy", "no-referrer")
+	rw.Header().Set("Cache-Control", "no-store")
+	http.Redirect(rw, req, publications.URL(wctx.Params["id"], wctx.Params["key"]), http.StatusMovedPermanently)
+}
+
+// openPublication reads and decrypts a publication, it also returns if the publication is
+// password protected
+func openPublication(ps publications.Service, id, key, password string) (*publications.Snapshot, bool, error) {
+	p, err := ps.Read(id)
+	if err != nil {
+		return nil, false, err
+	}
+	snapshot, err := p.Open(key, password)
+	if err != nil {
+		return nil, p.Protected, err
+	}
+	return snapshot, p.Protected, nil
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestReadPublication(t *testing.T) {
+	server, logs := newServer(t)
+	token := login(t, server)
+
+	item := struct {
+		ID string `json:"id"`
+	}{}
+	call(t, http.MethodPost, server.URL+"/items", token, map[string]string{"title": "Runbook"}, &item)
+	published := struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+		URL string `json:"url"`
+	}{}
+	call(t, http.MethodPost, server.URL+"/items/"+item.ID+"/publish", token, nil, &published)
+	if published.URL != "/p/"+published.ID+"#"+published.Key {
+		t.Fatalf("Expected the key in the fragment of the link, got '%s'", published.URL)
+	}
+
+	// JSON clients send the key in the header
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/p/"+published.ID+"?format=json", nil)
+	req.Header.Set(keyHeader, published.Key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Runbook") {
+		t.Fatalf("Expected the snapshot, got %d %s", resp.StatusCode, body)
+	}
+
+	// browsers get a page posting the key from the fragment
+	resp, err = http.Get(server.URL + "/p/" + published.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `name="key"`) || strings.Contains(string(body), "Runbook") {
+		t.Fatalf("Expected the form posting the key, got %d %s", resp.StatusCode, body)
+	}
+	resp, err = http.PostForm(server.URL+"/p/"+published.ID, url.Values{"key": {published.Key}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<h1>Runbook</h1>") {
+		t.Fatalf("Expected the snapshot, got %d %s", resp.StatusCode, body)
+	}
+
+	// the links with the key in the path are redirected
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = noRedirect.Get(server.URL + "/p/" + published.ID + "/" + published.Key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != published.URL {
+		t.Fatalf("Expected a redirect to %s, got %d %s", published.URL, resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if strings.Contains(logs.String(), published.Key) {
+		t.Fatalf("Expected the key not to be logged, got %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "/p/:id/:key") {
+		t.Fatalf("Expected the pattern of the route to be logged, got %s", logs.String())
+	}
+}
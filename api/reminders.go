@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+)
+
+// reminderInput is the payload to add a reminder to an item
+type reminderInput struct {
+	At         time.Time `json:"at"`
+	Recurrence string    `json:"recurrence"`
+	Channel    string    `json:"channel"`
+	Target     string    `json:"target"`
+}
+
+// userAddReminder adds a reminder to an item of the user
+func (h *Handler) userAddReminder(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := reminderInput{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	r, err := h.users(req).AddReminder(
+		user,
+		wctx.Params["id"],
+		input.At,
+		input.Recurrence,
+		input.Channel,
+		input.Target,
+	)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R201(rw, r)
+}
+
+// userItemReminders returns all the reminders of an item of the user
+func (h *Handler) userItemReminders(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	rr, err := h.users(req).Reminders(user, wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, rr)
+}
+
+// userDeleteReminder deletes a reminder of an item of the user
+func (h *Handler) userDeleteReminder(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	r, err := h.users(req).DeleteReminder(user, wctx.Params["id"], wctx.Params["reminderID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, r)
+}
@@ -2,12 +2,46 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/bnkamalesh/webgo"
 )
 
-// Routes returns all the HTTP routes of the app
+// apiVersion is the prefix of all the routes of the current version of the API
+const apiVersion = "/v1"
+
+// Routes returns all the HTTP routes of the app. Every route is mounted under the API version,
+// and also without it, for the clients and the public links created before the API was
+// versioned.
 func (handler *Handler) Routes() []*webgo.Route {
+	routes := handler.routes()
+	all := make([]*webgo.Route, 0, len(routes)*2)
+	for _, r := range routes {
+		versioned := *r
+		versioned.Name = strings.TrimPrefix(apiVersion, "/") + "." + r.Name
+		versioned.Pattern = strings.TrimSuffix(apiVersion+r.Pattern, "/")
+		versioned.Handlers = namedRoute(versioned.Name, versioned.Pattern, r.Handlers)
+		r.Handlers = namedRoute(r.Name, r.Pattern, r.Handlers)
+		all = append(all, &versioned)
+	}
+	return append(all, routes...)
+}
+
+// NewRouter returns the router serving all the routes of the handler, with the middleware of
+// the app
+func NewRouter(handler *Handler, cfg *webgo.Config) *webgo.Router {
+	router := webgo.NewRouter(cfg, handler.Routes())
+	router.NotFound = handler.NotFound
+	router.Use(handler.AccessLog)
+	router.Use(RequestID)
+	router.Use(Tracing)
+	router.Use(Metrics)
+	router.Use(Streams)
+	return router
+}
+
+// routes returns the routes of the API, without the version
+func (handler *Handler) routes() []*webgo.Route {
 	return []*webgo.Route{
 		&webgo.Route{
 			Name:     "home",
@@ -15,6 +49,42 @@ func (handler *Handler) Routes() []*webgo.Route {
 			Pattern:  "/",
 			Handlers: []http.HandlerFunc{handler.Home},
 		},
+		&webgo.Route{
+			Name:     "openAPI",
+			Method:   http.MethodGet,
+			Pattern:  "/openapi.json",
+			Handlers: []http.HandlerFunc{handler.openAPISpec},
+		},
+		&webgo.Route{
+			Name:     "appMetrics",
+			Method:   http.MethodGet,
+			Pattern:  "/metrics",
+			Handlers: []http.HandlerFunc{handler.mwareAdmin, handler.appMetrics},
+		},
+		&webgo.Route{
+			Name:     "appLiveness",
+			Method:   http.MethodGet,
+			Pattern:  "/healthz",
+			Handlers: []http.HandlerFunc{handler.appLiveness},
+		},
+		&webgo.Route{
+			Name:     "appReadiness",
+			Method:   http.MethodGet,
+			Pattern:  "/readyz",
+			Handlers: []http.HandlerFunc{handler.appReadiness},
+		},
+		&webgo.Route{
+			Name:     "adminLogLevels",
+			Method:   http.MethodGet,
+			Pattern:  "/admin/logs",
+			Handlers: []http.HandlerFunc{handler.mwareAdmin, handler.adminLogLevels},
+		},
+		&webgo.Route{
+			Name:     "adminSetLogLevels",
+			Method:   http.MethodPut,
+			Pattern:  "/admin/logs",
+			Handlers: []http.HandlerFunc{handler.mwareAdmin, handler.adminSetLogLevels},
+		},
 		&webgo.Route{
 			Name:     "userSignup",
 			Method:   http.MethodPost,
@@ -27,6 +97,12 @@ func (handler *Handler) Routes() []*webgo.Route {
 			Pattern:  "/login",
 			Handlers: []http.HandlerFunc{handler.userLogin},
 		},
+		&webgo.Route{
+			Name:     "userRefreshToken",
+			Method:   http.MethodPost,
+			Pattern:  "/token/refresh",
+			Handlers: []http.HandlerFunc{handler.userRefreshToken},
+		},
 		&webgo.Route{
 			Name:     "userItems",
 			Method:   http.MethodGet,
@@ -39,6 +115,12 @@ func (handler *Handler) Routes() []*webgo.Route {
 			Pattern:  "/items",
 			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userCreateItem},
 		},
+		&webgo.Route{
+			Name:     "userSearchItems",
+			Method:   http.MethodGet,
+			Pattern:  "/items/search",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userSearchItems},
+		},
 		&webgo.Route{
 			Name:     "userReadItem",
 			Method:   http.MethodGet,
@@ -51,11 +133,233 @@ func (handler *Handler) Routes() []*webgo.Route {
 			Pattern:  "/items/:id",
 			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userUpdateItem},
 		},
+		&webgo.Route{
+			Name:     "userPatchItem",
+			Method:   http.MethodPatch,
+			Pattern:  "/items/:id",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userPatchItem},
+		},
 		&webgo.Route{
 			Name:     "userDeleteItem",
 			Method:   http.MethodDelete,
 			Pattern:  "/items/:id",
 			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userDeleteItem},
 		},
+		&webgo.Route{
+			Name:     "userAddChecklistEntry",
+			Method:   http.MethodPost,
+			Pattern:  "/items/:id/checklist",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userAddChecklistEntry},
+		},
+		&webgo.Route{
+			Name:     "userReorderChecklist",
+			Method:   http.MethodPut,
+			Pattern:  "/items/:id/checklist",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userReorderChecklist},
+		},
+		&webgo.Route{
+			Name:     "userUpdateChecklistEntry",
+			Method:   http.MethodPatch,
+			Pattern:  "/items/:id/checklist/:entryID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userUpdateChecklistEntry},
+		},
+		&webgo.Route{
+			Name:     "userToggleChecklistEntry",
+			Method:   http.MethodPost,
+			Pattern:  "/items/:id/checklist/:entryID/toggle",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userToggleChecklistEntry},
+		},
+		&webgo.Route{
+			Name:     "userRemoveChecklistEntry",
+			Method:   http.MethodDelete,
+			Pattern:  "/items/:id/checklist/:entryID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userRemoveChecklistEntry},
+		},
+		&webgo.Route{
+			Name:     "userAddReminder",
+			Method:   http.MethodPost,
+			Pattern:  "/items/:id/reminders",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userAddReminder},
+		},
+		&webgo.Route{
+			Name:     "userItemReminders",
+			Method:   http.MethodGet,
+			Pattern:  "/items/:id/reminders",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userItemReminders},
+		},
+		&webgo.Route{
+			Name:     "userDeleteReminder",
+			Method:   http.MethodDelete,
+			Pattern:  "/items/:id/reminders/:reminderID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userDeleteReminder},
+		},
+		&webgo.Route{
+			Name:     "userShareItem",
+			Method:   http.MethodPost,
+			Pattern:  "/items/:id/shares",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userShareItem},
+		},
+		&webgo.Route{
+			Name:     "userItemShares",
+			Method:   http.MethodGet,
+			Pattern:  "/items/:id/shares",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userItemShares},
+		},
+		&webgo.Route{
+			Name:     "userRevokeShare",
+			Method:   http.MethodDelete,
+			Pattern:  "/items/:id/shares/:shareID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userRevokeShare},
+		},
+		&webgo.Route{
+			Name:     "userAddAttachment",
+			Method:   http.MethodPost,
+			Pattern:  "/items/:id/attachments",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userAddAttachment},
+		},
+		&webgo.Route{
+			Name:     "userItemAttachments",
+			Method:   http.MethodGet,
+			Pattern:  "/items/:id/attachments",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userItemAttachments},
+		},
+		&webgo.Route{
+			Name:     "userDownloadAttachment",
+			Method:   http.MethodGet,
+			Pattern:  "/items/:id/attachments/:attachmentID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userDownloadAttachment},
+		},
+		&webgo.Route{
+			Name:     "userDeleteAttachment",
+			Method:   http.MethodDelete,
+			Pattern:  "/items/:id/attachments/:attachmentID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userDeleteAttachment},
+		},
+		&webgo.Route{
+			Name:     "userAttachmentUsage",
+			Method:   http.MethodGet,
+			Pattern:  "/attachments/usage",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userAttachmentUsage},
+		},
+		&webgo.Route{
+			Name:     "userPublishItem",
+			Method:   http.MethodPost,
+			Pattern:  "/items/:id/publish",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userPublishItem},
+		},
+		&webgo.Route{
+			Name:     "userItemPublications",
+			Method:   http.MethodGet,
+			Pattern:  "/items/:id/publish",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userItemPublications},
+		},
+		&webgo.Route{
+			Name:     "userUnpublishItem",
+			Method:   http.MethodDelete,
+			Pattern:  "/items/:id/publish/:publicationID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userUnpublishItem},
+		},
+		&webgo.Route{
+			Name:     "userExport",
+			Method:   http.MethodGet,
+			Pattern:  "/me/export",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userExport},
+		},
+		&webgo.Route{
+			Name:     "userExportEncrypted",
+			Method:   http.MethodPost,
+			Pattern:  "/me/export",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userExport},
+		},
+		&webgo.Route{
+			Name:     "userImport",
+			Method:   http.MethodPost,
+			Pattern:  "/me/import",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userImport},
+		},
+		&webgo.Route{
+			Name:     "userImportJob",
+			Method:   http.MethodGet,
+			Pattern:  "/me/import/:jobID",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userImportJob},
+		},
+		&webgo.Route{
+			Name:     "userAddWebhook",
+			Method:   http.MethodPost,
+			Pattern:  "/webhooks",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userAddWebhook},
+		},
+		&webgo.Route{
+			Name:     "userWebhooks",
+			Method:   http.MethodGet,
+			Pattern:  "/webhooks",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userWebhooks},
+		},
+		&webgo.Route{
+			Name:     "userDeleteWebhook",
+			Method:   http.MethodDelete,
+			Pattern:  "/webhooks/:id",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userDeleteWebhook},
+		},
+		&webgo.Route{
+			Name:     "userWebhookDeliveries",
+			Method:   http.MethodGet,
+			Pattern:  "/webhooks/:id/deliveries",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userWebhookDeliveries},
+		},
+		&webgo.Route{
+			Name:     "userReplayDelivery",
+			Method:   http.MethodPost,
+			Pattern:  "/webhooks/:id/deliveries/:deliveryID/replay",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userReplayDelivery},
+		},
+		&webgo.Route{
+			Name:     "userSyncChanges",
+			Method:   http.MethodGet,
+			Pattern:  "/sync",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userSyncChanges},
+		},
+		&webgo.Route{
+			Name:     "userSync",
+			Method:   http.MethodPost,
+			Pattern:  "/sync",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userSync},
+		},
+		&webgo.Route{
+			Name:     "userEvents",
+			Method:   http.MethodGet,
+			Pattern:  "/events",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.userEvents},
+		},
+		&webgo.Route{
+			Name:     "createSecret",
+			Method:   http.MethodPost,
+			Pattern:  "/secrets",
+			Handlers: []http.HandlerFunc{handler.mwareAuthenticate, handler.createSecret},
+		},
+		&webgo.Route{
+			Name:     "readSecret",
+			Method:   http.MethodGet,
+			Pattern:  "/secrets/:id",
+			Handlers: []http.HandlerFunc{handler.readSecret},
+		},
+		&webgo.Route{
+			Name:     "readPublication",
+			Method:   http.MethodGet,
+			Pattern:  "/p/:id",
+			Handlers: []http.HandlerFunc{handler.readPublication},
+		},
+		&webgo.Route{
+			Name:     "openPublication",
+			Method:   http.MethodPost,
+			Pattern:  "/p/:id",
+			Handlers: []http.HandlerFunc{handler.readPublication},
+		},
+		&webgo.Route{
+			Name:     "legacyPublication",
+			Method:   http.MethodGet,
+			Pattern:  "/p/:id/:key",
+			Handlers: []http.HandlerFunc{handler.legacyPublication},
+		},
 	}
 }
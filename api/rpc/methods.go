@@ -0,0 +1,183 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/users"
+)
+
+// listPageSize is the number of items fetched at a time while streaming the items
+const listPageSize = 100
+
+// usersServer implements the Users service
+type usersServer struct {
+	*Server
+	UnimplementedUsersServer
+}
+
+// itemsServer implements the Items service
+type itemsServer struct {
+	*Server
+	UnimplementedItemsServer
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// itemData returns the input of an item, in the format of the HTTP API
+func itemData(title, description string, tags []string, dueAt *timestamppb.Timestamp) map[string]string {
+	data := map[string]string{
+		"title":       title,
+		"description": description,
+		"tags":        strings.Join(tags, ","),
+	}
+	if dueAt != nil {
+		data["dueAt"] = dueAt.AsTime().Format(time.RFC3339)
+	}
+	return data
+}
+
+func toUser(u *users.User) *User {
+	return &User{
+		Id:         u.ID,
+		Name:       u.Name,
+		Email:      u.Email,
+		AuthToken:  u.AuthToken,
+		CreatedAt:  toTimestamp(u.CreatedAt),
+		ModifiedAt: toTimestamp(u.ModifiedAt),
+	}
+}
+
+func toItem(i *items.Item) *Item {
+	checklist := make([]*Entry, 0, len(i.Checklist))
+	for _, e := range i.Checklist {
+		checklist = append(checklist, &Entry{Id: e.ID, Text: e.Text, Checked: e.Checked})
+	}
+	return &Item{
+		Id:          i.ID,
+		Title:       i.Title,
+		Description: i.Description,
+		Checklist:   checklist,
+		Tags:        i.Tags,
+		DueAt:       toTimestamp(i.DueAt),
+		Status:      i.Status,
+		CreatedAt:   toTimestamp(i.CreatedAt),
+		ModifiedAt:  toTimestamp(i.ModifiedAt),
+	}
+}
+
+func (s *usersServer) Signup(ctx context.Context, req *SignupRequest) (*User, error) {
+	user, err := users.New(map[string]string{
+		"name":     req.Name,
+		"email":    req.Email,
+		"password": req.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.users(ctx).Create(*user)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *usersServer) Login(ctx context.Context, req *LoginRequest) (*User, error) {
+	user, err := s.users(ctx).Authenticate(req.Email, req.Password, saltOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *itemsServer) CreateItem(ctx context.Context, req *CreateItemRequest) (*Item, error) {
+	user := userOf(ctx)
+	item, err := s.users(ctx).CreateItem(user, itemData(req.Title, req.Description, req.Tags, req.DueAt))
+	if err != nil {
+		return nil, err
+	}
+	return toItem(item), nil
+}
+
+func (s *itemsServer) GetItem(ctx context.Context, req *GetItemRequest) (*Item, error) {
+	user := userOf(ctx)
+	item, err := s.users(ctx).Item(user, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toItem(item), nil
+}
+
+func (s *itemsServer) UpdateItem(ctx context.Context, req *UpdateItemRequest) (*Item, error) {
+	user := userOf(ctx)
+	item, err := s.users(ctx).UpdateItem(user, req.Id, itemData(req.Title, req.Description, req.Tags, req.DueAt))
+	if err != nil {
+		return nil, err
+	}
+	return toItem(item), nil
+}
+
+func (s *itemsServer) DeleteItem(ctx context.Context, req *DeleteItemRequest) (*Item, error) {
+	user := userOf(ctx)
+	item, err := s.users(ctx).DeleteItem(user, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toItem(item), nil
+}
+
+func (s *itemsServer) SearchItems(ctx context.Context, req *SearchItemsRequest) (*SearchItemsResponse, error) {
+	user := userOf(ctx)
+	list, err := s.users(ctx).SearchItems(user, req.Query, int(req.Limit))
+	if err != nil {
+		return nil, err
+	}
+	resp := &SearchItemsResponse{Items: make([]*Item, 0, len(list))}
+	for i := range list {
+		resp.Items = append(resp.Items, toItem(&list[i]))
+	}
+	return resp, nil
+}
+
+// listItems streams the items of the user, a page at a time
+func (s *itemsServer) ListItems(req *ListItemsRequest, stream grpc.ServerStreamingServer[Item]) error {
+	ctx := stream.Context()
+	user := userOf(ctx)
+	opts := items.ListOptions{
+		Sort:   req.Sort,
+		Status: req.Status,
+		Limit:  listPageSize,
+	}
+
+	sent := 0
+	for {
+		page, err := s.users(ctx).Items(user, opts)
+		if err != nil {
+			return err
+		}
+		for i := range page.Items {
+			if req.Limit > 0 && sent >= int(req.Limit) {
+				return nil
+			}
+			err = stream.Send(toItem(&page.Items[i]))
+			if err != nil {
+				return err
+			}
+			sent++
+		}
+		if page.Next == "" {
+			return nil
+		}
+		opts.After = page.Next
+	}
+}
@@ -0,0 +1,160 @@
+// Package rpc serves the gRPC API. The messages and the services are generated from notes.proto,
+// this package implements the services and maps the errors of the app to the gRPC status codes.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative notes.proto
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+	"github.com/bnkamalesh/notes/pkg/services"
+	"github.com/bnkamalesh/notes/pkg/users"
+)
+
+type ctxKey string
+
+const userCtxKey = ctxKey("user")
+
+// publicMethods are called without authenticating the user
+var publicMethods = map[string]bool{
+	Users_Signup_FullMethodName: true,
+	Users_Login_FullMethodName:  true,
+}
+
+// Server implements the services of the API
+type Server struct {
+	Services services.Handler
+	Logger   logger.Service
+}
+
+// NewServer returns a gRPC server of the API with the services
+func NewServer(s services.Handler, l logger.Service) *grpc.Server {
+	srv := &Server{
+		Services: s,
+		Logger:   l,
+	}
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(srv.unary),
+		grpc.StreamInterceptor(srv.stream),
+	)
+	RegisterUsersServer(gs, &usersServer{Server: srv})
+	RegisterItemsServer(gs, &itemsServer{Server: srv})
+	return gs
+}
+
+// saltOf returns the token salt of the client making the call, its address like the HTTP API
+func saltOf(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// userOf returns the user making the call, it's nil in the public methods
+func userOf(ctx context.Context) *users.User {
+	u, _ := ctx.Value(userCtxKey).(*users.User)
+	return u
+}
+
+// metadataValue returns the first value of the key in the metadata of the call
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (s *Server) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var resp interface{}
+	err := s.intercept(ctx, info.FullMethod, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (s *Server) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return s.intercept(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// serverStream is a stream with the context of the call set by the interceptor
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// intercept traces the call, authenticates the user if the method is not public, and returns
+// the error of the call as a gRPC status
+func (s *Server) intercept(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	parent, ok := tracing.ParseTraceparent(metadataValue(ctx, tracing.TraceparentHeader))
+	if ok {
+		ctx = tracing.ContextWithRemoteParent(ctx, parent)
+	}
+	name := "grpc " + method
+	ctx, span := tracing.Start(ctx, name, tracing.KindServer)
+	ctx = logger.ContextWith(ctx, "method", name)
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+
+	err := s.authenticate(ctx, method, call)
+	st := status.New(codes.OK, "")
+	if err != nil {
+		var internal bool
+		st, internal = statusOf(err)
+		if internal {
+			logger.FromContext(ctx, s.Logger).Error("grpc", method, err.Error())
+			span.SetError(err)
+		}
+	}
+	span.SetAttribute("rpc.grpc.status_code", strconv.Itoa(int(st.Code())))
+	return st.Err()
+}
+
+// authenticate calls the method with the user in the context, if the method is not public
+func (s *Server) authenticate(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	if publicMethods[method] {
+		return call(ctx)
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(metadataValue(ctx, "authorization"), "Bearer "))
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "Sorry, the auth token is required in the authorization metadata")
+	}
+	user, err := s.users(ctx).AuthUser(token, saltOf(ctx))
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "Sorry, you are not authorized")
+	}
+	ctx = logger.ContextWith(ctx, "userId", user.ID)
+	return call(context.WithValue(ctx, userCtxKey, user))
+}
+
+// users returns the users service, tracing its operations as part of the call
+func (s *Server) users(ctx context.Context) *users.Service {
+	us := s.Services.Users.WithContext(ctx)
+	return &us
+}
@@ -0,0 +1,196 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/services/servicestest"
+	"github.com/bnkamalesh/notes/pkg/testenv"
+)
+
+// testClient calls the gRPC API over an in-memory connection
+type testClient struct {
+	users UsersClient
+	items ItemsClient
+	conn  *grpc.ClientConn
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	env := testenv.New(t)
+
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(servicestest.New(env), env.Logger)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testClient{
+		users: NewUsersClient(conn),
+		items: NewItemsClient(conn),
+		conn:  conn,
+	}
+}
+
+// login signs up and logs in a user, and returns the context of the calls of the user
+func (c *testClient) login(t *testing.T) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	_, err := c.users.Signup(ctx, &SignupRequest{Email: "jane@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	user, err := c.users.Login(ctx, &LoginRequest{Email: "jane@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if user.AuthToken == "" {
+		t.Fatalf("Expected the user with a token, got %v", user)
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", user.AuthToken)
+}
+
+func TestServer(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.users.Signup(ctx, &SignupRequest{})
+	if status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() == "" {
+		t.Fatalf("Expected %v with a message, got %v", codes.InvalidArgument, err)
+	}
+	_, err = c.items.GetItem(ctx, &GetItemRequest{Id: "x"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected %v without a token, got %v", codes.Unauthenticated, err)
+	}
+
+	ctx = c.login(t)
+	_, err = c.users.Signup(ctx, &SignupRequest{Email: "jane@example.com", Password: "password"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("Expected %v, got %v", codes.AlreadyExists, err)
+	}
+
+	due := time.Now().Add(time.Hour).Truncate(time.Second)
+	item, err := c.items.CreateItem(ctx, &CreateItemRequest{
+		Title:       "Groceries",
+		Description: "milk",
+		Tags:        []string{"home"},
+		DueAt:       timestamppb.New(due),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Id == "" || item.Title != "Groceries" || item.DueAt.GetSeconds() != due.Unix() {
+		t.Fatalf("Unexpected item %v", item)
+	}
+
+	got, err := c.items.GetItem(ctx, &GetItemRequest{Id: item.Id})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got.Description != "milk" {
+		t.Fatalf("Expected the item, got %v", got)
+	}
+
+	_, err = c.items.GetItem(ctx, &GetItemRequest{Id: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected %v, got %v", codes.NotFound, err)
+	}
+
+	err = c.conn.Invoke(ctx, "/notes.v1.Items/Unknown", &GetItemRequest{}, &Item{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("Expected %v, got %v", codes.Unimplemented, err)
+	}
+}
+
+func TestListItems(t *testing.T) {
+	c := newTestClient(t)
+	ctx := c.login(t)
+	for i := 0; i < 5; i++ {
+		_, err := c.items.CreateItem(ctx, &CreateItemRequest{Title: "item " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	list := func(req *ListItemsRequest) []*Item {
+		t.Helper()
+		stream, err := c.items.ListItems(ctx, req)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		ii := []*Item{}
+		for {
+			item, err := stream.Recv()
+			if err == io.EOF {
+				return ii
+			}
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			ii = append(ii, item)
+		}
+	}
+
+	ii := list(&ListItemsRequest{Sort: "title"})
+	if len(ii) != 5 {
+		t.Fatalf("Expected 5 items, got %d", len(ii))
+	}
+	for i, item := range ii {
+		if item.Title != "item "+strconv.Itoa(i) {
+			t.Fatalf("Expected the items sorted by title, got %v", ii)
+		}
+	}
+
+	ii = list(&ListItemsRequest{Sort: "-title", Limit: 2})
+	if len(ii) != 2 || ii[0].Title != "item 4" {
+		t.Fatalf("Expected the first 2 items, got %v", ii)
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		code     codes.Code
+		internal bool
+	}{
+		{errs.Invalid("Sorry, invalid"), codes.InvalidArgument, false},
+		{errs.NotFound("Sorry, not found"), codes.NotFound, false},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, false},
+		{status.Error(codes.Unauthenticated, "token"), codes.Unauthenticated, false},
+		{io.ErrUnexpectedEOF, codes.Internal, true},
+	}
+	for _, tc := range tests {
+		st, internal := statusOf(tc.err)
+		if st.Code() != tc.code || internal != tc.internal {
+			t.Errorf("Expected %v %v for '%v', got %v %v", tc.code, tc.internal, tc.err, st.Code(), internal)
+		}
+	}
+	st, _ := statusOf(io.ErrUnexpectedEOF)
+	if st.Message() != internalMessage {
+		t.Fatalf("Expected '%s', got '%s'", internalMessage, st.Message())
+	}
+}
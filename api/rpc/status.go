@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+// internalMessage is sent instead of the message of internal errors
+const internalMessage = "Sorry, something went wrong"
+
+var kindCodes = map[errs.Kind]codes.Code{
+	errs.KindValidation:   codes.InvalidArgument,
+	errs.KindNotFound:     codes.NotFound,
+	errs.KindConflict:     codes.AlreadyExists,
+	errs.KindUnauthorized: codes.Unauthenticated,
+	errs.KindForbidden:    codes.PermissionDenied,
+	errs.KindTooLarge:     codes.ResourceExhausted,
+	errs.KindInternal:     codes.Internal,
+}
+
+// statusOf returns the status of the error, based on its kind. The second value is true if the
+// error is internal, its message is replaced and it should be logged.
+func statusOf(err error) (*status.Status, bool) {
+	if s, ok := status.FromError(err); ok {
+		return s, false
+	}
+	switch err {
+	case context.Canceled, context.DeadlineExceeded:
+		return status.FromContextError(err), false
+	}
+
+	code, ok := kindCodes[errs.KindOf(err)]
+	if !ok || code == codes.Internal {
+		return status.New(codes.Internal, internalMessage), true
+	}
+	return status.New(code, err.Error()), false
+}
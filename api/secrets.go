@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/secrets"
+)
+
+// secretInput is the payload to create a secret
+type secretInput struct {
+	Content  string `json:"content"`
+	MaxViews int    `json:"maxViews"`
+	// TTL is the time to live of the secret in seconds
+	TTL int `json:"ttl"`
+}
+
+// createSecret creates a one time secret. The key of the secret is returned only in this
+// response, and it's not possible to read the secret without it
+func (h *Handler) createSecret(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := secretInput{}
+	err := decodeJSON(http.MaxBytesReader(rw, req.Body, secrets.MaxSize*2), &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	services := h.Services
+	created, err := services.Secrets.Create(input.Content, input.MaxViews, time.Duration(input.TTL)*time.Second)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	rw.Header().Set("Cache-Control", "no-store")
+	webgo.R201(rw, created)
+}
+
+// readSecret returns the encrypted content of a secret and burns it on its last view. The
+// secret has to be decrypted by the client using the key in the fragment of its URL.
+func (h *Handler) readSecret(rw http.ResponseWriter, req *http.Request) {
+	wctx := webgo.Context(req)
+	services := h.Services
+	secret, err := services.Secrets.Read(wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	rw.Header().Set("Cache-Control", "no-store")
+	webgo.R200(rw, secret)
+}
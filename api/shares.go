@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bnkamalesh/webgo"
+)
+
+// userShareItem shares an item of the user with another user
+func (h *Handler) userShareItem(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := make(map[string]string, 2)
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	share, err := h.users(req).ShareItem(user, wctx.Params["id"], input["email"], input["permission"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R201(rw, share)
+}
+
+// userItemShares returns all the shares of an item of the user
+func (h *Handler) userItemShares(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	ss, err := h.users(req).ItemShares(user, wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, ss)
+}
+
+// userRevokeShare revokes a share of an item of the user
+func (h *Handler) userRevokeShare(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	share, err := h.users(req).RevokeShare(user, wctx.Params["id"], wctx.Params["shareID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, share)
+}
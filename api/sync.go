@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/pkg/users"
+)
+
+// syncInput is the payload of the changes made by a client
+type syncInput struct {
+	Changes []users.SyncChange `json:"changes"`
+}
+
+// userSyncChanges returns the changes of the items of the user after the sequence in the since
+// query parameter, including the deleted items
+func (h *Handler) userSyncChanges(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	query := req.URL.Query()
+	since, _ := strconv.ParseInt(strings.TrimSpace(query.Get("since")), 10, 64)
+	limit, _ := strconv.Atoi(strings.TrimSpace(query.Get("limit")))
+
+	changes, err := h.users(req).Changes(user, since, limit)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, changes)
+}
+
+// userSync saves a batch of changes made by a client, and returns the result of each change
+func (h *Handler) userSync(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := syncInput{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	results, err := h.users(req).Sync(user, input.Changes)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, results)
+}
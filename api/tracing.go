@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+	"github.com/bnkamalesh/notes/pkg/users"
+)
+
+// Tracing is a middleware which starts the span of the request, continuing the trace of the
+// client if it sent a traceparent header. The span is named after the route, and it should be
+// added before the Streams middleware.
+func Tracing(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	req, route := withRoute(req)
+	ctx := req.Context()
+	parent, ok := tracing.ParseTraceparent(req.Header.Get(tracing.TraceparentHeader))
+	if ok {
+		ctx = tracing.ContextWithRemoteParent(ctx, parent)
+	}
+	ctx, span := tracing.Start(ctx, "HTTP "+req.Method, tracing.KindServer)
+	defer span.End()
+	span.SetAttribute("http.method", req.Method)
+
+	w := &statusWriter{ResponseWriter: rw, code: http.StatusOK}
+	next(w, req.WithContext(ctx))
+
+	span.SetName(route.name)
+	span.SetAttribute("http.route", route.name)
+	span.SetAttribute("http.status_code", strconv.Itoa(w.code))
+	if w.code >= http.StatusInternalServerError {
+		span.SetError(errHTTPStatus(w.code))
+	}
+}
+
+// errHTTPStatus is the error of a span whose request failed on the server
+type errHTTPStatus int
+
+func (e errHTTPStatus) Error() string {
+	return http.StatusText(int(e))
+}
+
+// users returns the users service, tracing its operations as part of the request
+func (h *Handler) users(req *http.Request) *users.Service {
+	us := h.Services.Users.WithContext(req.Context())
+	return &us
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/importer"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+)
+
+// spanRecorder keeps the exported spans
+type spanRecorder struct {
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(spans []tracing.SpanData) error {
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	server, _ := newServer(t)
+	token := login(t, server)
+	item := struct {
+		ID string `json:"id"`
+	}{}
+	call(t, http.MethodPost, server.URL+"/v1/items", token, map[string]string{"title": "Groceries", "description": "Milk"}, &item)
+
+	rec := &spanRecorder{}
+	tracing.SetExporter(rec, 1)
+	defer tracing.Shutdown()
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/items/"+item.ID, nil)
+	req.Header.Set("Authorization", token)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp.Body.Close()
+	tracing.Shutdown()
+
+	spans := map[string]tracing.SpanData{}
+	for _, s := range rec.spans {
+		if s.TraceID != traceID {
+			t.Errorf("Expected the trace of the client, got %+v", s)
+		}
+		spans[s.Name] = s
+	}
+	span, ok := spans["v1.userReadItem"]
+	if !ok || span.Kind != tracing.KindServer || span.ParentSpanID != "00f067aa0ba902b7" || span.Attributes["http.status_code"] != "200" {
+		t.Fatalf("Expected the span of the request, got %+v", rec.spans)
+	}
+	// the operations of the services are traced under the span of the request
+	for _, name := range []string{"users.Item", "items.Read", "users.decrypt"} {
+		if spans[name].ParentSpanID != span.SpanID {
+			t.Errorf("Expected the span of %s under the request, got %+v", name, rec.spans)
+		}
+	}
+}
+
+func TestTracingImport(t *testing.T) {
+	server, _ := newServer(t)
+	token := login(t, server)
+
+	rec := &spanRecorder{}
+	tracing.SetExporter(rec, 1)
+	defer tracing.Shutdown()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/me/import?name=groceries.md", strings.NewReader("# Groceries\n\nMilk\n"))
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	job := struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&struct {
+		Data interface{} `json:"data"`
+	}{Data: &job})
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 0; job.Status != importer.StatusDone; i++ {
+		if i == 100 || job.Status == importer.StatusFailed {
+			t.Fatalf("Expected the import to be done, got '%s'", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		call(t, http.MethodGet, server.URL+"/v1/me/import/"+job.ID, token, nil, &job)
+	}
+	tracing.Shutdown()
+
+	spans := map[string]tracing.SpanData{}
+	for _, s := range rec.spans {
+		spans[s.Name] = s
+	}
+	request := spans["v1.userImport"]
+	span, ok := spans["importer.Job"]
+	if !ok || span.TraceID != request.TraceID || span.ParentSpanID != request.SpanID {
+		t.Fatalf("Expected the span of the job in the trace of the request, got %+v", rec.spans)
+	}
+	// the import is traced under the span of the job, which outlives the request
+	if spans["users.Import"].ParentSpanID != span.SpanID {
+		t.Errorf("Expected the span of users.Import under the job, got %+v", rec.spans)
+	}
+}
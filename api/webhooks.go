@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bnkamalesh/webgo"
+)
+
+// webhookInput is the payload to register a webhook
+type webhookInput struct {
+	URL string `json:"url"`
+	// Events are one or more of item.created, item.updated and item.deleted
+	Events []string `json:"events"`
+	// IncludeContent includes the decrypted item in the payloads
+	IncludeContent bool `json:"includeContent"`
+}
+
+// userAddWebhook registers a webhook of the user, the response has the signing secret
+func (h *Handler) userAddWebhook(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	input := webhookInput{}
+	err := decodeJSON(req.Body, &input)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+
+	w, err := h.users(req).AddWebhook(user, input.URL, input.Events, input.IncludeContent)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R201(rw, w)
+}
+
+// userWebhooks returns all the webhooks of the user
+func (h *Handler) userWebhooks(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	ww, err := h.users(req).Webhooks(user)
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, ww)
+}
+
+// userDeleteWebhook deletes a webhook of the user
+func (h *Handler) userDeleteWebhook(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	w, err := h.users(req).DeleteWebhook(user, wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, w)
+}
+
+// userWebhookDeliveries returns the latest deliveries of a webhook of the user
+func (h *Handler) userWebhookDeliveries(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	dd, err := h.users(req).WebhookDeliveries(user, wctx.Params["id"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.R200(rw, dd)
+}
+
+// userReplayDelivery delivers the payload of a delivery of a webhook again
+func (h *Handler) userReplayDelivery(rw http.ResponseWriter, req *http.Request) {
+	user := getUser(req)
+	if user == nil {
+		h.sendError(rw, req, errUnidentified)
+		return
+	}
+
+	wctx := webgo.Context(req)
+	d, err := h.users(req).ReplayDelivery(user, wctx.Params["id"], wctx.Params["deliveryID"])
+	if err != nil {
+		h.sendError(rw, req, err)
+		return
+	}
+	webgo.SendResponse(rw, d, http.StatusAccepted)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+// serveGRPC serves the gRPC API without TLS, like the HTTP API it's expected to be behind a
+// proxy terminating TLS. It serves until the server is stopped.
+func serveGRPC(server *grpc.Server, addr string, l logger.Service) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		l.Fatal("gRPC server", err.Error())
+		return
+	}
+	l.Info("gRPC server, listening on", addr)
+	err = server.Serve(lis)
+	if err != nil {
+		l.Fatal("gRPC server", err.Error())
+	}
+}
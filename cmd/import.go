@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bnkamalesh/notes/pkg/importer"
+	"github.com/bnkamalesh/notes/pkg/services"
+)
+
+// importCommand is the subcommand of the server importing a file. It's not "import", which is
+// the command of the notes client uploading a file to the server.
+const importCommand = "import-notes"
+
+// runImport imports a file as items of a user, and prints the report as JSON. It returns the
+// exit code.
+//
+//	notes import-notes -email user@example.com [-format enex] [-dry-run] file
+//
+// The password is read from stdin, and prompted for if stdin is a terminal. It's not taken from
+// the arguments, which the other users of the host can see.
+func runImport(sh services.Handler, args []string) int {
+	fs := flag.NewFlagSet(importCommand, flag.ContinueOnError)
+	email := fs.String("email", "", "email of the user to import the notes for")
+	format := fs.String("format", "", "format of the file, one of enex, keep or markdown. Detected if empty")
+	dryRun := fs.Bool("dry-run", false, "parse and validate the notes without importing them")
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if *email == "" || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: "+importCommand+" -email <email> [-format <format>] [-dry-run] <file>")
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	user, err := sh.Users.Authenticate(*email, password, "cli")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	src := importer.Source{Name: filepath.Base(f.Name()), R: f, Size: info.Size()}
+	report, err := sh.Users.Import(user, src, *format, *dryRun, func(r *importer.Report) {
+		fmt.Fprintf(os.Stderr, "%d notes processed\n", r.Total)
+	})
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readPassword reads the first line of stdin, without echoing it if stdin is a terminal
+func readPassword() (string, error) {
+	if isTerminal(os.Stdin) {
+		if stty(os.Stdin, "-echo") == nil {
+			defer func() {
+				_ = stty(os.Stdin, "echo")
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("the password could not be read from stdin: %s", err.Error())
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// isTerminal returns true if the file is a terminal, and not a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func stty(tty *os.File, arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+// reloadLogs reads the levels file of the logs again on every SIGHUP
+func reloadLogs(l logger.Log) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		err := l.Reload()
+		if err != nil {
+			l.Error("log levels", err.Error())
+			continue
+		}
+		l.Info("log levels", l.Levels().String())
+	}
+}
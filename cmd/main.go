@@ -1,19 +1,39 @@
 package main
 
 import (
-	"github.com/bnkamalesh/webgo"
-	"github.com/bnkamalesh/webgo/middleware"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
 
 	"github.com/bnkamalesh/notes/api"
+	"github.com/bnkamalesh/notes/api/rpc"
 	"github.com/bnkamalesh/notes/configs"
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
 	"github.com/bnkamalesh/notes/pkg/platform/cache"
 	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
 	"github.com/bnkamalesh/notes/pkg/services"
 )
 
 func main() {
-	logHandler := logger.New(configs.Logs())
+	lc := configs.Logs()
+	logHandler, err := logger.NewLog(lc)
+	if err != nil {
+		logger.New([]string{"all"}).Fatal(lc.Sinks, err.Error())
+		return
+	}
+	defer logHandler.Close()
+	go reloadLogs(logHandler)
+
+	tc := configs.Tracing()
+	err = tracing.Setup(tc)
+	if err != nil {
+		logHandler.Fatal(tc.Exporter, err.Error())
+		return
+	}
 
 	sc := configs.Store()
 	storageService, err := storage.New(sc)
@@ -28,10 +48,73 @@ func main() {
 		return
 	}
 
-	serviceHandler := services.New(storageService, cacheService, logHandler)
-	apiHandler := api.NewHandler(serviceHandler)
+	bc := configs.Blob()
+	blobService, err := blob.New(bc)
+	if err != nil {
+		logHandler.Fatal(bc.Driver, err.Error())
+		return
+	}
+
+	serviceHandler := services.New(
+		storageService,
+		cacheService,
+		blobService,
+		logHandler,
+		configs.Reminders(),
+		configs.Attachments(),
+		configs.Webhooks(),
+	)
 
-	router := webgo.NewRouter(configs.Webgo(), apiHandler.Routes())
-	router.Use(middleware.AccessLog)
-	router.Start()
+	if len(os.Args) > 1 && os.Args[1] == importCommand {
+		code := runImport(serviceHandler, os.Args[2:])
+		cacheService.Close()
+		storageService.Close()
+		os.Exit(code)
+	}
+
+	serviceHandler.Scheduler.Start()
+	serviceHandler.Dispatcher.Start()
+	err = serviceHandler.Events.Start()
+	if err != nil {
+		logHandler.Error("events", err.Error())
+	}
+
+	var grpcServer *grpc.Server
+	if addr := configs.GRPC(); addr != "" {
+		grpcServer = rpc.NewServer(serviceHandler, logHandler)
+		go serveGRPC(grpcServer, addr, logHandler)
+	}
+
+	apiHandler := api.NewHandler(serviceHandler, logHandler)
+	apiHandler.AdminToken = configs.AdminToken()
+
+	router := api.NewRouter(&apiHandler, configs.Webgo())
+
+	stopped := make(chan struct{})
+	go func() {
+		router.Start()
+		close(stopped)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	drainDelay, timeout := configs.Shutdown()
+	select {
+	case sig := <-signals:
+		logHandler.Info("shutting down on", sig.String())
+	case <-stopped:
+		// the server could not start, there are no requests to drain
+		drainDelay = 0
+	}
+	signal.Stop(signals)
+
+	a := &app{
+		router:   router,
+		grpc:     grpcServer,
+		services: serviceHandler,
+		storage:  storageService,
+		cache:    cacheService,
+		logger:   logHandler,
+	}
+	a.shutdown(drainDelay, timeout)
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+var (
+	errNotLoggedIn = errors.New("not logged in, run notes login")
+	errExpired     = errors.New("the session has expired, run notes login")
+)
+
+// app has the dependencies shared by all the commands
+type app struct {
+	// usage is the usage of the command being run
+	usage      string
+	configPath string
+	cfg        *config
+
+	stdin  *os.File
+	stdout *os.File
+	stderr *os.File
+	// reader buffers stdin for the prompts
+	reader *bufio.Reader
+}
+
+func newApp() (*app, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return &app{
+		configPath: path,
+		cfg:        cfg,
+		stdin:      os.Stdin,
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		reader:     bufio.NewReader(os.Stdin),
+	}, nil
+}
+
+// flags returns the flag set of a command, printing the usage of the command on error
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(a.stderr, "usage: notes "+a.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags, and checks the number of positional arguments. max < 0 allows any
+// number of arguments.
+func (a *app) parse(fs *flag.FlagSet, args []string, min, max int) error {
+	// the flag set prints the error and the usage
+	err := fs.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// client returns a client with the session of the user. The token is refreshed if it's close
+// to expiry.
+func (a *app) client(ctx context.Context) (*client.Client, error) {
+	if a.cfg.Token == "" {
+		return nil, errNotLoggedIn
+	}
+	c := client.New(a.cfg.Server, client.WithToken(a.cfg.Token))
+	if time.Since(a.cfg.TokenAt) < refreshAfter {
+		return c, nil
+	}
+
+	_, err := c.RefreshToken(ctx)
+	if err != nil {
+		return nil, sessionError(err)
+	}
+	a.cfg.Token = c.Token()
+	a.cfg.TokenAt = time.Now()
+	return c, a.cfg.save(a.configPath)
+}
+
+// sessionError replaces the unauthorized errors with a hint to login again
+func sessionError(err error) error {
+	if errors.Is(err, client.ErrUnauthorized) {
+		return errExpired
+	}
+	return err
+}
+
+// isTerminal returns true if the file is a terminal, and not a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// prompt prints the label and reads a line from stdin. The label is not printed if stdin is
+// piped.
+func (a *app) prompt(label string) (string, error) {
+	if isTerminal(a.stdin) {
+		fmt.Fprint(a.stderr, label)
+	}
+	line, err := a.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptSecret reads a line from stdin without echoing it, if stdin is a terminal
+func (a *app) promptSecret(label string) (string, error) {
+	if !isTerminal(a.stdin) {
+		return a.prompt(label)
+	}
+
+	if stty(a.stdin, "-echo") == nil {
+		defer func() {
+			_ = stty(a.stdin, "echo")
+			fmt.Fprintln(a.stderr)
+		}()
+	}
+	fmt.Fprint(a.stderr, label)
+	line, err := a.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func stty(tty *os.File, arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
+
+// edit opens the text in $VISUAL or $EDITOR, and returns the text after the editor exits
+func (a *app) edit(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "notes-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// the editor may have arguments, e.g. "code --wait"
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin = a.stdin
+	cmd.Stdout = a.stdout
+	cmd.Stderr = a.stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("editor %s failed: %s", editor, err.Error())
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// printJSON writes the value as indented JSON to stdout
+func (a *app) printJSON(v interface{}) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultServer = "http://localhost:8080"
+	// refreshAfter is the age of the token after which it's refreshed, tokens expire in 24 hours
+	refreshAfter = 12 * time.Hour
+)
+
+// config is the configuration of the client, saved in the config file. It has the session
+// token, so the file is readable only by the user.
+type config struct {
+	Server  string    `json:"server"`
+	Email   string    `json:"email,omitempty"`
+	Token   string    `json:"token,omitempty"`
+	TokenAt time.Time `json:"tokenAt,omitempty"`
+}
+
+// configPath returns the path of the config file, $NOTES_CONFIG or notes/config.json in the
+// config directory of the user
+func configPath() (string, error) {
+	if p := os.Getenv("NOTES_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notes", "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file returns the default config. $NOTES_SERVER
+// overrides the server in the file.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			return nil, fmt.Errorf("%s is accessible by other users, it should have the permissions 0600", path)
+		}
+		err = json.NewDecoder(f).Decode(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %s", path, err.Error())
+		}
+	}
+
+	if s := os.Getenv("NOTES_SERVER"); s != "" {
+		cfg.Server = s
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	return cfg, nil
+}
+
+// save writes the config file with the permissions 0600. It's written to a temporary file
+// first, so that the file is never left partially written.
+func (cfg *config) save(path string) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = f.Chmod(0600)
+	if err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(cfg)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
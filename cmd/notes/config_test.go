@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	t.Setenv("NOTES_SERVER", "")
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if cfg.Server != defaultServer || cfg.Token != "" {
+		t.Fatalf("Expected the default config without the file, got %+v", cfg)
+	}
+
+	err = os.WriteFile(path, []byte(`{"server":"https://notes.example.com","token":"secret"}`), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "0600") {
+		t.Fatalf("Expected the config readable by other users to be refused, got %v", err)
+	}
+
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cfg, err = loadConfig(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if cfg.Server != "https://notes.example.com" || cfg.Token != "secret" {
+		t.Fatalf("Expected the config of the file, got %+v", cfg)
+	}
+
+	t.Setenv("NOTES_SERVER", "http://localhost:9090")
+	cfg, err = loadConfig(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if cfg.Server != "http://localhost:9090" || cfg.Token != "secret" {
+		t.Fatalf("Expected $NOTES_SERVER to override the server, got %+v", cfg)
+	}
+
+	err = os.WriteFile(path, []byte(`{"server":`), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "invalid config file") {
+		t.Fatalf("Expected an invalid config file, got %v", err)
+	}
+}
+
+func TestSaveConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "notes")
+	path := filepath.Join(dir, "config.json")
+	t.Setenv("NOTES_SERVER", "")
+
+	cfg := &config{Server: "https://notes.example.com", Email: "jane@example.com", Token: "secret", TokenAt: time.Now().UTC()}
+	err := cfg.save(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// saving again replaces the file
+	cfg.Token = "refreshed"
+	err = cfg.save(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the permissions 0600, got %v", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the config file, got %d files", len(entries))
+	}
+
+	saved, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if saved.Email != cfg.Email || saved.Token != "refreshed" || !saved.TokenAt.Equal(cfg.TokenAt) {
+		t.Fatalf("Expected the saved config, got %+v", saved)
+	}
+
+	// the config is kept if it cannot be replaced
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = os.Mkdir(path, 0700)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = cfg.save(path)
+	if err == nil {
+		t.Fatal("Expected an error replacing a directory")
+	}
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		t.Fatalf("Expected the temporary file to be removed, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+const (
+	pageSize = 100
+	dueUsage = "due date, as 2006-01-02, 2006-01-02 15:04 or RFC 3339"
+)
+
+var errNoTitle = errors.New("a title is required, with -t or a # heading on the first line")
+
+// parseDue parses the due date in the local time zone, unless the zone is specified
+func parseDue(str string) (*time.Time, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err == nil {
+		return &t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		t, err = time.ParseInLocation(layout, str, time.Local)
+		if err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid due date %q, it should be like 2006-01-02, 2006-01-02 15:04 or RFC 3339", str)
+}
+
+// parseTags splits a comma separated list of tags
+func parseTags(str string) []string {
+	tags := []string{}
+	for _, t := range strings.Split(str, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// splitTitle returns the # heading on the first line of the text as the title, and the rest as
+// the description
+func splitTitle(text string) (string, string) {
+	text = strings.TrimLeft(text, "\r\n")
+	line, rest, _ := strings.Cut(text, "\n")
+	if !strings.HasPrefix(line, "# ") {
+		return "", text
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "# ")), strings.TrimSpace(rest)
+}
+
+// readDescription reads the description from stdin if it's piped, or from the editor
+func (a *app) readDescription(current string) (string, error) {
+	if !isTerminal(a.stdin) {
+		b, err := io.ReadAll(a.reader)
+		return string(b), err
+	}
+	return a.edit(current)
+}
+
+// runList lists the items of the user
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("ls")
+	sortBy := fs.String("sort", "-modified", "created, modified or title, prefixed with - for descending order")
+	status := fs.String("status", "", "open or done, all the items are listed if empty")
+	limit := fs.Int("limit", 0, "maximum number of items, all the items are listed if 0")
+	asJSON := fs.Bool("json", false, "print the items as JSON")
+	err := a.parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := client.ListOptions{Sort: *sortBy, Status: *status, Limit: pageSize}
+	if *limit > 0 && *limit < pageSize {
+		opts.Limit = *limit
+	}
+
+	list := []client.Item{}
+	table := newItemTable(a.stdout)
+	it := c.Items(ctx, opts)
+	for (*limit == 0 || len(list) < *limit) && it.Next() {
+		list = append(list, it.Item())
+		if !*asJSON {
+			table.add(it.Item())
+		}
+	}
+	if it.Err() != nil {
+		return sessionError(it.Err())
+	}
+
+	if *asJSON {
+		return a.printJSON(list)
+	}
+	return table.flush()
+}
+
+// runCat prints an item
+func runCat(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("cat")
+	asJSON := fs.Bool("json", false, "print the item as JSON")
+	err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := c.Item(ctx, fs.Arg(0))
+	if err != nil {
+		return sessionError(err)
+	}
+
+	if *asJSON {
+		return a.printJSON(item)
+	}
+	printItem(a.stdout, item)
+	return nil
+}
+
+// runNew creates an item. The description is read from stdin if it's piped, otherwise it's
+// written in the editor. The ID of the item is printed, for use in scripts.
+func runNew(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("new")
+	title := fs.String("t", "", "title of the item, the # heading on the first line is used if empty")
+	tags := fs.String("tags", "", "comma separated list of tags")
+	due := fs.String("due", "", dueUsage)
+	asJSON := fs.Bool("json", false, "print the item as JSON")
+	err := a.parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	dueAt, err := parseDue(*due)
+	if err != nil {
+		return err
+	}
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	description, err := a.readDescription("")
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		*title, description = splitTitle(description)
+	}
+	if *title == "" {
+		return errNoTitle
+	}
+
+	item, err := c.CreateItem(ctx, client.ItemInput{
+		Title:       *title,
+		Description: description,
+		DueAt:       dueAt,
+		Tags:        parseTags(*tags),
+	})
+	if err != nil {
+		return sessionError(err)
+	}
+
+	if *asJSON {
+		return a.printJSON(item)
+	}
+	fmt.Fprintln(a.stdout, item.ID)
+	return nil
+}
+
+// runEdit updates an item. Without any flags, the description is edited in the editor, or
+// read from stdin if it's piped.
+func runEdit(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("edit")
+	title := fs.String("t", "", "new title of the item")
+	tags := fs.String("tags", "", "new comma separated list of tags, 'none' removes all the tags")
+	due := fs.String("due", "", dueUsage+", 'none' removes the due date")
+	asJSON := fs.Bool("json", false, "print the item as JSON")
+	err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id := fs.Arg(0)
+
+	patch := map[string]interface{}{}
+	if *title != "" {
+		patch["title"] = *title
+	}
+	switch *tags {
+	case "":
+	case "none":
+		patch["tags"] = nil
+	default:
+		patch["tags"] = parseTags(*tags)
+	}
+	switch *due {
+	case "":
+	case "none":
+		patch["dueAt"] = nil
+	default:
+		dueAt, err := parseDue(*due)
+		if err != nil {
+			return err
+		}
+		patch["dueAt"] = dueAt.Format(time.RFC3339)
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(patch) == 0 || !isTerminal(a.stdin) {
+		item, err := c.Item(ctx, id)
+		if err != nil {
+			return sessionError(err)
+		}
+		description, err := a.readDescription(item.Description)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(description) != strings.TrimSpace(item.Description) {
+			patch["description"] = description
+		}
+	}
+	if len(patch) == 0 {
+		fmt.Fprintln(a.stderr, "No changes")
+		return nil
+	}
+
+	item, err := c.PatchItem(ctx, id, patch)
+	if err != nil {
+		return sessionError(err)
+	}
+	if *asJSON {
+		return a.printJSON(item)
+	}
+	return nil
+}
+
+// runRemove deletes the items
+func runRemove(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("rm")
+	err := a.parse(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range fs.Args() {
+		err = c.DeleteItem(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%s: %s", id, sessionError(err).Error())
+		}
+	}
+	return nil
+}
+
+// runSearch lists the items matching the query
+func runSearch(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("search")
+	limit := fs.Int("limit", 0, "maximum number of items")
+	asJSON := fs.Bool("json", false, "print the items as JSON")
+	err := a.parse(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := c.SearchItems(ctx, strings.Join(fs.Args(), " "), *limit)
+	if err != nil {
+		return sessionError(err)
+	}
+
+	if *asJSON {
+		return a.printJSON(list)
+	}
+	table := newItemTable(a.stdout)
+	for _, item := range list {
+		table.add(item)
+	}
+	return table.flush()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/api"
+	"github.com/bnkamalesh/notes/pkg/client"
+	"github.com/bnkamalesh/notes/pkg/services/servicestest"
+	"github.com/bnkamalesh/notes/pkg/testenv"
+)
+
+// newTestApp returns an app logged in to a server running the API with the data in memory, and
+// the client of the same session
+func newTestApp(t *testing.T) (*app, *client.Client) {
+	t.Helper()
+	env := testenv.New(t)
+	h := api.NewHandler(servicestest.New(env), env.Logger)
+	server := httptest.NewServer(api.NewRouter(&h, &webgo.Config{}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	c := client.New(server.URL)
+	_, err := c.Signup(ctx, "Jane", "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = c.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dir := t.TempDir()
+	a := &app{
+		configPath: filepath.Join(dir, "config.json"),
+		cfg:        &config{Server: server.URL, Token: c.Token(), TokenAt: time.Now()},
+	}
+	a.stdout, err = os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	a.stderr, err = os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() {
+		a.stdout.Close()
+		a.stderr.Close()
+	})
+	return a, c
+}
+
+// pipe sets the stdin of the app to a file with the text, like a pipe it's not a terminal
+func pipe(t *testing.T, a *app, text string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	err := os.WriteFile(path, []byte(text), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	a.stdin, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { a.stdin.Close() })
+	a.reader = bufio.NewReader(a.stdin)
+}
+
+// output returns what the app wrote to stdout since the last call, and clears it
+func output(t *testing.T, a *app) string {
+	t.Helper()
+	b, err := os.ReadFile(a.stdout.Name())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = a.stdout.Truncate(0)
+	if err == nil {
+		_, err = a.stdout.Seek(0, 0)
+	}
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return string(b)
+}
+
+func TestNewFromStdin(t *testing.T) {
+	ctx := context.Background()
+	a, c := newTestApp(t)
+
+	pipe(t, a, "# Groceries\n\nMilk\nEggs\n")
+	err := runNew(ctx, a, []string{"-tags", "home, weekly"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	id := strings.TrimSpace(output(t, a))
+	item, err := c.Item(ctx, id)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Title != "Groceries" || item.Description != "Milk\nEggs" || strings.Join(item.Tags, ",") != "home,weekly" {
+		t.Fatalf("Expected the item read from stdin, got %+v", item)
+	}
+
+	// the title flag keeps the heading in the description
+	pipe(t, a, "# Heading\nText\n")
+	err = runNew(ctx, a, []string{"-t", "Notes", "-json"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	created := client.Item{}
+	err = json.Unmarshal([]byte(output(t, a)), &created)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if created.ID == "" || created.Title != "Notes" {
+		t.Fatalf("Expected the item as JSON, got %+v", created)
+	}
+	item, err = c.Item(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Description != "# Heading\nText" {
+		t.Fatalf("Expected the heading to be kept in the description, got %q", item.Description)
+	}
+
+	pipe(t, a, "no heading\n")
+	err = runNew(ctx, a, nil)
+	if err != errNoTitle {
+		t.Fatalf("Expected '%v', got '%v'", errNoTitle, err)
+	}
+}
+
+func TestEditFromStdin(t *testing.T) {
+	ctx := context.Background()
+	a, c := newTestApp(t)
+	item, err := c.CreateItem(ctx, client.ItemInput{Title: "Groceries", Description: "Milk", Tags: []string{"home"}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	pipe(t, a, "Milk\nEggs\n")
+	err = runEdit(ctx, a, []string{"-t", "Weekly groceries", item.ID})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	edited, err := c.Item(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if edited.Title != "Weekly groceries" || edited.Description != "Milk\nEggs" || strings.Join(edited.Tags, ",") != "home" {
+		t.Fatalf("Expected the title and the description to be updated, got %+v", edited)
+	}
+
+	// the same description is not sent again
+	pipe(t, a, "Milk\nEggs")
+	err = runEdit(ctx, a, []string{item.ID})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b, err := os.ReadFile(a.stderr.Name())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(string(b), "No changes") {
+		t.Fatalf("Expected no changes, got '%s'", b)
+	}
+	unchanged, err := c.Item(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if unchanged.Seq != edited.Seq {
+		t.Fatalf("Expected the item not to be updated, got %+v", unchanged)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+// runLogin authenticates the user and saves the session in the config file. The password is
+// read from $NOTES_PASSWORD if set, or prompted for.
+func runLogin(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("login")
+	server := fs.String("server", a.cfg.Server, "URL of the notes server")
+	email := fs.String("email", a.cfg.Email, "email of the user")
+	err := a.parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if *email == "" {
+		*email, err = a.prompt("Email: ")
+		if err != nil {
+			return err
+		}
+	}
+	password := os.Getenv("NOTES_PASSWORD")
+	if password == "" {
+		password, err = a.promptSecret("Password: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	c := client.New(strings.TrimSuffix(*server, "/"))
+	user, err := c.Login(ctx, *email, password)
+	if err != nil {
+		return err
+	}
+
+	a.cfg.Server = strings.TrimSuffix(*server, "/")
+	a.cfg.Email = user.Email
+	a.cfg.Token = user.AuthToken
+	a.cfg.TokenAt = time.Now()
+	err = a.cfg.save(a.configPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "Logged in to %s as %s\n", a.cfg.Server, user.Email)
+	return nil
+}
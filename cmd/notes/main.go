@@ -0,0 +1,102 @@
+// Command notes is the command-line client of the notes app.
+//
+//	notes login [-server url] [-email email]
+//	notes ls [-sort field] [-status open|done] [-limit n] [-json]
+//	notes cat [-json] <id>
+//	notes new -t title [-tags a,b] [-due date] [-json] < note.md
+//	notes edit [-t title] [-tags a,b] [-due date] [-json] <id>
+//	notes rm <id>...
+//	notes search [-limit n] [-json] <query>
+//	notes export [-o file] [-encrypt]
+//	notes import [-format enex|keep|markdown] [-dry-run] [-json] <file|->
+//	notes decrypt [-o file] <file|->
+//
+// The session is saved in $NOTES_CONFIG, or notes/config.json in the config directory of the
+// user, readable only by the user. $NOTES_SERVER overrides the server of the session.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+)
+
+// errUsage is returned by the commands when the arguments are invalid, after printing the
+// usage
+var errUsage = errors.New("invalid usage")
+
+// command is a sub command of the client
+type command struct {
+	usage string
+	run   func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"login":   {"login [-server url] [-email email]", runLogin},
+	"ls":      {"ls [-sort field] [-status open|done] [-limit n] [-json]", runList},
+	"cat":     {"cat [-json] <id>", runCat},
+	"new":     {"new -t title [-tags a,b] [-due date] [-json] < note.md", runNew},
+	"edit":    {"edit [-t title] [-tags a,b] [-due date] [-json] <id>", runEdit},
+	"rm":      {"rm <id>...", runRemove},
+	"search":  {"search [-limit n] [-json] <query>", runSearch},
+	"export":  {"export [-o file] [-encrypt]", runExport},
+	"import":  {"import [-format enex|keep|markdown] [-dry-run] [-json] <file|->", runImport},
+	"decrypt": {"decrypt [-o file] <file|->", runDecrypt},
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: notes <command> [arguments]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintln(w, "  notes "+commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage(os.Stdout)
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "notes: unknown command %q\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	a, err := newApp()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "notes: "+err.Error())
+		os.Exit(1)
+	}
+
+	a.usage = cmd.usage
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = cmd.run(ctx, a, os.Args[2:])
+	switch {
+	case err == errUsage:
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "notes: "+strings.TrimSpace(err.Error()))
+		os.Exit(1)
+	}
+}
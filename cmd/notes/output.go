@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+// itemTable writes the items as a table, for reading in a terminal
+type itemTable struct {
+	tw *tabwriter.Writer
+}
+
+func newItemTable(w io.Writer) *itemTable {
+	t := &itemTable{
+		tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0),
+	}
+	fmt.Fprintln(t.tw, "ID\tTITLE\tSTATUS\tDUE\tTAGS\tMODIFIED")
+	return t
+}
+
+func (t *itemTable) add(item client.Item) {
+	modified := item.ModifiedAt
+	if modified == nil {
+		modified = item.CreatedAt
+	}
+	fmt.Fprintf(
+		t.tw,
+		"%s\t%s\t%s\t%s\t%s\t%s\n",
+		item.ID,
+		truncate(item.Title, 40),
+		item.Status,
+		formatTime(item.DueAt),
+		strings.Join(item.Tags, ","),
+		formatTime(modified),
+	)
+}
+
+func (t *itemTable) flush() error {
+	return t.tw.Flush()
+}
+
+// printItem writes the item as text, for reading in a terminal or piping to other tools
+func printItem(w io.Writer, item *client.Item) {
+	fmt.Fprintf(w, "# %s\n", item.Title)
+	if item.DueAt != nil || len(item.Tags) > 0 {
+		fmt.Fprintln(w)
+	}
+	if item.DueAt != nil {
+		fmt.Fprintf(w, "Due: %s\n", formatTime(item.DueAt))
+	}
+	if len(item.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(item.Tags, ", "))
+	}
+	if item.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(item.Description, "\n"))
+	}
+	if len(item.Checklist) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, e := range item.Checklist {
+		mark := " "
+		if e.Checked {
+			mark = "x"
+		}
+		fmt.Fprintf(w, "- [%s] %s\n", mark, e.Text)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
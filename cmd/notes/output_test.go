@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+func TestItemTable(t *testing.T) {
+	due := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	buf := &bytes.Buffer{}
+	table := newItemTable(buf)
+	table.add(client.Item{ID: "item_1", Title: strings.Repeat("a", 50), Status: "open", DueAt: &due, Tags: []string{"home", "work"}, CreatedAt: &due})
+	table.add(client.Item{ID: "item_2", Title: "Groceries"})
+	err := table.flush()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected the header and 2 rows, got:\n%s", buf.String())
+	}
+	if strings.Join(strings.Fields(lines[0]), " ") != "ID TITLE STATUS DUE TAGS MODIFIED" {
+		t.Fatalf("Expected the header, got '%s'", lines[0])
+	}
+	expected := []string{"item_1", strings.Repeat("a", 39) + "…", "open", "2026-03-01", "09:30", "home,work", "2026-03-01", "09:30"}
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Fatalf("Expected the row %v, got %v", expected, got)
+	}
+	if got := strings.Fields(lines[2]); strings.Join(got, " ") != "item_2 Groceries - -" {
+		t.Fatalf("Expected the row without the empty fields, got %v", got)
+	}
+	// the columns are aligned
+	if strings.Index(lines[2], "-") != strings.Index(lines[0], "DUE") {
+		t.Fatalf("Expected the columns to be aligned, got:\n%s", buf.String())
+	}
+}
+
+func TestPrintItem(t *testing.T) {
+	due := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	buf := &bytes.Buffer{}
+	printItem(buf, &client.Item{
+		Title:       "Groceries",
+		Description: "For the week\n\n",
+		DueAt:       &due,
+		Tags:        []string{"home", "weekly"},
+		Checklist:   []client.Entry{{Text: "Milk", Checked: true}, {Text: "Eggs"}},
+	})
+	expected := "# Groceries\n\nDue: 2026-03-01 09:30\nTags: home, weekly\n\nFor the week\n\n- [x] Milk\n- [ ] Eggs\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	printItem(buf, &client.Item{Title: "Empty"})
+	if buf.String() != "# Empty\n" {
+		t.Fatalf("Expected only the title, got:\n%s", buf.String())
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer out.Close()
+	a := &app{stdout: out}
+
+	err = a.printJSON([]client.Item{{ID: "item_1", Title: "Groceries", Tags: []string{"home"}}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	items := []client.Item{}
+	err = json.Unmarshal(b, &items)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(items) != 1 || items[0].ID != "item_1" || items[0].Tags[0] != "home" {
+		t.Fatalf("Expected the items as JSON, got %s", b)
+	}
+	if !strings.Contains(string(b), "\n  {\n    \"id\": \"item_1\"") {
+		t.Fatalf("Expected indented JSON, got %s", b)
+	}
+}
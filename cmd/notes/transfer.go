@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/archive"
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+const importPollInterval = time.Second
+
+// runExport downloads an archive of all the items. The archive is written to stdout if it's
+// piped, otherwise to a file in the current directory.
+func runExport(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("export")
+	out := fs.String("o", "", "file to write the archive to, '-' for stdout")
+	encrypt := fs.Bool("encrypt", false, "encrypt the archive with a passphrase, read from $NOTES_PASSPHRASE or prompted for")
+	err := a.parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	passphrase := ""
+	if *encrypt {
+		passphrase, err = a.passphrase(true)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" || (*out == "" && !isTerminal(a.stdout)) {
+		return sessionError(c.Export(ctx, a.stdout, passphrase))
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("notes-export-%s.zip", time.Now().Format("2006-01-02"))
+		if *encrypt {
+			*out += ".enc"
+		}
+	}
+
+	// the archive is renamed only when it's complete
+	f, err := os.CreateTemp(filepath.Dir(*out), ".notes-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = c.Export(ctx, f, passphrase)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return sessionError(err)
+	}
+	err = os.Rename(f.Name(), *out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stderr, "Exported to "+*out)
+	return nil
+}
+
+// passphrase returns the passphrase of an encrypted archive, from $NOTES_PASSPHRASE or
+// prompted for. It's prompted for twice to confirm it, when it encrypts the archive.
+func (a *app) passphrase(confirm bool) (string, error) {
+	if p := os.Getenv("NOTES_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	if !isTerminal(a.stdin) {
+		return "", errors.New("$NOTES_PASSPHRASE is required for an encrypted archive when stdin is not a terminal")
+	}
+
+	p, err := a.promptSecret("Passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", errors.New("the passphrase can not be empty")
+	}
+	if !confirm {
+		return p, nil
+	}
+	again, err := a.promptSecret("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p != again {
+		return "", errors.New("the passphrases do not match")
+	}
+	return p, nil
+}
+
+// encrypted returns a reader of r, and the passphrase of the archive if r is an encrypted
+// archive
+func (a *app) encrypted(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(64)
+	if !archive.IsEncrypted(head) {
+		return br, "", nil
+	}
+	passphrase, err := a.passphrase(false)
+	return br, passphrase, err
+}
+
+// runDecrypt decrypts an archive exported with -encrypt back to its zip file, without the
+// server. The archive is read from stdin if it's '-', and the zip file is written to stdout if
+// it's piped, otherwise next to the archive.
+func runDecrypt(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("decrypt")
+	out := fs.String("o", "", "file to write the zip file to, '-' for stdout")
+	err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = a.reader
+	if fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	r, passphrase, err := a.encrypted(r)
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return archive.ErrNotEncrypted
+	}
+	r, err = archive.Decrypt(r, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" || (*out == "" && !isTerminal(a.stdout)) {
+		_, err = io.Copy(a.stdout, r)
+		return err
+	}
+	if *out == "" {
+		if fs.Arg(0) == "-" {
+			*out = fmt.Sprintf("notes-export-%s.zip", time.Now().Format("2006-01-02"))
+		} else {
+			*out = strings.TrimSuffix(fs.Arg(0), ".enc")
+			if *out == fs.Arg(0) {
+				*out += ".zip"
+			}
+		}
+	}
+
+	// the zip file is renamed only when it's complete, a wrong passphrase fails at the first
+	// chunk
+	f, err := os.CreateTemp(filepath.Dir(*out), ".notes-decrypt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Rename(f.Name(), *out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stderr, "Decrypted to "+*out)
+	return nil
+}
+
+// runImport uploads a file to be imported, and waits for the import to finish. The file is read
+// from stdin if it's '-'. The passphrase of an encrypted archive is sent along with it.
+func runImport(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("import")
+	format := fs.String("format", "", "format of the file, one of enex, keep or markdown. Detected if empty")
+	name := fs.String("name", "", "name of the file read from stdin, used to detect its format")
+	dryRun := fs.Bool("dry-run", false, "parse and validate the notes without importing them")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = a.reader
+	if fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+		*name = filepath.Base(f.Name())
+	}
+
+	r, passphrase, err := a.encrypted(r)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client(ctx)
+	if err != nil {
+		return err
+	}
+	job, err := c.Import(ctx, *name, r, client.ImportOptions{Format: *format, DryRun: *dryRun, Passphrase: passphrase})
+	if err != nil {
+		return sessionError(err)
+	}
+	job, err = c.WaitImport(ctx, job.ID, importPollInterval)
+	if err != nil {
+		return sessionError(err)
+	}
+
+	report := job.Report
+	if *asJSON {
+		err = a.printJSON(report)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, n := range report.Notes {
+			if n.Error != "" {
+				fmt.Fprintf(a.stderr, "%s: %s\n", n.Source, n.Error)
+			}
+		}
+		verb := "Imported"
+		if report.DryRun {
+			verb = "Validated"
+		}
+		fmt.Fprintf(a.stdout, "%s %d of %d notes (%s)\n", verb, report.Total-report.Failed, report.Total, report.Format)
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d notes failed", report.Failed)
+	}
+	return nil
+}
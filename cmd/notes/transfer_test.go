@@ -0,0 +1,77 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnkamalesh/notes/pkg/archive"
+	"github.com/bnkamalesh/notes/pkg/client"
+)
+
+func TestDecryptAndImport(t *testing.T) {
+	ctx := context.Background()
+	a, c := newTestApp(t)
+	_, err := c.CreateItem(ctx, client.ItemInput{Title: "Groceries", Description: "Milk"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.zip.enc")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = c.Export(ctx, f, "correct horse")
+	f.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// stdin is not a terminal, the passphrase is read from the environment
+	pipe(t, a, "")
+
+	t.Setenv("NOTES_PASSPHRASE", "wrong horse")
+	err = runDecrypt(ctx, a, []string{path})
+	if err != archive.ErrPassphrase {
+		t.Fatalf("Expected '%v', got '%v'", archive.ErrPassphrase, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.zip")); !os.IsNotExist(err) {
+		t.Fatal("Expected no zip file to be written with a wrong passphrase")
+	}
+
+	t.Setenv("NOTES_PASSPHRASE", "correct horse")
+	out := filepath.Join(dir, "decrypted.zip")
+	err = runDecrypt(ctx, a, []string{"-o", out, path})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer zr.Close()
+	found := false
+	for _, f := range zr.File {
+		found = found || strings.HasPrefix(f.Name, "items/groceries")
+	}
+	if !found {
+		t.Fatal("Expected the item in the decrypted archive")
+	}
+
+	err = runDecrypt(ctx, a, []string{out})
+	if err != archive.ErrNotEncrypted {
+		t.Fatalf("Expected '%v', got '%v'", archive.ErrNotEncrypted, err)
+	}
+
+	err = runImport(ctx, a, []string{path})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if o := output(t, a); !strings.Contains(o, "Imported 1 of 1 notes") {
+		t.Fatalf("Expected the encrypted archive to be imported, got '%s'", o)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+	"google.golang.org/grpc"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+	"github.com/bnkamalesh/notes/pkg/services"
+)
+
+// app has everything stopped when the app shuts down
+type app struct {
+	router *webgo.Router
+	// grpc is nil if the gRPC API is not served
+	grpc     *grpc.Server
+	services services.Handler
+	storage  storage.Service
+	cache    cache.Service
+	logger   logger.Service
+}
+
+// shutdown stops the app gracefully. The app stops being ready, and keeps serving requests for
+// the drain delay so that the load balancers stop sending new ones. Then the event streams are
+// ended, the servers stop accepting connections and wait for the requests in flight, the
+// background work is stopped, and the connections to the store and the cache are closed. Every
+// wait is limited by the timeout.
+func (a *app) shutdown(drainDelay, timeout time.Duration) {
+	a.services.Health.Shutdown()
+	time.Sleep(drainDelay)
+
+	// the streams do not end on their own, the servers would wait for them until the timeout.
+	// The clients resume from their last event on another instance.
+	a.services.Events.Stop()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// the router logs its own errors
+		a.router.Shutdown()
+	}()
+	if a.grpc != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stopped := make(chan struct{})
+			go func() {
+				a.grpc.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(timeout):
+				a.logger.Error("gRPC server", "the calls in flight are canceled after the timeout")
+				a.grpc.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+
+	a.services.Scheduler.Stop()
+	a.services.Dispatcher.Stop()
+	if !a.services.Importer.Wait(timeout) {
+		a.logger.Warn("shutdown", "imports still running are interrupted")
+	}
+	tracing.Shutdown()
+
+	err := a.cache.Close()
+	if err != nil {
+		a.logger.Error("cache", err.Error())
+	}
+	err = a.storage.Close()
+	if err != nil {
+		a.logger.Error("storage", err.Error())
+	}
+	a.logger.Info("shutdown", "complete")
+}
@@ -3,27 +3,98 @@ package configs
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bnkamalesh/webgo"
 
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
+	"github.com/bnkamalesh/notes/pkg/platform/blob/s3"
 	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+	"github.com/bnkamalesh/notes/pkg/webhooks"
 )
 
-// Logs returns which all logs should be enabled
-func Logs() []string {
-	return []string{"all"}
+// Logs returns the configurations of the logs. All the lines are written as JSON to the console,
+// unless the levels, format or sinks are set. The levels file, if set, is read again on SIGHUP.
+// Debug lines are sampled to the first 100 per second from the same line of code, then one of
+// every 100.
+func Logs() logger.Config {
+	format := os.Getenv("notes_log_format")
+	if format != logger.FormatText {
+		format = logger.FormatJSON
+	}
+	var sinks []string
+	if s := os.Getenv("notes_log_sinks"); s != "" {
+		sinks = strings.Split(s, ",")
+	}
+	maxSize, _ := strconv.ParseInt(os.Getenv("notes_log_fileMaxSize"), 10, 64)
+	maxAgeDays, _ := strconv.Atoi(os.Getenv("notes_log_fileMaxAgeDays"))
+	maxBackups, _ := strconv.Atoi(os.Getenv("notes_log_fileMaxBackups"))
+	return logger.Config{
+		Level:      os.Getenv("notes_log_level"),
+		LevelsFile: os.Getenv("notes_log_levelsFile"),
+		Format:     format,
+		Sinks:      sinks,
+		File: logger.FileConfig{
+			Path:       os.Getenv("notes_log_file"),
+			MaxSize:    maxSize,
+			MaxAge:     time.Hour * 24 * time.Duration(maxAgeDays),
+			MaxBackups: maxBackups,
+		},
+		SyslogTag: "notes",
+		Sampling: logger.Sampling{
+			First:      100,
+			Thereafter: 100,
+			Interval:   time.Second,
+		},
+	}
+}
+
+// AdminToken returns the token required by the admin API, it's disabled if the token is empty
+func AdminToken() string {
+	return os.Getenv("notes_admin_token")
 }
 
 // Webgo returns the configurations required for webgo
 func Webgo() *webgo.Config {
+	_, timeout := Shutdown()
 	return &webgo.Config{
-		Host: "",
-		Port: os.Getenv("notes_app_httpPort"),
+		Host:            "",
+		Port:            os.Getenv("notes_app_httpPort"),
+		ShutdownTimeout: timeout,
 	}
 }
 
+// Shutdown returns how long the app keeps serving requests once it's not ready, for the load
+// balancers to stop sending new ones, and then how long it waits for the requests in flight
+// and the background work to complete. They're 5 and 30 seconds unless set in seconds.
+func Shutdown() (drainDelay, timeout time.Duration) {
+	drain, err := strconv.Atoi(os.Getenv("notes_app_drainDelay"))
+	if err != nil || drain < 0 {
+		drain = 5
+	}
+	t, err := strconv.Atoi(os.Getenv("notes_app_shutdownTimeout"))
+	if err != nil || t <= 0 {
+		t = 30
+	}
+	return time.Second * time.Duration(drain), time.Second * time.Duration(t)
+}
+
+// GRPC returns the address of the gRPC server, it's not started if the port is not set
+func GRPC() string {
+	port := os.Getenv("notes_app_grpcPort")
+	if port == "" {
+		return ""
+	}
+	return ":" + port
+}
+
 // Store returns the configuration required for the primary datastore
 func Store() storage.Config {
 	return storage.Config{
@@ -47,3 +118,80 @@ func Cache() cache.Config {
 		WriteTimeout: time.Millisecond * 75,
 	}
 }
+
+// Reminders returns the configuration required for firing reminders
+func Reminders() reminders.Config {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("notes_reminders_allowPrivate"))
+	return reminders.Config{
+		Interval:       time.Second * 15,
+		WebhookTimeout: time.Second * 10,
+		AllowPrivate:   allowPrivate,
+		SMTP: reminders.SMTPConfig{
+			Host:     os.Getenv("notes_smtp_host"),
+			Port:     os.Getenv("notes_smtp_port"),
+			Username: os.Getenv("notes_smtp_username"),
+			Password: os.Getenv("notes_smtp_password"),
+			From:     os.Getenv("notes_smtp_from"),
+		},
+	}
+}
+
+// Webhooks returns the configuration required to deliver the webhooks
+func Webhooks() webhooks.Config {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("notes_webhooks_allowPrivate"))
+	return webhooks.Config{
+		Interval:     time.Second * 5,
+		Timeout:      time.Second * 10,
+		AllowPrivate: allowPrivate,
+	}
+}
+
+// Blob returns the configuration required for the blob store of attachments
+func Blob() blob.Config {
+	pathStyle, _ := strconv.ParseBool(os.Getenv("notes_s3_pathStyle"))
+	return blob.Config{
+		Driver: os.Getenv("notes_blob_driver"),
+		Dir:    os.Getenv("notes_blob_dir"),
+		S3: s3.Config{
+			Endpoint:  os.Getenv("notes_s3_endpoint"),
+			Region:    os.Getenv("notes_s3_region"),
+			Bucket:    os.Getenv("notes_s3_bucket"),
+			AccessKey: os.Getenv("notes_s3_accessKey"),
+			SecretKey: os.Getenv("notes_s3_secretKey"),
+			PathStyle: pathStyle,
+			Timeout:   time.Second * 30,
+		},
+	}
+}
+
+// Attachments returns the configuration required for attachments
+func Attachments() attachments.Config {
+	quota, _ := strconv.ParseInt(os.Getenv("notes_attachments_quota"), 10, 64)
+	maxSize, _ := strconv.ParseInt(os.Getenv("notes_attachments_maxSize"), 10, 64)
+	return attachments.Config{
+		Quota:   quota,
+		MaxSize: maxSize,
+	}
+}
+
+// Tracing returns the configuration required to export the traces. Traces are exported to an
+// OpenTelemetry collector with the otlp exporter, or written as JSON lines with the stdout and
+// file exporters. Every new trace is exported unless a lower sample ratio is set.
+func Tracing() tracing.Config {
+	endpoint := os.Getenv("notes_tracing_endpoint")
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	ratio, err := strconv.ParseFloat(os.Getenv("notes_tracing_sampleRatio"), 64)
+	if err != nil {
+		ratio = 1
+	}
+	return tracing.Config{
+		Exporter:    os.Getenv("notes_tracing_exporter"),
+		Endpoint:    endpoint,
+		File:        os.Getenv("notes_tracing_file"),
+		ServiceName: "notes",
+		SampleRatio: ratio,
+		Timeout:     time.Second * 10,
+	}
+}
@@ -0,0 +1,314 @@
+// Package archive handles the portable archive of all the notes of a user. An archive is a zip
+// file with one Markdown file per item, the attachments of the items, and a JSON manifest. It
+// can optionally be encrypted with a passphrase, and decrypted back to the zip file with Decrypt.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+)
+
+const (
+	// Version is the version of the archive format
+	Version = 1
+
+	manifestName   = "manifest.json"
+	itemsDir       = "items"
+	attachmentsDir = "attachments"
+	maxSlugLength  = 60
+	saltSize       = 16
+)
+
+// magic is the prefix of an encrypted archive, it's followed by the salt of the passphrase and
+// the encrypted stream of the zip file
+var magic = []byte("NOTESENC\x01")
+
+var (
+	// ErrUnknownItem is returned when adding an attachment before its item
+	ErrUnknownItem = errors.New("archive: attachment added before its item")
+	// ErrNotEncrypted is returned when decrypting an archive which is not encrypted
+	ErrNotEncrypted = errs.Invalid("Sorry, the archive is not encrypted")
+	// ErrNoPassphrase is returned when an encrypted archive is read without a passphrase
+	ErrNoPassphrase = errs.Field("passphrase", "Sorry, the archive is encrypted, the passphrase is required")
+	// ErrPassphrase is returned while reading an archive which could not be decrypted, the
+	// passphrase is wrong or the archive is damaged
+	ErrPassphrase = errs.Field("passphrase", "Sorry, the archive could not be decrypted, please check the passphrase")
+)
+
+// Manifest lists everything in the archive
+type Manifest struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Items      []ManifestItem `json:"items"`
+}
+
+// ManifestItem is a single item in the archive
+type ManifestItem struct {
+	ID          string               `json:"id"`
+	Title       string               `json:"title"`
+	File        string               `json:"file"`
+	CreatedAt   *time.Time           `json:"createdAt,omitempty"`
+	ModifiedAt  *time.Time           `json:"modifiedAt,omitempty"`
+	DueAt       *time.Time           `json:"dueAt,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Attachments []ManifestAttachment `json:"attachments,omitempty"`
+}
+
+// ManifestAttachment is a single attachment in the archive
+type ManifestAttachment struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	File        string `json:"file"`
+}
+
+// Writer writes an archive as a stream, nothing other than the manifest is held in memory
+type Writer struct {
+	zw *zip.Writer
+	// enc is the encryption of the archive, it's nil if there's no passphrase
+	enc      io.WriteCloser
+	manifest Manifest
+	// items maps the item IDs to their index in the manifest
+	items map[string]int
+	// files are all the file names used in the archive
+	files map[string]bool
+}
+
+// NewWriter returns a writer of an archive to w. If the passphrase is not empty, the archive
+// is encrypted with a key derived from it.
+func NewWriter(w io.Writer, passphrase string) (*Writer, error) {
+	aw := &Writer{
+		manifest: Manifest{
+			Version:    Version,
+			ExportedAt: time.Now().UTC(),
+			Items:      make([]ManifestItem, 0),
+		},
+		items: map[string]int{},
+		files: map[string]bool{},
+	}
+
+	if passphrase != "" {
+		salt := make([]byte, saltSize)
+		_, err := io.ReadFull(rand.Reader, salt)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := crypto.PasswordKey(passphrase, salt)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = w.Write(append(append([]byte{}, magic...), salt...))
+		if err != nil {
+			return nil, err
+		}
+
+		aw.enc, err = crypto.EncryptWriter(key, w)
+		if err != nil {
+			return nil, err
+		}
+		w = aw.enc
+	}
+
+	aw.zw = zip.NewWriter(w)
+	return aw, nil
+}
+
+// IsEncrypted returns true if head, the start of a file, is the start of an encrypted archive
+func IsEncrypted(head []byte) bool {
+	return bytes.HasPrefix(head, magic)
+}
+
+// decryptReader replaces the errors of a decrypted stream with ErrPassphrase
+type decryptReader struct {
+	r io.Reader
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err == crypto.ErrMalformedCipher || err == crypto.ErrTruncated {
+		err = ErrPassphrase
+	}
+	return n, err
+}
+
+// Decrypt returns a reader of the zip file of an archive encrypted with the passphrase, read
+// from r. The reader returns ErrPassphrase if the archive cannot be decrypted.
+func Decrypt(r io.Reader, passphrase string) (io.Reader, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+
+	head := make([]byte, len(magic)+saltSize)
+	_, err := io.ReadFull(r, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrNotEncrypted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(head) {
+		return nil, ErrNotEncrypted
+	}
+
+	key, err := crypto.PasswordKey(passphrase, head[len(magic):])
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := crypto.DecryptReader(key, r, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: dr}, nil
+}
+
+// slug returns a file name friendly version of the string
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	out := strings.TrimSuffix(b.String(), "-")
+	if len(out) > maxSlugLength {
+		out = strings.TrimSuffix(string([]rune(out)[:maxSlugLength]), "-")
+	}
+	return out
+}
+
+// unique returns a file name not used in the archive yet, by adding a counter to the name
+func (w *Writer) unique(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	out := name
+	for i := 2; w.files[out]; i++ {
+		out = base + "-" + strconv.Itoa(i) + ext
+	}
+	w.files[out] = true
+	return out
+}
+
+func (w *Writer) create(name string, modified *time.Time) (io.Writer, error) {
+	fh := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	}
+	if modified != nil {
+		fh.Modified = *modified
+	}
+	return w.zw.CreateHeader(fh)
+}
+
+// AddItem adds a decrypted item to the archive as a Markdown file
+func (w *Writer) AddItem(item *items.Item) error {
+	name := slug(item.Title)
+	if name == "" {
+		name = item.ID
+	}
+	file := w.unique(path.Join(itemsDir, name+".md"))
+
+	f, err := w.create(file, item.ModifiedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(Markdown(item))
+	if err != nil {
+		return err
+	}
+
+	w.items[item.ID] = len(w.manifest.Items)
+	w.manifest.Items = append(w.manifest.Items, ManifestItem{
+		ID:         item.ID,
+		Title:      item.Title,
+		File:       file,
+		CreatedAt:  item.CreatedAt,
+		ModifiedAt: item.ModifiedAt,
+		DueAt:      item.DueAt,
+		Tags:       item.Tags,
+	})
+	return nil
+}
+
+// AddAttachment adds the decrypted content of an attachment, read from r, to the archive. The
+// item of the attachment should be added before.
+func (w *Writer) AddAttachment(a *attachments.Attachment, r io.Reader) error {
+	idx, ok := w.items[a.ItemID]
+	if !ok {
+		return ErrUnknownItem
+	}
+	mi := &w.manifest.Items[idx]
+
+	dir := strings.TrimSuffix(path.Base(mi.File), ".md")
+	file := w.unique(path.Join(attachmentsDir, dir, a.Name))
+
+	f, err := w.create(file, a.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+
+	mi.Attachments = append(mi.Attachments, ManifestAttachment{
+		ID:          a.ID,
+		Name:        a.Name,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		File:        file,
+	})
+	return nil
+}
+
+// Close writes the manifest and completes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	f, err := w.create(manifestName, &w.manifest.ExportedAt)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(w.manifest)
+	if err != nil {
+		return err
+	}
+
+	err = w.zw.Close()
+	if err != nil {
+		return err
+	}
+
+	if w.enc != nil {
+		return w.enc.Close()
+	}
+	return nil
+}
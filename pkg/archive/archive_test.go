@@ -0,0 +1,181 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+func testItem(id, title string) *items.Item {
+	now := time.Date(2018, 7, 2, 9, 0, 0, 0, time.UTC)
+	return &items.Item{
+		ID:          id,
+		Title:       title,
+		Description: "Restart the service",
+		Checklist: []items.Entry{
+			{ID: "e1", Text: "Drain", Checked: true},
+			{ID: "e2", Text: "Restart"},
+		},
+		Tags:       []string{"ops", "prod"},
+		CreatedAt:  &now,
+		ModifiedAt: &now,
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := string(Markdown(testItem("item_1", `Runbook: "prod"`)))
+	expected := `---
+id: "item_1"
+title: "Runbook: \"prod\""
+created: 2018-07-02T09:00:00Z
+modified: 2018-07-02T09:00:00Z
+tags: ["ops", "prod"]
+---
+
+Restart the service
+
+- [x] Drain
+- [ ] Restart
+`
+	if md != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, md)
+	}
+}
+
+func readZip(t *testing.T, b []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func writeArchive(t *testing.T, passphrase string) []byte {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, passphrase)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, item := range []*items.Item{testItem("item_1", "Runbook"), testItem("item_2", "Runbook")} {
+		err = w.AddItem(item)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	a := &attachments.Attachment{ID: "attachment_1", ItemID: "item_2", Name: "log.txt", Size: 5}
+	err = w.AddAttachment(a, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = w.AddAttachment(&attachments.Attachment{ItemID: "item_3"}, strings.NewReader(""))
+	if err != ErrUnknownItem {
+		t.Fatalf("Expected '%v', got '%v'", ErrUnknownItem, err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return buf.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	files := readZip(t, writeArchive(t, ""))
+
+	for _, name := range []string{"items/runbook.md", "items/runbook-2.md", "attachments/runbook-2/log.txt", manifestName} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("Expected '%s' in the archive", name)
+		}
+	}
+
+	m := Manifest{}
+	err := json.Unmarshal([]byte(files[manifestName]), &m)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(m.Items) != 2 || len(m.Items[1].Attachments) != 1 || m.Items[1].Attachments[0].File != "attachments/runbook-2/log.txt" {
+		t.Fatalf("Unexpected manifest %+v", m)
+	}
+}
+
+func TestWriterEncrypted(t *testing.T) {
+	b := writeArchive(t, "correct horse")
+	if !IsEncrypted(b) {
+		t.Fatal("Expected the encrypted archive to start with the magic bytes")
+	}
+
+	dr, err := Decrypt(bytes.NewReader(b), "correct horse")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	plain, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files := readZip(t, plain)
+	if files["attachments/runbook-2/log.txt"] != "hello" {
+		t.Fatal("Expected the attachment in the decrypted archive")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	b := writeArchive(t, "correct horse")
+
+	dr, err := Decrypt(bytes.NewReader(b), "wrong horse")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = io.ReadAll(dr)
+	if err != ErrPassphrase {
+		t.Fatalf("Expected '%v', got '%v'", ErrPassphrase, err)
+	}
+
+	dr, err = Decrypt(bytes.NewReader(b[:len(b)-1]), "correct horse")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = io.ReadAll(dr)
+	if err != ErrPassphrase {
+		t.Fatalf("Expected '%v' for a truncated archive, got '%v'", ErrPassphrase, err)
+	}
+
+	_, err = Decrypt(bytes.NewReader(writeArchive(t, "")), "correct horse")
+	if err != ErrNotEncrypted {
+		t.Fatalf("Expected '%v', got '%v'", ErrNotEncrypted, err)
+	}
+
+	_, err = Decrypt(bytes.NewReader(b), "")
+	if err != ErrNoPassphrase {
+		t.Fatalf("Expected '%v', got '%v'", ErrNoPassphrase, err)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for in, expected := range map[string]string{
+		"Hello, World!":  "hello-world",
+		"  ünïcode  ok ": "ünïcode-ok",
+		"!!!":            "",
+	} {
+		if out := slug(in); out != expected {
+			t.Fatalf("Expected '%s' for '%s', got '%s'", expected, in, out)
+		}
+	}
+}
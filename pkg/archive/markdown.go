@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+const frontMatterDelim = "---"
+
+// yamlString quotes the string as YAML. A JSON string is a valid double quoted YAML scalar.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func writeField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func writeTime(buf *bytes.Buffer, key string, t *time.Time) {
+	if t == nil {
+		return
+	}
+	writeField(buf, key, t.UTC().Format(time.RFC3339))
+}
+
+// Markdown returns the decrypted item as Markdown, with its metadata in a YAML front matter
+// and the checklist as a task list after the description
+func Markdown(item *items.Item) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(frontMatterDelim + "\n")
+	writeField(buf, "id", yamlString(item.ID))
+	writeField(buf, "title", yamlString(item.Title))
+	writeTime(buf, "created", item.CreatedAt)
+	writeTime(buf, "modified", item.ModifiedAt)
+	writeTime(buf, "due", item.DueAt)
+	if len(item.Tags) > 0 {
+		tags := make([]string, 0, len(item.Tags))
+		for _, t := range item.Tags {
+			tags = append(tags, yamlString(t))
+		}
+		writeField(buf, "tags", "["+strings.Join(tags, ", ")+"]")
+	}
+	buf.WriteString(frontMatterDelim + "\n\n")
+
+	if item.Description != "" {
+		buf.WriteString(strings.TrimRight(item.Description, "\n"))
+		buf.WriteString("\n")
+	}
+
+	if len(item.Checklist) > 0 {
+		if item.Description != "" {
+			buf.WriteString("\n")
+		}
+		for _, e := range item.Checklist {
+			if e.Checked {
+				buf.WriteString("- [x] ")
+			} else {
+				buf.WriteString("- [ ] ")
+			}
+			buf.WriteString(strings.ReplaceAll(e.Text, "\n", " "))
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,303 @@
+// Package attachments handles the files attached to items. Every attachment is encrypted with
+// its own key, which is wrapped with the content key of the item. Attachments are encrypted
+// and decrypted as streams, so they are never held in memory as a whole.
+package attachments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	attachmentsBucket  = "attachments"
+	defaultName        = "attachment"
+	defaultContentType = "application/octet-stream"
+)
+
+var (
+	// ErrCreate is returned if there's an error saving an attachment
+	ErrCreate = errors.New("Sorry, an error occurred while saving the attachment")
+	// ErrNotFound is returned if the attachment does not exist
+	ErrNotFound = errs.NotFound("Sorry, attachment not found")
+	// ErrQuota is returned if the attachment would exceed the quota of the user
+	ErrQuota = errs.TooLarge("Sorry, you've run out of storage for attachments")
+	// ErrTooLarge is returned if the attachment is larger than the maximum size allowed
+	ErrTooLarge = errs.TooLarge("Sorry, the attachment is too large")
+)
+
+// Attachment is the metadata of a file attached to an item
+type Attachment struct {
+	ID     string `json:"id,omitempty" bson:"id,omitempty"`
+	ItemID string `json:"itemID,omitempty" bson:"itemID,omitempty"`
+	// OwnerID is the owner ID of the item, the attachment is counted in the owner's quota
+	OwnerID     string `json:"-" bson:"ownerID,omitempty"`
+	Name        string `json:"name,omitempty" bson:"name,omitempty"`
+	ContentType string `json:"contentType,omitempty" bson:"contentType,omitempty"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size" bson:"size"`
+	// Key is the key of the attachment wrapped with the content key of the item
+	Key       []byte     `json:"-" bson:"key,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+}
+
+// Usage is the storage used by the attachments of a user
+type Usage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
+
+func newAttachmentID() string {
+	return fmt.Sprintf("attachment_%s", uuid.New().String())
+}
+
+// blobKey returns the key of the blob of the attachment in the blob store
+func (a *Attachment) blobKey() string {
+	return path.Join(attachmentsBucket, a.ItemID, a.ID)
+}
+
+// cleanName returns the base name of the file without any control characters
+func cleanName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return defaultName
+	}
+	return name
+}
+
+// limitReader returns ErrTooLarge or ErrQuota as soon as more than the allowed bytes are read
+type limitReader struct {
+	r       io.Reader
+	n       int64
+	maxSize int64
+	left    int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.maxSize {
+		return n, ErrTooLarge
+	}
+	if l.n > l.left {
+		return n, ErrQuota
+	}
+	return n, err
+}
+
+// Create encrypts and saves everything read from r as an attachment of the item. key is the
+// content key of the item. The usage is checked again once the attachment is saved, since the
+// uploads of the owner running at the same time were all checked against the same usage; the
+// attachment is removed if they're over the quota together.
+func (s *Service) Create(ownerID, itemID, name, contentType string, key [crypto.KeySize]byte, r io.Reader) (*Attachment, error) {
+	usage, err := s.Usage(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if usage.Used >= usage.Quota {
+		return nil, ErrQuota
+	}
+
+	fileKey, err := crypto.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := crypto.WrapKey(key, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+
+	now := time.Now()
+	a := Attachment{
+		ID:          newAttachmentID(),
+		ItemID:      itemID,
+		OwnerID:     ownerID,
+		Name:        cleanName(name),
+		ContentType: contentType,
+		Key:         wrapped,
+		CreatedAt:   &now,
+	}
+
+	lr := &limitReader{r: r, maxSize: s.maxSize, left: usage.Quota - usage.Used}
+	er, err := crypto.EncryptReader(fileKey, lr)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.blob.Put(a.blobKey(), er)
+	if err != nil {
+		// the part written before the error may have been kept
+		s.blob.Delete(a.blobKey())
+		if errors.Is(err, ErrQuota) {
+			return nil, ErrQuota
+		}
+		if errors.Is(err, ErrTooLarge) {
+			return nil, ErrTooLarge
+		}
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+	a.Size = lr.n
+
+	_, err = s.store.Save(attachmentsBucket, a)
+	if err != nil {
+		s.logger.Error(err.Error())
+		s.blob.Delete(a.blobKey())
+		return nil, ErrCreate
+	}
+
+	usage, err = s.Usage(ownerID)
+	if err != nil {
+		s.Delete(&a)
+		return nil, err
+	}
+	if usage.Used > usage.Quota {
+		s.Delete(&a)
+		return nil, ErrQuota
+	}
+	return &a, nil
+}
+
+// Read reads an attachment given its ID
+func (s *Service) Read(id string) (*Attachment, error) {
+	a := Attachment{}
+	_, err := s.store.FindOne(attachmentsBucket, map[string]interface{}{"id": id}, nil, nil, &a)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *Service) find(query map[string]interface{}) ([]Attachment, error) {
+	out := make([]Attachment, 0)
+	_, err := s.store.Find(attachmentsBucket, query, nil, []string{"createdAt"}, 0, 0, &out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// ByItem returns all the attachments of an item
+func (s *Service) ByItem(itemID string) ([]Attachment, error) {
+	return s.find(map[string]interface{}{"itemID": itemID})
+}
+
+// Usage returns the storage used by all the attachments of the owner
+func (s *Service) Usage(ownerID string) (*Usage, error) {
+	aa, err := s.find(map[string]interface{}{"ownerID": ownerID})
+	if err != nil {
+		return nil, err
+	}
+
+	u := &Usage{Quota: s.quota}
+	for _, a := range aa {
+		u.Used += a.Size
+	}
+	return u, nil
+}
+
+// Open returns a reader of the decrypted attachment. key is the content key of the item.
+func (s *Service) Open(a *Attachment, key [crypto.KeySize]byte) (*Reader, error) {
+	fileKey, err := crypto.UnwrapKey(key, a.Key)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(s.blob, a.blobKey(), fileKey, a.Size), nil
+}
+
+// Rekey wraps the keys of all the attachments of the item with the new content key of the item.
+// The attachments already wrapped with the new key are skipped, so that it can be done again
+// if it fails midway.
+func (s *Service) Rekey(itemID string, oldKey, newKey [crypto.KeySize]byte) error {
+	aa, err := s.ByItem(itemID)
+	if err != nil {
+		return err
+	}
+
+	for idx := range aa {
+		a := &aa[idx]
+		_, err = crypto.UnwrapKey(newKey, a.Key)
+		if err == nil {
+			continue
+		}
+
+		fileKey, err := crypto.UnwrapKey(oldKey, a.Key)
+		if err != nil {
+			return err
+		}
+
+		a.Key, err = crypto.WrapKey(newKey, fileKey)
+		if err != nil {
+			return err
+		}
+
+		err = s.store.Update(attachmentsBucket, map[string]interface{}{"id": a.ID}, a)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete deletes the attachment along with its blob
+func (s *Service) Delete(a *Attachment) error {
+	err := s.blob.Delete(a.blobKey())
+	if err != nil && err != blob.ErrNotFound {
+		s.logger.Error(err.Error())
+		return err
+	}
+
+	err = s.store.Delete(attachmentsBucket, map[string]interface{}{"id": a.ID})
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteByItem deletes all the attachments of an item along with their blobs
+func (s *Service) DeleteByItem(itemID string) error {
+	aa, err := s.ByItem(itemID)
+	if err != nil {
+		return err
+	}
+
+	for idx := range aa {
+		err = s.Delete(&aa[idx])
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
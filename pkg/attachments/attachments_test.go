@@ -0,0 +1,158 @@
+package attachments
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/blob/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+func newTestReader(t *testing.T, plain []byte) *Reader {
+	fileKey, _ := crypto.NewKey()
+	er, err := crypto.EncryptReader(fileKey, bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	bs := memory.New()
+	_, err = bs.Put("blob", er)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return NewReader(bs, "blob", fileKey, int64(len(plain)))
+}
+
+func TestReader(t *testing.T) {
+	plain := make([]byte, crypto.ChunkSize*2+100)
+	rand.Read(plain)
+	r := newTestReader(t, plain)
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(b, plain) {
+		t.Fatal("Decrypted attachment does not match")
+	}
+
+	offset := int64(crypto.ChunkSize + 10)
+	_, err = r.Seek(offset, io.SeekStart)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b = make([]byte, 20)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(b, plain[offset:offset+20]) {
+		t.Fatal("Read after seek does not match")
+	}
+}
+
+func TestReaderRange(t *testing.T) {
+	plain := make([]byte, crypto.ChunkSize*3)
+	rand.Read(plain)
+	r := newTestReader(t, plain)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=65530-65545")
+	rw := httptest.NewRecorder()
+	http.ServeContent(rw, req, "file", time.Now(), r)
+
+	if rw.Code != http.StatusPartialContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusPartialContent, rw.Code)
+	}
+	if !bytes.Equal(rw.Body.Bytes(), plain[65530:65546]) {
+		t.Fatal("Range does not match")
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	lr := &limitReader{r: bytes.NewReader(make([]byte, 100)), maxSize: 50, left: 1000}
+	_, err := io.ReadAll(lr)
+	if err != ErrTooLarge {
+		t.Fatalf("Expected '%v', got '%v'", ErrTooLarge, err)
+	}
+
+	lr = &limitReader{r: bytes.NewReader(make([]byte, 100)), maxSize: 1000, left: 50}
+	_, err = io.ReadAll(lr)
+	if err != ErrQuota {
+		t.Fatalf("Expected '%v', got '%v'", ErrQuota, err)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for in, expected := range map[string]string{
+		"report.pdf":           "report.pdf",
+		"../../etc/passwd":     "passwd",
+		"C:\\Users\\me\\a.txt": "a.txt",
+		"bad\nname.txt":        "badname.txt",
+		"":                     defaultName,
+		"/":                    defaultName,
+	} {
+		if out := cleanName(in); out != expected {
+			t.Fatalf("Expected '%s' for '%s', got '%s'", expected, in, out)
+		}
+	}
+}
+
+// uploadDuring is a reader which runs an upload the first time it's read, like an upload of
+// the same owner running at the same time
+type uploadDuring struct {
+	io.Reader
+	upload func()
+}
+
+func (u *uploadDuring) Read(p []byte) (int, error) {
+	if u.upload != nil {
+		u.upload()
+		u.upload = nil
+	}
+	return u.Reader.Read(p)
+}
+
+func TestCreateQuota(t *testing.T) {
+	bs := memory.New()
+	s := NewService(storagemem.New(), bs, logger.New(nil), Config{Quota: 10})
+	key, _ := crypto.NewKey()
+
+	var other *Attachment
+	r := &uploadDuring{
+		Reader: bytes.NewReader(make([]byte, 8)),
+		upload: func() {
+			var err error
+			other, err = s.Create("owner", "item_1", "other", "", key, bytes.NewReader(make([]byte, 8)))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		},
+	}
+	_, err := s.Create("owner", "item_1", "first", "", key, r)
+	if err != ErrQuota {
+		t.Fatalf("Expected '%v', got '%v'", ErrQuota, err)
+	}
+
+	aa, err := s.ByItem("item_1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(aa) != 1 || aa[0].ID != other.ID {
+		t.Fatalf("Expected only the attachment within the quota to be kept, got %+v", aa)
+	}
+	usage, _ := s.Usage("owner")
+	if usage.Used != 8 {
+		t.Fatalf("Expected 8 bytes used, got %d", usage.Used)
+	}
+	if n := bs.Len(); n != 1 {
+		t.Fatalf("Expected the blob of the removed attachment to be deleted, got %d blobs", n)
+	}
+}
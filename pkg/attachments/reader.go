@@ -0,0 +1,113 @@
+package attachments
+
+import (
+	"errors"
+	"io"
+
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+)
+
+// errSeek is returned when seeking to a negative position
+var errSeek = errors.New("attachments: invalid seek position")
+
+// Reader reads a decrypted attachment. It implements io.ReadSeeker, so it can be served with
+// http.ServeContent for range requests. Only the chunks from the current position are fetched
+// from the blob store.
+type Reader struct {
+	blob blob.Service
+	key  string
+	// fileKey is the key of the attachment
+	fileKey [crypto.KeySize]byte
+	size    int64
+	pos     int64
+
+	rc io.ReadCloser
+	r  io.Reader
+}
+
+// NewReader returns a reader of the blob encrypted with the file key. size is the size of the
+// decrypted attachment.
+func NewReader(bs blob.Service, key string, fileKey [crypto.KeySize]byte, size int64) *Reader {
+	return &Reader{
+		blob:    bs,
+		key:     key,
+		fileKey: fileKey,
+		size:    size,
+	}
+}
+
+// open starts decrypting from the chunk of the current position
+func (r *Reader) open() error {
+	chunk := r.pos / crypto.ChunkSize
+	rc, err := r.blob.Get(r.key, crypto.ChunkOffset(chunk), -1)
+	if err != nil {
+		return err
+	}
+
+	dr, err := crypto.DecryptReader(r.fileKey, rc, chunk, r.size)
+	if err != nil {
+		rc.Close()
+		return err
+	}
+
+	_, err = io.CopyN(io.Discard, dr, r.pos-chunk*crypto.ChunkSize)
+	if err != nil {
+		rc.Close()
+		return err
+	}
+
+	r.rc = rc
+	r.r = dr
+	return nil
+}
+
+// Read reads the decrypted attachment from the current position
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.r == nil {
+		err := r.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.r.Read(p)
+	r.pos += int64(n)
+	return n, err
+}
+
+// Seek sets the position of the next read. The blob is fetched again on the next read only if
+// the position changes.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	pos := offset
+	switch whence {
+	case io.SeekCurrent:
+		pos += r.pos
+	case io.SeekEnd:
+		pos += r.size
+	}
+	if pos < 0 {
+		return r.pos, errSeek
+	}
+
+	if pos != r.pos {
+		r.Close()
+		r.pos = pos
+	}
+	return pos, nil
+}
+
+// Close closes the blob being read
+func (r *Reader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.rc = nil
+	r.r = nil
+	return err
+}
@@ -0,0 +1,46 @@
+package attachments
+
+import (
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	defaultQuota   = 1024 * 1024 * 1024
+	defaultMaxSize = 100 * 1024 * 1024
+)
+
+// Service holds all the dependencies of attachments
+type Service struct {
+	store   storage.Service
+	blob    blob.Service
+	logger  logger.Service
+	quota   int64
+	maxSize int64
+}
+
+// Config holds all the configurations required for attachments
+type Config struct {
+	// Quota is the total size of attachments allowed per user, in bytes
+	Quota int64
+	// MaxSize is the maximum size of a single attachment, in bytes
+	MaxSize int64
+}
+
+// NewService returns a new instance of Service with all the dependencies initialized
+func NewService(ss storage.Service, bs blob.Service, l logger.Service, c Config) Service {
+	if c.Quota <= 0 {
+		c.Quota = defaultQuota
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = defaultMaxSize
+	}
+	return Service{
+		store:   ss,
+		blob:    bs,
+		logger:  l,
+		quota:   c.Quota,
+		maxSize: c.MaxSize,
+	}
+}
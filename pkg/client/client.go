@@ -0,0 +1,271 @@
+// Package client is the Go client of the notes API.
+//
+//	c := client.New("https://notes.example.com")
+//	_, err := c.Login(ctx, "jane@example.com", "password")
+//	...
+//	it := c.Items(ctx, client.ListOptions{Sort: "-modified"})
+//	for it.Next() {
+//		fmt.Println(it.Item().Title)
+//	}
+//	if it.Err() != nil {
+//		...
+//	}
+//
+// Idempotent requests (GET, PUT and DELETE) are retried with an exponential backoff when the
+// request fails on the network, or the server is temporarily unavailable.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// apiVersion is the prefix of the version of the API used by the client
+	apiVersion = "/v1"
+
+	defaultRetries = 3
+	defaultBackoff = 200 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// Client is a client of the notes API, it's safe for concurrent use
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option configures the client
+type Option func(c *Client)
+
+// WithHTTPClient sets the HTTP client used for all the requests
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken sets the auth token of a previous login
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRetries sets the number of retries of the idempotent requests, and the delay before the
+// first retry. The delay doubles with every retry.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client of the API served at baseURL, e.g. https://notes.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the current auth token
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken sets the auth token used for the requests
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// response is the envelope of the successful responses
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Status int             `json:"status"`
+}
+
+// idempotent returns true if the request can be safely sent more than once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable returns true if the request failed with a status which may not be returned on
+// a retry
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the server in the Retry-After header, if any
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After")))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// do sends the request and decodes the data of the response into result. body is encoded as
+// JSON, if not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, contentType string, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := c.baseURL + apiVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	retries := 0
+	if idempotent(method) {
+		retries = c.retries
+	}
+
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u, payload, contentType)
+		if attempt < retries && (err != nil || retryable(resp.StatusCode)) {
+			wait := delay
+			if ra := retryAfter(resp); ra > wait {
+				wait = ra
+			}
+			if resp != nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+
+			delay *= 2
+			if delay > maxBackoff {
+				delay = maxBackoff
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return decodeResponse(resp, result)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, payload []byte, contentType string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return c.httpClient.Do(req)
+}
+
+// stream sends the request with the body as is, and returns the response if it's successful.
+// It's not retried, since the body can be read only once. The response body should be closed
+// by the caller.
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	u := c.baseURL + apiVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// decodeResponse decodes the data of a successful response into result, or the error of a
+// failed one
+func decodeResponse(resp *http.Response, result interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	r := response{}
+	err := json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(r.Data, result)
+}
@@ -0,0 +1,490 @@
+package client
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/webgo"
+
+	"github.com/bnkamalesh/notes/api"
+	"github.com/bnkamalesh/notes/pkg/archive"
+	"github.com/bnkamalesh/notes/pkg/services/servicestest"
+	"github.com/bnkamalesh/notes/pkg/testenv"
+)
+
+// newServer returns a server running the API, with the data in memory
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	env := testenv.New(t)
+	h := api.NewHandler(servicestest.New(env), env.Logger)
+	server := httptest.NewServer(api.NewRouter(&h, &webgo.Config{}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(t *testing.T) *Client {
+	t.Helper()
+	ctx := context.Background()
+	c := New(newServer(t).URL)
+	_, err := c.Signup(ctx, "Jane", "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = c.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return c
+}
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+	c := New(newServer(t).URL)
+
+	_, err := c.Signup(ctx, "", "", "")
+	e := &Error{}
+	if !errors.As(err, &e) || !errors.Is(err, ErrValidation) || e.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if len(e.Details) < 2 || e.RequestID == "" {
+		t.Fatalf("Expected the details of the invalid fields and the request ID, got %+v", e)
+	}
+
+	_, err = c.Signup(ctx, "Jane", "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = c.Login(ctx, "jane@example.com", "wrong")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected %v, got %v", ErrUnauthorized, err)
+	}
+
+	user, err := c.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if c.Token() == "" || c.Token() != user.AuthToken {
+		t.Fatal("Expected the client to use the token of the login")
+	}
+
+	old := c.Token()
+	_, err = c.RefreshToken(ctx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if c.Token() == old {
+		t.Fatal("Expected a new token")
+	}
+	_, err = c.ListItems(ctx, ListOptions{})
+	if err != nil {
+		t.Fatalf("Expected the new token to work, got %v", err)
+	}
+
+	c.SetToken(old)
+	_, err = c.ListItems(ctx, ListOptions{})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected the old token to be rejected with %v, got %v", ErrUnauthorized, err)
+	}
+}
+
+func TestItems(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+
+	due := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	item, err := c.CreateItem(ctx, ItemInput{
+		Title:       "Groceries",
+		Description: "milk",
+		DueAt:       &due,
+		Tags:        []string{"home"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.ID == "" || item.Title != "Groceries" || item.DueAt == nil || !item.DueAt.Equal(due) {
+		t.Fatalf("Unexpected item %+v", item)
+	}
+
+	item, err = c.UpdateItem(ctx, item.ID, ItemInput{Title: "Shopping", Description: "milk, eggs"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	item, err = c.PatchItem(ctx, item.ID, map[string]interface{}{"description": "bread"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	item, err = c.Item(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Title != "Shopping" || item.Description != "bread" {
+		t.Fatalf("Unexpected item %+v", item)
+	}
+
+	found, err := c.SearchItems(ctx, "shopping", 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(found) != 1 || found[0].ID != item.ID {
+		t.Fatalf("Expected to find the item, got %+v", found)
+	}
+
+	err = c.DeleteItem(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = c.Item(ctx, item.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected %v, got %v", ErrNotFound, err)
+	}
+}
+
+func TestItemIterator(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+
+	for i := 0; i < 5; i++ {
+		_, err := c.CreateItem(ctx, ItemInput{Title: "item " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	it := c.Items(ctx, ListOptions{Sort: "title", Limit: 2})
+	titles := []string{}
+	for it.Next() {
+		titles = append(titles, it.Item().Title)
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err().Error())
+	}
+	if len(titles) != 5 {
+		t.Fatalf("Expected 5 items, got %v", titles)
+	}
+	for i, title := range titles {
+		if title != "item "+strconv.Itoa(i) {
+			t.Fatalf("Expected the items sorted by title, got %v", titles)
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	calls := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		webgo.R200(rw, Item{ID: "1"})
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	item, err := c.Item(ctx, "1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.ID != "1" || calls != 3 {
+		t.Fatalf("Expected the item after 3 calls, got %+v after %d calls", item, calls)
+	}
+
+	calls = 0
+	_, err = c.CreateItem(ctx, ItemInput{Title: "title"})
+	e := &Error{}
+	if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Fatalf("Expected the POST to not be retried, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	c = New(server.URL, WithRetries(5, time.Second))
+	_, err = c.Item(ctx, "1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+
+	// large enough for the archive to be written in several parts
+	md := &strings.Builder{}
+	md.WriteString("---\ntags: [home]\n---\n# Groceries\n\n")
+	for i := 0; i < 20000; i++ {
+		md.WriteString("milk " + strconv.Itoa(i*7919) + "\n")
+	}
+	job, err := c.Import(ctx, "groceries.md", strings.NewReader(md.String()), ImportOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	job, err = c.WaitImport(ctx, job.ID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if job.Report == nil || job.Report.Imported != 1 {
+		t.Fatalf("Expected 1 note imported, got %+v", job.Report)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = c.Export(ctx, buf, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(zr.File) == 0 {
+		t.Fatal("Expected the exported items in the archive")
+	}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_, err = io.Copy(io.Discard, r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Corrupt file %s in the archive: %s", f.Name, err.Error())
+		}
+	}
+}
+
+func TestTransferEncrypted(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+	_, err := c.CreateItem(ctx, ItemInput{Title: "Groceries", Description: "Milk"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = c.Export(ctx, buf, "correct horse")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the archive can be decrypted without the server
+	dr, err := archive.Decrypt(bytes.NewReader(buf.Bytes()), "correct horse")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	plain, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = zip.NewReader(bytes.NewReader(plain), int64(len(plain)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, passphrase := range []string{"", "wrong horse"} {
+		_, err = c.Import(ctx, "export.zip.enc", bytes.NewReader(buf.Bytes()), ImportOptions{Passphrase: passphrase})
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("Expected '%v' with the passphrase '%s', got '%v'", ErrValidation, passphrase, err)
+		}
+	}
+
+	job, err := c.Import(ctx, "export.zip.enc", bytes.NewReader(buf.Bytes()), ImportOptions{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	job, err = c.WaitImport(ctx, job.ID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if job.Format != "markdown" || job.Report == nil || job.Report.Imported != 1 {
+		t.Fatalf("Expected the item imported from the encrypted archive, got %+v", job)
+	}
+}
+
+// readEvent returns the ID, type and data of the next server-sent event
+func readEvent(t *testing.T, r *bufio.Reader) (string, string, map[string]interface{}) {
+	t.Helper()
+	id, typ, data := "", "", map[string]interface{}{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if typ != "" {
+				return id, typ, data
+			}
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+
+	sctx, cancel := context.WithCancel(ctx)
+	resp, err := c.stream(sctx, http.MethodGet, "/events", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+
+	item, err := c.CreateItem(ctx, ItemInput{Title: "Groceries"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, typ, data := readEvent(t, r)
+	if typ != "item.created" || data["itemID"] != item.ID {
+		t.Fatalf("Expected the creation of %s, got %s %v", item.ID, typ, data)
+	}
+	_, err = c.UpdateItem(ctx, item.ID, ItemInput{Title: "Groceries", Description: "milk"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	lastID, typ, data := readEvent(t, r)
+	if typ != "item.updated" || data["itemID"] != item.ID || data["version"] == nil {
+		t.Fatalf("Expected the update of %s, got %s %v", item.ID, typ, data)
+	}
+	cancel()
+	resp.Body.Close()
+
+	err = c.DeleteItem(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the events missed while disconnected are sent first when resuming
+	header := http.Header{}
+	header.Set("Last-Event-ID", lastID)
+	resp, err = c.stream(ctx, http.MethodGet, "/events", nil, nil, header)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	_, typ, data = readEvent(t, bufio.NewReader(resp.Body))
+	if typ != "item.deleted" || data["itemID"] != item.ID {
+		t.Fatalf("Expected the deletion of %s, got %s %v", item.ID, typ, data)
+	}
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+
+	item, err := c.CreateItem(ctx, ItemInput{Title: "Groceries", Description: "milk"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	changes, err := c.Changes(ctx, 0, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(changes.Changes) != 1 || changes.Changes[0].Type != ChangeCreated || changes.Changes[0].Item.Description != "milk" {
+		t.Fatalf("Expected the created item, got %+v", changes)
+	}
+	since := changes.Seq
+
+	// changes made offline, one of them on the latest version of the item
+	results, err := c.Sync(ctx, []SyncChange{
+		{Op: SyncCreate, ClientID: "local-1", Item: &ItemContent{Title: "Books", Tags: []string{"home"}}},
+		{Op: SyncUpdate, ID: item.ID, BaseSeq: item.Seq, Item: &ItemContent{Title: "Groceries", Description: "milk, eggs"}},
+		{Op: "move", ID: item.ID},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %+v", results)
+	}
+	if results[0].Status != SyncApplied || results[0].ClientID != "local-1" || results[0].ID == "" {
+		t.Fatalf("Expected the item to be created, got %+v", results[0])
+	}
+	if results[1].Status != SyncApplied || results[1].Item.Description != "milk, eggs" {
+		t.Fatalf("Expected the item to be updated, got %+v", results[1])
+	}
+	if results[2].Status != SyncFailed || results[2].Error == "" {
+		t.Fatalf("Expected an unknown operation to fail, got %+v", results[2])
+	}
+
+	// another client changing the same version keeps both versions
+	results, err = c.Sync(ctx, []SyncChange{
+		{Op: SyncUpdate, ID: item.ID, BaseSeq: item.Seq, Item: &ItemContent{Title: "Groceries", Description: "bread"}},
+		{Op: SyncDelete, ID: item.ID, BaseSeq: item.Seq},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if results[0].Status != SyncConflict || results[0].ConflictID == "" || results[0].Item.Title != "Groceries (conflict copy)" {
+		t.Fatalf("Expected a conflict copy, got %+v", results[0])
+	}
+	if results[1].Status != SyncConflict || results[1].Item.Description != "milk, eggs" {
+		t.Fatalf("Expected the deletion to conflict, got %+v", results[1])
+	}
+	latest, err := c.Item(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if latest.Description != "milk, eggs" {
+		t.Fatalf("Expected the item to be unchanged, got %+v", latest)
+	}
+
+	err = c.DeleteItem(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// the changes of a deleted item are replaced by its deletion
+	changes, err = c.Changes(ctx, since, 2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(changes.Changes) != 2 || !changes.More || changes.Changes[1].Seq != changes.Seq {
+		t.Fatalf("Expected the first page of changes, got %+v", changes)
+	}
+	if changes.Changes[0].Type != ChangeCreated || changes.Changes[1].ID != results[0].ConflictID {
+		t.Fatalf("Expected the created item and the conflict copy, got %+v", changes.Changes)
+	}
+	changes, err = c.Changes(ctx, changes.Seq, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(changes.Changes) != 1 || changes.More || changes.Changes[0].Type != ChangeDeleted || changes.Changes[0].ID != item.ID {
+		t.Fatalf("Expected the deletion of the item, got %+v", changes)
+	}
+
+	// updating a deleted item saves a copy of it
+	results, err = c.Sync(ctx, []SyncChange{
+		{Op: SyncUpdate, ID: item.ID, BaseSeq: latest.Seq, Item: &ItemContent{Title: "Groceries"}},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if results[0].Status != SyncConflict || results[0].ConflictID == "" {
+		t.Fatalf("Expected a conflict copy, got %+v", results[0])
+	}
+}
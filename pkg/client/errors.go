@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// CodeInternal is returned for unexpected errors on the server
+	CodeInternal = "internal"
+	// CodeValidation is returned when the input is invalid, Details lists the invalid fields
+	CodeValidation = "validation_failed"
+	// CodeNotFound is returned when the resource does not exist
+	CodeNotFound = "not_found"
+	// CodeConflict is returned when the resource conflicts with an existing one
+	CodeConflict = "conflict"
+	// CodeUnauthorized is returned when the auth token is missing, invalid or expired
+	CodeUnauthorized = "unauthorized"
+	// CodeForbidden is returned when the user is not allowed to access the resource
+	CodeForbidden = "forbidden"
+	// CodeTooLarge is returned when the request body is too large
+	CodeTooLarge = "too_large"
+)
+
+var (
+	// ErrInternal matches the errors with the code internal
+	ErrInternal = &Error{Code: CodeInternal}
+	// ErrValidation matches the errors with the code validation_failed
+	ErrValidation = &Error{Code: CodeValidation}
+	// ErrNotFound matches the errors with the code not_found
+	ErrNotFound = &Error{Code: CodeNotFound}
+	// ErrConflict matches the errors with the code conflict
+	ErrConflict = &Error{Code: CodeConflict}
+	// ErrUnauthorized matches the errors with the code unauthorized
+	ErrUnauthorized = &Error{Code: CodeUnauthorized}
+	// ErrForbidden matches the errors with the code forbidden
+	ErrForbidden = &Error{Code: CodeForbidden}
+	// ErrTooLarge matches the errors with the code too_large
+	ErrTooLarge = &Error{Code: CodeTooLarge}
+
+	// statusCodes are the codes of the errors without a body, e.g. returned by a proxy
+	statusCodes = map[int]string{
+		http.StatusBadRequest:            CodeValidation,
+		http.StatusUnauthorized:          CodeUnauthorized,
+		http.StatusForbidden:             CodeForbidden,
+		http.StatusNotFound:              CodeNotFound,
+		http.StatusConflict:              CodeConflict,
+		http.StatusRequestEntityTooLarge: CodeTooLarge,
+	}
+)
+
+// FieldError is the error of an invalid field in the input
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error returned by the API. Use errors.Is with the Err variables to check the kind
+// of the error.
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Details    []FieldError `json:"details"`
+	// RequestID identifies the request in the logs of the server
+	RequestID string `json:"requestId"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is returns true if the target is an *Error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// decodeError decodes the error in the response. Responses without an error body get a code
+// based on their status.
+func decodeError(resp *http.Response) error {
+	e := &Error{}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = json.Unmarshal(b, e)
+
+	e.StatusCode = resp.StatusCode
+	if e.Code == "" {
+		e.Code = statusCodes[resp.StatusCode]
+		if e.Code == "" {
+			e.Code = CodeInternal
+		}
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	return e
+}
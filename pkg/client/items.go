@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// Entry is an entry of the checklist of an item
+type Entry struct {
+	ID      string `json:"id"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+// Progress is the progress of the checklist of an item
+type Progress struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
+	Open  int `json:"open"`
+}
+
+// Item is a note of the user
+type Item struct {
+	ID          string     `json:"id,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Checklist   []Entry    `json:"checklist,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Progress    *Progress  `json:"progress,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	ModifiedAt  *time.Time `json:"modifiedAt,omitempty"`
+	// Seq is the version of the item, it's the sequence of its last change
+	Seq int64 `json:"seq,omitempty"`
+}
+
+// ItemInput is the input to create or replace an item
+type ItemInput struct {
+	Title       string
+	Description string
+	DueAt       *time.Time
+	Tags        []string
+}
+
+func (in ItemInput) body() map[string]string {
+	body := map[string]string{
+		"title":       in.Title,
+		"description": in.Description,
+	}
+	if in.DueAt != nil {
+		body["dueAt"] = in.DueAt.Format(time.RFC3339)
+	}
+	if len(in.Tags) > 0 {
+		body["tags"] = strings.Join(in.Tags, ",")
+	}
+	return body
+}
+
+// Page is a page of items
+type Page struct {
+	Items []Item `json:"items"`
+	// Next is the cursor of the next page, it's empty on the last page
+	Next string `json:"next,omitempty"`
+	// Total is the total number of items, it's set only if requested
+	Total *int `json:"total,omitempty"`
+}
+
+// ListOptions are the options to list the items
+type ListOptions struct {
+	// Sort is created, modified or title, prefixed with - for descending order
+	Sort string
+	// After is the cursor of the page, from Page.Next
+	After string
+	// Limit is the maximum number of items in a page
+	Limit int
+	// Total includes the total number of items in the page
+	Total bool
+	// Status is open or done
+	Status string
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.After != "" {
+		q.Set("after", o.After)
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Total {
+		q.Set("total", "true")
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	return q
+}
+
+func itemPath(id string) string {
+	return "/items/" + url.PathEscape(id)
+}
+
+// CreateItem creates a new item
+func (c *Client) CreateItem(ctx context.Context, in ItemInput) (*Item, error) {
+	item := &Item{}
+	err := c.do(ctx, http.MethodPost, "/items", nil, in.body(), "", item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Item returns the item with the ID
+func (c *Client) Item(ctx context.Context, id string) (*Item, error) {
+	item := &Item{}
+	err := c.do(ctx, http.MethodGet, itemPath(id), nil, nil, "", item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateItem replaces the item with the input
+func (c *Client) UpdateItem(ctx context.Context, id string, in ItemInput) (*Item, error) {
+	item := &Item{}
+	err := c.do(ctx, http.MethodPut, itemPath(id), nil, in.body(), "", item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// PatchItem updates the fields of the item in the patch, as a JSON Merge Patch. A nil value
+// removes the field.
+func (c *Client) PatchItem(ctx context.Context, id string, patch map[string]interface{}) (*Item, error) {
+	item := &Item{}
+	err := c.do(ctx, http.MethodPatch, itemPath(id), nil, patch, mergePatchContentType, item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteItem deletes the item
+func (c *Client) DeleteItem(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, itemPath(id), nil, nil, "", nil)
+}
+
+// ListItems returns a page of the items of the user
+func (c *Client) ListItems(ctx context.Context, opts ListOptions) (*Page, error) {
+	page := &Page{}
+	err := c.do(ctx, http.MethodGet, "/items", opts.query(), nil, "", page)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// SearchItems returns the items matching the search query
+func (c *Client) SearchItems(ctx context.Context, q string, limit int) ([]Item, error) {
+	query := url.Values{"q": {q}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	list := []Item{}
+	err := c.do(ctx, http.MethodGet, "/items/search", query, nil, "", &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ItemIterator iterates over all the items of the user, fetching the pages as required
+type ItemIterator struct {
+	ctx    context.Context
+	client *Client
+	opts   ListOptions
+
+	items []Item
+	item  Item
+	done  bool
+	err   error
+}
+
+// Items returns an iterator over all the items of the user, starting at opts.After
+func (c *Client) Items(ctx context.Context, opts ListOptions) *ItemIterator {
+	return &ItemIterator{
+		ctx:    ctx,
+		client: c,
+		opts:   opts,
+	}
+}
+
+// Next advances to the next item, it returns false when there are no more items, or on error
+func (it *ItemIterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		page, err := it.client.ListItems(it.ctx, it.opts)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = page.Items
+		it.opts.After = page.Next
+		it.done = page.Next == ""
+	}
+
+	it.item = it.items[0]
+	it.items = it.items[1:]
+	return true
+}
+
+// Item returns the current item
+func (it *ItemIterator) Item() Item {
+	return it.item
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *ItemIterator) Err() error {
+	return it.err
+}
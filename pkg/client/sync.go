@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// ChangeCreated is the type of the change of an item created after the sequence
+	ChangeCreated = "created"
+	// ChangeUpdated is the type of the change of an item updated after the sequence
+	ChangeUpdated = "updated"
+	// ChangeDeleted is the type of the change of an item deleted after the sequence
+	ChangeDeleted = "deleted"
+
+	// SyncCreate creates an item
+	SyncCreate = "create"
+	// SyncUpdate replaces the content of an item
+	SyncUpdate = "update"
+	// SyncDelete deletes an item
+	SyncDelete = "delete"
+
+	// SyncApplied is the status of a change saved on the server
+	SyncApplied = "applied"
+	// SyncConflict is the status of a change made on an older version of the item. Updates are
+	// saved as a copy of the item, deletions are ignored.
+	SyncConflict = "conflict"
+	// SyncFailed is the status of a change which could not be saved
+	SyncFailed = "failed"
+)
+
+// Change is a change of an item on the server
+type Change struct {
+	Seq  int64  `json:"seq"`
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// Item is the item as of the change, it's nil if the item was deleted
+	Item *Item `json:"item,omitempty"`
+}
+
+// Changes is a page of the changes of the items, in the order they happened
+type Changes struct {
+	Changes []Change `json:"changes"`
+	// Seq is the sequence to fetch the next changes from
+	Seq int64 `json:"seq"`
+	// More is true if there are more changes after this page
+	More bool `json:"more"`
+}
+
+// ItemContent is the content of an item changed by the client
+type ItemContent struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Checklist   []Entry    `json:"checklist,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+}
+
+// SyncChange is a change of an item made by the client, to save on the server
+type SyncChange struct {
+	// Op is SyncCreate, SyncUpdate or SyncDelete
+	Op string `json:"op"`
+	// ID is the ID of the item to update or delete
+	ID string `json:"id,omitempty"`
+	// ClientID is the ID of a new item on the client, it's sent back with the result
+	ClientID string `json:"clientID,omitempty"`
+	// BaseSeq is the version of the item the change was made on
+	BaseSeq int64        `json:"baseSeq,omitempty"`
+	Item    *ItemContent `json:"item,omitempty"`
+}
+
+// SyncResult is the result of a change made by the client
+type SyncResult struct {
+	Op       string `json:"op"`
+	ID       string `json:"id,omitempty"`
+	ClientID string `json:"clientID,omitempty"`
+	// Status is SyncApplied, SyncConflict or SyncFailed
+	Status string `json:"status"`
+	// Item is the item saved, the copy of the item if an update conflicted, or the latest
+	// version of the item if a deletion conflicted
+	Item *Item `json:"item,omitempty"`
+	// ConflictID is the ID of the copy of the item saved if an update conflicted
+	ConflictID string `json:"conflictID,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Changes returns the changes of the items of the user after the sequence, 0 to fetch all the
+// items. Changes.Seq is the sequence to fetch the next changes from.
+func (c *Client) Changes(ctx context.Context, since int64, limit int) (*Changes, error) {
+	query := url.Values{"since": {strconv.FormatInt(since, 10)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	changes := &Changes{}
+	err := c.do(ctx, http.MethodGet, "/sync", query, nil, "", changes)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Sync saves the changes made by the client, in order. The result of every change is returned,
+// even if some of them failed.
+func (c *Client) Sync(ctx context.Context, changes []SyncChange) ([]SyncResult, error) {
+	results := []SyncResult{}
+	err := c.do(ctx, http.MethodPost, "/sync", nil, map[string]interface{}{"changes": changes}, "", &results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
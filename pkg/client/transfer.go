@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// ImportQueued is the status of an import waiting to be run
+	ImportQueued = "queued"
+	// ImportRunning is the status of an import in progress
+	ImportRunning = "running"
+	// ImportDone is the status of a finished import, some notes may have failed
+	ImportDone = "done"
+	// ImportFailed is the status of an import which could not be run
+	ImportFailed = "failed"
+)
+
+// ImportOptions are the options of an import
+type ImportOptions struct {
+	// Format is enex, keep or markdown, it's detected if empty
+	Format string
+	// DryRun only parses and validates the notes
+	DryRun bool
+	// Passphrase decrypts an encrypted archive exported with a passphrase
+	Passphrase string
+}
+
+// ImportNote is the result of importing a note
+type ImportNote struct {
+	// Source is the name of the note in the imported file
+	Source string   `json:"source"`
+	Title  string   `json:"title,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// ItemID is the ID of the item created, it's empty for a dry run
+	ItemID string `json:"itemID,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the result of an import
+type ImportReport struct {
+	Format   string       `json:"format"`
+	DryRun   bool         `json:"dryRun"`
+	Total    int          `json:"total"`
+	Imported int          `json:"imported"`
+	Failed   int          `json:"failed"`
+	Notes    []ImportNote `json:"notes"`
+	// Truncated is set if there were more notes than listed
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ImportJob is an import running in the background on the server
+type ImportJob struct {
+	ID         string        `json:"id"`
+	Status     string        `json:"status"`
+	Format     string        `json:"format"`
+	DryRun     bool          `json:"dryRun"`
+	Report     *ImportReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  *time.Time    `json:"createdAt,omitempty"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+// Finished returns true if the job is done or failed
+func (j *ImportJob) Finished() bool {
+	return j.Status == ImportDone || j.Status == ImportFailed
+}
+
+// Export writes a zip archive of all the items of the user to w. The archive is encrypted with
+// the passphrase, if not empty.
+func (c *Client) Export(ctx context.Context, w io.Writer, passphrase string) error {
+	header := http.Header{}
+	if passphrase != "" {
+		header.Set("X-Export-Passphrase", passphrase)
+	}
+	resp, err := c.stream(ctx, http.MethodGet, "/me/export", nil, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import uploads a file to be imported as items of the user. The import runs in the background,
+// use WaitImport to wait for the report.
+func (c *Client) Import(ctx context.Context, name string, r io.Reader, opts ImportOptions) (*ImportJob, error) {
+	query := url.Values{"name": {name}}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if opts.DryRun {
+		query.Set("dryRun", strconv.FormatBool(opts.DryRun))
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Accept", "application/json")
+	if opts.Passphrase != "" {
+		header.Set("X-Import-Passphrase", opts.Passphrase)
+	}
+
+	resp, err := c.stream(ctx, http.MethodPost, "/me/import", query, r, header)
+	if err != nil {
+		return nil, err
+	}
+	job := &ImportJob{}
+	err = decodeResponse(resp, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ImportJob returns the current status of an import
+func (c *Client) ImportJob(ctx context.Context, id string) (*ImportJob, error) {
+	job := &ImportJob{}
+	err := c.do(ctx, http.MethodGet, "/me/import/"+url.PathEscape(id), nil, nil, "", job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// WaitImport polls the import every interval until it's finished
+func (c *Client) WaitImport(ctx context.Context, id string, interval time.Duration) (*ImportJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.ImportJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Finished() {
+			if job.Status == ImportFailed {
+				return job, errors.New("import failed: " + job.Error)
+			}
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// User is a user of the app
+type User struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	// AuthToken is set on login and token refresh
+	AuthToken  string     `json:"authToken,omitempty"`
+	PublicKey  []byte     `json:"publicKey,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty"`
+}
+
+// Signup creates a new user, it does not login
+func (c *Client) Signup(ctx context.Context, name, email, password string) (*User, error) {
+	user := &User{}
+	err := c.do(ctx, http.MethodPost, "/signup", nil, map[string]string{
+		"name":     name,
+		"email":    email,
+		"password": password,
+	}, "", user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login authenticates the user, the auth token is used for all the subsequent requests. Tokens
+// expire after 24 hours, and are bound to the IP address of the client.
+func (c *Client) Login(ctx context.Context, email, password string) (*User, error) {
+	user := &User{}
+	err := c.do(ctx, http.MethodPost, "/login", nil, map[string]string{
+		"email":    email,
+		"password": password,
+	}, "", user)
+	if err != nil {
+		return nil, err
+	}
+	c.SetToken(user.AuthToken)
+	return user, nil
+}
+
+// RefreshToken replaces the auth token with a new one, valid for another 24 hours
+func (c *Client) RefreshToken(ctx context.Context) (*User, error) {
+	user := &User{}
+	err := c.do(ctx, http.MethodPost, "/token/refresh", nil, nil, "", user)
+	if err != nil {
+		return nil, err
+	}
+	c.SetToken(user.AuthToken)
+	return user, nil
+}
@@ -0,0 +1,150 @@
+// Package errs defines the kinds of errors returned by the services, so that every consumer
+// can handle them consistently, without matching every error of every package. The HTTP API
+// maps the kinds to status codes.
+package errs
+
+import "errors"
+
+// Kind is the kind of an error
+type Kind string
+
+const (
+	// KindInternal is an unexpected error, errors without a kind are internal
+	KindInternal = Kind("internal")
+	// KindValidation is returned when the input is invalid
+	KindValidation = Kind("validation_failed")
+	// KindNotFound is returned when the resource does not exist
+	KindNotFound = Kind("not_found")
+	// KindConflict is returned when the resource conflicts with an existing one
+	KindConflict = Kind("conflict")
+	// KindUnauthorized is returned when the user is not authenticated
+	KindUnauthorized = Kind("unauthorized")
+	// KindForbidden is returned when the user is not allowed to access the resource
+	KindForbidden = Kind("forbidden")
+	// KindTooLarge is returned when the input is larger than allowed
+	KindTooLarge = Kind("too_large")
+)
+
+// invalidFields is the message of a validation error with more than one invalid field
+const invalidFields = "Sorry, some of the fields are invalid"
+
+// FieldError is the validation error of a single input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a kind. Validation errors list all the invalid fields.
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []FieldError
+	// errs are the errors of each field of a validation error, so that errors.Is matches
+	// any of them
+	errs []error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is returns true if target is the error of one of the invalid fields
+func (e *Error) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns a new error of the kind
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// NotFound returns a new not found error
+func NotFound(message string) *Error {
+	return New(KindNotFound, message)
+}
+
+// Conflict returns a new conflict error
+func Conflict(message string) *Error {
+	return New(KindConflict, message)
+}
+
+// Unauthorized returns a new unauthorized error
+func Unauthorized(message string) *Error {
+	return New(KindUnauthorized, message)
+}
+
+// Forbidden returns a new forbidden error
+func Forbidden(message string) *Error {
+	return New(KindForbidden, message)
+}
+
+// TooLarge returns a new too large error
+func TooLarge(message string) *Error {
+	return New(KindTooLarge, message)
+}
+
+// Invalid returns a new validation error which is not specific to a field
+func Invalid(message string) *Error {
+	return New(KindValidation, message)
+}
+
+// Field returns a new validation error of the field
+func Field(field, message string) *Error {
+	return &Error{
+		Kind:    KindValidation,
+		Message: message,
+		Fields:  []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Validation combines the validation errors of several fields, nil errors are skipped. It
+// returns nil if there are no errors, and the error itself if there's only one.
+func Validation(errs ...error) error {
+	list := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			list = append(list, err)
+		}
+	}
+
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+
+	combined := &Error{Kind: KindValidation, Message: invalidFields, errs: list}
+	for _, err := range list {
+		e := &Error{}
+		if errors.As(err, &e) && len(e.Fields) > 0 {
+			combined.Fields = append(combined.Fields, e.Fields...)
+			continue
+		}
+		combined.Fields = append(combined.Fields, FieldError{Message: err.Error()})
+	}
+	return combined
+}
+
+// KindOf returns the kind of the error, KindInternal if it does not have one
+func KindOf(err error) Kind {
+	e := &Error{}
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// FieldsOf returns the invalid fields of a validation error
+func FieldsOf(err error) []FieldError {
+	e := &Error{}
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
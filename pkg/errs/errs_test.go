@@ -0,0 +1,42 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	errNotFound := NotFound("not found")
+	if KindOf(fmt.Errorf("wrapped: %w", errNotFound)) != KindNotFound {
+		t.Fatal("Expected the kind of a wrapped error")
+	}
+	if KindOf(errors.New("plain")) != KindInternal {
+		t.Fatal("Expected errors without a kind to be internal")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	errEmail := Field("email", "invalid email")
+	errPassword := Field("password", "invalid password")
+
+	if Validation(nil, nil) != nil {
+		t.Fatal("Expected nil without errors")
+	}
+	if Validation(nil, errEmail) != errEmail {
+		t.Fatal("Expected a single error to be returned as is")
+	}
+
+	err := Validation(errEmail, errPassword, errors.New("other"))
+	if KindOf(err) != KindValidation {
+		t.Fatalf("Unexpected kind '%s'", KindOf(err))
+	}
+	if !errors.Is(err, errEmail) || !errors.Is(err, errPassword) {
+		t.Fatal("Expected the combined error to match every field error")
+	}
+
+	fields := FieldsOf(err)
+	if len(fields) != 3 || fields[0].Field != "email" || fields[1].Field != "password" || fields[2].Message != "other" {
+		t.Fatalf("Unexpected fields %+v", fields)
+	}
+}
@@ -0,0 +1,427 @@
+// Package events notifies the clients of the users when their items change, on every instance
+// of the app. Events have only the IDs and versions of the items, never their content.
+//
+// Events are published on a channel of the cache, and every instance keeps the recent events of
+// each subscriber in memory, so that a client reconnecting to any instance can resume from the
+// last event it received.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+const (
+	// ItemCreated is the type of the event of a new item
+	ItemCreated = "item.created"
+	// ItemUpdated is the type of the event of an item whose content changed
+	ItemUpdated = "item.updated"
+	// ItemDeleted is the type of the event of a deleted item
+	ItemDeleted = "item.deleted"
+	// Reset is sent when resuming, if some of the events since the last event may have been
+	// lost. The client should fetch the items again.
+	Reset = "reset"
+
+	// channel is the channel of the cache the events are published on
+	channel = "events"
+	// seqKey is the key of the counter of the event IDs
+	seqKey = "events:seq"
+
+	// replaySize is the maximum number of events kept per subscriber key for resuming
+	replaySize = 100
+	// replayWindow is how long the events are kept for resuming
+	replayWindow = 15 * time.Minute
+	// subscriberBuffer is the number of events buffered for a subscriber. A subscriber which
+	// falls behind is closed, and can resume from its last event.
+	subscriberBuffer = 64
+	// resubscribeDelay is the delay between the attempts to subscribe to the cache again
+	resubscribeDelay = time.Second
+)
+
+// Event is a change of an item
+type Event struct {
+	// ID increases with every event, across all the users
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	ItemID string `json:"itemID,omitempty"`
+	// Version is the version of the item after the change, it increases with every change of
+	// the item
+	Version int64     `json:"version,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// message is an event as published on the cache
+type message struct {
+	Event
+	// Keys are the keys of the subscribers of the event
+	Keys []string `json:"keys"`
+}
+
+// buffer is the recent events of a subscriber key
+type buffer struct {
+	events []Event
+	// trimmed is the ID of the last event removed from the buffer
+	trimmed int64
+}
+
+// subscriber is a client listening to events
+type subscriber struct {
+	keys   []string
+	events chan Event
+}
+
+// hub receives the events from the cache, and sends them to the subscribers in this instance
+type hub struct {
+	once sync.Once
+	err  error
+
+	mu sync.Mutex
+	// cancel stops receiving the events from the cache
+	cancel context.CancelFunc
+	// stopped is true once the hub is stopped, there are no subscribers anymore
+	stopped bool
+	// startID is an ID generated when the hub started, events with smaller IDs may have been
+	// missed
+	startID int64
+	// lastID is the ID of the last event received
+	lastID      int64
+	subscribers map[string]map[*subscriber]struct{}
+	buffers     map[string]*buffer
+	// swept is the ID of the last event of the buffers removed for being idle
+	swept int64
+}
+
+// Service publishes and subscribes to the events
+type Service struct {
+	cache  cache.Service
+	logger logger.Service
+	hub    *hub
+}
+
+// NewService returns a new instance of the events service
+func NewService(cs cache.Service, l logger.Service) Service {
+	return Service{
+		cache:  cs,
+		logger: l,
+		hub: &hub{
+			subscribers: map[string]map[*subscriber]struct{}{},
+			buffers:     map[string]*buffer{},
+		},
+	}
+}
+
+// ErrNotStarted is returned when subscribing if the events could not be received from the cache
+var ErrNotStarted = errors.New("Events are not being received")
+
+// Start starts receiving the events of all the instances. Events published before it are not
+// available to resume from, so it should be called when the app starts. Subscribe calls it if it
+// was not called.
+func (s Service) Start() error {
+	s.hub.once.Do(func() {
+		s.hub.startID, s.hub.err = s.cache.Increment(seqKey, 0)
+		if s.hub.err != nil {
+			return
+		}
+		s.hub.lastID = s.hub.startID
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var messages <-chan []byte
+		messages, s.hub.err = s.cache.Subscribe(ctx, channel)
+		if s.hub.err != nil {
+			cancel()
+			return
+		}
+		s.hub.mu.Lock()
+		s.hub.cancel = cancel
+		s.hub.mu.Unlock()
+		go s.receive(ctx, messages)
+	})
+	if s.hub.err != nil {
+		s.logger.Error("events", s.hub.err.Error())
+		return ErrNotStarted
+	}
+	return nil
+}
+
+// Stop stops receiving the events, and closes the channels of all the subscribers so that their
+// streams end. The clients resume from their last event on another instance. Subscribing fails
+// after.
+func (s Service) Stop() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return
+	}
+	h.stopped = true
+	if h.cancel != nil {
+		h.cancel()
+	}
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Publish publishes an event of the item to the subscribers with the keys
+func (s Service) Publish(typ, itemID string, version int64, keys ...string) error {
+	id, err := s.cache.Increment(seqKey, 0)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(message{
+		Event: Event{
+			ID:      id,
+			Type:    typ,
+			ItemID:  itemID,
+			Version: version,
+			At:      time.Now().UTC(),
+		},
+		Keys: keys,
+	})
+	if err != nil {
+		return err
+	}
+	return s.cache.Publish(channel, b)
+}
+
+// receive dispatches the events from the cache until the context is done
+func (s Service) receive(ctx context.Context, messages <-chan []byte) {
+	sweep := time.NewTicker(time.Minute)
+	defer sweep.Stop()
+	for {
+		select {
+		case b, ok := <-messages:
+			if !ok {
+				messages = s.resubscribe(ctx)
+				if messages == nil {
+					return
+				}
+				continue
+			}
+			m := message{}
+			err := json.Unmarshal(b, &m)
+			if err != nil {
+				s.logger.Error("events", err.Error())
+				continue
+			}
+			s.hub.dispatch(m)
+		case now := <-sweep.C:
+			s.hub.sweep(now)
+		}
+	}
+}
+
+// resubscribe subscribes to the cache again, once the subscription was closed for falling
+// behind, and resets the subscribers since the events published meanwhile are lost. It returns
+// nil once the context is done.
+func (s Service) resubscribe(ctx context.Context) <-chan []byte {
+	var messages <-chan []byte
+	var err error
+	for ctx.Err() == nil {
+		if messages == nil {
+			messages, err = s.cache.Subscribe(ctx, channel)
+		}
+		if err == nil {
+			// the events published after the new ID are received by the new subscription
+			var id int64
+			id, err = s.cache.Increment(seqKey, 0)
+			if err == nil {
+				s.hub.reset(id)
+				return messages
+			}
+		}
+		s.logger.Error("events", err.Error())
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(resubscribeDelay):
+		}
+	}
+	return nil
+}
+
+// reset sends a reset event to all the subscribers, since the events before startID may have
+// been lost. The clients resuming from an event before it get a reset event as well.
+func (h *hub) reset(startID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if startID > h.startID {
+		h.startID = startID
+	}
+	if startID > h.lastID {
+		h.lastID = startID
+	}
+
+	e := Event{ID: h.lastID, Type: Reset, At: time.Now().UTC()}
+	sent := map[*subscriber]bool{}
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			if sent[sub] {
+				continue
+			}
+			sent[sub] = true
+			select {
+			case sub.events <- e:
+			default:
+				h.remove(sub)
+			}
+		}
+	}
+}
+
+func (h *hub) dispatch(m message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if m.ID > h.lastID {
+		h.lastID = m.ID
+	}
+	sent := map[*subscriber]bool{}
+	for _, key := range m.Keys {
+		buf := h.buffers[key]
+		if buf == nil {
+			buf = &buffer{}
+			h.buffers[key] = buf
+		}
+		buf.events = append(buf.events, m.Event)
+		if len(buf.events) > replaySize {
+			buf.trimmed = buf.events[0].ID
+			buf.events = buf.events[1:]
+		}
+
+		for sub := range h.subscribers[key] {
+			if sent[sub] {
+				continue
+			}
+			sent[sub] = true
+			select {
+			case sub.events <- m.Event:
+			default:
+				h.remove(sub)
+			}
+		}
+	}
+}
+
+// sweep removes the events older than the replay window
+func (h *hub) sweep(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, buf := range h.buffers {
+		i := 0
+		for i < len(buf.events) && now.Sub(buf.events[i].At) > replayWindow {
+			buf.trimmed = buf.events[i].ID
+			i++
+		}
+		buf.events = buf.events[i:]
+		if len(buf.events) == 0 {
+			if buf.trimmed > h.swept {
+				h.swept = buf.trimmed
+			}
+			delete(h.buffers, key)
+		}
+	}
+}
+
+// remove removes the subscriber and closes its channel, the lock should be held
+func (h *hub) remove(sub *subscriber) {
+	for _, key := range sub.keys {
+		subs := h.subscribers[key]
+		if _, ok := subs[sub]; !ok {
+			return
+		}
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subscribers, key)
+		}
+	}
+	close(sub.events)
+}
+
+// replay returns the events for the keys after lastID, and false if some may have been lost. The
+// lock should be held.
+func (h *hub) replay(keys []string, lastID int64) ([]Event, bool) {
+	if lastID < h.startID || lastID < h.swept {
+		return nil, false
+	}
+
+	seen := map[int64]bool{}
+	events := []Event{}
+	for _, key := range keys {
+		buf := h.buffers[key]
+		if buf == nil {
+			continue
+		}
+		if lastID < buf.trimmed {
+			return nil, false
+		}
+		for _, e := range buf.events {
+			if e.ID > lastID && !seen[e.ID] {
+				seen[e.ID] = true
+				events = append(events, e)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, true
+}
+
+// Subscribe returns the events of the subscriber keys until the context is done. If lastID is
+// not 0, the events after it are sent first, or a reset event if some of them were lost. The ID
+// of the reset event is the ID of the last event, to resume from after fetching the items again.
+// The channel is closed if the subscriber does not keep up with the events.
+func (s Service) Subscribe(ctx context.Context, keys []string, lastID int64) (<-chan Event, error) {
+	err := s.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	h := s.hub
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return nil, ErrNotStarted
+	}
+	var replay []Event
+	if lastID > 0 {
+		var ok bool
+		replay, ok = h.replay(keys, lastID)
+		if !ok {
+			replay = []Event{{ID: h.lastID, Type: Reset, At: time.Now().UTC()}}
+		}
+	}
+
+	sub := &subscriber{
+		keys:   keys,
+		events: make(chan Event, subscriberBuffer+len(replay)),
+	}
+	for _, e := range replay {
+		sub.events <- e
+	}
+	for _, key := range keys {
+		if h.subscribers[key] == nil {
+			h.subscribers[key] = map[*subscriber]struct{}{}
+		}
+		h.subscribers[key][sub] = struct{}{}
+	}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		h.remove(sub)
+		h.mu.Unlock()
+	}()
+	return sub.events, nil
+}
@@ -0,0 +1,302 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/cache/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("Events closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("No event received")
+	}
+	return Event{}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := NewService(memory.New(), logger.New(nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	owner, err := s.Subscribe(ctx, []string{"owner:a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	both, err := s.Subscribe(ctx, []string{"owner:b", "user:b"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Publish(ItemCreated, "item1", 10, "owner:a", "user:b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Publish(ItemUpdated, "item2", 20, "owner:b", "user:b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := receive(t, owner)
+	if e.Type != ItemCreated || e.ItemID != "item1" || e.Version != 10 || e.ID == 0 {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+	e = receive(t, both)
+	if e.ItemID != "item1" {
+		t.Fatalf("Expected item1, got %+v", e)
+	}
+	// The event is sent once even if the subscriber has several of its keys
+	e = receive(t, both)
+	if e.Type != ItemUpdated || e.ItemID != "item2" {
+		t.Fatalf("Expected the update of item2, got %+v", e)
+	}
+	select {
+	case e := <-both:
+		t.Fatalf("Unexpected event %+v", e)
+	case <-owner:
+		t.Fatal("Unexpected event for owner:a")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-owner:
+		if ok {
+			t.Fatal("Expected the events to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Events not closed after the context is done")
+	}
+}
+
+func TestResume(t *testing.T) {
+	s := NewService(memory.New(), logger.New(nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := s.Subscribe(ctx, []string{"owner:a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"item1", "item2", "item3"} {
+		err = s.Publish(ItemCreated, id, 1, "owner:a")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := receive(t, events)
+	receive(t, events)
+	receive(t, events)
+
+	resumed, err := s.Subscribe(ctx, []string{"owner:a"}, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"item2", "item3"} {
+		e := receive(t, resumed)
+		if e.ItemID != id {
+			t.Fatalf("Expected %s, got %+v", id, e)
+		}
+	}
+
+	// Another instance started after the events may have missed them
+	other := NewService(s.cache, logger.New(nil))
+	reset, err := other.Subscribe(ctx, []string{"owner:a"}, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := receive(t, reset)
+	if e.Type != Reset {
+		t.Fatalf("Expected a reset, got %+v", e)
+	}
+
+	// After fetching the items again, the client resumes from the reset
+	err = s.Publish(ItemDeleted, "item1", 0, "owner:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive(t, reset)
+	resumed, err = other.Subscribe(ctx, []string{"owner:a"}, e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = receive(t, resumed)
+	if e.Type != ItemDeleted || e.ItemID != "item1" {
+		t.Fatalf("Expected the deletion of item1, got %+v", e)
+	}
+}
+
+func TestTrimmed(t *testing.T) {
+	h := &hub{
+		subscribers: map[string]map[*subscriber]struct{}{},
+		buffers:     map[string]*buffer{},
+	}
+	now := time.Now()
+	for i := int64(1); i <= replaySize+5; i++ {
+		h.dispatch(message{Event: Event{ID: i, At: now}, Keys: []string{"owner:a"}})
+	}
+
+	events, ok := h.replay([]string{"owner:a"}, 10)
+	if !ok || len(events) != replaySize-5 || events[0].ID != 11 {
+		t.Fatalf("Unexpected replay of %d events, %v", len(events), ok)
+	}
+	_, ok = h.replay([]string{"owner:a"}, 2)
+	if ok {
+		t.Fatal("Expected the trimmed events to be lost")
+	}
+
+	h.sweep(now.Add(replayWindow + time.Second))
+	_, ok = h.replay([]string{"owner:b"}, replaySize)
+	if ok {
+		t.Fatal("Expected the swept events to be lost")
+	}
+	events, ok = h.replay([]string{"owner:b"}, replaySize+5)
+	if !ok || len(events) != 0 {
+		t.Fatalf("Unexpected replay of %d events, %v", len(events), ok)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	h := &hub{
+		subscribers: map[string]map[*subscriber]struct{}{},
+		buffers:     map[string]*buffer{},
+	}
+	sub := &subscriber{keys: []string{"owner:a"}, events: make(chan Event, subscriberBuffer)}
+	h.subscribers["owner:a"] = map[*subscriber]struct{}{sub: {}}
+
+	for i := int64(1); i <= subscriberBuffer+1; i++ {
+		h.dispatch(message{Event: Event{ID: i, At: time.Now()}, Keys: []string{"owner:a"}})
+	}
+	if len(h.subscribers) != 0 {
+		t.Fatal("Expected a slow subscriber to be removed")
+	}
+	for range sub.events {
+	}
+	// Removing it again when its context is done is safe
+	h.remove(sub)
+}
+
+func TestStop(t *testing.T) {
+	s := NewService(memory.New(), logger.New(nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := s.Subscribe(ctx, []string{"owner:a", "user:a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	s.Stop()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("Expected no events after stopping")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the events to be closed")
+	}
+	_, err = s.Subscribe(ctx, []string{"owner:a"}, 0)
+	if err != ErrNotStarted {
+		t.Fatalf("Expected %v after stopping, got %v", ErrNotStarted, err)
+	}
+}
+
+// closingCache is a cache whose subscriptions are closed by the test, as if they fell behind
+type closingCache struct {
+	cache.Service
+	mu   sync.Mutex
+	subs []chan []byte
+}
+
+func (c *closingCache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan []byte, 16)
+	c.subs = append(c.subs, ch)
+	return ch, nil
+}
+
+func (c *closingCache) Publish(channel string, message []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.subs {
+		ch <- message
+	}
+	return nil
+}
+
+// close closes the oldest subscription
+func (c *closingCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	close(c.subs[0])
+	c.subs = c.subs[1:]
+}
+
+func TestResubscribe(t *testing.T) {
+	cs := &closingCache{Service: memory.New()}
+	s := NewService(cs, logger.New(nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := s.Subscribe(ctx, []string{"owner:a", "user:a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Publish(ItemCreated, "item1", 1, "owner:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := receive(t, events)
+
+	cs.close()
+	reset := receive(t, events)
+	if reset.Type != Reset || reset.ID <= created.ID {
+		t.Fatalf("Expected a reset after the subscription was closed, got %+v", reset)
+	}
+
+	// the events are received from the new subscription
+	err = s.Publish(ItemUpdated, "item1", 2, "owner:a", "user:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := receive(t, events)
+	if e.Type != ItemUpdated || e.ID <= reset.ID {
+		t.Fatalf("Expected the update after the reset, got %+v", e)
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("Expected the event to be sent once, got %+v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// the events before the reset may have been lost
+	resumed, err := s.Subscribe(ctx, []string{"owner:a"}, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = receive(t, resumed)
+	if e.Type != Reset {
+		t.Fatalf("Expected a reset when resuming from before the reset, got %+v", e)
+	}
+	resumed, err = s.Subscribe(ctx, []string{"owner:a"}, reset.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = receive(t, resumed)
+	if e.Type != ItemUpdated {
+		t.Fatalf("Expected the update when resuming from the reset, got %+v", e)
+	}
+}
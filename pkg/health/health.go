@@ -0,0 +1,178 @@
+// Package health checks the dependencies of the app, to tell if it's ready to serve requests
+package health
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+const (
+	// cacheTTL is how long the results of the checks are reused, so that frequent probes do not
+	// hammer the dependencies
+	cacheTTL = time.Second * 5
+	// checkTimeout is the time after which a dependency which did not answer is down
+	checkTimeout = time.Second * 2
+
+	// errTimeout is the error of a check which timed out
+	errTimeout = "timeout"
+	// errUnavailable is the error of a check which failed. The error itself is logged, it may
+	// have details of the infrastructure.
+	errUnavailable = "unavailable"
+)
+
+// Check is the result of the check of a dependency
+type Check struct {
+	Name string `json:"name"`
+	Up   bool   `json:"up"`
+	// LatencyMS is the duration of the check in milliseconds
+	LatencyMS float64 `json:"latencyMs"`
+	// Error is timeout or unavailable if the dependency is down
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Report is the readiness of the app, with the checks of its dependencies
+type Report struct {
+	// Ready is true if all the dependencies are up and the app is not shutting down
+	Ready bool `json:"ready"`
+	// ShuttingDown is true once the app started shutting down, so that it gets no new requests.
+	// The dependencies are not checked anymore.
+	ShuttingDown bool    `json:"shuttingDown,omitempty"`
+	Checks       []Check `json:"checks"`
+}
+
+type dependency struct {
+	name  string
+	check func() error
+}
+
+// Checker checks the dependencies of the app. The results are cached for a few seconds, and
+// the app is not ready once it's shutting down.
+type Checker struct {
+	logger       logger.Service
+	dependencies []dependency
+	shuttingDown int32
+	ttl          time.Duration
+	timeout      time.Duration
+
+	mu        sync.Mutex
+	checks    []Check
+	checkedAt time.Time
+}
+
+// NewChecker returns a checker without any dependencies
+func NewChecker(l logger.Service) *Checker {
+	return &Checker{logger: l, ttl: cacheTTL, timeout: checkTimeout}
+}
+
+// Add adds a dependency, check should return an error if it's down. The dependencies should
+// be added before the checker is used.
+func (c *Checker) Add(name string, check func() error) {
+	c.dependencies = append(c.dependencies, dependency{name: name, check: check})
+}
+
+// Ready returns the readiness of the app. The dependencies are checked concurrently, at most
+// once every few seconds.
+func (c *Checker) Ready() Report {
+	if c.ShuttingDown() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return Report{
+			ShuttingDown: true,
+			Checks:       append([]Check{}, c.checks...),
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checks == nil || time.Since(c.checkedAt) >= c.ttl {
+		c.checks = c.run()
+		c.checkedAt = time.Now()
+	}
+
+	r := Report{Ready: true, Checks: append([]Check{}, c.checks...)}
+	for _, check := range r.Checks {
+		if !check.Up {
+			r.Ready = false
+		}
+	}
+	return r
+}
+
+// run checks all the dependencies, sorted by name
+func (c *Checker) run() []Check {
+	results := make(chan Check, len(c.dependencies))
+	for _, d := range c.dependencies {
+		go func(d dependency) {
+			results <- c.check(d)
+		}(d)
+	}
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+	start := time.Now()
+	done := make(map[string]Check, len(c.dependencies))
+	for len(done) < len(c.dependencies) {
+		select {
+		case r := <-results:
+			done[r.Name] = r
+		case <-timeout.C:
+			// the checks still running can't block the probes, their results are dropped
+			for _, d := range c.dependencies {
+				if _, ok := done[d.name]; ok {
+					continue
+				}
+				c.logger.Error("health", d.name, errTimeout)
+				done[d.name] = Check{
+					Name:      d.name,
+					LatencyMS: milliseconds(time.Since(start)),
+					Error:     errTimeout,
+					CheckedAt: start,
+				}
+			}
+		}
+	}
+
+	checks := make([]Check, 0, len(done))
+	for _, r := range done {
+		checks = append(checks, r)
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+	return checks
+}
+
+func (c *Checker) check(d dependency) Check {
+	start := time.Now()
+	err := d.check()
+	r := Check{
+		Name:      d.name,
+		Up:        err == nil,
+		LatencyMS: milliseconds(time.Since(start)),
+		CheckedAt: start,
+	}
+	if err != nil {
+		c.logger.Error("health", d.name, err.Error())
+		r.Error = errUnavailable
+	}
+	return r
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Shutdown marks the app as shutting down, it's not ready anymore
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+}
+
+// ShuttingDown returns true once the app started shutting down
+func (c *Checker) ShuttingDown() bool {
+	return atomic.LoadInt32(&c.shuttingDown) == 1
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+func TestReady(t *testing.T) {
+	c := NewChecker(logger.New(nil))
+	var calls int32
+	down := errors.New("connection refused")
+	var cacheErr error
+	c.Add("storage", func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	c.Add("cache", func() error {
+		return cacheErr
+	})
+
+	r := c.Ready()
+	if !r.Ready || len(r.Checks) != 2 {
+		t.Fatalf("Expected the app to be ready with 2 checks, got %+v", r)
+	}
+	if r.Checks[0].Name != "cache" || r.Checks[1].Name != "storage" {
+		t.Errorf("Expected the checks sorted by name, got %+v", r.Checks)
+	}
+
+	// the results are cached
+	cacheErr = down
+	r = c.Ready()
+	if !r.Ready || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected the cached results, got %+v after %d checks", r, calls)
+	}
+
+	c.ttl = 0
+	r = c.Ready()
+	if r.Ready || r.Checks[0].Up || r.Checks[0].Error != errUnavailable {
+		t.Errorf("Expected the cache to be down, got %+v", r)
+	}
+	if !r.Checks[1].Up {
+		t.Errorf("Expected the storage to be up, got %+v", r.Checks[1])
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := NewChecker(logger.New(nil))
+	c.timeout = time.Millisecond * 20
+	release := make(chan struct{})
+	defer close(release)
+	c.Add("storage", func() error {
+		<-release
+		return nil
+	})
+	c.Add("cache", func() error {
+		return nil
+	})
+
+	start := time.Now()
+	r := c.Ready()
+	if time.Since(start) > time.Second {
+		t.Fatalf("Expected the check to time out, it took %s", time.Since(start))
+	}
+	if r.Ready || r.Checks[1].Name != "storage" || r.Checks[1].Error != errTimeout {
+		t.Errorf("Expected the storage to time out, got %+v", r)
+	}
+	if !r.Checks[0].Up {
+		t.Errorf("Expected the cache to be up, got %+v", r.Checks[0])
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	c := NewChecker(logger.New(nil))
+	var calls int32
+	c.Add("storage", func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	c.ttl = 0
+	if !c.Ready().Ready {
+		t.Fatal("Expected the app to be ready")
+	}
+
+	c.Shutdown()
+	r := c.Ready()
+	if r.Ready || !r.ShuttingDown {
+		t.Errorf("Expected the app not to be ready while shutting down, got %+v", r)
+	}
+	if len(r.Checks) != 1 || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected the last checks without checking again, got %+v after %d checks", r, calls)
+	}
+}
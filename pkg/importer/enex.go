@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// enexTime is the format of timestamps in ENEX
+const enexTime = "20060102T150405Z"
+
+var (
+	// enmlBlocks are the ENML elements which start a new line
+	enmlBlocks = map[string]bool{
+		"div": true, "p": true, "li": true, "tr": true, "ul": true, "ol": true, "table": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"blockquote": true, "pre": true, "hr": true,
+	}
+
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+type enexParser struct{}
+
+type enexNote struct {
+	Title   string   `xml:"title"`
+	Content string   `xml:"content"`
+	Created string   `xml:"created"`
+	Updated string   `xml:"updated"`
+	Tags    []string `xml:"tag"`
+}
+
+func parseENEXTime(s string) *time.Time {
+	t, err := time.Parse(enexTime, strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// Parse parses every note element of the export as it's read, resources are skipped
+func (enexParser) Parse(src Source, fn func(Note) error) error {
+	dec := xml.NewDecoder(io.NewSectionReader(src.R, 0, src.Size))
+	dec.Strict = false
+
+	count := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "note" {
+			continue
+		}
+
+		count++
+		en := enexNote{}
+		err = dec.DecodeElement(&en, &se)
+		if err != nil {
+			return err
+		}
+
+		n := Note{
+			Source:     fmt.Sprintf("%s#%d", src.Name, count),
+			Title:      strings.TrimSpace(en.Title),
+			Tags:       en.Tags,
+			CreatedAt:  parseENEXTime(en.Created),
+			ModifiedAt: parseENEXTime(en.Updated),
+		}
+		n.Description, n.Checklist, n.Err = enmlText(en.Content)
+
+		err = fn(n)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// enmlText converts the ENML content of a note to plain text. Todos are returned as the
+// checklist.
+func enmlText(content string) (string, []Entry, error) {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var (
+		lines     []string
+		checklist []Entry
+		line      strings.Builder
+		todo      *Entry
+	)
+
+	flush := func(force bool) {
+		text := strings.TrimSpace(line.String())
+		line.Reset()
+		if todo != nil {
+			if text != "" {
+				todo.Text = text
+				checklist = append(checklist, *todo)
+			}
+			todo = nil
+			return
+		}
+		if text != "" || force {
+			lines = append(lines, text)
+		}
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "br":
+				flush(true)
+			case t.Name.Local == "en-todo":
+				flush(false)
+				todo = &Entry{}
+				for _, a := range t.Attr {
+					if a.Name.Local == "checked" && a.Value == "true" {
+						todo.Checked = true
+					}
+				}
+			case enmlBlocks[t.Name.Local]:
+				flush(false)
+				if t.Name.Local == "li" {
+					line.WriteString("- ")
+				}
+			}
+		case xml.EndElement:
+			if enmlBlocks[t.Name.Local] {
+				flush(false)
+			}
+		case xml.CharData:
+			line.Write(t)
+		}
+	}
+	flush(false)
+
+	description := strings.TrimSpace(strings.Join(lines, "\n"))
+	return blankLines.ReplaceAllString(description, "\n\n"), checklist, nil
+}
@@ -0,0 +1,182 @@
+// Package importer parses notes exported from other apps, so that they can be imported as
+// items. Evernote ENEX, Google Keep Takeout and Markdown files are supported.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+const (
+	// FormatENEX is the XML export of Evernote
+	FormatENEX = "enex"
+	// FormatKeep is the JSON export of Google Keep from Google Takeout, as a zip or a single file
+	FormatKeep = "keep"
+	// FormatMarkdown is a zip of Markdown files or a single Markdown file, with an optional
+	// YAML front matter. Archives exported from this app are in this format.
+	FormatMarkdown = "markdown"
+
+	// maxNoteSize is the maximum size of a single note file in an archive
+	maxNoteSize = 10 * 1024 * 1024
+)
+
+var (
+	// ErrUnknownFormat is returned if the format is not supported or could not be detected
+	ErrUnknownFormat = errs.Field("format", "Sorry, the format should be one of enex, keep or markdown")
+	// ErrNoteTooLarge is returned for a note larger than the maximum size allowed
+	ErrNoteTooLarge = errs.TooLarge("Sorry, the note is too large")
+
+	zipMagic = []byte("PK\x03\x04")
+)
+
+// Entry is a single checklist entry of a note
+type Entry struct {
+	Text    string
+	Checked bool
+}
+
+// Note is a single note parsed from the source
+type Note struct {
+	// Source is the name of the file, or the position of the note in the source
+	Source      string
+	Title       string
+	Description string
+	Checklist   []Entry
+	Tags        []string
+	CreatedAt   *time.Time
+	ModifiedAt  *time.Time
+	DueAt       *time.Time
+	// Err is set if the note could not be parsed, the rest of the source is still parsed
+	Err error
+}
+
+// Source is the file being imported
+type Source struct {
+	// Name is the name of the file, it's used to detect the format
+	Name string
+	R    io.ReaderAt
+	Size int64
+}
+
+// Parser parses all the notes in a source, and calls fn for every note. Parsing stops if fn
+// returns an error.
+type Parser interface {
+	Parse(src Source, fn func(Note) error) error
+}
+
+var parsers = map[string]Parser{
+	FormatENEX:     enexParser{},
+	FormatKeep:     keepParser{},
+	FormatMarkdown: markdownParser{},
+}
+
+// isZip returns true if the source is a zip file
+func isZip(src Source) bool {
+	b := make([]byte, len(zipMagic))
+	_, err := src.R.ReadAt(b, 0)
+	return err == nil && bytes.Equal(b, zipMagic)
+}
+
+func openZip(src Source) (*zip.Reader, error) {
+	return zip.NewReader(src.R, src.Size)
+}
+
+// readZipFile reads a file in a zip archive, up to the maximum note size
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxNoteSize {
+		return nil, ErrNoteTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(io.LimitReader(rc, maxNoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxNoteSize {
+		return nil, ErrNoteTooLarge
+	}
+	return b, nil
+}
+
+// skipZipFile returns true for directories, hidden files and metadata added by archivers
+func skipZipFile(f *zip.File) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+		return true
+	}
+	return strings.HasPrefix(path.Base(f.Name), ".")
+}
+
+// Detect returns the format of the source, based on its name and content
+func Detect(src Source) (string, error) {
+	switch strings.ToLower(path.Ext(src.Name)) {
+	case ".enex":
+		return FormatENEX, nil
+	case ".json":
+		return FormatKeep, nil
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	}
+
+	if isZip(src) {
+		zr, err := openZip(src)
+		if err != nil {
+			return "", err
+		}
+		format := ""
+		for _, f := range zr.File {
+			if skipZipFile(f) {
+				continue
+			}
+			ext := strings.ToLower(path.Ext(f.Name))
+			if ext == ".json" && strings.Contains(f.Name, "Keep/") {
+				return FormatKeep, nil
+			}
+			if ext == ".md" || ext == ".markdown" {
+				format = FormatMarkdown
+			}
+		}
+		if format == "" {
+			return "", ErrUnknownFormat
+		}
+		return format, nil
+	}
+
+	head := make([]byte, 1024)
+	n, _ := src.R.ReadAt(head, 0)
+	head = bytes.TrimSpace(head[:n])
+	switch {
+	case bytes.Contains(head, []byte("<en-export")):
+		return FormatENEX, nil
+	case bytes.HasPrefix(head, []byte("{")):
+		return FormatKeep, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ParserFor returns the parser of the format, the format is detected if it's empty
+func ParserFor(format string, src Source) (Parser, string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		var err error
+		format, err = Detect(src)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	p, ok := parsers[format]
+	if !ok {
+		return nil, "", ErrUnknownFormat
+	}
+	return p, format, nil
+}
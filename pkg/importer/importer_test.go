@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+const enex = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export export-date="20200102T030405Z" application="Evernote">
+<note>
+	<title>Groceries</title>
+	<content><![CDATA[<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><div>Buy these&nbsp;today</div><div><br/></div><ul><li>soon</li></ul><div><en-todo checked="true"/>milk</div><div><en-todo/>eggs</div></en-note>]]></content>
+	<created>20200101T101112Z</created>
+	<updated>20200102T101112Z</updated>
+	<tag>home</tag>
+	<tag>shopping</tag>
+	<resource><data encoding="base64">aGVsbG8=</data></resource>
+</note>
+<note>
+	<title>Second</title>
+	<content><![CDATA[<en-note>plain</en-note>]]></content>
+</note>
+</en-export>`
+
+func source(name string, b []byte) Source {
+	return Source{Name: name, R: bytes.NewReader(b), Size: int64(len(b))}
+}
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		w.Write([]byte(content))
+	}
+	err := zw.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return buf.Bytes()
+}
+
+func parseAll(t *testing.T, format string, src Source) []Note {
+	p, detected, err := ParserFor("", src)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if detected != format {
+		t.Fatalf("Expected format '%s', got '%s'", format, detected)
+	}
+
+	notes := []Note{}
+	err = p.Parse(src, func(n Note) error {
+		if n.Err != nil {
+			t.Fatalf("%s: %s", n.Source, n.Err.Error())
+		}
+		notes = append(notes, n)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return notes
+}
+
+func TestENEX(t *testing.T) {
+	notes := parseAll(t, FormatENEX, source("export", []byte(enex)))
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 notes, got %d", len(notes))
+	}
+
+	n := notes[0]
+	if n.Title != "Groceries" || strings.Join(n.Tags, ",") != "home,shopping" {
+		t.Fatalf("Unexpected note %+v", n)
+	}
+	if n.Description != "Buy these today\n\n- soon" {
+		t.Fatalf("Unexpected description %q", n.Description)
+	}
+	if len(n.Checklist) != 2 || n.Checklist[0] != (Entry{Text: "milk", Checked: true}) || n.Checklist[1] != (Entry{Text: "eggs"}) {
+		t.Fatalf("Unexpected checklist %+v", n.Checklist)
+	}
+	if n.CreatedAt == nil || !n.CreatedAt.Equal(time.Date(2020, 1, 1, 10, 11, 12, 0, time.UTC)) {
+		t.Fatalf("Unexpected created time %v", n.CreatedAt)
+	}
+	if notes[1].Description != "plain" || notes[1].CreatedAt != nil {
+		t.Fatalf("Unexpected note %+v", notes[1])
+	}
+}
+
+func TestKeep(t *testing.T) {
+	b := zipOf(t, map[string]string{
+		"Takeout/Keep/a.json": `{"title":"List","listContent":[{"text":"one","isChecked":true},{"text":"two"}],
+			"labels":[{"name":"work"}],"createdTimestampUsec":1577836800000000,"userEditedTimestampUsec":1577923200000000}`,
+		"Takeout/Keep/b.json":     `{"title":"Trashed","textContent":"gone","isTrashed":true}`,
+		"Takeout/Keep/Labels.txt": "work",
+	})
+	notes := parseAll(t, FormatKeep, source("takeout.zip", b))
+	if len(notes) != 1 {
+		t.Fatalf("Expected 1 note, got %d", len(notes))
+	}
+
+	n := notes[0]
+	if n.Title != "List" || len(n.Checklist) != 2 || !n.Checklist[0].Checked || n.Tags[0] != "work" {
+		t.Fatalf("Unexpected note %+v", n)
+	}
+	if !n.CreatedAt.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) || !n.ModifiedAt.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected timestamps %v %v", n.CreatedAt, n.ModifiedAt)
+	}
+
+	notes = parseAll(t, FormatKeep, source("note.json", []byte(`{"title":"Single","textContent":"text"}`)))
+	if len(notes) != 1 || notes[0].Description != "text" {
+		t.Fatalf("Unexpected notes %+v", notes)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	b := zipOf(t, map[string]string{
+		"items/one.md":  "---\ntitle: \"One \\\"quoted\\\"\"\ncreated: 2020-01-01T10:00:00Z\ndue: 2020-02-01\ntags: [\"a\", b]\n---\n\nBody\n\n- [x] done\n- [ ] todo\n",
+		"two.md":        "---\ntags:\n  - x\n  - 'y'\n---\n# Heading\n\ntext\n",
+		"plain.md":      "just text",
+		"manifest.json": "{}",
+	})
+	notes := parseAll(t, FormatMarkdown, source("notes.zip", b))
+	if len(notes) != 3 {
+		t.Fatalf("Expected 3 notes, got %d", len(notes))
+	}
+
+	byTitle := map[string]Note{}
+	for _, n := range notes {
+		byTitle[n.Title] = n
+	}
+
+	one := byTitle[`One "quoted"`]
+	if one.Description != "Body" || strings.Join(one.Tags, ",") != "a,b" || len(one.Checklist) != 2 || !one.Checklist[0].Checked {
+		t.Fatalf("Unexpected note %+v", one)
+	}
+	if one.CreatedAt == nil || one.DueAt == nil || !one.DueAt.Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected times %v %v", one.CreatedAt, one.DueAt)
+	}
+
+	two := byTitle["Heading"]
+	if two.Description != "text" || strings.Join(two.Tags, ",") != "x,y" {
+		t.Fatalf("Unexpected note %+v", two)
+	}
+
+	if byTitle["plain"].Description != "just text" {
+		t.Fatalf("Unexpected notes %+v", byTitle)
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	_, _, err := ParserFor("", source("notes.txt", []byte("hello")))
+	if err != ErrUnknownFormat {
+		t.Fatalf("Expected ErrUnknownFormat, got %v", err)
+	}
+	_, _, err = ParserFor("doc", source("notes.md", []byte("hello")))
+	if err != ErrUnknownFormat {
+		t.Fatalf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestReportTruncated(t *testing.T) {
+	r := NewReport(FormatKeep, true)
+	for i := 0; i < maxReportNotes+5; i++ {
+		var err error
+		if i%2 == 0 {
+			err = ErrNoteTooLarge
+		}
+		r.Add(Note{Source: "n"}, "", err)
+	}
+	if r.Total != maxReportNotes+5 || len(r.Notes) != maxReportNotes || !r.Truncated || r.Failed != r.Total-r.Imported {
+		t.Fatalf("Unexpected report %d %d %d %v", r.Total, r.Failed, len(r.Notes), r.Truncated)
+	}
+}
+
+func TestJob(t *testing.T) {
+	s := NewService(memory.New(), logger.New([]string{"all"}))
+
+	done := make(chan struct{})
+	job, err := s.Start("user", "note.md", strings.NewReader("# Title\n"), "", true,
+		func(src Source, format string, progress func(*Report)) (*Report, error) {
+			defer close(done)
+			r := NewReport(format, true)
+			p, _, err := ParserFor(format, src)
+			if err != nil {
+				return nil, err
+			}
+			err = p.Parse(src, func(n Note) error {
+				r.Add(n, "", nil)
+				progress(r)
+				return nil
+			})
+			return r, err
+		},
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if job.Format != FormatMarkdown || job.Status != StatusQueued {
+		t.Fatalf("Unexpected job %+v", job)
+	}
+
+	<-done
+	if !s.Wait(time.Second) {
+		t.Fatal("Expected the job to complete")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, err := s.Job(job.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if got.Status == StatusDone {
+			if got.UserID != "user" || got.Report.Imported != 1 || got.Report.Notes[0].Title != "Title" {
+				t.Fatalf("Unexpected job %+v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job not done, status '%s'", got.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, err = s.Start("user", "notes.txt", strings.NewReader("hello"), "", false, nil)
+	if err != ErrUnknownFormat {
+		t.Fatalf("Expected ErrUnknownFormat, got %v", err)
+	}
+	_, err = s.Job("missing")
+	if err != ErrJobNotFound {
+		t.Fatalf("Expected ErrJobNotFound, got %v", err)
+	}
+}
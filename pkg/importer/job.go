@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+const (
+	// StatusQueued is the status of a job which is yet to start
+	StatusQueued = "queued"
+	// StatusRunning is the status of a job which is importing notes
+	StatusRunning = "running"
+	// StatusDone is the status of a job which has finished, some notes may have failed
+	StatusDone = "done"
+	// StatusFailed is the status of a job which could not finish
+	StatusFailed = "failed"
+
+	cachePrefix = "imports"
+	// jobTTL is how long the status of a job is kept after it's last updated
+	jobTTL = 24 * time.Hour
+	// maxImportSize is the maximum size of a file to import
+	maxImportSize = 512 * 1024 * 1024
+)
+
+var (
+	// ErrJobNotFound is returned if the job does not exist, or has expired
+	ErrJobNotFound = errs.NotFound("Sorry, the import could not be found")
+	// ErrTooLarge is returned if the file to import is larger than the maximum allowed
+	ErrTooLarge = errs.TooLarge("Sorry, the file is too large to import")
+	// ErrEmpty is returned if the file to import is empty
+	ErrEmpty = errs.Invalid("Sorry, the file to import is empty")
+)
+
+// RunFunc imports all the notes in the source, calling progress with the report so far
+type RunFunc func(src Source, format string, progress func(*Report)) (*Report, error)
+
+// Job is an import running in the background
+type Job struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Status     string     `json:"status"`
+	Format     string     `json:"format"`
+	DryRun     bool       `json:"dryRun"`
+	Report     *Report    `json:"report,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// jobRecord is the job as saved in the cache, with the user ID which is not sent in responses
+type jobRecord struct {
+	Job
+	UserID string `json:"userID"`
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func jobKey(id string) string {
+	return fmt.Sprintf("%s:%s", cachePrefix, id)
+}
+
+func (s *Service) save(job *Job) {
+	err := s.cache.Set(jobKey(job.ID), jobRecord{Job: *job, UserID: job.UserID}, jobTTL)
+	if err != nil {
+		s.logger.Error(err.Error())
+	}
+}
+
+// spool saves the upload to a temporary file, so that it can be read at random by the parsers
+func spool(r io.Reader) (*os.File, int64, error) {
+	f, err := os.CreateTemp("", "notes-import-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(f, io.LimitReader(r, maxImportSize+1))
+	if err == nil && size > maxImportSize {
+		err = ErrTooLarge
+	}
+	if err == nil && size == 0 {
+		err = ErrEmpty
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, err
+	}
+	return f, size, nil
+}
+
+// Start saves the file and starts importing it in the background with run. The format is
+// detected if it's empty, an unknown format is returned as an error right away.
+func (s *Service) Start(userID, name string, r io.Reader, format string, dryRun bool, run RunFunc) (*Job, error) {
+	f, size, err := spool(r)
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	src := Source{Name: name, R: f, Size: size}
+	_, format, err = ParserFor(format, src)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        id,
+		UserID:    userID,
+		Status:    StatusQueued,
+		Format:    format,
+		DryRun:    dryRun,
+		Report:    NewReport(format, dryRun),
+		CreatedAt: &now,
+	}
+	s.save(job)
+
+	s.running.Add(1)
+	go func(job Job) {
+		defer s.running.Done()
+		defer cleanup()
+
+		job.Status = StatusRunning
+		s.save(&job)
+
+		report, err := run(src, format, func(r *Report) {
+			job.Report = r
+			s.save(&job)
+		})
+
+		now := time.Now()
+		job.Report = report
+		job.FinishedAt = &now
+		job.Status = StatusDone
+		if err != nil {
+			s.logger.Error(err.Error())
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+		s.save(&job)
+	}(*job)
+
+	return job, nil
+}
+
+// Wait waits for the imports running in the background to complete, at most for the timeout.
+// It returns false if some are still running.
+func (s *Service) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Job returns the import job with the ID
+func (s *Service) Job(id string) (*Job, error) {
+	rec := jobRecord{}
+	err := s.cache.Get(jobKey(id), &rec)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	job := rec.Job
+	job.UserID = rec.UserID
+	return &job, nil
+}
@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+)
+
+type keepParser struct{}
+
+type keepNote struct {
+	Title       string `json:"title"`
+	TextContent string `json:"textContent"`
+	ListContent []struct {
+		Text      string `json:"text"`
+		IsChecked bool   `json:"isChecked"`
+	} `json:"listContent"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	CreatedTimestampUsec    int64 `json:"createdTimestampUsec"`
+	UserEditedTimestampUsec int64 `json:"userEditedTimestampUsec"`
+	IsTrashed               bool  `json:"isTrashed"`
+}
+
+func usecTime(usec int64) *time.Time {
+	if usec <= 0 {
+		return nil
+	}
+	t := time.UnixMicro(usec).UTC()
+	return &t
+}
+
+// keepNoteFrom parses a single note, it returns false if the JSON is not a note or the note is
+// in the trash
+func keepNoteFrom(source string, b []byte) (Note, bool) {
+	raw := map[string]json.RawMessage{}
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return Note{Source: source, Err: err}, true
+	}
+	_, text := raw["textContent"]
+	_, list := raw["listContent"]
+	if !text && !list {
+		return Note{}, false
+	}
+
+	kn := keepNote{}
+	err = json.Unmarshal(b, &kn)
+	if err != nil {
+		return Note{Source: source, Err: err}, true
+	}
+	if kn.IsTrashed {
+		return Note{}, false
+	}
+
+	n := Note{
+		Source:      source,
+		Title:       strings.TrimSpace(kn.Title),
+		Description: strings.TrimSpace(kn.TextContent),
+		CreatedAt:   usecTime(kn.CreatedTimestampUsec),
+		ModifiedAt:  usecTime(kn.UserEditedTimestampUsec),
+	}
+	for _, l := range kn.Labels {
+		n.Tags = append(n.Tags, l.Name)
+	}
+	for _, e := range kn.ListContent {
+		n.Checklist = append(n.Checklist, Entry{Text: e.Text, Checked: e.IsChecked})
+	}
+	return n, true
+}
+
+// Parse parses a Takeout zip with one JSON file per note, or a single JSON file
+func (keepParser) Parse(src Source, fn func(Note) error) error {
+	if !isZip(src) {
+		if src.Size > maxNoteSize {
+			return fn(Note{Source: src.Name, Err: ErrNoteTooLarge})
+		}
+		b := make([]byte, src.Size)
+		_, err := src.R.ReadAt(b, 0)
+		if err != nil {
+			return err
+		}
+		n, ok := keepNoteFrom(src.Name, b)
+		if !ok {
+			return nil
+		}
+		return fn(n)
+	}
+
+	zr, err := openZip(src)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if skipZipFile(f) || strings.ToLower(path.Ext(f.Name)) != ".json" {
+			continue
+		}
+
+		b, err := readZipFile(f)
+		if err != nil {
+			err = fn(Note{Source: f.Name, Err: err})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		n, ok := keepNoteFrom(f.Name, b)
+		if !ok {
+			continue
+		}
+		err = fn(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const frontMatterDelim = "---"
+
+var (
+	taskLine = regexp.MustCompile(`^\s*[-*+] \[([ xX])\]\s+(.*)$`)
+
+	// markdownTimes are the layouts accepted for times in the front matter
+	markdownTimes = []string{
+		time.RFC3339,
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+)
+
+type markdownParser struct{}
+
+// yamlValue unquotes a scalar YAML value
+func yamlValue(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		v := ""
+		if json.Unmarshal([]byte(s), &v) == nil {
+			return v
+		}
+		return s[1 : len(s)-1]
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// yamlList returns the values of a flow list (`[a, "b"]`) or a comma separated value
+func yamlList(s string) []string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		v = yamlValue(v)
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func parseMarkdownTime(s string) *time.Time {
+	s = yamlValue(s)
+	for _, layout := range markdownTimes {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// frontMatter splits the YAML front matter from the body. Only flat keys with scalar values,
+// flow lists and block lists are supported, which covers what note taking apps export.
+func frontMatter(b []byte) (map[string][]string, []byte) {
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(b, []byte(frontMatterDelim+"\n")) && !bytes.HasPrefix(b, []byte(frontMatterDelim+"\r\n")) {
+		return nil, b
+	}
+
+	fields := map[string][]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, maxNoteSize)
+	sc.Scan()
+	offset := len(sc.Bytes()) + 1
+	key := ""
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		offset += len(sc.Bytes()) + 1
+		if line == frontMatterDelim {
+			if offset > len(b) {
+				offset = len(b)
+			}
+			return fields, b[offset:]
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") && key != "" {
+			fields[key] = append(fields[key], yamlValue(trimmed[2:]))
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx < 0 || strings.HasPrefix(line, " ") {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(line[:idx]))
+		value := strings.TrimSpace(line[idx+1:])
+		if value == "" {
+			fields[key] = nil
+			continue
+		}
+		if strings.HasPrefix(value, "[") {
+			fields[key] = yamlList(value)
+			continue
+		}
+		fields[key] = []string{yamlValue(value)}
+	}
+
+	// the front matter was never closed, it's just the body
+	return nil, b
+}
+
+// first returns the first value of any of the keys
+func first(fields map[string][]string, keys ...string) string {
+	for _, k := range keys {
+		if len(fields[k]) > 0 {
+			return fields[k][0]
+		}
+	}
+	return ""
+}
+
+// markdownNote parses a single Markdown file. A task list at the end of the file is the
+// checklist.
+func markdownNote(source string, b []byte) Note {
+	fields, body := frontMatter(b)
+	n := Note{
+		Source:     source,
+		Title:      first(fields, "title"),
+		CreatedAt:  parseMarkdownTime(first(fields, "created", "date")),
+		ModifiedAt: parseMarkdownTime(first(fields, "modified", "updated")),
+		DueAt:      parseMarkdownTime(first(fields, "due")),
+	}
+	for _, k := range []string{"tags", "labels"} {
+		for _, v := range fields[k] {
+			n.Tags = append(n.Tags, yamlList(v)...)
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if n.Title == "" && len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		n.Title = strings.TrimSpace(lines[0][2:])
+		lines = lines[1:]
+	}
+	if n.Title == "" {
+		base := path.Base(source)
+		n.Title = strings.TrimSuffix(base, path.Ext(base))
+	}
+
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	start := end
+	for start > 0 && taskLine.MatchString(lines[start-1]) {
+		start--
+	}
+	for _, line := range lines[start:end] {
+		m := taskLine.FindStringSubmatch(line)
+		n.Checklist = append(n.Checklist, Entry{
+			Text:    strings.TrimSpace(m[2]),
+			Checked: m[1] != " ",
+		})
+	}
+
+	n.Description = strings.TrimSpace(strings.Join(lines[:start], "\n"))
+	return n
+}
+
+// Parse parses a zip of Markdown files, or a single Markdown file
+func (markdownParser) Parse(src Source, fn func(Note) error) error {
+	if !isZip(src) {
+		if src.Size > maxNoteSize {
+			return fn(Note{Source: src.Name, Err: ErrNoteTooLarge})
+		}
+		b := make([]byte, src.Size)
+		_, err := src.R.ReadAt(b, 0)
+		if err != nil {
+			return err
+		}
+		return fn(markdownNote(src.Name, b))
+	}
+
+	zr, err := openZip(src)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if skipZipFile(f) || (ext != ".md" && ext != ".markdown") {
+			continue
+		}
+
+		b, err := readZipFile(f)
+		n := Note{Source: f.Name, Err: err}
+		if err == nil {
+			n = markdownNote(f.Name, b)
+		}
+		err = fn(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
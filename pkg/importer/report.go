@@ -0,0 +1,58 @@
+package importer
+
+// maxReportNotes is the maximum number of notes listed in a report
+const maxReportNotes = 1000
+
+// ReportNote is the result of importing a single note
+type ReportNote struct {
+	Source string   `json:"source"`
+	Title  string   `json:"title,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// ItemID is the ID of the item created, it's empty for a dry run
+	ItemID string `json:"itemID,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the result of an import
+type Report struct {
+	Format   string `json:"format"`
+	DryRun   bool   `json:"dryRun"`
+	Total    int    `json:"total"`
+	Imported int    `json:"imported"`
+	Failed   int    `json:"failed"`
+	// Notes lists every note, up to the first 1000. Truncated is set if there were more.
+	Notes     []ReportNote `json:"notes"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// NewReport returns a new empty report
+func NewReport(format string, dryRun bool) *Report {
+	return &Report{
+		Format: format,
+		DryRun: dryRun,
+		Notes:  []ReportNote{},
+	}
+}
+
+// Add adds the result of importing a note to the report
+func (r *Report) Add(n Note, itemID string, err error) {
+	r.Total++
+	rn := ReportNote{
+		Source: n.Source,
+		Title:  n.Title,
+		Tags:   n.Tags,
+		ItemID: itemID,
+	}
+	if err != nil {
+		r.Failed++
+		rn.Error = err.Error()
+	} else {
+		r.Imported++
+	}
+
+	if len(r.Notes) >= maxReportNotes {
+		r.Truncated = true
+		return
+	}
+	r.Notes = append(r.Notes, rn)
+}
@@ -0,0 +1,25 @@
+package importer
+
+import (
+	"sync"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+// Service holds all the dependencies of imports
+type Service struct {
+	cache  cache.Service
+	logger logger.Service
+	// running are the imports running in the background
+	running *sync.WaitGroup
+}
+
+// NewService returns a new instance of Service with all the dependencies initialized
+func NewService(cs cache.Service, l logger.Service) Service {
+	return Service{
+		cache:   cs,
+		logger:  l,
+		running: &sync.WaitGroup{},
+	}
+}
@@ -0,0 +1,183 @@
+package items
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+const (
+	// StatusOpen filters items which have unchecked checklist entries
+	StatusOpen = "open"
+	// StatusDone filters items whose checklist entries are all checked
+	StatusDone = "done"
+)
+
+var (
+	// ErrEntryNotFound is returned if the checklist entry does not exist in the item
+	ErrEntryNotFound = errs.NotFound("Sorry, checklist entry not found")
+	// ErrEntryText is returned if a checklist entry is added without any text
+	ErrEntryText = errs.Field("text", "Sorry, checklist entry text cannot be empty")
+	// ErrInvOrder is returned if the new order does not have exactly all the existing entries
+	ErrInvOrder = errs.Field("order", "Sorry, the order should include all the checklist entries exactly once")
+	// ErrInvStatus is returned if the status filter is not supported
+	ErrInvStatus = errs.Field("status", "Sorry, status can only be open or done")
+)
+
+// Entry is a single entry of a checklist
+type Entry struct {
+	// ID is the unique identifier of the entry within the item
+	ID string `json:"id"`
+	// Text is the content of the entry
+	Text string `json:"text"`
+	// Checked is true if the entry is marked as done
+	Checked bool `json:"checked"`
+}
+
+// Progress is the summary of a checklist
+type Progress struct {
+	// Total is the total number of entries in the checklist
+	Total int `json:"total" bson:"total"`
+	// Done is the number of checked entries
+	Done int `json:"done" bson:"done"`
+	// Open is the number of unchecked entries
+	Open int `json:"open" bson:"open"`
+}
+
+func newEntryID() string {
+	return fmt.Sprintf("entry_%s", uuid.New().String())
+}
+
+// progress computes the progress of the item's checklist, it's nil if there's no checklist
+func (i *Item) progress() *Progress {
+	if len(i.Checklist) == 0 {
+		return nil
+	}
+	p := &Progress{Total: len(i.Checklist)}
+	for _, e := range i.Checklist {
+		if e.Checked {
+			p.Done++
+		}
+	}
+	p.Open = p.Total - p.Done
+	return p
+}
+
+func (i *Item) entryIndex(entryID string) (int, error) {
+	for idx, e := range i.Checklist {
+		if e.ID == entryID {
+			return idx, nil
+		}
+	}
+	return -1, ErrEntryNotFound
+}
+
+// AddEntry adds a new unchecked entry to the checklist at the given position. If the position
+// is out of range, it's added at the end.
+func (i *Item) AddEntry(text string, position int) (*Entry, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEntryText
+	}
+
+	e := Entry{
+		ID:   newEntryID(),
+		Text: text,
+	}
+
+	if position < 0 || position >= len(i.Checklist) {
+		i.Checklist = append(i.Checklist, e)
+		return &e, nil
+	}
+
+	i.Checklist = append(i.Checklist, Entry{})
+	copy(i.Checklist[position+1:], i.Checklist[position:])
+	i.Checklist[position] = e
+	return &e, nil
+}
+
+// UpdateEntry updates the text and/or checked state of an entry, nil values are ignored
+func (i *Item) UpdateEntry(entryID string, text *string, checked *bool) (*Entry, error) {
+	idx, err := i.entryIndex(entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &i.Checklist[idx]
+	if text != nil {
+		t := strings.TrimSpace(*text)
+		if t == "" {
+			return nil, ErrEntryText
+		}
+		e.Text = t
+	}
+	if checked != nil {
+		e.Checked = *checked
+	}
+	return e, nil
+}
+
+// ToggleEntry flips the checked state of an entry
+func (i *Item) ToggleEntry(entryID string) (*Entry, error) {
+	idx, err := i.entryIndex(entryID)
+	if err != nil {
+		return nil, err
+	}
+	i.Checklist[idx].Checked = !i.Checklist[idx].Checked
+	return &i.Checklist[idx], nil
+}
+
+// RemoveEntry removes an entry from the checklist
+func (i *Item) RemoveEntry(entryID string) error {
+	idx, err := i.entryIndex(entryID)
+	if err != nil {
+		return err
+	}
+	i.Checklist = append(i.Checklist[:idx], i.Checklist[idx+1:]...)
+	return nil
+}
+
+// ReorderEntries sets the order of the checklist as per the given entry IDs
+func (i *Item) ReorderEntries(entryIDs []string) error {
+	if len(entryIDs) != len(i.Checklist) {
+		return ErrInvOrder
+	}
+
+	entries := make(map[string]Entry, len(i.Checklist))
+	for _, e := range i.Checklist {
+		entries[e.ID] = e
+	}
+
+	ordered := make([]Entry, 0, len(entryIDs))
+	for _, id := range entryIDs {
+		e, ok := entries[id]
+		if !ok {
+			return ErrInvOrder
+		}
+		delete(entries, id)
+		ordered = append(ordered, e)
+	}
+	i.Checklist = ordered
+	return nil
+}
+
+// statusQuery returns the store query to filter items by their checklist status
+func statusQuery(status string) (map[string]interface{}, error) {
+	switch strings.TrimSpace(status) {
+	case "":
+		return nil, nil
+	case StatusOpen:
+		return map[string]interface{}{
+			"progress.open": map[string]interface{}{"$gt": 0},
+		}, nil
+	case StatusDone:
+		return map[string]interface{}{
+			"progress.total": map[string]interface{}{"$gt": 0},
+			"progress.open":  0,
+		}, nil
+	}
+	return nil, ErrInvStatus
+}
@@ -0,0 +1,67 @@
+package items
+
+import (
+	"testing"
+)
+
+func TestChecklist(t *testing.T) {
+	item, _, err := newItem()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	milk, err := item.AddEntry("milk", -1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	eggs, err := item.AddEntry("eggs", 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Checklist[0].ID != eggs.ID || item.Checklist[1].ID != milk.ID {
+		t.Fatalf("Expected entries 'eggs', 'milk', got %v", item.Checklist)
+	}
+
+	_, err = item.ToggleEntry(milk.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = item.ReorderEntries([]string{milk.ID, eggs.ID})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = item.ReorderEntries([]string{milk.ID, milk.ID})
+	if err != ErrInvOrder {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvOrder, err)
+	}
+
+	var key [32]byte
+	err = item.Encrypt(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Checklist != nil || item.Description != "" {
+		t.Fatal("Expected checklist and description to be cleared after encryption")
+	}
+	if item.Progress == nil || item.Progress.Total != 2 || item.Progress.Done != 1 || item.Progress.Open != 1 {
+		t.Fatalf("Expected progress 2 total, 1 done, 1 open, got %+v", item.Progress)
+	}
+
+	err = item.Decrypt(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(item.Checklist) != 2 || item.Checklist[0].ID != milk.ID || !item.Checklist[0].Checked {
+		t.Fatalf("Expected checked 'milk' first after decryption, got %v", item.Checklist)
+	}
+
+	err = item.RemoveEntry(eggs.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = item.RemoveEntry(eggs.ID)
+	if err != ErrEntryNotFound {
+		t.Fatalf("Expected '%v', got '%v'", ErrEntryNotFound, err)
+	}
+}
@@ -0,0 +1,188 @@
+package items
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+const (
+	// SortCreated sorts the items by their creation time
+	SortCreated = "created"
+	// SortModified sorts the items by the time they were last modified
+	SortModified = "modified"
+	// SortTitle sorts the items alphabetically by title
+	SortTitle = "title"
+
+	// defaultSort is used when no sort is provided, latest modified items first
+	defaultSort = "-" + SortModified
+)
+
+var (
+	// ErrInvCursor is returned if the pagination cursor is malformed or does not match the sort
+	ErrInvCursor = errs.Field("after", "Sorry, invalid pagination cursor provided")
+	// ErrInvSort is returned if the requested sort field is not supported
+	ErrInvSort = errs.Field("sort", "Sorry, items can only be sorted by created, modified or title")
+
+	// sortFields maps the supported sort names to the respective store fields
+	sortFields = map[string]string{
+		SortCreated:  "createdAt",
+		SortModified: "modifiedAt",
+		SortTitle:    "title",
+	}
+)
+
+// ListOptions holds all the options for listing items
+type ListOptions struct {
+	// Sort is one of created, modified or title. A '-' prefix sorts in descending order
+	Sort string
+	// After is the cursor returned with the previous page
+	After string
+	// Start is the number of items to skip
+	Start int
+	// Limit is the maximum number of items in the page
+	Limit int
+	// Total if true, counts all the items of the owner matching the filters
+	Total bool
+	// Status filters the items by their checklist status, open or done
+	Status string
+}
+
+// Page is a single page of items
+type Page struct {
+	Items []Item `json:"items"`
+	// Next is the cursor to fetch the next page, it's empty if this is the last page
+	Next string `json:"next,omitempty"`
+	// Total is the total number of items, it's set only if requested
+	Total *int `json:"total,omitempty"`
+}
+
+// cursor is the position of an item in a sorted list of items. It's encoded and shared as an
+// opaque token with the client
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+	// untitled is true if the item of the cursor has no title field, like the untitled items
+	// saved before empty titles were stored
+	untitled bool
+}
+
+// sortOrder parses the sort option and returns the sort name, the store field and if it's
+// in descending order
+func sortOrder(sort string) (string, string, bool, error) {
+	sort = strings.TrimSpace(sort)
+	if sort == "" {
+		sort = defaultSort
+	}
+
+	desc := strings.HasPrefix(sort, "-")
+	name := strings.TrimPrefix(sort, "-")
+	field, ok := sortFields[name]
+	if !ok {
+		return "", "", false, ErrInvSort
+	}
+	return sort, field, desc, nil
+}
+
+// sortValue returns the value of the sort field of the item, as a string
+func (i *Item) sortValue(field string) string {
+	switch field {
+	case "createdAt":
+		if i.CreatedAt != nil {
+			return i.CreatedAt.UTC().Format(time.RFC3339Nano)
+		}
+	case "modifiedAt":
+		if i.ModifiedAt != nil {
+			return i.ModifiedAt.UTC().Format(time.RFC3339Nano)
+		}
+	case "title":
+		return i.Title
+	}
+	return ""
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvCursor
+	}
+	c := cursor{}
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.ID == "" {
+		return nil, ErrInvCursor
+	}
+	return &c, nil
+}
+
+// afterQuery returns the query which matches all items after the cursor, for the given sort
+// order. Items are ordered by the sort field, and by ID when the field has the same value.
+func afterQuery(c *cursor, field string, desc bool) (map[string]interface{}, error) {
+	var value interface{} = c.Value
+	if field != "title" {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvCursor
+		}
+		value = t
+	}
+
+	op := "$gt"
+	if desc {
+		op = "$lt"
+	}
+
+	if field == "title" && c.Value == "" {
+		return untitledQuery(c, op, desc), nil
+	}
+
+	return map[string]interface{}{
+		"$or": []interface{}{
+			map[string]interface{}{
+				field: map[string]interface{}{op: value},
+			},
+			map[string]interface{}{
+				field: value,
+				"id":  map[string]interface{}{op: c.ID},
+			},
+		},
+	}, nil
+}
+
+// untitledQuery returns the query which matches all items after the cursor of an untitled item,
+// sorting by title. The items without a title field are sorted before the items with an empty
+// title, so they're matched separately.
+func untitledQuery(c *cursor, op string, desc bool) map[string]interface{} {
+	missing := map[string]interface{}{"$exists": false}
+	if c.untitled && desc {
+		return map[string]interface{}{
+			"title": missing,
+			"id":    map[string]interface{}{op: c.ID},
+		}
+	}
+	if c.untitled {
+		return map[string]interface{}{
+			"$or": []interface{}{
+				map[string]interface{}{"title": map[string]interface{}{"$exists": true}},
+				map[string]interface{}{"title": missing, "id": map[string]interface{}{op: c.ID}},
+			},
+		}
+	}
+
+	after := []interface{}{
+		map[string]interface{}{"title": map[string]interface{}{op: ""}},
+		map[string]interface{}{"title": "", "id": map[string]interface{}{op: c.ID}},
+	}
+	if desc {
+		after = append(after, map[string]interface{}{"title": missing})
+	}
+	return map[string]interface{}{"$or": after}
+}
@@ -0,0 +1,102 @@
+package items
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+func TestCursor(t *testing.T) {
+	now := time.Now().UTC()
+	item := Item{ID: "item_1", Title: "hello", ModifiedAt: &now}
+
+	sort, field, desc, err := sortOrder("")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if sort != "-modified" || field != "modifiedAt" || !desc {
+		t.Fatalf("Expected default sort '-modified', got '%s' '%s' %v", sort, field, desc)
+	}
+
+	token := encodeCursor(cursor{Sort: sort, Value: item.sortValue(field), ID: item.ID})
+	c, err := decodeCursor(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if c.ID != item.ID || c.Sort != sort {
+		t.Fatalf("Expected cursor for '%s', got '%s'", item.ID, c.ID)
+	}
+
+	q, err := afterQuery(c, field, desc)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	or := q["$or"].([]interface{})
+	lt := or[0].(map[string]interface{})[field].(map[string]interface{})["$lt"].(time.Time)
+	if !lt.Equal(now) {
+		t.Fatalf("Expected cursor time '%s', got '%s'", now, lt)
+	}
+
+	_, err = decodeCursor("not a cursor")
+	if err != ErrInvCursor {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvCursor, err)
+	}
+
+	_, _, _, err = sortOrder("-priority")
+	if err != ErrInvSort {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvSort, err)
+	}
+}
+
+func TestListUntitled(t *testing.T) {
+	store := memory.New()
+	s := NewService(store, logger.New(nil))
+
+	want := map[string]bool{}
+	for _, title := range []string{"b", "", "a", "", "c", ""} {
+		item, _ := New(map[string]string{"title": title}, "owner")
+		_, err := s.Create(*item)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		want[item.ID] = true
+	}
+	// items saved before empty titles were stored have no title field
+	for i := 0; i < 2; i++ {
+		legacy := struct {
+			ID      string `bson:"id"`
+			OwnerID string `bson:"ownerID"`
+		}{newItemID(), "owner"}
+		_, err := store.Save(itemsBucket, legacy)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		want[legacy.ID] = true
+	}
+
+	for _, sort := range []string{SortTitle, "-" + SortTitle} {
+		seen := map[string]bool{}
+		opts := ListOptions{Sort: sort, Limit: 1}
+		for {
+			page, err := s.List("owner", opts)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			for _, item := range page.Items {
+				if seen[item.ID] {
+					t.Fatalf("Expected each item once sorting by %s, got %s again", sort, item.ID)
+				}
+				seen[item.ID] = true
+			}
+			if page.Next == "" {
+				break
+			}
+			opts.After = page.Next
+		}
+		if len(seen) != len(want) {
+			t.Fatalf("Expected %d items sorting by %s, got %d", len(want), sort, len(seen))
+		}
+	}
+}
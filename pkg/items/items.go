@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +13,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
 )
 
 const (
@@ -20,6 +24,9 @@ const (
 	itemsBucket   = "items"
 	minStart      = 0
 	maxLimit      = 50
+
+	// contentVersion is the version of the encrypted content format
+	contentVersion = 1
 )
 
 var (
@@ -27,28 +34,64 @@ var (
 	ErrCreate = errors.New("Sorry, an error occurred while creating")
 	// ErrRead is returned if there's an error reading an item
 	ErrRead = errors.New("Sorry, unable to fetch item")
+	// ErrNotFound is returned if the item does not exist
+	ErrNotFound = errs.NotFound("Sorry, item not found")
 	// ErrInvOwnerID is returned if the owner ID is blank or invalid
 	ErrInvOwnerID = errors.New("Sorry, invalid owner ID provided")
+	// ErrInvDueAt is returned if the due date is not a valid RFC3339 timestamp
+	ErrInvDueAt = errs.Field("dueAt", "Sorry, due date should be a valid RFC3339 timestamp")
 )
 
 // Item holds a single item
 type Item struct {
 	// ID is the unique identifier of a single item
 	ID string `json:"id,omitempty" bson:"id,omitempty"`
-	// Title is the title of a single item
-	Title string `json:"title,omitempty" bson:"title,omitempty"`
+	// Title is the title of a single item. It's stored even if it's empty, so that the untitled
+	// items are matched when paginating by title.
+	Title string `json:"title,omitempty" bson:"title"`
 	// Description is the description of a single item
 	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	// Checklist is the ordered list of checklist entries, it's encrypted along with the description
+	Checklist []Entry `json:"checklist,omitempty" bson:"-"`
+	// Tags are the labels of the item, they're encrypted along with the description
+	Tags []string `json:"tags,omitempty" bson:"-"`
+	// Progress is the summary of the checklist, it's computed every time the item is encrypted
+	Progress *Progress `json:"progress,omitempty" bson:"progress,omitempty"`
+	// DueAt is the UTC timestamp of when the item is due
+	DueAt *time.Time `json:"dueAt,omitempty" bson:"dueAt,omitempty"`
 	// Status is the current status of the item, it's set only while returning a deleted item
 	Status string `json:"status,omitempty" bson:"status,omitempty"`
 	// OwnerID is the unique identifier of an owner
 	OwnerID string `json:"-" bson:"ownerID,omitempty"`
 	// Blob stores the encrypted byte of Item
 	Blob []byte `json:"-" bson:"blob,omitempty"`
+	// Key is the content key of the item, encrypted with the owner's data key. Items created
+	// before content keys were introduced are encrypted directly with the data key, and have no Key
+	Key []byte `json:"-" bson:"key,omitempty"`
+	// PrevKey is the previous content key of the item, encrypted with the owner's data key. It's
+	// kept after the key is rotated, until the keys of the attachments and the shares of the
+	// item are wrapped with the new one.
+	PrevKey []byte `json:"-" bson:"prevKey,omitempty"`
+	// Unindexed is true if the item was changed by a recipient. The search index is keyed by the
+	// owner's data key, so it's updated the next time the owner searches.
+	Unindexed bool `json:"-" bson:"unindexed,omitempty"`
 	// CreatedAt is a UTC timestamp of when the item was created
 	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
 	// ModifiedAt is the UTC timestamp of when the item was last updated
 	ModifiedAt *time.Time `json:"modifiedAt,omitempty" bson:"modifiedAt,omitempty"`
+	// Seq is the sequence of the last change of the item, among the changes of all the items of
+	// the owner. It's the version of the item for syncing.
+	Seq int64 `json:"seq,omitempty" bson:"seq,omitempty"`
+	// CreatedSeq is the sequence of the creation of the item
+	CreatedSeq int64 `json:"-" bson:"createdSeq,omitempty"`
+}
+
+// content is the part of an item which is encrypted
+type content struct {
+	Version     int      `json:"v"`
+	Description string   `json:"description,omitempty"`
+	Checklist   []Entry  `json:"checklist,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 func newItemID() string {
@@ -62,17 +105,38 @@ func New(data map[string]string, ownerID string) (*Item, error) {
 		return nil, ErrInvOwnerID
 	}
 
+	dueAt, err := parseDueAt(data["dueAt"])
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	return &Item{
 		ID:          newItemID(),
 		Title:       strings.TrimSpace(data["title"]),
 		Description: strings.TrimSpace(data["description"]),
+		Tags:        ParseTags(data["tags"]),
+		DueAt:       dueAt,
 		OwnerID:     ownerID,
 		CreatedAt:   &now,
 		ModifiedAt:  &now,
 	}, nil
 }
 
+// parseDueAt parses an RFC3339 due date, it returns nil if the input is empty
+func parseDueAt(str string) (*time.Time, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return nil, ErrInvDueAt
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
 // Encrypt encrypts the item description and sets the Blob with encrypted bytes
 func (i *Item) Encrypt(key [32]byte) error {
 	block, err := aes.NewCipher(key[:])
@@ -91,10 +155,23 @@ func (i *Item) Encrypt(key [32]byte) error {
 		return err
 	}
 
-	i.Blob = gcm.Seal(nonce, nonce, []byte(i.Description), nil)
+	plain, err := json.Marshal(content{
+		Version:     contentVersion,
+		Description: i.Description,
+		Checklist:   i.Checklist,
+		Tags:        i.Tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	i.Blob = gcm.Seal(nonce, nonce, plain, nil)
+	i.Progress = i.progress()
 
-	// Emptying the description to prevent it from being saved as plain text
+	// Emptying the description, checklist & tags to prevent them from being saved as plain text
 	i.Description = ""
+	i.Checklist = nil
+	i.Tags = nil
 	return nil
 }
 
@@ -122,12 +199,36 @@ func (i *Item) Decrypt(key [32]byte) error {
 	if err != nil {
 		return err
 	}
-	i.Description = string(str)
+
+	c := content{}
+	err = json.Unmarshal(str, &c)
+	if err != nil || c.Version != contentVersion {
+		// items encrypted before the content format was introduced have only the description
+		i.Description = string(str)
+		return nil
+	}
+
+	i.Description = c.Description
+	i.Checklist = c.Checklist
+	i.Tags = c.Tags
 	return nil
 }
 
+// Text returns all the searchable text of a decrypted item, other than the title
+func (i *Item) Text() string {
+	parts := make([]string, 0, len(i.Checklist)+len(i.Tags)+1)
+	parts = append(parts, i.Description)
+	for _, e := range i.Checklist {
+		parts = append(parts, e.Text)
+	}
+	parts = append(parts, i.Tags...)
+	return strings.Join(parts, "\n")
+}
+
 // Create creates a new item
 func (s *Service) Create(item Item) (*Item, error) {
+	span := s.trace("Create")
+	defer span.End()
 	_, err := s.store.Save(itemsBucket, item)
 	if err != nil {
 		s.logger.Error(err.Error())
@@ -138,6 +239,8 @@ func (s *Service) Create(item Item) (*Item, error) {
 
 // Read reads an item given the item ID
 func (s *Service) Read(id string) (*Item, error) {
+	span := s.trace("Read")
+	defer span.End()
 	item := Item{}
 	_, err := s.store.FindOne(
 		itemsBucket,
@@ -146,6 +249,9 @@ func (s *Service) Read(id string) (*Item, error) {
 		[]string{"-modifiedAt"},
 		&item)
 	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
 		s.logger.Error(err)
 		return nil, err
 	}
@@ -154,6 +260,8 @@ func (s *Service) Read(id string) (*Item, error) {
 
 // Update updates an item given the ID
 func (s *Service) Update(id string, data Item) (*Item, error) {
+	span := s.trace("Update")
+	defer span.End()
 	item, err := s.Read(id)
 	if err != nil {
 		s.logger.Error(err.Error())
@@ -163,6 +271,12 @@ func (s *Service) Update(id string, data Item) (*Item, error) {
 	item.Title = data.Title
 	item.Description = data.Description
 	item.Blob = data.Blob
+	item.Key = data.Key
+	item.PrevKey = data.PrevKey
+	item.Unindexed = data.Unindexed
+	item.Progress = data.Progress
+	item.DueAt = data.DueAt
+	item.Seq = data.Seq
 
 	now := time.Now()
 	item.ModifiedAt = &now
@@ -182,6 +296,8 @@ func (s *Service) Update(id string, data Item) (*Item, error) {
 
 // Delete deletes an item given the ID
 func (s *Service) Delete(id string) (*Item, error) {
+	span := s.trace("Delete")
+	defer span.End()
 	item, err := s.Read(id)
 	if err != nil {
 		s.logger.Error(err.Error())
@@ -202,26 +318,107 @@ func (s *Service) Delete(id string) (*Item, error) {
 	return item, nil
 }
 
-// List returns the list of items given the owner ID
-func (s *Service) List(ownerID string, start, limit int) ([]Item, error) {
+// untitled returns true if the item has no title field
+func (s *Service) untitled(id string) (bool, error) {
+	n, err := s.store.Count(itemsBucket, map[string]interface{}{
+		"id":    id,
+		"title": map[string]interface{}{"$exists": false},
+	})
+	if err != nil {
+		s.logger.Error(err.Error())
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// List returns a page of items given the owner ID. Items are ordered by the requested sort
+// field and then by ID, so that the pages remain stable even if items are modified in between.
+func (s *Service) List(ownerID string, opts ListOptions) (*Page, error) {
+	span := s.trace("List")
+	defer span.End()
+	sort, field, desc, err := sortOrder(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
 	query := map[string]interface{}{
 		"ownerID": ownerID,
 	}
 
+	status, err := statusQuery(opts.Status)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range status {
+		query[k] = v
+	}
+
+	start := opts.Start
 	if start < minStart {
 		start = minStart
 	}
 
+	limit := opts.Limit
 	if limit <= 0 || limit > maxLimit {
 		limit = maxLimit
 	}
 
-	out := make([]Item, 0)
-	_, err := s.store.Find(itemsBucket, query, nil, []string{"-modifiedAt"}, start, limit, &out)
+	page := &Page{}
+	if opts.Total {
+		total, err := s.store.Count(itemsBucket, query)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	if opts.After != "" {
+		c, err := decodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sort {
+			return nil, ErrInvCursor
+		}
+		if field == "title" && c.Value == "" {
+			c.untitled, err = s.untitled(c.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		after, err := afterQuery(c, field, desc)
+		if err != nil {
+			return nil, err
+		}
+		query = map[string]interface{}{
+			"$and": []interface{}{query, after},
+		}
+	}
+
+	order := []string{field, "id"}
+	if desc {
+		order = []string{"-" + field, "-id"}
+	}
+
+	// fetching one item more than the limit to know if there's a next page
+	out := make([]Item, 0, limit+1)
+	_, err = s.store.Find(itemsBucket, query, nil, order, start, limit+1, &out)
 	if err != nil {
 		s.logger.Error(err.Error())
 		return nil, err
 	}
 
-	return out, nil
+	if len(out) > limit {
+		out = out[:limit]
+		last := out[len(out)-1]
+		page.Next = encodeCursor(cursor{
+			Sort:  sort,
+			Value: last.sortValue(field),
+			ID:    last.ID,
+		})
+	}
+	page.Items = out
+
+	return page, nil
 }
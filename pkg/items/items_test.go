@@ -15,7 +15,7 @@ func service() (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
-	logHandler := logger.New()
+	logHandler := logger.New([]string{"all"})
 	service := NewService(store, logHandler)
 	return &service, nil
 }
@@ -192,10 +192,11 @@ func TestList(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	ii, err := s.List("testOwner", 0, 100)
+	page, err := s.List("testOwner", ListOptions{Limit: 100})
 	if err != nil {
 		t.Fatal(err.Error())
 	}
+	ii := page.Items
 
 	if len(ii) != 2 {
 		t.Fatalf("Expected '%d', got '%d' results.", 2, len(ii))
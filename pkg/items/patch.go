@@ -0,0 +1,212 @@
+package items
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+var (
+	// ErrIncomplete is returned if an item is replaced without providing all of its fields
+	ErrIncomplete = errs.Invalid("Sorry, title and description are required to replace an item")
+	// ErrInvPatch is returned if the patch has unknown fields or values of the wrong type
+	ErrInvPatch = errs.Invalid("Sorry, invalid patch provided")
+	// ErrInvField is returned if the input has fields which are not part of an item
+	ErrInvField = errs.Invalid("Sorry, unknown fields provided")
+)
+
+var (
+	// contentFields are the fields of an item which can be set by the owner
+	contentFields = map[string]bool{
+		"title":       true,
+		"description": true,
+	}
+	// optionalFields are the fields of an item which can be set by the owner, but are not
+	// required while replacing an item
+	optionalFields = map[string]bool{
+		"dueAt": true,
+		"tags":  true,
+	}
+)
+
+// MergePatch applies the patch to the target as per JSON Merge Patch (RFC 7396) and returns
+// the patched document. Keys with null values are removed, objects are merged recursively
+// and everything else replaces the existing value.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = MergePatch(t[k], v)
+	}
+	return t
+}
+
+// document returns the owner editable content of the item as a JSON document
+func (i *Item) document() map[string]interface{} {
+	checklist := make([]interface{}, 0, len(i.Checklist))
+	for _, e := range i.Checklist {
+		checklist = append(checklist, map[string]interface{}{
+			"id":      e.ID,
+			"text":    e.Text,
+			"checked": e.Checked,
+		})
+	}
+	tags := make([]interface{}, 0, len(i.Tags))
+	for _, t := range i.Tags {
+		tags = append(tags, t)
+	}
+	doc := map[string]interface{}{
+		"title":       i.Title,
+		"description": i.Description,
+		"checklist":   checklist,
+		"tags":        tags,
+	}
+	if i.DueAt != nil {
+		doc["dueAt"] = i.DueAt.Format(time.RFC3339)
+	}
+	return doc
+}
+
+// documentChecklist returns the checklist entries from the checklist of a JSON document.
+// Entries without an ID are treated as new entries.
+func documentChecklist(v interface{}) ([]Entry, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, ErrInvPatch
+	}
+
+	entries := make([]Entry, 0, len(list))
+	for _, le := range list {
+		m, ok := le.(map[string]interface{})
+		if !ok {
+			return nil, ErrInvPatch
+		}
+		id, _ := m["id"].(string)
+		text, _ := m["text"].(string)
+		checked, _ := m["checked"].(bool)
+
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, ErrEntryText
+		}
+		if strings.TrimSpace(id) == "" {
+			id = newEntryID()
+		}
+		entries = append(entries, Entry{ID: id, Text: text, Checked: checked})
+	}
+	return entries, nil
+}
+
+// setDocument updates the item content from the JSON document
+func (i *Item) setDocument(doc map[string]interface{}) error {
+	title, description := "", ""
+	var checklist []Entry
+	var tags []string
+	var dueAt *time.Time
+	for k, v := range doc {
+		if k == "dueAt" {
+			str, ok := v.(string)
+			if !ok {
+				return ErrInvDueAt
+			}
+			t, err := parseDueAt(str)
+			if err != nil {
+				return err
+			}
+			dueAt = t
+			continue
+		}
+
+		if k == "checklist" {
+			entries, err := documentChecklist(v)
+			if err != nil {
+				return err
+			}
+			checklist = entries
+			continue
+		}
+
+		if k == "tags" {
+			t, err := documentTags(v)
+			if err != nil {
+				return err
+			}
+			tags = t
+			continue
+		}
+
+		if !contentFields[k] {
+			return ErrInvPatch
+		}
+		str, ok := v.(string)
+		if !ok {
+			return ErrInvPatch
+		}
+		switch k {
+		case "title":
+			title = strings.TrimSpace(str)
+		case "description":
+			description = strings.TrimSpace(str)
+		}
+	}
+
+	i.Title = title
+	i.Description = description
+	i.Checklist = checklist
+	i.Tags = tags
+	i.DueAt = dueAt
+	return nil
+}
+
+// SetContent replaces the content of a decrypted item with the JSON document, as the one sent by
+// the clients syncing the items. The fields which are not in the document are cleared.
+func (i *Item) SetContent(doc map[string]interface{}) error {
+	return i.setDocument(doc)
+}
+
+// Patch applies a JSON Merge Patch to the content of a decrypted item
+func (i *Item) Patch(patch map[string]interface{}) error {
+	doc, _ := MergePatch(i.document(), patch).(map[string]interface{})
+	return i.setDocument(doc)
+}
+
+// Replace replaces the complete content of the item with the provided data. All the content
+// fields are required, optional fields which are not provided are cleared. The checklist is
+// not part of the content and is left as is.
+func (i *Item) Replace(data map[string]string) error {
+	for k := range data {
+		if !contentFields[k] && !optionalFields[k] {
+			return ErrInvField
+		}
+	}
+
+	dueAt, err := parseDueAt(data["dueAt"])
+	if err != nil {
+		return err
+	}
+
+	for k := range contentFields {
+		if _, ok := data[k]; !ok {
+			return ErrIncomplete
+		}
+	}
+
+	i.Title = strings.TrimSpace(data["title"])
+	i.Description = strings.TrimSpace(data["description"])
+	i.Tags = ParseTags(data["tags"])
+	i.DueAt = dueAt
+	return nil
+}
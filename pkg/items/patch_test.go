@@ -0,0 +1,57 @@
+package items
+
+import (
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	item := Item{Title: "Hello", Description: "world"}
+
+	err := item.Patch(map[string]interface{}{"description": "there"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Title != "Hello" || item.Description != "there" {
+		t.Fatalf("Expected 'Hello' 'there', got '%s' '%s'", item.Title, item.Description)
+	}
+
+	err = item.Patch(map[string]interface{}{"title": nil})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Title != "" || item.Description != "there" {
+		t.Fatalf("Expected '' 'there', got '%s' '%s'", item.Title, item.Description)
+	}
+
+	err = item.Patch(map[string]interface{}{"title": 42})
+	if err != ErrInvPatch {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvPatch, err)
+	}
+
+	err = item.Patch(map[string]interface{}{"ownerID": "someone"})
+	if err != ErrInvPatch {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvPatch, err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	item := Item{Title: "Hello", Description: "world"}
+
+	err := item.Replace(map[string]string{"description": "there"})
+	if err != ErrIncomplete {
+		t.Fatalf("Expected '%v', got '%v'", ErrIncomplete, err)
+	}
+
+	err = item.Replace(map[string]string{"title": "Hi", "description": "there", "id": "x"})
+	if err != ErrInvField {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvField, err)
+	}
+
+	err = item.Replace(map[string]string{"title": "Hi", "description": ""})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Title != "Hi" || item.Description != "" {
+		t.Fatalf("Expected 'Hi' '', got '%s' '%s'", item.Title, item.Description)
+	}
+}
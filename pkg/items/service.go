@@ -1,14 +1,19 @@
 package items
 
 import (
+	"context"
+
 	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
 )
 
 // Service holds all the dependencies of items
 type Service struct {
 	store  storage.Service
 	logger logger.Service
+	// ctx has the span of the trace the operations are part of
+	ctx context.Context
 }
 
 // NewService returns a new instance of Service with all the dependencies initialized
@@ -18,3 +23,19 @@ func NewService(ss storage.Service, l logger.Service) Service {
 		logger: l,
 	}
 }
+
+// WithContext returns a copy of the service tracing its operations as part of the trace in the
+// context
+func (s Service) WithContext(ctx context.Context) Service {
+	s.ctx = ctx
+	s.store = storage.WithContext(ctx, s.store)
+	s.logger = logger.FromContext(ctx, s.logger)
+	return s
+}
+
+// trace starts the span of the operation. The service is not copied for every operation, so
+// the operations it calls are traced alongside it, under the span the service was created with.
+func (s *Service) trace(operation string) *tracing.Span {
+	_, span := tracing.Start(s.ctx, "items."+operation, tracing.KindInternal)
+	return span
+}
@@ -0,0 +1,225 @@
+package items
+
+import (
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	tombstonesBucket = "tombstones"
+
+	// ChangeCreated is the type of the change of an item created after the sequence
+	ChangeCreated = "created"
+	// ChangeUpdated is the type of the change of an item updated after the sequence
+	ChangeUpdated = "updated"
+	// ChangeDeleted is the type of the change of an item deleted after the sequence
+	ChangeDeleted = "deleted"
+
+	// maxChanges is the maximum number of changes in a page
+	maxChanges = 500
+)
+
+// Tombstone is the record of a deleted item, it's kept so that the clients syncing after the
+// deletion remove the item too
+type Tombstone struct {
+	ItemID  string `json:"id" bson:"itemID"`
+	OwnerID string `json:"-" bson:"ownerID"`
+	// Seq is the sequence of the deletion
+	Seq       int64      `json:"seq" bson:"seq"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+}
+
+// Change is a change of an item of the owner
+type Change struct {
+	Seq  int64  `json:"seq"`
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// Item is the item as of the change, it's nil if the item was deleted
+	Item *Item `json:"item,omitempty"`
+}
+
+// Changes is a page of the changes of the items of an owner, in the order they happened
+type Changes struct {
+	Changes []Change `json:"changes"`
+	// Seq is the sequence to fetch the next changes from
+	Seq int64 `json:"seq"`
+	// More is true if there are more changes after this page
+	More bool `json:"more"`
+}
+
+// LastSeq returns the sequence of the last change of the items of the owner, 0 if there are none
+func (s *Service) LastSeq(ownerID string) (int64, error) {
+	span := s.trace("LastSeq")
+	defer span.End()
+	var last int64
+	for _, bucket := range []string{itemsBucket, tombstonesBucket} {
+		out := struct {
+			Seq int64 `bson:"seq"`
+		}{}
+		_, err := s.store.FindOne(
+			bucket,
+			map[string]interface{}{"ownerID": ownerID, "seq": map[string]interface{}{"$exists": true}},
+			nil,
+			[]string{"-seq"},
+			&out,
+		)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			s.logger.Error(err.Error())
+			return 0, err
+		}
+		if out.Seq > last {
+			last = out.Seq
+		}
+	}
+	return last, nil
+}
+
+// Unsequenced returns the items of the owner saved before sequences were introduced
+func (s *Service) Unsequenced(ownerID string) ([]Item, error) {
+	span := s.trace("Unsequenced")
+	defer span.End()
+	out := make([]Item, 0)
+	_, err := s.store.Find(
+		itemsBucket,
+		map[string]interface{}{"ownerID": ownerID, "seq": map[string]interface{}{"$exists": false}},
+		nil,
+		[]string{"createdAt", "id"},
+		0,
+		0,
+		&out,
+	)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Unindexed returns the items of the owner changed by a recipient, which are not indexed yet
+func (s *Service) Unindexed(ownerID string) ([]Item, error) {
+	span := s.trace("Unindexed")
+	defer span.End()
+	out := make([]Item, 0)
+	_, err := s.store.Find(
+		itemsBucket,
+		map[string]interface{}{"ownerID": ownerID, "unindexed": true},
+		nil,
+		[]string{"createdAt", "id"},
+		0,
+		0,
+		&out,
+	)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Sequence sets the sequence of an item saved before sequences were introduced, as if it was
+// created then. Its modification time is left as is.
+func (s *Service) Sequence(item *Item, seq int64) error {
+	span := s.trace("Sequence")
+	defer span.End()
+	item.Seq = seq
+	item.CreatedSeq = seq
+	err := s.store.Update(itemsBucket, map[string]interface{}{"id": item.ID}, item)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// CreateTombstone records the deletion of the item
+func (s *Service) CreateTombstone(item *Item, seq int64) (*Tombstone, error) {
+	span := s.trace("CreateTombstone")
+	defer span.End()
+	now := time.Now()
+	t := Tombstone{
+		ItemID:    item.ID,
+		OwnerID:   item.OwnerID,
+		Seq:       seq,
+		DeletedAt: &now,
+	}
+	_, err := s.store.Save(tombstonesBucket, t)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ReadTombstone returns the tombstone of a deleted item
+func (s *Service) ReadTombstone(itemID string) (*Tombstone, error) {
+	span := s.trace("ReadTombstone")
+	defer span.End()
+	t := Tombstone{}
+	_, err := s.store.FindOne(tombstonesBucket, map[string]interface{}{"itemID": itemID}, nil, nil, &t)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Changes returns the changes of the items of the owner after the sequence, with the items
+// still encrypted
+func (s *Service) Changes(ownerID string, since int64, limit int) (*Changes, error) {
+	span := s.trace("Changes")
+	defer span.End()
+	if limit <= 0 || limit > maxChanges {
+		limit = maxChanges
+	}
+	query := map[string]interface{}{
+		"ownerID": ownerID,
+		"seq":     map[string]interface{}{"$gt": since},
+	}
+
+	// fetching one change more than the limit to know if there are more
+	ii := make([]Item, 0, limit+1)
+	_, err := s.store.Find(itemsBucket, query, nil, []string{"seq"}, 0, limit+1, &ii)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	tt := make([]Tombstone, 0, limit+1)
+	_, err = s.store.Find(tombstonesBucket, query, nil, []string{"seq"}, 0, limit+1, &tt)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+
+	changes := &Changes{Changes: make([]Change, 0, limit), Seq: since}
+	for len(ii)+len(tt) > 0 {
+		if len(changes.Changes) == limit {
+			changes.More = true
+			break
+		}
+
+		var c Change
+		if len(tt) == 0 || (len(ii) > 0 && ii[0].Seq < tt[0].Seq) {
+			item := ii[0]
+			ii = ii[1:]
+			typ := ChangeUpdated
+			if item.CreatedSeq > since {
+				typ = ChangeCreated
+			}
+			c = Change{Seq: item.Seq, Type: typ, ID: item.ID, Item: &item}
+		} else {
+			t := tt[0]
+			tt = tt[1:]
+			c = Change{Seq: t.Seq, Type: ChangeDeleted, ID: t.ItemID}
+		}
+		changes.Changes = append(changes.Changes, c)
+		changes.Seq = c.Seq
+	}
+	return changes, nil
+}
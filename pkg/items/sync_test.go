@@ -0,0 +1,95 @@
+package items
+
+import (
+	"testing"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+func TestChanges(t *testing.T) {
+	s := NewService(memory.New(), logger.New(nil))
+
+	legacy, _ := New(map[string]string{"title": "legacy"}, "owner")
+	_, err := s.Create(*legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, err := s.LastSeq("owner")
+	if err != nil || seq != 0 {
+		t.Fatalf("Expected no sequence, got %d %v", seq, err)
+	}
+	unsequenced, err := s.Unsequenced("owner")
+	if err != nil || len(unsequenced) != 1 {
+		t.Fatalf("Expected the legacy item, got %d %v", len(unsequenced), err)
+	}
+	err = s.Sequence(&unsequenced[0], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, title := range []string{"a", "b"} {
+		item, _ := New(map[string]string{"title": title}, "owner")
+		item.Seq = int64(i + 2)
+		item.CreatedSeq = item.Seq
+		_, err = s.Create(*item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title == "a" {
+			_, err = s.CreateTombstone(item, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = s.Delete(item.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	other, _ := New(map[string]string{"title": "other"}, "other")
+	other.Seq = 10
+	_, err = s.Create(*other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seq, err = s.LastSeq("owner")
+	if err != nil || seq != 4 {
+		t.Fatalf("Expected the sequence of the deletion, got %d %v", seq, err)
+	}
+
+	changes, err := s.Changes("owner", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Changes) != 2 || !changes.More || changes.Seq != 3 {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+	if changes.Changes[0].Item.Title != "legacy" || changes.Changes[1].Type != ChangeCreated {
+		t.Fatalf("Unexpected changes %+v", changes.Changes)
+	}
+
+	changes, err = s.Changes("owner", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Changes) != 2 || changes.More || changes.Seq != 4 || changes.Changes[1].Type != ChangeDeleted {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+
+	// updates of items created before the sequence
+	b := changes.Changes[0].Item
+	b.Seq = 5
+	_, err = s.Update(b.ID, *b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err = s.Changes("owner", 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Changes) != 2 || changes.Changes[1].Type != ChangeUpdated || changes.Changes[1].Seq != 5 {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+}
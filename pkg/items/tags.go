@@ -0,0 +1,59 @@
+package items
+
+import (
+	"strings"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+// maxTagLength is the maximum length of a single tag, longer tags are truncated
+const maxTagLength = 64
+
+// ErrInvTags is returned if the tags in a patch are not a list of strings
+var ErrInvTags = errs.Field("tags", "Sorry, tags should be a list of strings")
+
+// NormalizeTags trims the tags, and removes empty and duplicate tags. Tags are compared case
+// insensitively, the first occurrence is kept.
+func NormalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if len([]rune(t)) > maxTagLength {
+			t = string([]rune(t)[:maxTagLength])
+		}
+		key := strings.ToLower(t)
+		if t == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// ParseTags returns the tags from a comma separated list
+func ParseTags(str string) []string {
+	return NormalizeTags(strings.Split(str, ","))
+}
+
+// documentTags returns the tags from the tags of a JSON document
+func documentTags(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, ErrInvTags
+	}
+
+	tags := make([]string, 0, len(list))
+	for _, lv := range list {
+		t, ok := lv.(string)
+		if !ok {
+			return nil, ErrInvTags
+		}
+		tags = append(tags, t)
+	}
+	return NormalizeTags(tags), nil
+}
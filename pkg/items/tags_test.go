@@ -0,0 +1,63 @@
+package items
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tags := ParseTags(" work, Home ,,work, home,ops ")
+	expected := []string{"work", "Home", "ops"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("Expected %v, got %v", expected, tags)
+	}
+
+	if ParseTags("") != nil {
+		t.Fatal("Expected no tags")
+	}
+}
+
+func TestPatchTags(t *testing.T) {
+	item := Item{Title: "Hello", Tags: []string{"work"}}
+
+	err := item.Patch(map[string]interface{}{"tags": []interface{}{"ops", "OPS", "home"}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !reflect.DeepEqual(item.Tags, []string{"ops", "home"}) {
+		t.Fatalf("Unexpected tags %v", item.Tags)
+	}
+
+	err = item.Patch(map[string]interface{}{"tags": "ops"})
+	if err != ErrInvTags {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvTags, err)
+	}
+
+	err = item.Patch(map[string]interface{}{"tags": nil})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Tags != nil {
+		t.Fatalf("Expected no tags, got %v", item.Tags)
+	}
+}
+
+func TestEncryptTags(t *testing.T) {
+	var key [32]byte
+	item := Item{Title: "Hello", Description: "world", Tags: []string{"work"}}
+	err := item.Encrypt(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if item.Tags != nil {
+		t.Fatal("Expected tags to be cleared after encryption")
+	}
+
+	err = item.Decrypt(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !reflect.DeepEqual(item.Tags, []string{"work"}) {
+		t.Fatalf("Expected the tags to be decrypted, got %v", item.Tags)
+	}
+}
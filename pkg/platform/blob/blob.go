@@ -0,0 +1,110 @@
+// Package blob handles the store of large binary objects, like attachments
+package blob
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/bnkamalesh/notes/pkg/platform/blob/local"
+	"github.com/bnkamalesh/notes/pkg/platform/blob/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/blob/s3"
+)
+
+const (
+	// DriverLocal stores blobs in the local filesystem
+	DriverLocal = "local"
+	// DriverS3 stores blobs in an S3 compatible object store
+	DriverS3 = "s3"
+	// DriverMemory stores blobs in memory, it's meant only for tests
+	DriverMemory = "memory"
+)
+
+var (
+	// ErrNotFound is returned if the blob does not exist
+	ErrNotFound = errors.New("Blob not found")
+	// ErrInvDriver is returned if the driver in the config is not supported
+	ErrInvDriver = errors.New("Unsupported blob store driver")
+)
+
+// Service defines all the methods implemented by the blob store
+type Service interface {
+	// Put saves everything read from r as the blob with the given key, and returns the number
+	// of bytes saved. The blob is not saved if there's an error reading r.
+	Put(key string, r io.Reader) (int64, error)
+
+	// Get returns length bytes of the blob, starting from offset. A negative length reads
+	// till the end of the blob.
+	Get(key string, offset, length int64) (io.ReadCloser, error)
+
+	// Delete deletes the blob
+	Delete(key string) error
+}
+
+// Config holds all the configurations required for the blob store
+type Config struct {
+	// Driver is one of local, s3 or memory. Defaults to local
+	Driver string
+	// Dir is the directory where blobs are stored by the local driver
+	Dir string
+	// S3 is the configuration of the s3 driver
+	S3 s3.Config
+}
+
+// Store holds all the dependencies
+type Store struct {
+	handler Service
+}
+
+// Put saves a blob
+func (s *Store) Put(key string, r io.Reader) (int64, error) {
+	return s.handler.Put(key, r)
+}
+
+// Get returns a part of a blob
+func (s *Store) Get(key string, offset, length int64) (io.ReadCloser, error) {
+	rc, err := s.handler.Get(key, offset, length)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Delete deletes a blob
+func (s *Store) Delete(key string) error {
+	err := s.handler.Delete(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// New returns a new blob store for the driver in the config
+func New(c Config) (*Store, error) {
+	var (
+		h   Service
+		err error
+	)
+
+	switch c.Driver {
+	case "", DriverLocal:
+		h, err = local.New(c.Dir)
+	case DriverS3:
+		h, err = s3.New(c.S3)
+	case DriverMemory:
+		h = memory.New()
+	default:
+		return nil, ErrInvDriver
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{handler: h}, nil
+}
@@ -0,0 +1,113 @@
+// Package local implements a blob store on the local filesystem
+package local
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvKey is returned if the key would resolve outside the directory of the store
+var ErrInvKey = errors.New("Invalid blob key")
+
+// Handler stores every blob as a file in a directory
+type Handler struct {
+	dir string
+}
+
+// New returns a store in the given directory, the directory is created if it does not exist
+func New(dir string) (*Handler, error) {
+	if dir == "" {
+		dir = "data/blobs"
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{dir: dir}, nil
+}
+
+func (h *Handler) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", ErrInvKey
+	}
+	return filepath.Join(h.dir, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the blob to a temporary file, which is moved in place only after all of r is
+// written. So a failed upload never leaves a partial blob.
+func (h *Handler) Put(key string, r io.Reader) (int64, error) {
+	path, err := h.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+type section struct {
+	io.Reader
+	io.Closer
+}
+
+// Get returns a part of the blob
+func (h *Handler) Get(key string, offset, length int64) (io.ReadCloser, error) {
+	path, err := h.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if length < 0 {
+		return f, nil
+	}
+	return section{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+// Delete deletes the blob
+func (h *Handler) Delete(key string) error {
+	path, err := h.path(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
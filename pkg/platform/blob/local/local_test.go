@@ -0,0 +1,65 @@
+package local
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	n, err := h.Put("item/blob", strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if n != 11 {
+		t.Fatalf("Expected 11 bytes, got %d", n)
+	}
+
+	rc, err := h.Get("item/blob", 6, 3)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "wor" {
+		t.Fatalf("Expected 'wor', got '%s'", string(b))
+	}
+
+	_, err = h.Put("../escape", strings.NewReader("x"))
+	if err != ErrInvKey {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvKey, err)
+	}
+
+	// a failed upload should not leave a blob behind
+	_, err = h.Put("item/failed", io.MultiReader(strings.NewReader("x"), errReader{}))
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	entries, _ := os.ReadDir(h.dir + "/item")
+	if len(entries) != 1 {
+		t.Fatalf("Expected only 1 blob, found %d files", len(entries))
+	}
+
+	err = h.Delete("item/blob")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = h.Get("item/blob", 0, -1)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected not exist error, got '%v'", err)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
@@ -0,0 +1,72 @@
+// Package memory implements an in-process blob store, meant for tests
+package memory
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// Handler stores all the blobs in memory
+type Handler struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// New returns a new empty store
+func New() *Handler {
+	return &Handler{
+		blobs: map[string][]byte{},
+	}
+}
+
+// Put saves the blob
+func (h *Handler) Put(key string, r io.Reader) (int64, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	h.blobs[key] = b
+	h.mu.Unlock()
+	return int64(len(b)), nil
+}
+
+// Get returns a part of the blob
+func (h *Handler) Get(key string, offset, length int64) (io.ReadCloser, error) {
+	h.mu.RLock()
+	b, ok := h.blobs[key]
+	h.mu.RUnlock()
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	b = b[offset:]
+	if length >= 0 && length < int64(len(b)) {
+		b = b[:length]
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// Delete deletes the blob
+func (h *Handler) Delete(key string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.blobs[key]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(h.blobs, key)
+	return nil
+}
+
+// Len returns the number of blobs in the store
+func (h *Handler) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.blobs)
+}
@@ -0,0 +1,271 @@
+// Package s3 implements a blob store on an S3 compatible object store
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// partSize is the size of every part of a multipart upload, except the last. It's the
+	// minimum allowed by S3, and the most held in memory per upload
+	partSize = 5 * 1024 * 1024
+)
+
+var (
+	// ErrInvConfig is returned if the endpoint, bucket or credentials are missing
+	ErrInvConfig = errors.New("Endpoint, bucket, access key and secret key are required for S3")
+)
+
+// Config holds all the configurations required for an S3 compatible store
+type Config struct {
+	// Endpoint is the URL of the store, e.g. https://s3.amazonaws.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle uses the bucket in the path instead of the host, required by most self hosted
+	// stores
+	PathStyle bool
+	Timeout   time.Duration
+}
+
+// Handler stores blobs as objects in a bucket
+type Handler struct {
+	config   Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// New returns a new S3 store
+func New(c Config) (*Handler, error) {
+	if c.Endpoint == "" || c.Bucket == "" || c.AccessKey == "" || c.SecretKey == "" {
+		return nil, ErrInvConfig
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		config:   c,
+		endpoint: u,
+		// the timeout is not set on the client, since it would limit the time taken to
+		// stream a whole blob
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: c.Timeout,
+			},
+		},
+	}, nil
+}
+
+// objectURL returns the URL of the object with the key
+func (h *Handler) objectURL(key string, query url.Values) *url.URL {
+	u := *h.endpoint
+	if h.config.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + h.config.Bucket + "/" + key
+	} else {
+		u.Host = h.config.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do signs and sends a request, and returns the response if it's successful
+func (h *Handler) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, h.objectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	payloadHash := emptyHash
+	if len(body) > 0 {
+		payloadHash = hashHex(body)
+	}
+	sign(req, h.config.AccessKey, h.config.SecretKey, h.config.Region, payloadHash, time.Now())
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fs.ErrNotExist
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3: %s %s: %s %s", method, key, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// Put uploads the blob. A blob smaller than a part is uploaded in a single request, larger
+// blobs are streamed with a multipart upload, holding only one part in memory.
+func (h *Handler) Put(key string, r io.Reader) (int64, error) {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		resp, err := h.do(http.MethodPut, key, nil, nil, buf[:n])
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return int64(n), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	uploadID, err := h.createUpload(key)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := h.uploadParts(key, uploadID, buf, r)
+	if err != nil {
+		h.abortUpload(key, uploadID)
+		return 0, err
+	}
+	return total, nil
+}
+
+type initiateResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+func (h *Handler) createUpload(key string) (string, error) {
+	resp, err := h.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	result := initiateResult{}
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+// uploadParts uploads the first part in buf, followed by the rest of r
+func (h *Handler) uploadParts(key, uploadID string, buf []byte, r io.Reader) (int64, error) {
+	var (
+		total int64
+		parts []completePart
+		n     = len(buf)
+		last  bool
+	)
+
+	for number := 1; ; number++ {
+		resp, err := h.do(
+			http.MethodPut,
+			key,
+			url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}},
+			nil,
+			buf[:n],
+		)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+
+		total += int64(n)
+		parts = append(parts, completePart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		if last {
+			break
+		}
+
+		n, err = io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			last = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	body, err := xml.Marshal(completeUpload{Parts: parts})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := h.do(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return total, nil
+}
+
+func (h *Handler) abortUpload(key, uploadID string) {
+	resp, err := h.do(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Get returns a part of the blob, using a range request
+func (h *Handler) Get(key string, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	header := http.Header{}
+	if offset > 0 || length >= 0 {
+		rng := fmt.Sprintf("bytes=%d-", offset)
+		if length >= 0 {
+			rng += strconv.FormatInt(offset+length-1, 10)
+		}
+		header.Set("Range", rng)
+	}
+
+	resp, err := h.do(http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete deletes the blob
+func (h *Handler) Delete(key string) error {
+	resp, err := h.do(http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 implements the subset of the S3 API used by the handler
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	parts   map[string]map[int][]byte
+}
+
+func (f *fakeS3) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		rw.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(req.URL.Path, "/bucket/")
+	q := req.URL.Query()
+	body, _ := io.ReadAll(req.Body)
+
+	switch {
+	case req.Method == http.MethodPost && q.Has("uploads"):
+		id := strconv.Itoa(len(f.parts) + 1)
+		f.parts[id] = map[int][]byte{}
+		fmt.Fprintf(rw, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case req.Method == http.MethodPut && q.Has("uploadId"):
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		f.parts[q.Get("uploadId")][n] = body
+		rw.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+	case req.Method == http.MethodPost && q.Has("uploadId"):
+		c := completeUpload{}
+		xml.Unmarshal(body, &c)
+		sort.Slice(c.Parts, func(i, j int) bool { return c.Parts[i].PartNumber < c.Parts[j].PartNumber })
+		var obj []byte
+		for _, p := range c.Parts {
+			obj = append(obj, f.parts[q.Get("uploadId")][p.PartNumber]...)
+		}
+		f.objects[key] = obj
+		delete(f.parts, q.Get("uploadId"))
+	case req.Method == http.MethodPut:
+		f.objects[key] = body
+	case req.Method == http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(rw, req, key, time.Time{}, bytes.NewReader(obj))
+	case req.Method == http.MethodDelete:
+		delete(f.objects, key)
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, parts: map[string]map[int][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	h, err := New(Config{
+		Endpoint:  server.URL,
+		Bucket:    "bucket",
+		AccessKey: "key",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, size := range []int{10, partSize, partSize*2 + 5} {
+		blob := make([]byte, size)
+		rand.Read(blob)
+
+		n, err := h.Put("a/b", bytes.NewReader(blob))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if n != int64(size) || !bytes.Equal(fake.objects["a/b"], blob) {
+			t.Fatalf("Expected %d bytes uploaded, got %d", size, n)
+		}
+
+		rc, err := h.Get("a/b", 5, 4)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		part, _ := io.ReadAll(rc)
+		rc.Close()
+		if !bytes.Equal(part, blob[5:9]) {
+			t.Fatal("Ranged get does not match")
+		}
+	}
+
+	err = h.Delete("a/b")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = h.Get("a/b", 0, -1)
+	if err != fs.ErrNotExist {
+		t.Fatalf("Expected '%v', got '%v'", fs.ErrNotExist, err)
+	}
+}
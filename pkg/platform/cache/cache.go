@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"time"
@@ -23,11 +24,28 @@ var ErrCacheNoHn = errors.New("No cache handler initialized")
 type Service interface {
 	Set(key string, value interface{}, expiry time.Duration) error
 	Get(key string, result interface{}) error
+	// SetNX sets the value only if the key does not exist, and returns true if it was set
+	SetNX(key string, value interface{}, expiry time.Duration) (bool, error)
 	// HSet(string, string, interface{}, time.Duration, bool) error
 	// HGet(string, string, interface{}) (error)
-	// Delete(...string) error
+	Delete(keys ...string) error
+	// Increment increments the counter of the key by 1 and returns the new value. The expiry is
+	// set when the counter is created
+	Increment(key string, expiry time.Duration) (int64, error)
+	// DeleteIf deletes the key only if it has the value, and returns true if it was deleted. It's
+	// meant to release locks, without releasing a lock taken by someone else after it expired.
+	DeleteIf(key string, value interface{}) (bool, error)
 	// HDelete(string, ...string) error
 	Ping() error
+	// Publish sends the message to all the subscribers of the channel, on every instance of the
+	// app
+	Publish(channel string, message []byte) error
+	// Subscribe returns the messages published on the channel, until the context is done.
+	// The channel is closed if the subscriber can't keep up, so that it knows messages were
+	// lost and subscribes again.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+	// Close closes the connections to the cache, it should not be used after
+	Close() error
 }
 
 type Config struct {
@@ -43,6 +61,20 @@ type Handler struct {
 	client Service
 }
 
+// WithContext returns the cache tracing its operations as part of the trace in the context. It
+// returns the cache as is if it does not trace its operations, like the cache in memory.
+func WithContext(ctx context.Context, cs Service) Service {
+	h, ok := cs.(*Handler)
+	if !ok {
+		return cs
+	}
+	rh, ok := h.client.(*redis.Handler)
+	if !ok {
+		return cs
+	}
+	return &Handler{client: rh.WithContext(ctx)}
+}
+
 func (h *Handler) Set(key string, value interface{}, expiry time.Duration) error {
 	return h.client.Set(key, value, expiry)
 }
@@ -51,10 +83,38 @@ func (h *Handler) Get(key string, result interface{}) error {
 	return h.client.Get(key, result)
 }
 
+func (h *Handler) SetNX(key string, value interface{}, expiry time.Duration) (bool, error) {
+	return h.client.SetNX(key, value, expiry)
+}
+
+func (h *Handler) Delete(keys ...string) error {
+	return h.client.Delete(keys...)
+}
+
+func (h *Handler) Increment(key string, expiry time.Duration) (int64, error) {
+	return h.client.Increment(key, expiry)
+}
+
+func (h *Handler) DeleteIf(key string, value interface{}) (bool, error) {
+	return h.client.DeleteIf(key, value)
+}
+
 func (h *Handler) Ping() error {
 	return h.client.Ping()
 }
 
+func (h *Handler) Close() error {
+	return h.client.Close()
+}
+
+func (h *Handler) Publish(channel string, message []byte) error {
+	return h.client.Publish(channel, message)
+}
+
+func (h *Handler) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	return h.client.Subscribe(ctx, channel)
+}
+
 func New(c Config) (*Handler, error) {
 	h := &Handler{}
 	db, _ := strconv.Atoi(c.Name)
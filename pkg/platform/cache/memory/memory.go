@@ -0,0 +1,197 @@
+// Package memory implements an in-process cache, meant for tests and local development. Values
+// are encoded with msgpack like the Redis cache, so that they behave the same.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+)
+
+type entry struct {
+	value     []byte
+	counter   int64
+	expiresAt time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// subscriberBuffer is the number of messages buffered for a subscriber
+const subscriberBuffer = 256
+
+// Handler stores all the keys in memory
+type Handler struct {
+	mu          sync.Mutex
+	entries     map[string]entry
+	subscribers map[string]map[chan []byte]struct{}
+}
+
+// New returns a new empty cache
+func New() *Handler {
+	return &Handler{
+		entries:     map[string]entry{},
+		subscribers: map[string]map[chan []byte]struct{}{},
+	}
+}
+
+func expiresAt(expiry time.Duration) time.Time {
+	if expiry <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(expiry)
+}
+
+// get returns the entry of the key if it exists and has not expired, the lock should be held
+func (h *Handler) get(key string) (entry, bool) {
+	e, ok := h.entries[key]
+	if !ok {
+		return e, false
+	}
+	if e.expired(time.Now()) {
+		delete(h.entries, key)
+		return e, false
+	}
+	return e, true
+}
+
+// Set saves the value with the given key and expiry
+func (h *Handler) Set(key string, value interface{}, expiry time.Duration) error {
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.entries[key] = entry{value: b, expiresAt: expiresAt(expiry)}
+	h.mu.Unlock()
+	return nil
+}
+
+// Get loads the value of the key to result
+func (h *Handler) Get(key string, result interface{}) error {
+	h.mu.Lock()
+	e, ok := h.get(key)
+	h.mu.Unlock()
+	if !ok || e.value == nil {
+		return cache.ErrNotFound
+	}
+	return msgpack.Unmarshal(e.value, result)
+}
+
+// SetNX saves the value only if the key does not exist, it returns true if it was saved
+func (h *Handler) SetNX(key string, value interface{}, expiry time.Duration) (bool, error) {
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.get(key); ok {
+		return false, nil
+	}
+	h.entries[key] = entry{value: b, expiresAt: expiresAt(expiry)}
+	return true, nil
+}
+
+// Delete removes the keys
+func (h *Handler) Delete(keys ...string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range keys {
+		delete(h.entries, k)
+	}
+	return nil
+}
+
+// Increment increments the counter of the key by 1 and returns the new value. The expiry is
+// set only when the counter is created.
+func (h *Handler) Increment(key string, expiry time.Duration) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.get(key)
+	if !ok {
+		e = entry{expiresAt: expiresAt(expiry)}
+	}
+	e.counter++
+	h.entries[key] = e
+	return e.counter, nil
+}
+
+// DeleteIf deletes the key only if it has the value, and returns true if it was deleted
+func (h *Handler) DeleteIf(key string, value interface{}) (bool, error) {
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.get(key)
+	if !ok || !bytes.Equal(e.value, b) {
+		return false, nil
+	}
+	delete(h.entries, key)
+	return true, nil
+}
+
+// Ping always succeeds
+func (h *Handler) Ping() error {
+	return nil
+}
+
+// Close does nothing, the keys are kept
+func (h *Handler) Close() error {
+	return nil
+}
+
+// Publish sends the message to the subscribers of the channel in this process. The subscribers
+// which can't keep up are closed.
+func (h *Handler) Publish(channel string, message []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[channel] {
+		select {
+		case ch <- append([]byte(nil), message...):
+		default:
+			h.unsubscribe(channel, ch)
+		}
+	}
+	return nil
+}
+
+// unsubscribe removes the subscriber and closes its channel, the lock should be held
+func (h *Handler) unsubscribe(channel string, ch chan []byte) {
+	if _, ok := h.subscribers[channel][ch]; !ok {
+		return
+	}
+	delete(h.subscribers[channel], ch)
+	close(ch)
+}
+
+// Subscribe returns the messages published on the channel until the context is done, or until
+// the subscriber falls behind
+func (h *Handler) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriberBuffer)
+	h.mu.Lock()
+	if h.subscribers[channel] == nil {
+		h.subscribers[channel] = map[chan []byte]struct{}{}
+	}
+	h.subscribers[channel][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		h.unsubscribe(channel, ch)
+		h.mu.Unlock()
+	}()
+	return ch, nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+)
+
+func TestCache(t *testing.T) {
+	h := New()
+	type value struct {
+		Name   string
+		Secret []byte `json:"-"`
+	}
+
+	err := h.Set("key", value{Name: "name", Secret: []byte("secret")}, time.Hour)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	v := value{}
+	err = h.Get("key", &v)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if v.Name != "name" || string(v.Secret) != "secret" {
+		t.Fatalf("Unexpected value %+v", v)
+	}
+
+	ok, _ := h.SetNX("key", value{}, time.Hour)
+	if ok {
+		t.Fatal("Expected SetNX to not overwrite an existing key")
+	}
+
+	h.Set("expired", value{}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if h.Get("expired", &v) != cache.ErrNotFound {
+		t.Fatal("Expected the key to have expired")
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		n, _ := h.Increment("counter", time.Hour)
+		if n != i {
+			t.Fatalf("Expected %d, got %d", i, n)
+		}
+	}
+
+	h.Delete("key", "counter")
+	if h.Get("key", &v) != cache.ErrNotFound {
+		t.Fatal("Expected the key to be deleted")
+	}
+	n, _ := h.Increment("counter", time.Hour)
+	if n != 1 {
+		t.Fatalf("Expected the counter to be reset, got %d", n)
+	}
+
+	h.SetNX("lock", "token", time.Hour)
+	ok, _ = h.DeleteIf("lock", "other")
+	if ok || h.Get("lock", new(string)) != nil {
+		t.Fatal("Expected the key with another value to be kept")
+	}
+	ok, _ = h.DeleteIf("lock", "token")
+	if !ok || h.Get("lock", new(string)) != cache.ErrNotFound {
+		t.Fatal("Expected the key with the value to be deleted")
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	h := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := h.Subscribe(ctx, "events")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		err = h.Publish("events", []byte("message"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	n := 0
+	for range messages {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Fatalf("Expected the %d buffered messages before the channel is closed, got %d", subscriberBuffer, n)
+	}
+
+	// the context is done after the subscriber was closed
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+}
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -8,15 +9,66 @@ import (
 	"github.com/go-redis/cache"
 	"github.com/go-redis/redis"
 	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/bnkamalesh/notes/pkg/platform/metrics"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
 )
 
+// subscriberBuffer is the number of messages buffered for a subscriber
+const subscriberBuffer = 256
+
+// deleteIf deletes the key only if it has the value, in a single step
+var deleteIf = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+// increment increments the key, and sets its expiry in milliseconds if it has none, in a single
+// step. The keys left without an expiry before it was done in a single step get one as well.
+var increment = redis.NewScript(`
+local n = redis.call("incr", KEYS[1])
+if tonumber(ARGV[1]) > 0 and redis.call("pttl", KEYS[1]) == -1 then
+	redis.call("pexpire", KEYS[1], ARGV[1])
+end
+return n`)
+
 var (
 	// ErrInvHosts is returned when the provided host(s) is/are invalid
 	ErrInvHosts = errors.New("Invalid hosts provided")
 	// ErrPing is the error returned in case of ping failure
 	ErrPing = errors.New("Ping failed")
+
+	operationDuration = metrics.NewHistogram(
+		"notes_redis_operation_duration_seconds",
+		"Duration of the Redis operations",
+		metrics.DefaultBuckets,
+		"operation",
+	)
+	operationErrors = metrics.NewCounter(
+		"notes_redis_operation_errors_total",
+		"Number of Redis operations which failed, cache misses are not counted",
+		"operation",
+	)
 )
 
+// observe starts the span of the operation, and returns the function recording its duration
+// and its error if any. The returned function is deferred with a pointer to the returned error.
+func (h *Handler) observe(operation string) func(err *error) {
+	start := time.Now()
+	_, span := tracing.Start(h.ctx, "redis."+operation, tracing.KindClient)
+	span.SetAttribute("db.system", "redis")
+	span.SetAttribute("db.operation", operation)
+	return func(err *error) {
+		operationDuration.Since(start, operation)
+		if *err != nil && *err != cache.ErrCacheMiss {
+			operationErrors.Inc(operation)
+			span.SetError(*err)
+		}
+		span.End()
+	}
+}
+
 // Config struct has all the configurations required for redis
 type Config struct {
 	Hosts           []string
@@ -40,10 +92,21 @@ type Config struct {
 type Handler struct {
 	ring  *redis.Ring
 	codec *cache.Codec
+	// ctx has the span of the trace the operations are part of
+	ctx context.Context
+}
+
+// WithContext returns a copy of the handler tracing its operations as part of the trace in
+// the context
+func (h *Handler) WithContext(ctx context.Context) *Handler {
+	c := *h
+	c.ctx = ctx
+	return &c
 }
 
 // Set saves a new value in Redis with the given key, value and expiry
-func (h *Handler) Set(key string, value interface{}, expiry time.Duration) error {
+func (h *Handler) Set(key string, value interface{}, expiry time.Duration) (err error) {
+	defer h.observe("set")(&err)
 	return h.codec.Set(&cache.Item{
 		Key:        key,
 		Object:     value,
@@ -52,12 +115,60 @@ func (h *Handler) Set(key string, value interface{}, expiry time.Duration) error
 }
 
 // Get loads the value of the given key, from Redis to result
-func (h *Handler) Get(key string, result interface{}) error {
+func (h *Handler) Get(key string, result interface{}) (err error) {
+	defer h.observe("get")(&err)
 	return h.codec.Get(key, result)
 }
 
+// SetNX saves a new value in Redis only if the key does not exist already. It returns true if
+// the value was saved
+func (h *Handler) SetNX(key string, value interface{}, expiry time.Duration) (ok bool, err error) {
+	defer h.observe("setnx")(&err)
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return h.ring.SetNX(key, b, expiry).Result()
+}
+
+// Delete removes the given keys from Redis
+func (h *Handler) Delete(keys ...string) (err error) {
+	defer h.observe("delete")(&err)
+	return h.ring.Del(keys...).Err()
+}
+
+// Increment increments the integer value of the key by 1 and returns the new value. The expiry
+// is set only when the key is created by the increment
+func (h *Handler) Increment(key string, expiry time.Duration) (n int64, err error) {
+	defer h.observe("incr")(&err)
+	v, err := increment.Run(h.ring, []string{key}, int64(expiry/time.Millisecond)).Result()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v to the increment", v)
+	}
+	return n, nil
+}
+
+// DeleteIf deletes the key only if it has the value, and returns true if it was deleted
+func (h *Handler) DeleteIf(key string, value interface{}) (ok bool, err error) {
+	defer h.observe("deleteif")(&err)
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	n, err := deleteIf.Run(h.ring, []string{key}, b).Result()
+	if err != nil {
+		return false, err
+	}
+	return n == int64(1), nil
+}
+
 // Ping pings the redis server
-func (h *Handler) Ping() error {
+func (h *Handler) Ping() (err error) {
+	defer h.observe("ping")(&err)
 	result := h.ring.Ping()
 	if result.Val() != "PONG" {
 		return ErrPing
@@ -66,6 +177,53 @@ func (h *Handler) Ping() error {
 	return nil
 }
 
+// Close closes the connections to all the servers of the ring
+func (h *Handler) Close() error {
+	return h.ring.Close()
+}
+
+// Publish publishes the message on the channel
+func (h *Handler) Publish(channel string, message []byte) (err error) {
+	defer h.observe("publish")(&err)
+	return h.ring.Publish(channel, message).Err()
+}
+
+// Subscribe subscribes to the channel, and sends the messages on the returned channel until the
+// context is done. The subscription is restored if the connection is lost, the messages
+// published meanwhile are lost. The channel is closed if the subscriber falls behind.
+func (h *Handler) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ps := h.ring.Subscribe(channel)
+	// the first reply confirms the subscription
+	_, err := ps.Receive()
+	if err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	messages := make(chan []byte, subscriberBuffer)
+	go func() {
+		defer close(messages)
+		defer ps.Close()
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- []byte(msg.Payload):
+				default:
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
+
 // New returns a handler instance with all the required attributes initialized
 func New(c Config) (*Handler, error) {
 	if len(c.Hosts) == 0 {
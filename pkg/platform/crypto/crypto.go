@@ -0,0 +1,196 @@
+// Package crypto provides the symmetric and public key encryption primitives used across the app
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/metrics"
+)
+
+const (
+	// KeySize is the size of a symmetric key in bytes
+	KeySize = 32
+	// passwordIterations is the number of PBKDF2 iterations used to derive a key from a password
+	passwordIterations = 100000
+)
+
+var (
+	// ErrMalformedCipher is returned when the cipher text is invalid and cannot be used
+	ErrMalformedCipher = errors.New("malformed ciphertext")
+	// ErrInvPublicKey is returned when the public key is invalid
+	ErrInvPublicKey = errors.New("invalid public key")
+
+	duration = metrics.NewHistogram(
+		"notes_crypto_duration_seconds",
+		"Duration of the encryption, decryption and key derivation operations",
+		metrics.FastBuckets,
+		"operation",
+	)
+)
+
+// NewKey returns a new random symmetric key
+func NewKey() ([KeySize]byte, error) {
+	var key [KeySize]byte
+	_, err := io.ReadFull(rand.Reader, key[:])
+	return key, err
+}
+
+// Seal encrypts the plain text with AES-GCM, the random nonce is prefixed to the output
+func Seal(key [KeySize]byte, plain []byte) ([]byte, error) {
+	defer duration.Since(time.Now(), "encrypt")
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// Open decrypts a cipher text created by Seal
+func Open(key [KeySize]byte, sealed []byte) ([]byte, error) {
+	defer duration.Since(time.Now(), "decrypt")
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformedCipher
+	}
+
+	return gcm.Open(nil,
+		sealed[:gcm.NonceSize()],
+		sealed[gcm.NonceSize():],
+		nil,
+	)
+}
+
+// WrapKey encrypts a symmetric key with another key
+func WrapKey(kek [KeySize]byte, key [KeySize]byte) ([]byte, error) {
+	return Seal(kek, key[:])
+}
+
+// UnwrapKey decrypts a symmetric key wrapped by WrapKey
+func UnwrapKey(kek [KeySize]byte, wrapped []byte) ([KeySize]byte, error) {
+	var key [KeySize]byte
+	b, err := Open(kek, wrapped)
+	if err != nil {
+		return key, err
+	}
+	if len(b) != KeySize {
+		return key, ErrMalformedCipher
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+// PasswordKey derives a symmetric key from a password using PBKDF2-SHA256
+func PasswordKey(password string, salt []byte) ([KeySize]byte, error) {
+	defer duration.Since(time.Now(), "deriveKey")
+	var key [KeySize]byte
+	b, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, KeySize)
+	if err != nil {
+		return key, err
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+// NewKeyPair returns a new X25519 key pair
+func NewKeyPair() (public []byte, private []byte, err error) {
+	pk, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pk.PublicKey().Bytes(), pk.Bytes(), nil
+}
+
+// boxKey derives the symmetric key of a box from the X25519 shared secret and both the
+// public keys
+func boxKey(secret, ephemeral, recipient []byte) [KeySize]byte {
+	h := sha256.New()
+	h.Write(secret)
+	h.Write(ephemeral)
+	h.Write(recipient)
+	var key [KeySize]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// SealTo encrypts the plain text such that only the owner of the private key of the given
+// public key can decrypt it. A new ephemeral key pair is used for every box, and its public
+// key is prefixed to the output.
+func SealTo(publicKey []byte, plain []byte) ([]byte, error) {
+	curve := ecdh.X25519()
+	recipient, err := curve.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, ErrInvPublicKey
+	}
+
+	ephemeral, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	epub := ephemeral.PublicKey().Bytes()
+	sealed, err := Seal(boxKey(secret, epub, publicKey), plain)
+	if err != nil {
+		return nil, err
+	}
+	return append(epub, sealed...), nil
+}
+
+// OpenFrom decrypts a box created by SealTo, using the recipient's private key
+func OpenFrom(privateKey []byte, box []byte) ([]byte, error) {
+	curve := ecdh.X25519()
+	private, err := curve.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(private.PublicKey().Bytes())
+	if len(box) < size {
+		return nil, ErrMalformedCipher
+	}
+
+	ephemeral, err := curve.NewPublicKey(box[:size])
+	if err != nil {
+		return nil, ErrMalformedCipher
+	}
+
+	secret, err := private.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	return Open(boxKey(secret, box[:size], private.PublicKey().Bytes()), box[size:])
+}
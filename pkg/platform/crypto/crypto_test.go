@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	sealed, err := Seal(key, []byte("hello world"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	plain, err := Open(key, sealed)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(plain) != "hello world" {
+		t.Fatalf("Expected 'hello world', got '%s'", string(plain))
+	}
+
+	other, _ := NewKey()
+	_, err = Open(other, sealed)
+	if err == nil {
+		t.Fatal("Expected error opening with a different key")
+	}
+
+	wrapped, err := WrapKey(key, other)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	unwrapped, err := UnwrapKey(key, wrapped)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if unwrapped != other {
+		t.Fatal("Expected unwrapped key to match")
+	}
+}
+
+func TestBox(t *testing.T) {
+	public, private, err := NewKeyPair()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	key, _ := NewKey()
+	box, err := SealTo(public, key[:])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	plain, err := OpenFrom(private, box)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(plain, key[:]) {
+		t.Fatal("Expected the opened box to match the sealed key")
+	}
+
+	_, otherPrivate, _ := NewKeyPair()
+	_, err = OpenFrom(otherPrivate, box)
+	if err == nil {
+		t.Fatal("Expected error opening the box with a different private key")
+	}
+
+	_, err = SealTo([]byte("short"), key[:])
+	if err != ErrInvPublicKey {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvPublicKey, err)
+	}
+}
+
+func TestPasswordKey(t *testing.T) {
+	salt := []byte("salt")
+	a, err := PasswordKey("secret", salt)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b, _ := PasswordKey("secret", salt)
+	if a != b {
+		t.Fatal("Expected the same key for the same password and salt")
+	}
+	c, _ := PasswordKey("secret", []byte("pepper"))
+	if a == c {
+		t.Fatal("Expected different keys for different salts")
+	}
+}
@@ -0,0 +1,227 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// ChunkSize is the size of a plain text chunk of a stream
+	ChunkSize = 64 * 1024
+	// chunkOverhead is the size of the authentication tag added to every chunk
+	chunkOverhead = 16
+)
+
+// ErrTruncated is returned when a stream ends before its last chunk
+var ErrTruncated = errors.New("truncated stream")
+
+// A stream is encrypted in chunks of ChunkSize, each sealed with AES-GCM, so that it can be
+// encrypted and decrypted without holding it in memory and decrypted from any chunk.
+// The nonce of a chunk is its index, with a flag set on the last chunk so that a truncated
+// stream is detected. Since the nonces are not random, a key must be used for only one stream.
+// The last chunk is always shorter than ChunkSize, and is empty if the size of the stream is a
+// multiple of ChunkSize.
+
+// CipherSize returns the size of the encrypted stream of plain text of the given size
+func CipherSize(size int64) int64 {
+	return size + (size/ChunkSize+1)*chunkOverhead
+}
+
+// ChunkOffset returns the offset of a chunk in the encrypted stream
+func ChunkOffset(chunk int64) int64 {
+	return chunk * (ChunkSize + chunkOverhead)
+}
+
+func chunkNonce(chunk uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, chunk)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func newGCM(key [KeySize]byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type encryptReader struct {
+	gcm   cipher.AEAD
+	r     io.Reader
+	chunk uint64
+	plain []byte
+	out   []byte
+	// buf is the part of out yet to be read
+	buf  []byte
+	done bool
+}
+
+// EncryptReader returns a reader which encrypts everything read from r
+func EncryptReader(key [KeySize]byte, r io.Reader) (io.Reader, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptReader{
+		gcm:   gcm,
+		r:     r,
+		plain: make([]byte, ChunkSize),
+		out:   make([]byte, 0, ChunkSize+chunkOverhead),
+	}, nil
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.buf) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(e.r, e.plain)
+		last := false
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			last = true
+		} else if err != nil {
+			return 0, err
+		}
+
+		e.out = e.gcm.Seal(e.out[:0], chunkNonce(e.chunk, last), e.plain[:n], nil)
+		e.buf = e.out
+		e.chunk++
+		e.done = last
+	}
+
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+type encryptWriter struct {
+	gcm    cipher.AEAD
+	w      io.Writer
+	chunk  uint64
+	plain  []byte
+	out    []byte
+	closed bool
+}
+
+// EncryptWriter returns a writer which encrypts everything written to it, and writes it to w.
+// Close must be called to write the last chunk, it does not close w.
+func EncryptWriter(key [KeySize]byte, w io.Writer) (io.WriteCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		gcm:   gcm,
+		w:     w,
+		plain: make([]byte, 0, ChunkSize),
+		out:   make([]byte, 0, ChunkSize+chunkOverhead),
+	}, nil
+}
+
+func (e *encryptWriter) flush(last bool) error {
+	e.out = e.gcm.Seal(e.out[:0], chunkNonce(e.chunk, last), e.plain, nil)
+	e.chunk++
+	e.plain = e.plain[:0]
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		n := copy(e.plain[len(e.plain):cap(e.plain)], p)
+		e.plain = e.plain[:len(e.plain)+n]
+		p = p[n:]
+		if len(e.plain) == ChunkSize {
+			err := e.flush(false)
+			if err != nil {
+				return total - len(p), err
+			}
+		}
+	}
+	return total, nil
+}
+
+// Close writes the last chunk
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(true)
+}
+
+type decryptReader struct {
+	gcm   cipher.AEAD
+	r     io.Reader
+	chunk uint64
+	last  uint64
+	// sized is true if the size of the stream, and so the index of its last chunk, is known
+	sized bool
+	in    []byte
+	out   []byte
+	buf   []byte
+	done  bool
+}
+
+// DecryptReader returns a reader which decrypts a stream of plain text of the given size. r
+// should be positioned at the start of the given chunk, see ChunkOffset. If the size is
+// negative, the stream is read till its last chunk.
+func DecryptReader(key [KeySize]byte, r io.Reader, chunk, size int64) (io.Reader, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		gcm:   gcm,
+		r:     r,
+		chunk: uint64(chunk),
+		last:  uint64(size / ChunkSize),
+		sized: size >= 0,
+		in:    make([]byte, ChunkSize+chunkOverhead),
+		out:   make([]byte, 0, ChunkSize),
+		done:  size >= 0 && chunk > size/ChunkSize,
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+
+		last := d.sized && d.chunk == d.last
+		n, err := io.ReadFull(d.r, d.in)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if n == 0 || (d.sized && !last) {
+				return 0, ErrTruncated
+			}
+			// the last chunk is the only one shorter than a full chunk
+			last = true
+		} else if err != nil {
+			return 0, err
+		} else if last {
+			return 0, ErrMalformedCipher
+		}
+
+		d.out, err = d.gcm.Open(d.out[:0], chunkNonce(d.chunk, last), d.in[:n], nil)
+		if err != nil {
+			return 0, ErrMalformedCipher
+		}
+		d.buf = d.out
+		d.chunk++
+		d.done = last
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	key, _ := NewKey()
+
+	for _, size := range []int64{0, 1, ChunkSize - 1, ChunkSize, ChunkSize*3 + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		er, err := EncryptReader(key, bytes.NewReader(plain))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		sealed, err := io.ReadAll(er)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if int64(len(sealed)) != CipherSize(size) {
+			t.Fatalf("Expected cipher size %d, got %d", CipherSize(size), len(sealed))
+		}
+
+		dr, _ := DecryptReader(key, bytes.NewReader(sealed), 0, size)
+		out, err := io.ReadAll(dr)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !bytes.Equal(out, plain) {
+			t.Fatalf("Decrypted stream of size %d does not match", size)
+		}
+
+		// decrypting from the second chunk
+		if size > ChunkSize {
+			dr, _ = DecryptReader(key, bytes.NewReader(sealed[ChunkOffset(1):]), 1, size)
+			out, err = io.ReadAll(dr)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !bytes.Equal(out, plain[ChunkSize:]) {
+				t.Fatal("Decrypted stream from the second chunk does not match")
+			}
+		}
+	}
+}
+
+func TestStreamTampered(t *testing.T) {
+	key, _ := NewKey()
+	plain := make([]byte, ChunkSize*2+10)
+	er, _ := EncryptReader(key, bytes.NewReader(plain))
+	sealed, _ := io.ReadAll(er)
+
+	// dropping the last chunk
+	dr, _ := DecryptReader(key, bytes.NewReader(sealed[:ChunkOffset(2)]), 0, int64(len(plain)))
+	_, err := io.ReadAll(dr)
+	if err != ErrTruncated {
+		t.Fatalf("Expected '%v', got '%v'", ErrTruncated, err)
+	}
+
+	// swapping the first two chunks
+	swapped := append([]byte{}, sealed[ChunkOffset(1):ChunkOffset(2)]...)
+	swapped = append(swapped, sealed[:ChunkOffset(1)]...)
+	swapped = append(swapped, sealed[ChunkOffset(2):]...)
+	dr, _ = DecryptReader(key, bytes.NewReader(swapped), 0, int64(len(plain)))
+	_, err = io.ReadAll(dr)
+	if err != ErrMalformedCipher {
+		t.Fatalf("Expected '%v', got '%v'", ErrMalformedCipher, err)
+	}
+}
+
+func TestStreamWriter(t *testing.T) {
+	key, _ := NewKey()
+
+	for _, size := range []int{0, 10, ChunkSize, ChunkSize*2 + 3} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		sealed := &bytes.Buffer{}
+		ew, err := EncryptWriter(key, sealed)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		// writing in odd sized pieces
+		for i := 0; i < size; i += 1000 {
+			end := i + 1000
+			if end > size {
+				end = size
+			}
+			ew.Write(plain[i:end])
+		}
+		err = ew.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if int64(sealed.Len()) != CipherSize(int64(size)) {
+			t.Fatalf("Expected cipher size %d, got %d", CipherSize(int64(size)), sealed.Len())
+		}
+
+		// the size is not known while decrypting
+		dr, _ := DecryptReader(key, bytes.NewReader(sealed.Bytes()), 0, -1)
+		out, err := io.ReadAll(dr)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !bytes.Equal(out, plain) {
+			t.Fatalf("Decrypted stream of size %d does not match", size)
+		}
+
+		if size >= ChunkSize {
+			dr, _ = DecryptReader(key, bytes.NewReader(sealed.Bytes()[:ChunkOffset(1)]), 0, -1)
+			_, err = io.ReadAll(dr)
+			if err != ErrTruncated {
+				t.Fatalf("Expected '%v', got '%v'", ErrTruncated, err)
+			}
+		}
+	}
+}
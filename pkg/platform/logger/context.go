@@ -0,0 +1,31 @@
+package logger
+
+import "context"
+
+type ctxKey string
+
+const fieldsCtxKey = ctxKey("fields")
+
+// ContextWith returns a context carrying the key/value pairs, in addition to the ones of the
+// context, to be added to the lines logged while serving a request
+func ContextWith(ctx context.Context, kv ...interface{}) context.Context {
+	prev := FieldsFrom(ctx)
+	all := make([]interface{}, 0, len(prev)+len(kv))
+	all = append(all, prev...)
+	return context.WithValue(ctx, fieldsCtxKey, append(all, kv...))
+}
+
+// FieldsFrom returns the key/value pairs carried by the context
+func FieldsFrom(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	kv, _ := ctx.Value(fieldsCtxKey).([]interface{})
+	return kv
+}
+
+// FromContext returns the logger adding the key/value pairs carried by the context to every
+// line, e.g. the ID of the request and of the user
+func FromContext(ctx context.Context, l Service) Service {
+	return With(l, FieldsFrom(ctx)...)
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encoder writes a log line, ending with a new line
+type Encoder interface {
+	Encode(buf *bytes.Buffer, e *Entry)
+}
+
+// JSONEncoder writes every line as a JSON object, with the fields after the time, level,
+// caller and message
+type JSONEncoder struct{}
+
+// Encode writes the entry as a JSON object
+func (JSONEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	buf.WriteString(`{"time":`)
+	writeJSON(buf, e.Time.UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(buf, e.Level.String())
+	if e.Caller != "" {
+		buf.WriteString(`,"caller":`)
+		writeJSON(buf, e.Caller)
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSON(buf, e.Message)
+	for _, f := range e.Fields {
+		buf.WriteByte(',')
+		writeJSON(buf, f.Key)
+		buf.WriteByte(':')
+		writeJSON(buf, f.Value)
+	}
+	buf.WriteString("}\n")
+}
+
+// writeJSON writes the value as JSON, or its string if it can't be encoded
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+// TextEncoder writes every line as text: the time, level, caller and message, followed by the
+// fields as key=value pairs
+type TextEncoder struct{}
+
+// Encode writes the entry as text
+func (TextEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	buf.WriteString(e.Time.Format("2006/01/02 15:04:05"))
+	buf.WriteByte(' ')
+	buf.WriteString(strings.ToUpper(e.Level.String()))
+	if e.Caller != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(e.Caller)
+	}
+	if e.Message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(e.Message)
+	}
+	for _, f := range e.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(quote(f.Key))
+		buf.WriteByte('=')
+		buf.WriteString(quote(formatValue(f.Value)))
+	}
+	buf.WriteByte('\n')
+}
+
+// quote quotes the text if it's empty or has spaces, quotes or equal signs
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// formatValue returns the text of a value, composite values are written as JSON
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return t
+	case fmt.Stringer:
+		return t.String()
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(t)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
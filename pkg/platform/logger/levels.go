@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvLevel is returned when a level or a level of a package is unknown
+	ErrInvLevel = errors.New("Invalid log level")
+	// ErrInvLevels is returned when the levels are not a comma separated list of a level and
+	// package=level pairs
+	ErrInvLevels = errors.New("Invalid log levels, expected e.g. info,users=debug,mongo=warn")
+)
+
+// Levels are the minimum levels of the lines written, a default one and one per package. The
+// package of a line is the name of the directory of its caller, e.g. users or mongo. They can be
+// changed while the app is running, the copies of a logger share them.
+type Levels struct {
+	mu       sync.RWMutex
+	min      Level
+	packages map[string]Level
+	// lowest is the lowest of all the levels, lines below it are dropped without looking up
+	// their package
+	lowest Level
+}
+
+// NewLevels returns the levels of the spec, as parsed by ParseLevels
+func NewLevels(spec string) (*Levels, error) {
+	l := &Levels{}
+	err := l.Set(spec)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ParseLevels parses a comma separated list of levels, e.g. info,users=debug,mongo=warn. The
+// level without a package is the default, debug if there's none. A level is one of debug,
+// info, warn, error, fatal, all (same as debug) or off.
+func ParseLevels(spec string) (Level, map[string]Level, error) {
+	min := LevelDebug
+	packages := make(map[string]Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pkg, name := "", part
+		if i := strings.Index(part, "="); i >= 0 {
+			pkg, name = strings.TrimSpace(part[:i]), part[i+1:]
+			if pkg == "" {
+				return 0, nil, ErrInvLevels
+			}
+		}
+		level, ok := ParseLevel(name)
+		if !ok {
+			return 0, nil, ErrInvLevel
+		}
+		if pkg == "" {
+			min = level
+			continue
+		}
+		packages[pkg] = level
+	}
+	return min, packages, nil
+}
+
+// Set replaces the levels with the ones of the spec, as parsed by ParseLevels. The levels are
+// not changed if the spec is invalid.
+func (l *Levels) Set(spec string) error {
+	min, packages, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+
+	lowest := min
+	for _, level := range packages {
+		if level < lowest {
+			lowest = level
+		}
+	}
+
+	l.mu.Lock()
+	l.min = min
+	l.packages = packages
+	l.lowest = lowest
+	l.mu.Unlock()
+	return nil
+}
+
+// String returns the spec of the levels, with the packages sorted by name
+func (l *Levels) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	parts := make([]string, 0, len(l.packages)+1)
+	parts = append(parts, l.min.String())
+	for pkg, level := range l.packages {
+		parts = append(parts, pkg+"="+level.String())
+	}
+	sort.Strings(parts[1:])
+	return strings.Join(parts, ",")
+}
+
+// Enabled returns true if the lines of the level are written for the package, the default
+// level is used if the package is empty or has no level of its own
+func (l *Levels) Enabled(level Level, pkg string) bool {
+	if level < LevelDebug || level > LevelFatal {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	min, ok := l.packages[pkg]
+	if !ok {
+		min = l.min
+	}
+	return level >= min
+}
+
+// maybeEnabled returns false if the lines of the level are not written for any package
+func (l *Levels) maybeEnabled(level Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return level >= l.lowest
+}
+
+// levelsOf returns the spec of the levels enabled by the names, as accepted by New. The lowest
+// level named is the minimum, all is every level and none disables the logs.
+func levelsOf(types []string) string {
+	min := LevelOff
+	for _, t := range types {
+		if t == "*" {
+			t = "all"
+		}
+		level, ok := ParseLevel(t)
+		if ok && level < min {
+			min = level
+		}
+	}
+	return min.String()
+}
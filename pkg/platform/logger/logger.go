@@ -1,36 +1,70 @@
-// Package logger provides multi-level log functionality
+// Package logger provides multi-level structured logs. Every line has the time, the level, the
+// caller, a message and key/value fields, encoded as JSON or as text. The values of the
+// sensitive fields, like passwords and auth tokens, are redacted.
 package logger
 
 import (
-	"log"
+	"fmt"
+	"io"
 	"os"
-	"runtime"
+	"strings"
 )
 
+// Level is the severity of a log line
+type Level int
+
 const (
-	// Svr1 defines severity level 1, highest level.
-	Svr1 = severity("Fatal ")
-	// Svr2 defines severity level 2, medium level.
-	Svr2 = severity("Error ")
-	// Svr3 defines severity level 3, low level.
-	Svr3 = severity("Warning ")
-	// Svr4 defines severity level 4, Info.
-	Svr4 = severity("Info ")
-	// Svr5 defines severity level 5, Debug.
-	Svr5 = severity("Debug ")
+	// LevelDebug is the level of the details useful while debugging
+	LevelDebug = Level(iota)
+	// LevelInfo is the level of the events of the normal operation of the app
+	LevelInfo
+	// LevelWarn is the level of the unexpected events the app recovers from
+	LevelWarn
+	// LevelError is the level of the operations which failed
+	LevelError
+	// LevelFatal is the level of the errors the app can't run with
+	LevelFatal
+	// LevelOff is above every level, to disable the logs
+	LevelOff
 )
 
-var (
-	svr1Logger = log.New(os.Stderr, string(Svr1), log.LstdFlags)
-	svr2Logger = log.New(os.Stderr, string(Svr2), log.LstdFlags)
-	svr3Logger = log.New(os.Stderr, string(Svr3), log.LstdFlags)
-	svr4Logger = log.New(os.Stdout, string(Svr4), log.LstdFlags)
-	svr5Logger = log.New(os.Stdout, string(Svr5), log.LstdFlags)
-)
+var levelNames = []string{"debug", "info", "warn", "error", "fatal", "off"}
+
+// String returns the lowercase name of the level
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelOff {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level of the name, it returns false if the name is unknown. All is the
+// same as debug, the lowest level.
+func ParseLevel(name string) (Level, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "warning":
+		return LevelWarn, true
+	case "all":
+		return LevelDebug, true
+	}
+	for i, n := range levelNames {
+		if n == name {
+			return Level(i), true
+		}
+	}
+	return 0, false
+}
 
-type severity string
+const (
+	// FormatText encodes the lines as text, with the fields as key=value pairs
+	FormatText = "text"
+	// FormatJSON encodes every line as a JSON object
+	FormatJSON = "json"
+)
 
-// Service defines all the logging methods to be implemented
+// Service defines all the logging methods to be implemented. The data of a line is printed as
+// its message, separated by spaces.
 type Service interface {
 	Debug(data ...interface{})
 	Info(data ...interface{})
@@ -39,99 +73,142 @@ type Service interface {
 	Fatal(data ...interface{})
 }
 
-// Log handles all the dependencies for logger
+// Config has the configurations of the logger
+type Config struct {
+	// Level is the minimum level of the lines written, and of the lines of each package, e.g.
+	// info,users=debug. All the lines are written if it's empty.
+	Level string
+	// LevelsFile is a file with the levels, in the same format as Level. It replaces Level if
+	// it exists, and it's read again when the logger is reloaded.
+	LevelsFile string
+	// Format is text or json, text if empty
+	Format string
+	// Sinks are where the lines are written: console, stderr, file or syslog. Console is the
+	// sink if there's none.
+	Sinks []string
+	// File configures the file sink
+	File FileConfig
+	// SyslogTag is the tag of the lines sent to syslog, notes if it's empty
+	SyslogTag string
+	// Sampling limits the debug lines written, they're all written by default
+	Sampling Sampling
+	// Output replaces the sinks if it's set, e.g. in tests
+	Output io.Writer
+}
+
+// Log implements Service on top of the structured Logger, so that the packages logging with
+// positional data keep working
 type Log struct {
-	debugLogs bool
-	infoLogs  bool
-	warnLogs  bool
-	errorLogs bool
-	fataLogs  bool
+	logger *Logger
 }
 
-// New returns an instance of Log with all the dependencies initialized
+// New returns an instance of Log writing text lines to the console. The lowest of the levels
+// named is the minimum level of the lines written, none are written if there's none.
 func New(types []string) Log {
-	l := Log{}
-	for _, t := range types {
-		switch t {
-		case "debug":
-			{
-				l.debugLogs = true
-			}
-		case "info":
-			{
-				l.infoLogs = true
-			}
-		case "warn":
-			{
-				l.warnLogs = true
-			}
-		case "error":
-			{
-				l.errorLogs = true
-			}
-		case "fatal":
-			{
-				l.fataLogs = true
-			}
-		case "all", "*":
-			{
-				l.debugLogs = true
-				l.infoLogs = true
-				l.warnLogs = true
-				l.errorLogs = true
-				l.fataLogs = true
-			}
-		}
-	}
+	// the levels are valid, and the console can't fail to open
+	l, _ := NewLog(Config{Level: levelsOf(types)})
 	return l
 }
 
+// NewLog returns an instance of Log configured as given
+func NewLog(c Config) (Log, error) {
+	l, err := NewLogger(c)
+	if err != nil {
+		return Log{}, err
+	}
+	return Log{logger: l}, nil
+}
+
+// Logger returns the structured logger of the Log
+func (l Log) Logger() *Logger {
+	return l.logger
+}
+
+// Levels returns the levels of the logger, which can be changed while it's in use
+func (l Log) Levels() *Levels {
+	return l.logger.Levels()
+}
+
+// Reload reads the levels file again, if there's one
+func (l Log) Reload() error {
+	return l.logger.Reload()
+}
+
+// Close closes the sinks of the logger
+func (l Log) Close() error {
+	return l.logger.Close()
+}
+
+// With returns a copy of the logger adding the key/value pairs as fields of every line
+func (l Log) With(kv ...interface{}) Service {
+	return Log{logger: l.logger.With(kv...)}
+}
+
 // Debug prints log of severity 5
 func (l Log) Debug(data ...interface{}) {
-	if !l.debugLogs {
-		return
-	}
-	_, file, lin, _ := runtime.Caller(1)
-	data = append([]interface{}{file, lin}, data...)
-	svr5Logger.Println(data...)
+	l.logger.log(LevelDebug, callerSkip, message(data), nil)
 }
 
 // Info prints logs of severity 4
 func (l Log) Info(data ...interface{}) {
-	if !l.infoLogs {
-		return
-	}
-	_, file, lin, _ := runtime.Caller(1)
-	data = append([]interface{}{file, lin}, data...)
-	svr4Logger.Println(data...)
+	l.logger.log(LevelInfo, callerSkip, message(data), nil)
 }
 
 // Warn prints log of severity 3
 func (l Log) Warn(data ...interface{}) {
-	if !l.warnLogs {
-		return
-	}
-	_, file, lin, _ := runtime.Caller(1)
-	data = append([]interface{}{file, lin}, data...)
-	svr3Logger.Println(data...)
+	l.logger.log(LevelWarn, callerSkip, message(data), nil)
 }
 
-//  Error prints log of severity 2
+// Error prints log of severity 2
 func (l Log) Error(data ...interface{}) {
-	if !l.errorLogs {
-		return
-	}
-	_, file, lin, _ := runtime.Caller(1)
-	data = append([]interface{}{file, lin}, data...)
-	svr2Logger.Println(data...)
+	l.logger.log(LevelError, callerSkip, message(data), nil)
 }
 
 // Fatal prints log of severity 1
 func (l Log) Fatal(data ...interface{}) {
-	if !l.fataLogs {
-		return
+	l.logger.log(LevelFatal, callerSkip, message(data), nil)
+}
+
+// message returns the positional data as the message of a line
+func message(data []interface{}) string {
+	parts := make([]string, 0, len(data))
+	for _, d := range data {
+		parts = append(parts, formatValue(redact(d)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// With returns the logger adding the key/value pairs to every line, if it supports it. It
+// returns the logger as is otherwise.
+func With(l Service, kv ...interface{}) Service {
+	if len(kv) == 0 {
+		return l
+	}
+	w, ok := l.(interface {
+		With(kv ...interface{}) Service
+	})
+	if !ok {
+		return l
+	}
+	return w.With(kv...)
+}
+
+// LevelsOf returns the levels of the logger, nil if it does not have levels which can be changed
+func LevelsOf(l Service) *Levels {
+	lv, ok := l.(interface {
+		Levels() *Levels
+	})
+	if !ok {
+		return nil
+	}
+	return lv.Levels()
+}
+
+// defaultOutput writes the debug and info lines to the standard output, and the others to the
+// standard error
+func defaultOutput(level Level) io.Writer {
+	if level >= LevelWarn {
+		return os.Stderr
 	}
-	_, file, lin, _ := runtime.Caller(1)
-	data = append([]interface{}{file, lin}, data...)
-	svr1Logger.Println(data...)
+	return os.Stdout
 }
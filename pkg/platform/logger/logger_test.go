@@ -0,0 +1,324 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type credentials struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	AuthToken string `json:"authToken"`
+	Blob      []byte `json:"blob"`
+	Size      int    `json:"size"`
+}
+
+func newLogger(t *testing.T, c Config) *Logger {
+	t.Helper()
+	l, err := NewLogger(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	lines := []map[string]interface{}{}
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if l == "" {
+			continue
+		}
+		line := map[string]interface{}{}
+		err := json.Unmarshal([]byte(l), &line)
+		if err != nil {
+			t.Fatalf("invalid JSON line %q: %s", l, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger(t, Config{Level: "info", Format: FormatJSON, Output: buf})
+
+	l.With("requestId", "r1").Info("created", "itemId", "i1", "count", 2)
+	l.Debug("skipped")
+	l.Error("failed", "err", errors.New("boom"), "odd")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+
+	first := lines[0]
+	expected := map[string]interface{}{
+		"level":     "info",
+		"msg":       "created",
+		"requestId": "r1",
+		"itemId":    "i1",
+		"count":     float64(2),
+	}
+	for k, v := range expected {
+		if first[k] != v {
+			t.Errorf("expected %s to be %v, got %v", k, v, first[k])
+		}
+	}
+	if _, ok := first["time"]; !ok {
+		t.Error("expected the time of the line")
+	}
+	if !strings.HasPrefix(first["caller"].(string), "logger/logger_test.go:") {
+		t.Errorf("expected the caller to be the test, got %v", first["caller"])
+	}
+
+	second := lines[1]
+	if second["err"] != "boom" || second[badKey] != "odd" {
+		t.Errorf("unexpected fields of the error line: %v", second)
+	}
+}
+
+func TestText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger(t, Config{Output: buf})
+	l.Warn("slow request", "route", "listItems", "path", "/items?q=a b")
+
+	line := buf.String()
+	for _, part := range []string{
+		" WARN logger/logger_test.go:",
+		" slow request route=listItems ",
+		`path="/items?q=a b"`,
+	} {
+		if !strings.Contains(line, part) {
+			t.Errorf("expected %q in %q", part, line)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger(t, Config{Format: FormatJSON, Output: buf})
+
+	c := credentials{
+		Email:     "a@b.c",
+		Password:  "hunter2",
+		AuthToken: "t0k3n",
+		Blob:      []byte("ciphertext"),
+		Size:      10,
+	}
+	l.Info(
+		"login",
+		"user", &c,
+		"password", "hunter2",
+		"Authorization", "Bearer t0k3n",
+		"body", map[string]interface{}{"auth_token": "t0k3n", "items": []interface{}{c}},
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "t0k3n", "ciphertext"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q to be redacted, got %s", secret, out)
+		}
+	}
+
+	line := decodeLines(t, buf)[0]
+	user := line["user"].(map[string]interface{})
+	if user["email"] != "a@b.c" || user["size"] != float64(10) {
+		t.Errorf("expected the other fields to be logged, got %v", user)
+	}
+	if user["password"] != redacted || user["authToken"] != redacted || user["blob"] != redacted {
+		t.Errorf("expected the sensitive fields to be redacted, got %v", user)
+	}
+
+	// the positional logger redacts too
+	buf.Reset()
+	Log{logger: l}.Error("login", c)
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("expected the password to be redacted, got %s", buf.String())
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := Log{logger: newLogger(t, Config{Format: FormatJSON, Output: buf})}
+
+	ctx := ContextWith(context.Background(), "requestId", "r1")
+	ctx = ContextWith(ctx, "userId", "u1", "route", "readItem")
+	FromContext(ctx, l).Error("GET", "/items/1", "not found")
+	FromContext(context.Background(), l).Info("no fields")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	for k, v := range map[string]string{
+		"msg":       "GET /items/1 not found",
+		"level":     "error",
+		"requestId": "r1",
+		"userId":    "u1",
+		"route":     "readItem",
+	} {
+		if lines[0][k] != v {
+			t.Errorf("expected %s to be %q, got %v", k, v, lines[0][k])
+		}
+	}
+	if _, ok := lines[1]["requestId"]; ok {
+		t.Errorf("expected no request ID without the context, got %v", lines[1])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]Level{
+		"debug":   LevelDebug,
+		"INFO":    LevelInfo,
+		"warning": LevelWarn,
+		" error ": LevelError,
+		"fatal":   LevelFatal,
+	} {
+		level, ok := ParseLevel(name)
+		if !ok || level != expected {
+			t.Errorf("expected %q to be %s, got %s", name, expected, level)
+		}
+	}
+	_, ok := ParseLevel("verbose")
+	if ok {
+		t.Error("expected an unknown level to be invalid")
+	}
+}
+
+func TestLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger(t, Config{Level: "error,logger=debug", Output: buf})
+	if l.Enabled(LevelWarn) {
+		t.Error("expected warn to be disabled by default")
+	}
+
+	// the lines of this package have a level of their own
+	l.Debug("debug")
+	if !strings.Contains(buf.String(), " debug\n") {
+		t.Errorf("expected the debug line of the package, got %q", buf.String())
+	}
+
+	buf.Reset()
+	err := l.Levels().Set("debug, logger = off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Fatal("fatal")
+	if buf.Len() != 0 {
+		t.Errorf("expected no lines once the package is off, got %q", buf.String())
+	}
+	if l.Levels().String() != "debug,logger=off" {
+		t.Errorf("unexpected levels %q", l.Levels().String())
+	}
+
+	for _, spec := range []string{"verbose", "users=", "=debug"} {
+		err = l.Levels().Set(spec)
+		if err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+	if l.Levels().String() != "debug,logger=off" {
+		t.Errorf("expected the levels to be kept, got %q", l.Levels().String())
+	}
+
+	if levelsOf(nil) != "off" || levelsOf([]string{"error", "info"}) != "info" || levelsOf([]string{"*"}) != "debug" {
+		t.Error("unexpected levels of the names of New")
+	}
+}
+
+func TestReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "levels")
+	err := os.WriteFile(file, []byte("warn,users=debug\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newLogger(t, Config{Level: "info", LevelsFile: file, Output: &bytes.Buffer{}})
+	if l.Levels().String() != "warn,users=debug" {
+		t.Errorf("expected the levels of the file, got %q", l.Levels().String())
+	}
+
+	os.WriteFile(file, []byte("error"), 0644)
+	err = l.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Levels().String() != "error" {
+		t.Errorf("expected the levels to be reloaded, got %q", l.Levels().String())
+	}
+}
+
+func TestSampling(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := newLogger(t, Config{
+		Output:   buf,
+		Sampling: Sampling{First: 2, Thereafter: 3, Interval: time.Hour},
+	})
+	for i := 0; i < 10; i++ {
+		l.Debug("noisy")
+		l.Info("not sampled")
+	}
+	// the first 2, then the 5th and the 8th
+	if n := strings.Count(buf.String(), "noisy"); n != 4 {
+		t.Errorf("expected 4 debug lines, got %d", n)
+	}
+	if n := strings.Count(buf.String(), "not sampled"); n != 10 {
+		t.Errorf("expected all the info lines, got %d", n)
+	}
+
+	s := newSampler(Sampling{First: 1, Interval: time.Minute})
+	now := time.Now()
+	if !s.allow("a", now) || s.allow("a", now) || !s.allow("b", now) {
+		t.Error("expected the first line of every caller only")
+	}
+	if !s.allow("a", now.Add(time.Minute)) {
+		t.Error("expected the counts to start over after the interval")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.log")
+	f, err := NewRotatingFile(FileConfig{Path: path, MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		err = f.WriteLine(LevelInfo, []byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the rotated files are named by the time of the rotation
+		time.Sleep(time.Millisecond * 2)
+	}
+
+	b, _ := os.ReadFile(path)
+	if string(b) != "fourth\n" {
+		t.Errorf("expected the last line in the file, got %q", b)
+	}
+	backups := f.backups()
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", backups)
+	}
+	b, _ = os.ReadFile(backups[0])
+	if string(b) != "second\n" {
+		t.Errorf("expected the oldest rotated file to have the second line, got %q", b)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(backups[0], old, old)
+	f.c.MaxAge = time.Minute
+	f.clean()
+	if len(f.backups()) != 1 {
+		t.Errorf("expected the old file to be deleted, got %v", f.backups())
+	}
+}
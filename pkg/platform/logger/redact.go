@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// redacted replaces the values of the sensitive fields
+	redacted = "[REDACTED]"
+	// maxDepth is the depth of the nested values beyond which values are not logged
+	maxDepth = 6
+)
+
+// sensitiveKeys are the keys whose values are redacted, compared in lowercase without dashes
+// and underscores. A key is sensitive if it contains any of them.
+var sensitiveKeys = []string{
+	"password",
+	"passphrase",
+	"token",
+	"secret",
+	"privatekey",
+	"authorization",
+	"cookie",
+}
+
+// sensitiveNames are the keys whose values are redacted, only if they match exactly
+var sensitiveNames = []string{
+	"blob",
+	"salt",
+	"key",
+	"ciphertext",
+	"snapshot",
+}
+
+// sensitive returns true if the values of the key should not be logged
+func sensitive(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	for _, s := range sensitiveNames {
+		if k == s {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns the value to be logged, with the values of the sensitive fields of structs and
+// maps redacted. Structs and maps are returned as maps, and slices as slices of values.
+func redact(v interface{}) interface{} {
+	return redactValue(reflect.ValueOf(v), 0)
+}
+
+var (
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func redactValue(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxDepth {
+		return "..."
+	}
+
+	t := v.Type()
+	switch {
+	case t.Implements(errorType):
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface().(error).Error()
+	case t.Implements(jsonMarshalerType), t.Implements(textMarshalerType):
+		// e.g. time.Time, which encodes itself without sensitive data
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem(), depth+1)
+
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			tag := strings.Split(f.Tag.Get("json"), ",")[0]
+			if tag != "" && tag != "-" {
+				name = tag
+			}
+			if sensitive(f.Name) || sensitive(name) {
+				out[name] = redacted
+				continue
+			}
+			out[name] = redactValue(v.Field(i), depth+1)
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, ok := redactValue(iter.Key(), depth+1).(string)
+			if !ok {
+				key = formatValue(redactValue(iter.Key(), depth+1))
+			}
+			if sensitive(key) {
+				out[key] = redacted
+				continue
+			}
+			out[key] = redactValue(iter.Value(), depth+1)
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// raw bytes could be anything, like encrypted data
+			return "[" + strconv.Itoa(v.Len()) + " bytes]"
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, redactValue(v.Index(i), depth+1))
+		}
+		return out
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return t.String()
+	}
+
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
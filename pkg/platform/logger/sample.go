@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// Sampling limits the debug lines written from the same line of code. In every interval, the
+// first lines are written, then one of every Thereafter lines.
+type Sampling struct {
+	// First is the number of lines written in every interval before sampling, lines are not
+	// sampled if it's 0
+	First int
+	// Thereafter is the number of lines of which one is written after the first ones, none are
+	// written if it's 0
+	Thereafter int
+	// Interval is the duration after which the counts start over, a second if it's 0
+	Interval time.Duration
+}
+
+// sampler counts the lines by their caller, to drop the ones beyond the sampling limits
+type sampler struct {
+	c      Sampling
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func newSampler(c Sampling) *sampler {
+	if c.First <= 0 {
+		return nil
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	return &sampler{c: c, counts: make(map[string]int)}
+}
+
+// allow returns true if the line of the caller should be written. A nil sampler allows all.
+func (s *sampler) allow(caller string, now time.Time) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.start) >= s.c.Interval {
+		s.start = now
+		s.counts = make(map[string]int, len(s.counts))
+	}
+	n := s.counts[caller] + 1
+	s.counts[caller] = n
+	if n <= s.c.First {
+		return true
+	}
+	return s.c.Thereafter > 0 && (n-s.c.First)%s.c.Thereafter == 0
+}
@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SinkConsole writes the debug and info lines to the standard output, and the others to the
+	// standard error
+	SinkConsole = "console"
+	// SinkStderr writes all the lines to the standard error
+	SinkStderr = "stderr"
+	// SinkFile appends the lines to a file, rotated by size
+	SinkFile = "file"
+	// SinkSyslog sends the lines to the local syslog daemon
+	SinkSyslog = "syslog"
+
+	// backupTime is the format of the time in the names of the rotated files, which sorts them
+	// by time
+	backupTime = "20060102T150405.000"
+)
+
+var (
+	// ErrInvSink is returned when a sink configured is unknown
+	ErrInvSink = errors.New("Invalid log sink")
+	// ErrNoFile is returned when the file sink is configured without a file
+	ErrNoFile = errors.New("No file provided for the log sink")
+)
+
+// Sink is a destination of the log lines
+type Sink interface {
+	// WriteLine writes a line of the level, encoded and ending with a new line
+	WriteLine(level Level, line []byte) error
+}
+
+// writerSink writes the lines to a writer
+type writerSink struct {
+	w io.Writer
+}
+
+func (s writerSink) WriteLine(_ Level, line []byte) error {
+	_, err := s.w.Write(line)
+	return err
+}
+
+// NewWriterSink returns a sink writing the lines to w. It's not closed with the logger.
+func NewWriterSink(w io.Writer) Sink {
+	return writerSink{w: w}
+}
+
+// consoleSink writes the debug and info lines to the standard output, and the others to the
+// standard error
+type consoleSink struct{}
+
+func (consoleSink) WriteLine(level Level, line []byte) error {
+	_, err := defaultOutput(level).Write(line)
+	return err
+}
+
+// newSink returns the sink of the name, as configured
+func newSink(name string, c Config) (Sink, error) {
+	switch strings.TrimSpace(name) {
+	case SinkConsole:
+		return consoleSink{}, nil
+	case SinkStderr:
+		return NewWriterSink(os.Stderr), nil
+	case SinkFile:
+		f, err := NewRotatingFile(c.File)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case SinkSyslog:
+		tag := c.SyslogTag
+		if tag == "" {
+			tag = "notes"
+		}
+		s, err := NewSyslog(tag)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, ErrInvSink
+}
+
+// FileConfig has the configurations of the file sink
+type FileConfig struct {
+	// Path is the path of the file, the rotated files are kept next to it
+	Path string
+	// MaxSize is the size in bytes beyond which the file is rotated, it's not rotated if 0
+	MaxSize int64
+	// MaxAge is the age beyond which the rotated files are deleted, they're not deleted by age
+	// if 0
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept, all are kept if 0
+	MaxBackups int
+}
+
+// RotatingFile is a sink appending the lines to a file. Once the file reaches its maximum size,
+// it's renamed with the time of the rotation appended to its name, and a new file is started.
+type RotatingFile struct {
+	c    FileConfig
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens the file configured, and deletes the rotated files beyond the limits
+func NewRotatingFile(c FileConfig) (*RotatingFile, error) {
+	if c.Path == "" {
+		return nil, ErrNoFile
+	}
+	f := &RotatingFile{c: c}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	f.clean()
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// WriteLine appends the line to the file, after rotating it if the line would exceed its size
+func (f *RotatingFile) WriteLine(_ Level, line []byte) error {
+	_, err := f.Write(line)
+	return err
+}
+
+// Write appends p to the file, after rotating it if p would exceed its size
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.c.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.c.MaxSize {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the file and opens a new one
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+	f.file = nil
+
+	err = os.Rename(f.c.Path, f.backupName(time.Now()))
+	if err != nil {
+		return err
+	}
+	err = f.open()
+	if err != nil {
+		return err
+	}
+	f.clean()
+	return nil
+}
+
+// backupName returns the name of the file rotated at the time, e.g. app-20200102T150405.000.log
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.c.Path)
+	return strings.TrimSuffix(f.c.Path, ext) + "-" + t.UTC().Format(backupTime) + ext
+}
+
+// backups returns the rotated files, the oldest first
+func (f *RotatingFile) backups() []string {
+	ext := filepath.Ext(f.c.Path)
+	prefix := strings.TrimSuffix(f.c.Path, ext) + "-"
+	matches, _ := filepath.Glob(prefix + "*" + ext)
+	backups := matches[:0]
+	for _, m := range matches {
+		_, err := time.Parse(backupTime, strings.TrimSuffix(strings.TrimPrefix(m, prefix), ext))
+		if err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups
+}
+
+// clean deletes the rotated files beyond the maximum number, and the ones older than the
+// maximum age. Errors are ignored, the files are deleted again on the next rotation.
+func (f *RotatingFile) clean() {
+	backups := f.backups()
+	if f.c.MaxBackups > 0 && len(backups) > f.c.MaxBackups {
+		for _, b := range backups[:len(backups)-f.c.MaxBackups] {
+			os.Remove(b)
+		}
+		backups = backups[len(backups)-f.c.MaxBackups:]
+	}
+	if f.c.MaxAge <= 0 {
+		return
+	}
+	for _, b := range backups {
+		info, err := os.Stat(b)
+		if err == nil && time.Since(info.ModTime()) > f.c.MaxAge {
+			os.Remove(b)
+		}
+	}
+}
+
+// Close closes the file, the lines written after are dropped
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// callerSkip is the number of frames from Logger.log up to the caller of a logging method
+const callerSkip = 2
+
+// badKey is the key of a value without a key, when a key/value list has an odd length
+const badKey = "!BADKEY"
+
+// Field is a key/value pair of a log line
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is a log line to be encoded
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Caller  string
+	Message string
+	Fields  []Field
+}
+
+// output is the destination of the lines, shared by the copies of a logger
+type output struct {
+	mu    sync.Mutex
+	sinks []Sink
+}
+
+// write writes the line to all the sinks. A sink failing does not stop the others, and there's
+// nowhere left to report it.
+func (o *output) write(level Level, line []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, s := range o.sinks {
+		s.WriteLine(level, line)
+	}
+}
+
+// close closes the sinks which can be closed
+func (o *output) close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var first error
+	for _, s := range o.sinks {
+		c, ok := s.(io.Closer)
+		if !ok {
+			continue
+		}
+		err := c.Close()
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Logger writes structured log lines
+type Logger struct {
+	out        *output
+	encoder    Encoder
+	levels     *Levels
+	levelsFile string
+	sampler    *sampler
+	fields     []Field
+}
+
+// NewLogger returns a structured logger configured as given. The levels of a levels file
+// replace the ones configured, if the file exists.
+func NewLogger(c Config) (*Logger, error) {
+	levels, err := NewLevels(c.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Logger{
+		out:        &output{},
+		encoder:    TextEncoder{},
+		levels:     levels,
+		levelsFile: c.LevelsFile,
+		sampler:    newSampler(c.Sampling),
+	}
+	if c.Format == FormatJSON {
+		l.encoder = JSONEncoder{}
+	}
+	err = l.Reload()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if c.Output != nil {
+		l.out.sinks = []Sink{NewWriterSink(c.Output)}
+		return l, nil
+	}
+	if len(c.Sinks) == 0 {
+		l.out.sinks = []Sink{consoleSink{}}
+		return l, nil
+	}
+	for _, name := range c.Sinks {
+		s, err := newSink(name, c)
+		if err != nil {
+			l.out.close()
+			return nil, err
+		}
+		l.out.sinks = append(l.out.sinks, s)
+	}
+	return l, nil
+}
+
+// Levels returns the levels of the logger, shared by its copies. They can be changed while the
+// logger is in use.
+func (l *Logger) Levels() *Levels {
+	return l.levels
+}
+
+// Reload replaces the levels with the ones of the levels file, if there's one. The file has the
+// levels as accepted by ParseLevels, e.g. info,users=debug.
+func (l *Logger) Reload() error {
+	if l.levelsFile == "" {
+		return nil
+	}
+	spec, err := os.ReadFile(l.levelsFile)
+	if err != nil {
+		return err
+	}
+	return l.levels.Set(strings.TrimSpace(string(spec)))
+}
+
+// Close closes the sinks of the logger, like files and connections. The logger and its copies
+// should not be used after.
+func (l *Logger) Close() error {
+	return l.out.close()
+}
+
+// With returns a copy of the logger adding the key/value pairs as fields of every line
+func (l *Logger) With(kv ...interface{}) *Logger {
+	c := *l
+	c.fields = append(l.fields[:len(l.fields):len(l.fields)], fields(kv)...)
+	return &c
+}
+
+// Enabled returns true if the lines of the level are written for the packages without a level
+// of their own
+func (l *Logger) Enabled(level Level) bool {
+	return l.levels.Enabled(level, "")
+}
+
+// Debug writes a debug line with the message and the key/value pairs as fields
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.log(LevelDebug, callerSkip, msg, kv)
+}
+
+// Info writes an info line with the message and the key/value pairs as fields
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log(LevelInfo, callerSkip, msg, kv)
+}
+
+// Warn writes a warning line with the message and the key/value pairs as fields
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log(LevelWarn, callerSkip, msg, kv)
+}
+
+// Error writes an error line with the message and the key/value pairs as fields
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log(LevelError, callerSkip, msg, kv)
+}
+
+// Fatal writes a fatal line with the message and the key/value pairs as fields. It does not
+// exit, the caller decides how to stop.
+func (l *Logger) Fatal(msg string, kv ...interface{}) {
+	l.log(LevelFatal, callerSkip, msg, kv)
+}
+
+// log writes the line, skip is the number of frames up to the caller of the logging method
+func (l *Logger) log(level Level, skip int, msg string, kv []interface{}) {
+	if !l.levels.maybeEnabled(level) {
+		return
+	}
+
+	pkg, caller := "", ""
+	_, file, line, ok := runtime.Caller(skip)
+	if ok {
+		pkg = filepath.Base(filepath.Dir(file))
+		caller = pkg + "/" + filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	if !l.levels.Enabled(level, pkg) {
+		return
+	}
+
+	now := time.Now()
+	if level == LevelDebug && !l.sampler.allow(caller, now) {
+		return
+	}
+
+	e := &Entry{
+		Time:    now,
+		Level:   level,
+		Caller:  caller,
+		Message: msg,
+		Fields:  append(l.fields[:len(l.fields):len(l.fields)], fields(kv)...),
+	}
+	buf := &bytes.Buffer{}
+	l.encoder.Encode(buf, e)
+	l.out.write(level, buf.Bytes())
+}
+
+// fields returns the fields of the key/value pairs, with the values of the sensitive keys
+// redacted
+func fields(kv []interface{}) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	out := make([]Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		if i == len(kv)-1 {
+			out = append(out, Field{Key: badKey, Value: redact(kv[i])})
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if sensitive(key) {
+			out = append(out, Field{Key: key, Value: redacted})
+			continue
+		}
+		out = append(out, Field{Key: key, Value: redact(kv[i+1])})
+	}
+	return out
+}
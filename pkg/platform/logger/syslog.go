@@ -0,0 +1,45 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logger
+
+import (
+	"bytes"
+	"log/syslog"
+)
+
+// Syslog is a sink sending the lines to the local syslog daemon, with the priority of their
+// level
+type Syslog struct {
+	w *syslog.Writer
+}
+
+// NewSyslog connects to the syslog daemon on its local socket
+func NewSyslog(tag string) (*Syslog, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &Syslog{w: w}, nil
+}
+
+// WriteLine sends the line with the priority of the level
+func (s *Syslog) WriteLine(level Level, line []byte) error {
+	msg := string(bytes.TrimSuffix(line, []byte("\n")))
+	switch level {
+	case LevelDebug:
+		return s.w.Debug(msg)
+	case LevelInfo:
+		return s.w.Info(msg)
+	case LevelWarn:
+		return s.w.Warning(msg)
+	case LevelError:
+		return s.w.Err(msg)
+	}
+	return s.w.Crit(msg)
+}
+
+// Close closes the connection to the daemon
+func (s *Syslog) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,28 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logger
+
+import "errors"
+
+// ErrNoSyslog is returned when the syslog sink is configured on a system without syslog
+var ErrNoSyslog = errors.New("Syslog is not supported on this system")
+
+// Syslog is a sink sending the lines to the local syslog daemon, it's not supported on this
+// system
+type Syslog struct{}
+
+// NewSyslog returns ErrNoSyslog
+func NewSyslog(tag string) (*Syslog, error) {
+	return nil, ErrNoSyslog
+}
+
+// WriteLine returns ErrNoSyslog
+func (s *Syslog) WriteLine(level Level, line []byte) error {
+	return ErrNoSyslog
+}
+
+// Close does nothing
+func (s *Syslog) Close() error {
+	return nil
+}
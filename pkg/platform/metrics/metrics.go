@@ -0,0 +1,401 @@
+// Package metrics collects the metrics of the app and writes them in the Prometheus text
+// exposition format. The labels of a metric must have a small and bounded set of values, since
+// every combination of values is kept in memory for the lifetime of the app.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the metrics written by a registry
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DefaultBuckets are the upper bounds of the histogram buckets, in seconds, suited to
+	// network calls
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// FastBuckets are the upper bounds of the histogram buckets, in seconds, suited to
+	// operations done in memory
+	FastBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5}
+
+	// Default is the registry of the metrics created by the package level functions
+	Default = NewRegistry()
+)
+
+// metric is implemented by every type of metric of a registry
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds a set of metrics with unique names
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry returns a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// register adds the metric to the registry, it panics if the name is already taken since
+// metrics are created when the app starts
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.metrics[m.name()]
+	if ok {
+		panic("metrics: duplicate metric " + m.name())
+	}
+	r.metrics[m.name()] = m
+}
+
+// WriteTo writes all the metrics of the registry, sorted by name, in the text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	ms := make([]metric, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		ms = append(ms, r.metrics[name])
+	}
+	r.mu.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range ms {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// desc describes a metric, and the labels of its series
+type desc struct {
+	metricName string
+	help       string
+	typ        string
+	labels     []string
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+// key returns the key of the series with the label values. It panics if the number of values
+// does not match the labels, which is a mistake in the code and not in the data.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.metricName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + d.metricName + " " + escapeHelp(d.help) + "\n")
+	w.WriteString("# TYPE " + d.metricName + " " + d.typ + "\n")
+}
+
+// writeSample writes a sample of the series with the label values, and an extra label if
+// extra is not empty
+func (d *desc) writeSample(w *bufio.Writer, suffix string, values []string, extra string, extraValue string, v float64) {
+	w.WriteString(d.metricName + suffix)
+	if len(values) > 0 || extra != "" {
+		w.WriteByte('{')
+		for i, l := range d.labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extra != "" {
+			if len(values) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// series is the value of a counter or gauge for a set of label values
+type series struct {
+	values []string
+	value  float64
+}
+
+// vec holds the series of a counter or gauge
+type vec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newVec(name, help, typ string, labels []string) *vec {
+	return &vec{
+		desc:   desc{metricName: name, help: help, typ: typ, labels: labels},
+		series: make(map[string]*series),
+	}
+}
+
+// update calls f with the series of the label values, creating it if required
+func (v *vec) update(values []string, f func(s *series)) {
+	key := v.key(values)
+	v.mu.Lock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	f(s)
+	v.mu.Unlock()
+}
+
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.writeHeader(w)
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := v.series[k]
+		v.writeSample(w, "", s.values, "", "", s.value)
+	}
+}
+
+// Counter is a metric which only goes up, like the number of requests served
+type Counter struct {
+	*vec
+}
+
+// NewCounter returns a new counter with the labels, registered in the registry
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// NewCounter returns a new counter with the labels, registered in the default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// Inc increments the counter of the label values by 1
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds the delta to the counter of the label values. Negative deltas are ignored.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	c.update(values, func(s *series) {
+		s.value += delta
+	})
+}
+
+// Gauge is a metric which can go up and down, like the number of open connections
+type Gauge struct {
+	*vec
+}
+
+// NewGauge returns a new gauge with the labels, registered in the registry
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: newVec(name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// NewGauge returns a new gauge with the labels, registered in the default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(value float64, values ...string) {
+	g.update(values, func(s *series) {
+		s.value = value
+	})
+}
+
+// Add adds the delta, which can be negative, to the gauge of the label values
+func (g *Gauge) Add(delta float64, values ...string) {
+	g.update(values, func(s *series) {
+		s.value += delta
+	})
+}
+
+// Inc increments the gauge of the label values by 1
+func (g *Gauge) Inc(values ...string) {
+	g.Add(1, values...)
+}
+
+// Dec decrements the gauge of the label values by 1
+func (g *Gauge) Dec(values ...string) {
+	g.Add(-1, values...)
+}
+
+// GaugeFunc is a gauge without labels, whose value is read from a function when the metrics
+// are written
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc returns a new gauge reading its value from fn, registered in the registry. fn
+// is called on every scrape, and must be safe for concurrent use.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{metricName: name, help: help, typ: "gauge"}, fn: fn}
+	r.register(g)
+	return g
+}
+
+// NewGaugeFunc returns a new gauge reading its value from fn, registered in the default
+// registry
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return Default.NewGaugeFunc(name, help, fn)
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	g.writeSample(w, "", nil, "", "", g.fn())
+}
+
+// histogramSeries is the distribution of the observations for a set of label values
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram is a metric counting the observations, like request durations, in buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// NewHistogram returns a new histogram with the bucket upper bounds and the labels, registered
+// in the registry. The buckets must be sorted in increasing order, the +Inf bucket is implicit.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	h := &Histogram{
+		desc:    desc{metricName: name, help: help, typ: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// NewHistogram returns a new histogram with the bucket upper bounds and the labels, registered
+// in the default registry
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// Observe adds the value to the distribution of the label values
+func (h *Histogram) Observe(value float64, values ...string) {
+	key := h.key(values)
+	i := sort.SearchFloat64s(h.buckets, value)
+
+	h.mu.Lock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			values: append([]string(nil), values...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+	h.mu.Unlock()
+}
+
+// Since observes the number of seconds elapsed since start, for the label values
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		cumulative := uint64(0)
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(w, "_bucket", s.values, "le", formatFloat(le), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", s.values, "le", "+Inf", float64(s.count))
+		h.writeSample(w, "_sum", s.values, "", "", s.sum)
+		h.writeSample(w, "_count", s.values, "", "", float64(s.count))
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Number of requests", "route", "code")
+	inflight := r.NewGauge("inflight", "Requests in flight")
+	latency := r.NewHistogram("latency_seconds", "Latency\nof the requests", []float64{.1, 1}, "route")
+	r.NewGaugeFunc("sessions", "Active sessions", func() float64 { return 3 })
+
+	requests.Inc("home", "200")
+	requests.Add(2, "home", "200")
+	requests.Inc(`a"b`, "500")
+	requests.Add(-1, "home", "200")
+	inflight.Inc()
+	inflight.Inc()
+	inflight.Dec()
+	latency.Observe(.05, "home")
+	latency.Observe(.1, "home")
+	latency.Observe(.5, "home")
+	latency.Observe(2, "home")
+
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes written, got %d", buf.Len(), n)
+	}
+
+	expected := strings.Join([]string{
+		"# HELP inflight Requests in flight",
+		"# TYPE inflight gauge",
+		"inflight 1",
+		`# HELP latency_seconds Latency\nof the requests`,
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{route="home",le="0.1"} 2`,
+		`latency_seconds_bucket{route="home",le="1"} 3`,
+		`latency_seconds_bucket{route="home",le="+Inf"} 4`,
+		`latency_seconds_sum{route="home"} 2.65`,
+		`latency_seconds_count{route="home"} 4`,
+		"# HELP requests_total Number of requests",
+		"# TYPE requests_total counter",
+		`requests_total{route="a\"b",code="500"} 1`,
+		`requests_total{route="home",code="200"} 3`,
+		"# HELP sessions Active sessions",
+		"# TYPE sessions gauge",
+		"sessions 3",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "Number of requests")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic registering a duplicate metric")
+		}
+	}()
+	r.NewGauge("requests_total", "Number of requests")
+}
+
+func TestLabels(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Number of requests", "route")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic with missing label values")
+		}
+	}()
+	c.Inc()
+}
@@ -0,0 +1,132 @@
+// Package netguard keeps the requests made by the app to URLs provided by the users, e.g.
+// webhooks, away from the network of the app itself: loopback, private, link-local and
+// unspecified addresses are rejected, both when the URL is checked and when it's dialled, so
+// that a name resolving to a different address later on cannot get around the check.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrForbidden is returned if the host resolves to an address which is not public
+	ErrForbidden = errors.New("Sorry, the address is not allowed")
+	// ErrUnresolved is returned if the host could not be resolved
+	ErrUnresolved = errors.New("Sorry, the host could not be resolved")
+	// ErrUnreachable is returned if the request failed. The actual error is not returned, so
+	// that the users cannot use the responses to probe the network.
+	ErrUnreachable = errors.New("Sorry, the address could not be reached")
+	// ErrTimeout is returned if the request timed out
+	ErrTimeout = errors.New("Sorry, the request timed out")
+)
+
+// sharedAddress is the shared address space of carrier-grade NATs, RFC 6598
+var sharedAddress = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Public returns true if the address is a public unicast address
+func Public(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddress.Contains(ip))
+}
+
+// Guard checks the addresses of the URLs provided by the users
+type Guard struct {
+	// AllowPrivate allows all the addresses, e.g. for local development
+	AllowPrivate bool
+}
+
+// CheckURL resolves the host of the URL and returns an error if any of its addresses is not
+// public
+func (g Guard) CheckURL(ctx context.Context, target string) error {
+	if g.AllowPrivate {
+		return nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !Public(ip) {
+			return ErrForbidden
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return ErrUnresolved
+	}
+	for _, a := range addrs {
+		if !Public(a.IP) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+// control rejects the connections to addresses which are not public. It's called with the
+// address actually dialled, after the host is resolved.
+func (g Guard) control(network, address string, _ syscall.RawConn) error {
+	if g.AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrForbidden
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !Public(ip) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Client returns an HTTP client which connects only to public addresses. Proxies from the
+// environment are not used, since the proxy would be dialled instead of the host.
+func (g Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: g.control,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   2,
+			IdleConnTimeout:       time.Minute,
+		},
+	}
+}
+
+// Error returns the error of a request made with the client of the guard, without the details
+// of the network
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrForbidden) {
+		return ErrForbidden
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return ErrTimeout
+	}
+	return ErrUnreachable
+}
@@ -0,0 +1,85 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"100.64.0.1":       false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if Public(net.ParseIP(addr)) != expected {
+			t.Errorf("Expected %s to be public: %v", addr, expected)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	g := Guard{}
+	ctx := context.Background()
+	for _, target := range []string{
+		"http://127.0.0.1:6379",
+		"http://[::1]/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://localhost:27017",
+	} {
+		err := g.CheckURL(ctx, target)
+		if err != ErrForbidden {
+			t.Errorf("Expected %s to be forbidden, got %v", target, err)
+		}
+	}
+
+	err := g.CheckURL(ctx, "https://93.184.216.34/hook")
+	if err != nil {
+		t.Errorf("Expected a public address to be allowed, got %v", err)
+	}
+	err = g.CheckURL(ctx, "https://notes.invalid/hook")
+	if err != ErrUnresolved {
+		t.Errorf("Expected an unresolved host, got %v", err)
+	}
+	err = Guard{AllowPrivate: true}.CheckURL(ctx, "http://127.0.0.1:6379")
+	if err != nil {
+		t.Errorf("Expected private addresses to be allowed, got %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	// the check is made when dialling, whatever the URL checked before
+	_, err := Guard{}.Client(time.Second).Get(server.URL)
+	if Error(err) != ErrForbidden {
+		t.Fatalf("Expected the loopback address to be forbidden, got %v", err)
+	}
+
+	resp, err := Guard{AllowPrivate: true}.Client(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	server.Close()
+	_, err = Guard{AllowPrivate: true}.Client(time.Second).Get(server.URL)
+	if Error(err) != ErrUnreachable {
+		t.Fatalf("Expected a generic error, got %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+// Package memory implements an in-process store with the query semantics of the Mongo store,
+// meant for tests and local development. Documents are encoded with BSON like in Mongo, and
+// queries support equality on fields and dotted paths, $and, $or, $in, $ne, $lt, $lte, $gt
+// and $gte. Projections are ignored, all the fields are returned.
+package memory
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+// Store keeps all the documents in memory
+type Store struct {
+	mu      sync.RWMutex
+	buckets map[string][]bson.M
+}
+
+// New returns a new empty store
+func New() *Store {
+	return &Store{
+		buckets: map[string][]bson.M{},
+	}
+}
+
+// toM encodes the value as a BSON document
+func toM(v interface{}) (bson.M, error) {
+	if v == nil {
+		return bson.M{}, nil
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := bson.M{}
+	err = bson.Unmarshal(b, &m)
+	return m, err
+}
+
+// decode decodes the document into result
+func decode(doc bson.M, result interface{}) error {
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(b, result)
+}
+
+// Save saves the data as a new document
+func (s *Store) Save(bucket string, data interface{}) (*storage.DocMeta, error) {
+	if data == nil {
+		return nil, nil
+	}
+	doc, err := toM(data)
+	if err != nil {
+		return nil, err
+	}
+	id := bson.NewObjectId()
+	doc["_id"] = id
+
+	s.mu.Lock()
+	s.buckets[bucket] = append(s.buckets[bucket], doc)
+	s.mu.Unlock()
+	return &storage.DocMeta{ID: id.Hex(), Count: 1}, nil
+}
+
+// find returns the indexes of the documents matching the query, sorted. The lock should be
+// held.
+func (s *Store) find(bucket string, query interface{}, sortBy []string) ([]int, error) {
+	q, err := toM(query)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := s.buckets[bucket]
+	idx := []int{}
+	for i, doc := range docs {
+		if match(doc, q) {
+			idx = append(idx, i)
+		}
+	}
+
+	if len(sortBy) > 0 {
+		sort.SliceStable(idx, func(a, b int) bool {
+			return less(docs[idx[a]], docs[idx[b]], sortBy)
+		})
+	}
+	return idx, nil
+}
+
+// Find finds all the documents matching the query. If result is nil, the documents are
+// returned as maps.
+func (s *Store) Find(bucket string, query, selectFields interface{}, sortBy []string, start, limit int, result interface{}) ([]map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, err := s.find(bucket, query, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	if start > len(idx) {
+		start = len(idx)
+	}
+	idx = idx[start:]
+	if limit > 0 && limit < len(idx) {
+		idx = idx[:limit]
+	}
+
+	docs := s.buckets[bucket]
+	if result == nil {
+		out := make([]map[string]interface{}, 0, len(idx))
+		for _, i := range idx {
+			out = append(out, map[string]interface{}(docs[i]))
+		}
+		return out, nil
+	}
+
+	rv := reflect.ValueOf(result).Elem()
+	list := reflect.MakeSlice(rv.Type(), 0, len(idx))
+	for _, i := range idx {
+		elem := reflect.New(rv.Type().Elem())
+		err = decode(docs[i], elem.Interface())
+		if err != nil {
+			return nil, err
+		}
+		list = reflect.Append(list, elem.Elem())
+	}
+	rv.Set(list)
+	return nil, nil
+}
+
+// FindOne finds the first document matching the query
+func (s *Store) FindOne(bucket string, query, selectFields interface{}, sortBy []string, result interface{}) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, err := s.find(bucket, query, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	doc := s.buckets[bucket][idx[0]]
+	if result == nil {
+		return map[string]interface{}(doc), nil
+	}
+	return nil, decode(doc, result)
+}
+
+// Count returns the number of documents matching the query
+func (s *Store) Count(bucket string, query interface{}) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, err := s.find(bucket, query, nil)
+	return len(idx), err
+}
+
+// Ping always succeeds
+func (s *Store) Ping() error {
+	return nil
+}
+
+// Close does nothing, the documents are kept
+func (s *Store) Close() error {
+	return nil
+}
+
+// Update replaces the first document matching the query with data
+func (s *Store) Update(bucket string, query interface{}, data interface{}) error {
+	doc, err := toM(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.find(bucket, query, nil)
+	if err != nil {
+		return err
+	}
+	if len(idx) == 0 {
+		return storage.ErrNotFound
+	}
+
+	docs := s.buckets[bucket]
+	doc["_id"] = docs[idx[0]]["_id"]
+	docs[idx[0]] = doc
+	return nil
+}
+
+// Delete deletes the first document matching the query
+func (s *Store) Delete(bucket string, query interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.find(bucket, query, nil)
+	if err != nil {
+		return err
+	}
+	if len(idx) == 0 {
+		return storage.ErrNotFound
+	}
+
+	docs := s.buckets[bucket]
+	s.buckets[bucket] = append(docs[:idx[0]:idx[0]], docs[idx[0]+1:]...)
+	return nil
+}
+
+// lookup returns the value at the dotted path in the document. Like in Mongo, a path through an
+// array of documents returns the values of all the documents in the array.
+func lookup(doc bson.M, path string) (interface{}, bool) {
+	return lookupPath(doc, strings.Split(path, "."))
+}
+
+func lookupPath(v interface{}, keys []string) (interface{}, bool) {
+	if len(keys) == 0 {
+		return v, true
+	}
+
+	switch val := v.(type) {
+	case bson.M:
+		next, ok := val[keys[0]]
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(next, keys[1:])
+	case []interface{}:
+		values := []interface{}{}
+		for _, elem := range val {
+			found, ok := lookupPath(elem, keys)
+			if !ok {
+				continue
+			}
+			if list, ok := found.([]interface{}); ok {
+				values = append(values, list...)
+				continue
+			}
+			values = append(values, found)
+		}
+		return values, len(values) > 0
+	}
+	return nil, false
+}
+
+// match returns true if the document matches the query
+func match(doc bson.M, query bson.M) bool {
+	for key, cond := range query {
+		switch key {
+		case "$and", "$or":
+			list, _ := cond.([]interface{})
+			matched := false
+			for _, sub := range list {
+				subq, _ := sub.(bson.M)
+				ok := match(doc, subq)
+				if key == "$and" && !ok {
+					return false
+				}
+				matched = matched || ok
+			}
+			if key == "$or" && !matched {
+				return false
+			}
+			continue
+		}
+
+		value, exists := lookup(doc, key)
+		if ops, ok := cond.(bson.M); ok && isOperator(ops) {
+			if !matchOps(value, exists, ops) {
+				return false
+			}
+			continue
+		}
+		if !matchValue(value, cond) {
+			return false
+		}
+	}
+	return true
+}
+
+func isOperator(m bson.M) bool {
+	for k := range m {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue returns true if the value equals cond, or if the value is an array with an element
+// equal to cond
+func matchValue(value, cond interface{}) bool {
+	if list, ok := value.([]interface{}); ok {
+		for _, v := range list {
+			if compare(v, cond) == 0 {
+				return true
+			}
+		}
+	}
+	return compare(value, cond) == 0
+}
+
+func matchOps(value interface{}, exists bool, ops bson.M) bool {
+	for op, arg := range ops {
+		switch op {
+		case "$ne":
+			if exists && matchValue(value, arg) {
+				return false
+			}
+		case "$in":
+			list, _ := arg.([]interface{})
+			found := false
+			for _, v := range list {
+				if exists && matchValue(value, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case "$exists":
+			want, _ := arg.(bool)
+			if exists != want {
+				return false
+			}
+		case "$lt", "$lte", "$gt", "$gte":
+			if !exists || value == nil || !orderable(value, arg) {
+				return false
+			}
+			c := compare(value, arg)
+			if (op == "$lt" && c >= 0) || (op == "$lte" && c > 0) ||
+				(op == "$gt" && c <= 0) || (op == "$gte" && c < 0) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// number returns the value as a float, if it's a number
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// orderable returns true if the values are of the same kind and can be ordered
+func orderable(a, b interface{}) bool {
+	_, an := number(a)
+	_, bn := number(b)
+	if an || bn {
+		return an && bn
+	}
+	switch a.(type) {
+	case string:
+		_, ok := b.(string)
+		return ok
+	case time.Time:
+		_, ok := b.(time.Time)
+		return ok
+	}
+	return false
+}
+
+// compare compares two BSON values, values of different kinds are ordered by kind
+func compare(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if an, ok := number(a); ok {
+		if bn, ok := number(b); ok {
+			switch {
+			case an < bn:
+				return -1
+			case an > bn:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			switch {
+			case av.Before(bv):
+				return -1
+			case av.After(bv):
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0
+			case !av:
+				return -1
+			}
+			return 1
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return strings.Compare(reflect.TypeOf(a).String(), reflect.TypeOf(b).String())
+}
+
+// less returns true if a sorts before b. Fields prefixed with - are sorted in descending order.
+func less(a, b bson.M, sortBy []string) bool {
+	for _, field := range sortBy {
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "-"), "+")
+		av, _ := lookup(a, field)
+		bv, _ := lookup(b, field)
+		c := compare(av, bv)
+		if c == 0 {
+			continue
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
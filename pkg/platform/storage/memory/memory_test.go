@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+type term struct {
+	Key string `bson:"key"`
+}
+
+type doc struct {
+	ID       string    `bson:"id"`
+	Owner    string    `bson:"owner"`
+	Tags     []string  `bson:"tags,omitempty"`
+	Priority int       `bson:"priority"`
+	Due      time.Time `bson:"due"`
+	Terms    []term    `bson:"terms,omitempty"`
+	Progress struct {
+		Open int `bson:"open"`
+	} `bson:"progress"`
+}
+
+func TestStore(t *testing.T) {
+	s := New()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i, d := range []doc{
+		{ID: "a", Owner: "u1", Tags: []string{"work"}, Priority: 2, Due: now},
+		{ID: "b", Owner: "u1", Tags: []string{"home", "work"}, Priority: 1, Due: now.Add(time.Hour)},
+		{ID: "c", Owner: "u2", Terms: []term{{"x"}, {"y"}}, Priority: 3, Due: now.Add(-time.Hour)},
+	} {
+		d.Progress.Open = i
+		_, err := s.Save("docs", d)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query map[string]interface{}
+		sort  []string
+		want  []string
+	}{
+		{"equality", map[string]interface{}{"owner": "u1"}, nil, []string{"a", "b"}},
+		{"array contains", map[string]interface{}{"tags": "home"}, nil, []string{"b"}},
+		{"in", map[string]interface{}{"tags": map[string]interface{}{"$in": []string{"home", "work"}}}, []string{"priority"}, []string{"b", "a"}},
+		{"dotted", map[string]interface{}{"progress.open": map[string]interface{}{"$gt": 0}}, nil, []string{"b", "c"}},
+		{"time", map[string]interface{}{"due": map[string]interface{}{"$lte": now}}, []string{"-due"}, []string{"a", "c"}},
+		{"array of documents", map[string]interface{}{"terms.key": map[string]interface{}{"$in": []string{"y", "z"}}}, nil, []string{"c"}},
+		{"ne", map[string]interface{}{"owner": map[string]interface{}{"$ne": "u1"}}, nil, []string{"c"}},
+		{
+			"or",
+			map[string]interface{}{"$or": []map[string]interface{}{{"id": "a"}, {"priority": 3}}},
+			[]string{"-priority"},
+			[]string{"c", "a"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := []doc{}
+			_, err := s.Find("docs", tc.query, nil, tc.sort, 0, 0, &result)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			got := []string{}
+			for _, d := range result {
+				got = append(got, d.ID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("Expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+
+	result := []doc{}
+	s.Find("docs", nil, nil, []string{"id"}, 1, 1, &result)
+	if len(result) != 1 || result[0].ID != "b" {
+		t.Fatalf("Expected only b with start and limit, got %+v", result)
+	}
+
+	d := doc{}
+	err := s.Update("docs", map[string]interface{}{"id": "a"}, doc{ID: "a", Owner: "u3"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.FindOne("docs", map[string]interface{}{"owner": "u3"}, nil, nil, &d)
+	if err != nil || d.ID != "a" {
+		t.Fatalf("Expected the updated document, got %+v, %v", d, err)
+	}
+
+	err = s.Delete("docs", map[string]interface{}{"id": "a"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.FindOne("docs", map[string]interface{}{"id": "a"}, nil, nil, &d)
+	if err != storage.ErrNotFound {
+		t.Fatalf("Expected %v, got %v", storage.ErrNotFound, err)
+	}
+	count, _ := s.Count("docs", nil)
+	if count != 2 {
+		t.Fatalf("Expected 2 documents, got %d", count)
+	}
+}
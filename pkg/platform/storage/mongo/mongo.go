@@ -7,6 +7,8 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/platform/metrics"
 )
 
 var (
@@ -16,8 +18,29 @@ var (
 
 	// ErrNotFound is returned when the document was not found in Mongo collection
 	ErrNotFound = errors.New("Document not found")
+
+	operationDuration = metrics.NewHistogram(
+		"notes_mongo_operation_duration_seconds",
+		"Duration of the MongoDB operations",
+		metrics.DefaultBuckets,
+		"operation", "collection",
+	)
+	operationErrors = metrics.NewCounter(
+		"notes_mongo_operation_errors_total",
+		"Number of MongoDB operations which failed, documents not found are not counted",
+		"operation", "collection",
+	)
 )
 
+// observe records the duration of the operation on the collection since start, and its error
+// if any. It's deferred with a pointer to the returned error.
+func observe(operation, collection string, start time.Time, err *error) {
+	operationDuration.Since(start, operation, collection)
+	if *err != nil && *err != ErrNotFound {
+		operationErrors.Inc(operation, collection)
+	}
+}
+
 // Config holds the config required for MongoDB
 type Config struct {
 	AppName          string   `json:"appName,omitempty"`
@@ -83,7 +106,8 @@ func New(c Config) (*Handler, error) {
 }
 
 // InsertInfo inserts a new document and return inserted document's ID
-func (ms *Handler) InsertInfo(collectionName string, data interface{}) (string, error) {
+func (ms *Handler) InsertInfo(collectionName string, data interface{}) (id string, err error) {
+	defer observe("insert", collectionName, time.Now(), &err)
 	session, collection := ms.sessionCollection(collectionName)
 	defer session.Close()
 
@@ -95,7 +119,6 @@ func (ms *Handler) InsertInfo(collectionName string, data interface{}) (string,
 		return "", err
 	}
 
-	id := ""
 	if objID, ok := info.UpsertedId.(bson.ObjectId); ok {
 		id = objID.Hex()
 	} else {
@@ -106,57 +129,86 @@ func (ms *Handler) InsertInfo(collectionName string, data interface{}) (string,
 }
 
 // Find finds all records matching the query
-func (ms *Handler) Find(collectionName string, query, selectFields interface{}, sort []string, start, limit int, result interface{}) ([]map[string]interface{}, error) {
+func (ms *Handler) Find(collectionName string, query, selectFields interface{}, sort []string, start, limit int, result interface{}) (out []map[string]interface{}, err error) {
+	defer observe("find", collectionName, time.Now(), &err)
 	session, collection := ms.sessionCollection(collectionName)
 	defer session.Close()
 	if result != nil {
-		err := collection.Find(query).Select(selectFields).Sort(sort...).All(result)
+		err = collection.Find(query).Select(selectFields).Sort(sort...).Skip(start).Limit(limit).All(result)
 		if err == mgo.ErrNotFound {
 			return nil, ErrNotFound
 		}
 
 		return nil, err
 	}
-	out := make([]map[string]interface{}, 0)
-	err := collection.Find(query).Select(selectFields).Sort(sort...).Skip(start).Limit(limit).All(&out)
+	out = make([]map[string]interface{}, 0)
+	err = collection.Find(query).Select(selectFields).Sort(sort...).Skip(start).Limit(limit).All(&out)
 	return out, err
 }
 
 // FindOne finds and returns the first matching document based on the provided query
-func (ms *Handler) FindOne(collectionName string, query, selectFields interface{}, sort []string, result interface{}) (map[string]interface{}, error) {
+func (ms *Handler) FindOne(collectionName string, query, selectFields interface{}, sort []string, result interface{}) (out map[string]interface{}, err error) {
+	defer observe("findOne", collectionName, time.Now(), &err)
 	session, collection := ms.sessionCollection(collectionName)
 	defer session.Close()
 	if result != nil {
-		err := collection.Find(query).Select(selectFields).Sort(sort...).One(result)
+		err = collection.Find(query).Select(selectFields).Sort(sort...).One(result)
 		if err == mgo.ErrNotFound {
 			return nil, ErrNotFound
 		}
 
 		return nil, err
 	}
-	out := make(map[string]interface{}, 0)
-	err := collection.Find(query).Select(selectFields).Sort(sort...).One(&out)
+	out = make(map[string]interface{}, 0)
+	err = collection.Find(query).Select(selectFields).Sort(sort...).One(&out)
 	return out, err
 }
 
+// Count returns the number of documents matching the query
+func (ms *Handler) Count(collectionName string, query interface{}) (n int, err error) {
+	defer observe("count", collectionName, time.Now(), &err)
+	session, collection := ms.sessionCollection(collectionName)
+	defer session.Close()
+
+	return collection.Find(query).Count()
+}
+
 // Update updates the first document matching the query
-func (ms *Handler) Update(collectionName string, query, data interface{}) error {
+func (ms *Handler) Update(collectionName string, query, data interface{}) (err error) {
+	defer observe("update", collectionName, time.Now(), &err)
 	session, collection := ms.sessionCollection(collectionName)
 	defer session.Close()
 
-	err := collection.Update(query, data)
+	err = collection.Update(query, data)
 	if err == mgo.ErrNotFound {
 		return ErrNotFound
 	}
 	return err
 }
 
+// Ping checks the connection to the server
+func (ms *Handler) Ping() (err error) {
+	defer observe("ping", "", time.Now(), &err)
+	session := ms.getSession()
+	defer session.Close()
+
+	return session.Ping()
+}
+
+// Close closes the master session, and with it the connections to the servers. The copies of
+// the session in use are closed once released.
+func (ms *Handler) Close() error {
+	ms.session.Close()
+	return nil
+}
+
 // Delete deletes the first document matching the given query
-func (ms *Handler) Delete(collectionName string, query interface{}) error {
+func (ms *Handler) Delete(collectionName string, query interface{}) (err error) {
+	defer observe("delete", collectionName, time.Now(), &err)
 	session, collection := ms.sessionCollection(collectionName)
 	defer session.Close()
 
-	err := collection.Remove(query)
+	err = collection.Remove(query)
 	if err == mgo.ErrNotFound {
 		return ErrNotFound
 	}
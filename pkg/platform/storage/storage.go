@@ -2,10 +2,12 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/bnkamalesh/notes/pkg/platform/storage/mongo"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
 )
 
 var (
@@ -29,6 +31,15 @@ type Service interface {
 
 	// FindOne finds the first matching document for the given query
 	FindOne(bucket string, query, selectFields interface{}, sort []string, result interface{}) (map[string]interface{}, error)
+
+	// Count returns the number of records matching the query
+	Count(bucket string, query interface{}) (int, error)
+
+	// Ping checks the connection to the store
+	Ping() error
+
+	// Close closes the connections to the store, it should not be used after
+	Close() error
 }
 
 // handlerServices interface defines all the methods required to be a storage service
@@ -47,6 +58,15 @@ type handlerServices interface {
 
 	// FindOne finds the first matching document for the given query
 	FindOne(bucket string, query, selectFields interface{}, sort []string, result interface{}) (map[string]interface{}, error)
+
+	// Count returns the number of records matching the query
+	Count(bucket string, query interface{}) (int, error)
+
+	// Ping checks the connection to the server
+	Ping() error
+
+	// Close closes the connections to the server
+	Close() error
 }
 
 // Config struct holds all the configurations required for the store
@@ -69,13 +89,46 @@ type DocMeta struct {
 // Store holds all the dependencies
 type Store struct {
 	handler handlerServices
+	// ctx has the span of the trace the operations are part of
+	ctx context.Context
+}
+
+// WithContext returns the store tracing its operations as part of the trace in the context. It
+// returns the store as is if it does not trace its operations, like the store in memory.
+func WithContext(ctx context.Context, ss Service) Service {
+	s, ok := ss.(*Store)
+	if !ok {
+		return ss
+	}
+	c := *s
+	c.ctx = ctx
+	return &c
+}
+
+// trace starts the span of the operation on the bucket
+func (s *Store) trace(operation, bucket string) *tracing.Span {
+	_, span := tracing.Start(s.ctx, "storage."+operation, tracing.KindClient)
+	span.SetAttribute("db.system", "mongodb")
+	span.SetAttribute("db.operation", operation)
+	span.SetAttribute("db.collection", bucket)
+	return span
+}
+
+// endSpan ends the span with the error of the operation, records not found are not errors.
+// It's deferred with a pointer to the returned error.
+func endSpan(span *tracing.Span, err *error) {
+	if *err != ErrNotFound {
+		span.SetError(*err)
+	}
+	span.End()
 }
 
 // Save saves data into the primary store
-func (s *Store) Save(bucket string, data interface{}) (*DocMeta, error) {
+func (s *Store) Save(bucket string, data interface{}) (meta *DocMeta, err error) {
 	if data == nil {
 		return nil, nil
 	}
+	defer endSpan(s.trace("save", bucket), &err)
 	id, err := s.handler.InsertInfo(bucket, data)
 	if err != nil {
 		return nil, err
@@ -88,8 +141,9 @@ func (s *Store) Save(bucket string, data interface{}) (*DocMeta, error) {
 }
 
 // Find finds all the records based on the provided query
-func (s *Store) Find(bucket string, query, selectFields interface{}, sort []string, start, limit int, result interface{}) ([]map[string]interface{}, error) {
-	out, err := s.handler.Find(bucket, query, selectFields, sort, start, limit, result)
+func (s *Store) Find(bucket string, query, selectFields interface{}, sort []string, start, limit int, result interface{}) (out []map[string]interface{}, err error) {
+	defer endSpan(s.trace("find", bucket), &err)
+	out, err = s.handler.Find(bucket, query, selectFields, sort, start, limit, result)
 	if err == mongo.ErrNotFound {
 		return nil, ErrNotFound
 	}
@@ -97,17 +151,36 @@ func (s *Store) Find(bucket string, query, selectFields interface{}, sort []stri
 }
 
 // FindOne finds the first document matching the provided query
-func (s *Store) FindOne(bucket string, query, selectFields interface{}, sort []string, result interface{}) (map[string]interface{}, error) {
-	out, err := s.handler.FindOne(bucket, query, selectFields, sort, result)
+func (s *Store) FindOne(bucket string, query, selectFields interface{}, sort []string, result interface{}) (out map[string]interface{}, err error) {
+	defer endSpan(s.trace("findOne", bucket), &err)
+	out, err = s.handler.FindOne(bucket, query, selectFields, sort, result)
 	if err == mongo.ErrNotFound {
 		return nil, ErrNotFound
 	}
 	return out, err
 }
 
+// Count returns the number of records matching the query
+func (s *Store) Count(bucket string, query interface{}) (n int, err error) {
+	defer endSpan(s.trace("count", bucket), &err)
+	return s.handler.Count(bucket, query)
+}
+
+// Ping checks the connection to the store
+func (s *Store) Ping() (err error) {
+	defer endSpan(s.trace("ping", ""), &err)
+	return s.handler.Ping()
+}
+
+// Close closes the connections to the store
+func (s *Store) Close() error {
+	return s.handler.Close()
+}
+
 // Update updates the first record matching the query
-func (s *Store) Update(bucket string, query interface{}, data interface{}) error {
-	err := s.handler.Update(bucket, query, data)
+func (s *Store) Update(bucket string, query interface{}, data interface{}) (err error) {
+	defer endSpan(s.trace("update", bucket), &err)
+	err = s.handler.Update(bucket, query, data)
 	if err != nil {
 		if err == mongo.ErrNotFound {
 			return ErrNotFound
@@ -118,8 +191,9 @@ func (s *Store) Update(bucket string, query interface{}, data interface{}) error
 }
 
 // Delete deletes the first record matching the query
-func (s *Store) Delete(bucket string, query interface{}) error {
-	err := s.handler.Delete(bucket, query)
+func (s *Store) Delete(bucket string, query interface{}) (err error) {
+	defer endSpan(s.trace("delete", bucket), &err)
+	err = s.handler.Delete(bucket, query)
 	if err != nil {
 		if err == mongo.ErrNotFound {
 			return ErrNotFound
@@ -0,0 +1,381 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ExporterOTLP exports the spans to an OpenTelemetry collector, with OTLP over HTTP
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes the spans to the standard output, one JSON object per line
+	ExporterStdout = "stdout"
+	// ExporterFile appends the spans to a file, one JSON object per line
+	ExporterFile = "file"
+
+	// batchSize is the maximum number of spans exported at once
+	batchSize = 512
+	// queueSize is the number of finished spans waiting to be exported, spans are dropped when
+	// the queue is full
+	queueSize = 4096
+	// batchInterval is the maximum duration a span waits to be exported
+	batchInterval = time.Second * 5
+)
+
+var (
+	// ErrInvExporter is returned when the exporter configured is unknown
+	ErrInvExporter = errors.New("Invalid trace exporter")
+	// ErrNoFile is returned when the file exporter is configured without a file
+	ErrNoFile = errors.New("No file provided for the trace exporter")
+)
+
+// Config has the configurations required to export the spans
+type Config struct {
+	// Exporter is otlp, stdout or file. Spans are not exported if it's empty, but the trace
+	// context is still propagated and logged.
+	Exporter string
+	// Endpoint is the base URL of the OTLP collector, the spans are sent to /v1/traces
+	Endpoint string
+	// File is the path of the file for the file exporter
+	File string
+	// ServiceName is the name of the app in the exported spans
+	ServiceName string
+	// SampleRatio is the ratio of the new traces which are exported, between 0 and 1. Traces
+	// continued from a client follow the decision of the client.
+	SampleRatio float64
+	// Timeout is the timeout of an export to the OTLP collector
+	Timeout time.Duration
+}
+
+// Exporter sends the finished spans to a tracing backend
+type Exporter interface {
+	Export(spans []SpanData) error
+}
+
+var (
+	mu       sync.RWMutex
+	ratio    = 1.0
+	exporter *batcher
+)
+
+func sampleRatio() float64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	return ratio
+}
+
+// export queues the span to be exported
+func export(data SpanData) {
+	mu.RLock()
+	b := exporter
+	mu.RUnlock()
+	if b != nil {
+		b.add(data)
+	}
+}
+
+// Setup starts exporting the spans as configured. It replaces the exporter set up before, after
+// flushing it.
+func Setup(c Config) error {
+	var e Exporter
+	switch c.Exporter {
+	case "":
+	case ExporterOTLP:
+		e = NewOTLPExporter(c.Endpoint, c.ServiceName, c.Timeout)
+	case ExporterStdout:
+		e = NewWriterExporter(os.Stdout)
+	case ExporterFile:
+		if c.File == "" {
+			return ErrNoFile
+		}
+		f, err := os.OpenFile(c.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		e = NewWriterExporter(f)
+	default:
+		return ErrInvExporter
+	}
+	SetExporter(e, c.SampleRatio)
+	return nil
+}
+
+// SetExporter replaces the exporter of the spans and the sample ratio of the new traces. The
+// previous exporter is flushed, and closed if it's an io.Closer. Spans are not exported if e
+// is nil.
+func SetExporter(e Exporter, sampleRatio float64) {
+	var b *batcher
+	if e != nil {
+		b = newBatcher(e)
+	}
+
+	mu.Lock()
+	old := exporter
+	exporter = b
+	ratio = sampleRatio
+	mu.Unlock()
+
+	if old != nil {
+		old.stop()
+	}
+}
+
+// Shutdown exports the spans queued and stops exporting
+func Shutdown() {
+	SetExporter(nil, sampleRatio())
+}
+
+// batcher queues the finished spans and exports them in batches, in the background
+type batcher struct {
+	exporter Exporter
+	queue    chan SpanData
+	done     chan struct{}
+	mu       sync.RWMutex
+	stopped  bool
+}
+
+func newBatcher(e Exporter) *batcher {
+	b := &batcher{
+		exporter: e,
+		queue:    make(chan SpanData, queueSize),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *batcher) add(data SpanData) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	// the span finished while the exporter was being replaced
+	if b.stopped {
+		return
+	}
+	select {
+	case b.queue <- data:
+	default:
+	}
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := b.exporter.Export(batch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error exporting spans", err.Error())
+		}
+		batch = make([]SpanData, 0, batchSize)
+	}
+
+	for {
+		select {
+		case data, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, data)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// stop exports the spans queued, and closes the exporter if it's an io.Closer
+func (b *batcher) stop() {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+	b.stopped = true
+	close(b.queue)
+	b.mu.Unlock()
+
+	<-b.done
+	c, ok := b.exporter.(io.Closer)
+	if ok {
+		c.Close()
+	}
+}
+
+// WriterExporter writes the spans to a writer, one JSON object per line
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterExporter returns an exporter writing the spans to w
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// Export writes the spans
+func (e *WriterExporter) Export(spans []SpanData) error {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, s := range spans {
+		err := enc.Encode(s)
+		if err != nil {
+			return err
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the writer if it's an io.Closer, other than the standard output
+func (e *WriterExporter) Close() error {
+	c, ok := e.w.(io.Closer)
+	if !ok || e.w == os.Stdout {
+		return nil
+	}
+	return c.Close()
+}
+
+// OTLPExporter sends the spans to an OpenTelemetry collector, using the JSON encoding of OTLP
+// over HTTP
+type OTLPExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter returns an exporter sending the spans to the collector at the endpoint, e.g.
+// http://localhost:4318
+func NewOTLPExporter(endpoint string, serviceName string, timeout time.Duration) *OTLPExporter {
+	if timeout <= 0 {
+		timeout = time.Second * 10
+	}
+	return &OTLPExporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// otlp status and span kind codes
+const (
+	otlpStatusError = 2
+
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+)
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func otlpAttributes(attrs map[string]string) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(attrs))
+	for k, v := range attrs {
+		out = append(out, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+	return out
+}
+
+// otlpRequest returns the body of the request to export the spans
+func (e *OTLPExporter) otlpRequest(spans []SpanData) map[string]interface{} {
+	ss := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		kind := otlpKindInternal
+		switch s.Kind {
+		case KindServer:
+			kind = otlpKindServer
+		case KindClient:
+			kind = otlpKindClient
+		}
+		o := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentSpanID,
+			Name:              s.Name,
+			Kind:              kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+		}
+		if s.Error != "" {
+			o.Status = otlpStatus{Code: otlpStatusError, Message: s.Error}
+		}
+		ss = append(ss, o)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{"service.name": e.serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "github.com/bnkamalesh/notes"},
+						"spans": ss,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Export sends the spans to the collector
+func (e *OTLPExporter) Export(spans []SpanData) error {
+	b, err := json.Marshal(e.otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
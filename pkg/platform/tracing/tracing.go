@@ -0,0 +1,284 @@
+// Package tracing records the spans of the operations done to serve a request, and exports
+// them in batches. The trace is carried in the context, and propagated across services with
+// the W3C traceparent header. Spans are recorded only within a trace started by a server span,
+// so that the background work of the app does not produce a trace for every operation.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+// TraceparentHeader is the header carrying the trace context, as defined by W3C
+const TraceparentHeader = "traceparent"
+
+// Kind is the role of the span in the trace
+type Kind string
+
+const (
+	// KindServer is the kind of the span serving a request
+	KindServer = Kind("server")
+	// KindClient is the kind of the span of a call to another service, e.g. a database
+	KindClient = Kind("client")
+	// KindInternal is the kind of the span of an operation within the app
+	KindInternal = Kind("internal")
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the ID in lowercase hex
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the ID in lowercase hex
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span which is propagated to its children
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled is true if the spans of the trace are exported
+	Sampled bool
+}
+
+// Valid returns true if neither the trace ID nor the span ID are zero
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent returns the value of the traceparent header for the span context
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent returns the span context of a traceparent header. It returns false if the
+// header is missing or invalid, in which case a new trace should be started.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	sc := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isLowerHex(parts[0]) {
+		return sc, false
+	}
+	// future versions may append fields, version 00 must not
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if !isLowerHex(parts[1]) || !isLowerHex(parts[2]) || !isLowerHex(parts[3]) {
+		return sc, false
+	}
+	hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	flags, _ := hex.DecodeString(parts[3])
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.Valid()
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// SpanData is a finished span, as given to the exporters
+type SpanData struct {
+	Name         string            `json:"name"`
+	Kind         Kind              `json:"kind"`
+	TraceID      string            `json:"traceId"`
+	SpanID       string            `json:"spanId"`
+	ParentSpanID string            `json:"parentSpanId,omitempty"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	// Error is the message of the error which failed the operation, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Span is an operation within a trace. A nil span is valid, and records nothing.
+type Span struct {
+	context SpanContext
+	mu      sync.Mutex
+	data    SpanData
+	ended   bool
+}
+
+// Context returns the span context to be propagated to the children of the span
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetName replaces the name of the span, e.g. once the route of a request is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute sets an attribute of the span. The values should not have sensitive data, like
+// the content of the items.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed with the error
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span, and queues it to be exported if the trace is sampled. Only the first
+// call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.context.Sampled {
+		export(data)
+	}
+}
+
+type spanKey string
+
+const (
+	spanCtxKey   = spanKey("span")
+	remoteCtxKey = spanKey("remote")
+)
+
+// FromContext returns the span in the context, nil if there's none
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanCtxKey).(*Span)
+	return s
+}
+
+// ContextWithRemoteParent returns a context with the span context received from a client, to be
+// used as the parent of the server span
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteCtxKey, sc)
+}
+
+// Start starts a span as a child of the span in the context, and returns the context with the
+// new span. A server span starts a new trace if there's no parent, or continues the trace of a
+// remote parent. Other kinds of spans are started only if there's a parent, they're nil
+// otherwise.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	parent := FromContext(ctx).Context()
+	if !parent.Valid() {
+		if kind != KindServer {
+			return ctx, nil
+		}
+		parent, _ = ctx.Value(remoteCtxKey).(SpanContext)
+	}
+
+	sc := SpanContext{
+		TraceID: parent.TraceID,
+		SpanID:  newSpanID(),
+		Sampled: parent.Sampled,
+	}
+	if !parent.Valid() {
+		sc.TraceID = newTraceID()
+		sc.Sampled = sample()
+	}
+
+	s := &Span{
+		context: sc,
+		data: SpanData{
+			Name:    name,
+			Kind:    kind,
+			TraceID: sc.TraceID.String(),
+			SpanID:  sc.SpanID.String(),
+			Start:   time.Now(),
+		},
+	}
+	if parent.Valid() {
+		s.data.ParentSpanID = parent.SpanID.String()
+	}
+	if kind == KindServer {
+		// the logs of the request can be found from its trace
+		ctx = logger.ContextWith(ctx, "traceId", s.data.TraceID)
+	}
+	return context.WithValue(ctx, spanCtxKey, s), s
+}
+
+func newTraceID() TraceID {
+	t := TraceID{}
+	rand.Read(t[:])
+	return t
+}
+
+func newSpanID() SpanID {
+	s := SpanID{}
+	rand.Read(s[:])
+	return s
+}
+
+// sample returns true if a new trace should be exported, given the sample ratio
+func sample() bool {
+	ratio := sampleRatio()
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return float64(binary.BigEndian.Uint64(b)>>11)/(1<<53) < ratio
+}
@@ -0,0 +1,171 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// spanRecorder keeps the exported spans
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *spanRecorder) Export(spans []SpanData) error {
+	r.mu.Lock()
+	r.spans = append(r.spans, spans...)
+	r.mu.Unlock()
+	return nil
+}
+
+func TestTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(header)
+	if !ok || !sc.Sampled {
+		t.Fatalf("Expected a sampled span context, got %+v", sc)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("Unexpected IDs %s %s", sc.TraceID, sc.SpanID)
+	}
+	if sc.Traceparent() != header {
+		t.Fatalf("Expected %s, got %s", header, sc.Traceparent())
+	}
+
+	for _, h := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, ok := ParseTraceparent(h)
+		if ok {
+			t.Errorf("Expected %q to be invalid", h)
+		}
+	}
+
+	_, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	if !ok {
+		t.Error("Expected the fields of a future version to be ignored")
+	}
+}
+
+func TestStart(t *testing.T) {
+	rec := &spanRecorder{}
+	SetExporter(rec, 1)
+
+	_, span := Start(context.Background(), "orphan", KindClient)
+	if span != nil {
+		t.Fatal("Expected no span without a parent")
+	}
+	span.SetAttribute("key", "value")
+	span.End()
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, server := Start(ContextWithRemoteParent(context.Background(), remote), "HTTP GET", KindServer)
+	_, client := Start(ctx, "storage.find", KindClient)
+	client.SetError(errors.New("timeout"))
+	client.End()
+	server.SetName("userItems")
+	server.End()
+	server.End()
+
+	_, other := Start(context.Background(), "HTTP POST", KindServer)
+	other.End()
+	Shutdown()
+
+	if len(rec.spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %+v", rec.spans)
+	}
+	c, s, o := rec.spans[0], rec.spans[1], rec.spans[2]
+	if s.Name != "userItems" || s.TraceID != remote.TraceID.String() || s.ParentSpanID != remote.SpanID.String() {
+		t.Errorf("Expected the server span to continue the remote trace, got %+v", s)
+	}
+	if c.TraceID != s.TraceID || c.ParentSpanID != s.SpanID || c.Error != "timeout" || c.Kind != KindClient {
+		t.Errorf("Expected a failed child of the server span, got %+v", c)
+	}
+	if o.TraceID == s.TraceID || o.ParentSpanID != "" {
+		t.Errorf("Expected a new trace, got %+v", o)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	rec := &spanRecorder{}
+	SetExporter(rec, 0)
+
+	ctx, span := Start(context.Background(), "HTTP GET", KindServer)
+	_, child := Start(ctx, "users.Item", KindInternal)
+	if span.Context().Sampled || child == nil || !child.Context().Valid() {
+		t.Fatalf("Expected spans which are not sampled, got %+v %+v", span, child)
+	}
+	child.End()
+	span.End()
+
+	// the decision of the client is followed
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span = Start(ContextWithRemoteParent(context.Background(), remote), "HTTP GET", KindServer)
+	span.End()
+	Shutdown()
+
+	if len(rec.spans) != 1 || rec.spans[0].TraceID != remote.TraceID.String() {
+		t.Fatalf("Expected only the span of the sampled trace, got %+v", rec.spans)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/traces" || req.Header.Get("Content-Type") != "application/json" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	e := NewOTLPExporter(server.URL+"/", "notes", 0)
+	ctx, span := Start(context.Background(), "HTTP GET", KindServer)
+	_, child := Start(ctx, "redis.get", KindClient)
+	child.SetAttribute("db.system", "redis")
+	child.SetError(errors.New("timeout"))
+	child.End()
+	span.End()
+
+	err := e.Export([]SpanData{child.data, span.data})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	attr := rs["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	if attr["key"] != "service.name" || attr["value"].(map[string]interface{})["stringValue"] != "notes" {
+		t.Errorf("Expected the service name in the resource, got %+v", attr)
+	}
+	spans := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", spans)
+	}
+	c := spans[0].(map[string]interface{})
+	if c["traceId"] != span.data.TraceID || c["parentSpanId"] != span.data.SpanID || c["kind"] != float64(otlpKindClient) {
+		t.Errorf("Expected the client span, got %+v", c)
+	}
+	if c["status"].(map[string]interface{})["code"] != float64(otlpStatusError) {
+		t.Errorf("Expected an error status, got %+v", c["status"])
+	}
+	if _, ok := c["startTimeUnixNano"].(string); !ok {
+		t.Errorf("Expected the start time as a string, got %+v", c["startTimeUnixNano"])
+	}
+
+	e = NewOTLPExporter(server.URL+"/missing", "notes", 0)
+	err = e.Export([]SpanData{span.data})
+	if err == nil {
+		t.Error("Expected an error when the collector fails")
+	}
+}
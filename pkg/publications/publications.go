@@ -0,0 +1,294 @@
+// Package publications handles the notes published at public, read-only links. A publication
+// is a snapshot of the item, encrypted with a random key which is part of the link and is never
+// stored. A publication can optionally be protected with a password and can expire.
+package publications
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	publicationsBucket = "publications"
+	saltSize           = 16
+)
+
+var (
+	// ErrCreate is returned if there's an error publishing an item
+	ErrCreate = errors.New("Sorry, an error occurred while publishing")
+	// ErrNotFound is returned if the publication does not exist or has expired
+	ErrNotFound = errs.NotFound("Sorry, publication not found")
+	// ErrInvKey is returned if the key in the link is malformed or wrong
+	ErrInvKey = errs.NotFound("Sorry, invalid publication link")
+	// ErrPasswordRequired is returned when reading a password protected publication without
+	// a password
+	ErrPasswordRequired = errs.Unauthorized("Sorry, the publication is password protected")
+	// ErrInvPassword is returned if the password of the publication is wrong
+	ErrInvPassword = errs.Unauthorized("Sorry, invalid password")
+	// ErrInvExpiry is returned if the expiry is not in the future
+	ErrInvExpiry = errs.Field("expiresAt", "Sorry, expiry should be in the future")
+)
+
+// Publication is a published snapshot of an item
+type Publication struct {
+	ID     string `json:"id,omitempty" bson:"id,omitempty"`
+	ItemID string `json:"itemID,omitempty" bson:"itemID,omitempty"`
+	// OwnerID is the owner ID of the item
+	OwnerID string `json:"-" bson:"ownerID,omitempty"`
+	// Snapshot is the encrypted Snapshot of the item
+	Snapshot []byte `json:"-" bson:"snapshot,omitempty"`
+	// Salt is used to derive the key from the password, it's empty if there's no password
+	Salt      []byte     `json:"-" bson:"salt,omitempty"`
+	Protected bool       `json:"protected" bson:"protected"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+}
+
+// Snapshot is the published content of an item
+type Snapshot struct {
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Checklist   []items.Entry `json:"checklist,omitempty"`
+	DueAt       *time.Time    `json:"dueAt,omitempty"`
+	PublishedAt time.Time     `json:"publishedAt"`
+}
+
+// Published is a new publication along with the key of its link. The key is not stored, so
+// it's available only when publishing
+type Published struct {
+	*Publication
+	Key string `json:"key"`
+	URL string `json:"url"`
+}
+
+// newID returns a new random, unguessable ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// URL returns the public path of a publication. The key is in the fragment, which browsers do
+// not send to the server, so that it does not end up in the logs of the server or of proxies.
+func URL(id, key string) string {
+	return fmt.Sprintf("/p/%s#%s", id, key)
+}
+
+// snapshotKey returns the key used to encrypt the snapshot. If there's a password, the key is
+// derived from both the key in the link and the password, so that neither is enough on its own.
+func snapshotKey(key [crypto.KeySize]byte, password string, salt []byte) ([crypto.KeySize]byte, error) {
+	if password == "" {
+		return key, nil
+	}
+
+	pk, err := crypto.PasswordKey(password, salt)
+	if err != nil {
+		return key, err
+	}
+
+	h := sha256.New()
+	h.Write(key[:])
+	h.Write(pk[:])
+	var out [crypto.KeySize]byte
+	copy(out[:], h.Sum(nil))
+	return out, nil
+}
+
+// New returns a new publication of a decrypted item, along with the key of its link
+func New(item *items.Item, password string, expiresAt *time.Time) (*Published, error) {
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, ErrInvExpiry
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := crypto.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publication{
+		ID:        id,
+		ItemID:    item.ID,
+		OwnerID:   item.OwnerID,
+		ExpiresAt: expiresAt,
+		CreatedAt: &now,
+	}
+
+	if password != "" {
+		p.Protected = true
+		p.Salt = make([]byte, saltSize)
+		_, err = io.ReadFull(rand.Reader, p.Salt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sk, err := snapshotKey(key, password, p.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(Snapshot{
+		Title:       item.Title,
+		Description: item.Description,
+		Checklist:   item.Checklist,
+		DueAt:       item.DueAt,
+		PublishedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.Snapshot, err = crypto.Seal(sk, b)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(key[:])
+	return &Published{
+		Publication: p,
+		Key:         encoded,
+		URL:         URL(id, encoded),
+	}, nil
+}
+
+// Expired returns true if the publication has expired at the given time
+func (p *Publication) Expired(now time.Time) bool {
+	return p.ExpiresAt != nil && !p.ExpiresAt.After(now)
+}
+
+// Open decrypts the snapshot using the key from the link, and the password if the publication
+// is protected
+func (p *Publication) Open(key, password string) (*Snapshot, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(b) != crypto.KeySize {
+		return nil, ErrInvKey
+	}
+	var k [crypto.KeySize]byte
+	copy(k[:], b)
+
+	if p.Protected && password == "" {
+		return nil, ErrPasswordRequired
+	}
+	if !p.Protected {
+		password = ""
+	}
+
+	sk, err := snapshotKey(k, password, p.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := crypto.Open(sk, p.Snapshot)
+	if err != nil {
+		if p.Protected {
+			// a wrong key and a wrong password cannot be told apart
+			return nil, ErrInvPassword
+		}
+		return nil, ErrInvKey
+	}
+
+	snapshot := Snapshot{}
+	err = json.Unmarshal(plain, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Create saves a new publication
+func (s *Service) Create(p Publication) (*Publication, error) {
+	_, err := s.store.Save(publicationsBucket, p)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+	return &p, nil
+}
+
+// Read reads a publication given its ID. Expired publications are deleted and not returned.
+func (s *Service) Read(id string) (*Publication, error) {
+	p := Publication{}
+	_, err := s.store.FindOne(publicationsBucket, map[string]interface{}{"id": id}, nil, nil, &p)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+
+	if p.Expired(time.Now()) {
+		s.Delete(id)
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+// ByItem returns all the publications of an item
+func (s *Service) ByItem(itemID string) ([]Publication, error) {
+	out := make([]Publication, 0)
+	_, err := s.store.Find(
+		publicationsBucket,
+		map[string]interface{}{"itemID": itemID},
+		nil,
+		[]string{"-createdAt"},
+		0,
+		0,
+		&out,
+	)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete deletes a publication given its ID
+func (s *Service) Delete(id string) error {
+	err := s.store.Delete(publicationsBucket, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteByItem deletes all the publications of an item
+func (s *Service) DeleteByItem(itemID string) error {
+	query := map[string]interface{}{"itemID": itemID}
+	for {
+		err := s.store.Delete(publicationsBucket, query)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			s.logger.Error(err.Error())
+			return err
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package publications
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+func TestOpen(t *testing.T) {
+	item := &items.Item{
+		ID:          "item_1",
+		OwnerID:     "owner",
+		Title:       "Runbook",
+		Description: "Restart the service",
+	}
+
+	published, err := New(item, "", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.HasSuffix(published.URL, "/"+published.ID+"#"+published.Key) {
+		t.Fatalf("Unexpected URL '%s'", published.URL)
+	}
+
+	snapshot, err := published.Open(published.Key, "ignored")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if snapshot.Title != item.Title || snapshot.Description != item.Description {
+		t.Fatalf("Unexpected snapshot %+v", snapshot)
+	}
+
+	other, _ := New(item, "", nil)
+	_, err = published.Open(other.Key, "")
+	if err != ErrInvKey {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvKey, err)
+	}
+}
+
+func TestOpenProtected(t *testing.T) {
+	item := &items.Item{ID: "item_1", Title: "Runbook"}
+	published, err := New(item, "s3cret", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !published.Protected {
+		t.Fatal("Expected the publication to be protected")
+	}
+
+	_, err = published.Open(published.Key, "")
+	if err != ErrPasswordRequired {
+		t.Fatalf("Expected '%v', got '%v'", ErrPasswordRequired, err)
+	}
+
+	_, err = published.Open(published.Key, "wrong")
+	if err != ErrInvPassword {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvPassword, err)
+	}
+
+	snapshot, err := published.Open(published.Key, "s3cret")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if snapshot.Title != "Runbook" {
+		t.Fatalf("Expected 'Runbook', got '%s'", snapshot.Title)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	item := &items.Item{ID: "item_1", Title: "Runbook"}
+	past := time.Now().Add(-time.Minute)
+	_, err := New(item, "", &past)
+	if err != ErrInvExpiry {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvExpiry, err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	published, err := New(item, "", &future)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if published.Expired(time.Now()) || !published.Expired(future) {
+		t.Fatal("Unexpected expiry")
+	}
+}
@@ -0,0 +1,156 @@
+package reminders
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/netguard"
+)
+
+const (
+	// ChannelWebhook delivers the reminder as a JSON POST request to the target URL
+	ChannelWebhook = "webhook"
+	// ChannelEmail delivers the reminder as an email to the target address
+	ChannelEmail = "email"
+	// ChannelMemory keeps the reminders in memory, it's meant for tests
+	ChannelMemory = "memory"
+)
+
+var (
+	// ErrDelivery is returned if the notification could not be delivered
+	ErrDelivery = errors.New("Sorry, the reminder could not be delivered")
+)
+
+// Notification is the payload delivered when a reminder fires. It never has the encrypted
+// content of the item.
+type Notification struct {
+	ReminderID string     `json:"reminderID"`
+	ItemID     string     `json:"itemID"`
+	Title      string     `json:"title,omitempty"`
+	DueAt      *time.Time `json:"dueAt,omitempty"`
+	At         time.Time  `json:"at"`
+	FiredAt    time.Time  `json:"firedAt"`
+}
+
+// Notifier delivers a notification to the target
+type Notifier interface {
+	Notify(target string, n Notification) error
+}
+
+// Webhook posts the notification as JSON to the target URL
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook returns a webhook notifier with the given request timeout. The addresses the
+// target URLs resolve to are checked by the guard when dialled.
+func NewWebhook(timeout time.Duration, g netguard.Guard) *Webhook {
+	return &Webhook{
+		client: g.Client(timeout),
+	}
+}
+
+// Notify posts the notification to the target URL
+func (w *Webhook) Notify(target string, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s, %s", ErrDelivery.Error(), netguard.Error(err).Error())
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s, webhook responded with status %d", ErrDelivery.Error(), resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPConfig holds all the configurations required to send emails
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends the notification as an email to the target address
+type SMTP struct {
+	cfg  SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP returns an email notifier
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	return &SMTP{
+		cfg:  cfg,
+		send: smtp.SendMail,
+	}
+}
+
+// Notify sends an email to the target address
+func (s *SMTP) Notify(target string, n Notification) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	subject := "Reminder"
+	if n.Title != "" {
+		subject = "Reminder: " + n.Title
+	}
+
+	body := &bytes.Buffer{}
+	fmt.Fprintf(body, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(body, "To: %s\r\n", target)
+	fmt.Fprintf(body, "Subject: %s\r\n", strings.NewReplacer("\r", "", "\n", "").Replace(subject))
+	fmt.Fprint(body, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(body, "Reminder for note %s", n.ItemID)
+	if n.DueAt != nil {
+		fmt.Fprintf(body, ", due at %s", n.DueAt.Format(time.RFC1123))
+	}
+	fmt.Fprint(body, ".\r\n")
+
+	return s.send(s.cfg.Host+":"+s.cfg.Port, auth, s.cfg.From, []string{target}, body.Bytes())
+}
+
+// Memory keeps all the notifications in memory
+type Memory struct {
+	mu            sync.Mutex
+	notifications map[string][]Notification
+}
+
+// NewMemory returns an in-memory notifier
+func NewMemory() *Memory {
+	return &Memory{
+		notifications: make(map[string][]Notification),
+	}
+}
+
+// Notify records the notification against the target
+func (m *Memory) Notify(target string, n Notification) error {
+	m.mu.Lock()
+	m.notifications[target] = append(m.notifications[target], n)
+	m.mu.Unlock()
+	return nil
+}
+
+// Notifications returns all the notifications delivered to the target
+func (m *Memory) Notifications(target string) []Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Notification, len(m.notifications[target]))
+	copy(out, m.notifications[target])
+	return out
+}
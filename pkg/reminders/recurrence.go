@@ -0,0 +1,191 @@
+package reminders
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+)
+
+const (
+	// FreqDaily repeats the reminder every day
+	FreqDaily = "DAILY"
+	// FreqWeekly repeats the reminder every week
+	FreqWeekly = "WEEKLY"
+	// FreqMonthly repeats the reminder every month
+	FreqMonthly = "MONTHLY"
+
+	// maxIterations is the maximum number of days looked ahead to find the next occurrence
+	maxIterations = 7 * 53
+)
+
+var (
+	// ErrInvRecurrence is returned if the recurrence rule is invalid or not supported
+	ErrInvRecurrence = errs.Field("recurrence", "Sorry, invalid recurrence. Supported values are daily, weekly, monthly or an RRULE with FREQ, INTERVAL, BYDAY, COUNT and UNTIL")
+
+	weekdays = map[string]time.Weekday{
+		"MO": time.Monday,
+		"TU": time.Tuesday,
+		"WE": time.Wednesday,
+		"TH": time.Thursday,
+		"FR": time.Friday,
+		"SA": time.Saturday,
+		"SU": time.Sunday,
+	}
+)
+
+// Recurrence is a parsed subset of an iCalendar RRULE (RFC 5545)
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time
+}
+
+// ParseRecurrence parses the recurrence of a reminder. It accepts daily, weekly, monthly or an
+// RRULE e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10". It returns nil for an empty rule.
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	rule = strings.TrimPrefix(rule, "RRULE:")
+	if rule == "" {
+		return nil, nil
+	}
+
+	switch rule {
+	case FreqDaily, FreqWeekly, FreqMonthly:
+		return &Recurrence{Freq: rule, Interval: 1}, nil
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrInvRecurrence
+		}
+		key, value := kv[0], kv[1]
+
+		switch key {
+		case "FREQ":
+			if value != FreqDaily && value != FreqWeekly && value != FreqMonthly {
+				return nil, ErrInvRecurrence
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, ErrInvRecurrence
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, ErrInvRecurrence
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, ErrInvRecurrence
+			}
+			r.Until = &t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return nil, ErrInvRecurrence
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return nil, ErrInvRecurrence
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, ErrInvRecurrence
+	}
+	if len(r.ByDay) > 0 && r.Freq != FreqWeekly {
+		return nil, ErrInvRecurrence
+	}
+	return r, nil
+}
+
+// parseUntil parses the UNTIL value, which is either a date or a UTC date-time
+func parseUntil(value string) (time.Time, error) {
+	t, err := time.Parse("20060102T150405Z", value)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse("20060102", value)
+	if err != nil {
+		return t, err
+	}
+	// a date is inclusive of the whole day
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+// Next returns the occurrence after prev. start is the first occurrence and fired is the number
+// of times the reminder has fired so far. It returns false if there are no more occurrences.
+func (r *Recurrence) Next(start, prev time.Time, fired int) (time.Time, bool) {
+	if r.Count > 0 && fired >= r.Count {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	switch r.Freq {
+	case FreqDaily:
+		next = prev.AddDate(0, 0, r.Interval)
+	case FreqMonthly:
+		// computing from start, so that the day of month isn't lost after a shorter month
+		next = start.AddDate(0, r.Interval*fired, 0)
+		for !next.After(prev) {
+			fired++
+			next = start.AddDate(0, r.Interval*fired, 0)
+		}
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			next = prev.AddDate(0, 0, 7*r.Interval)
+			break
+		}
+		found := false
+		for i := 1; i <= maxIterations; i++ {
+			next = prev.AddDate(0, 0, i)
+			if r.matchesDay(next) && weeksBetween(start, next)%r.Interval == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r *Recurrence) matchesDay(t time.Time) bool {
+	for _, d := range r.ByDay {
+		if t.Weekday() == d {
+			return true
+		}
+	}
+	return false
+}
+
+// weeksBetween returns the number of weeks, starting Monday, between the 2 times
+func weeksBetween(a, b time.Time) int {
+	return int(weekStart(b).Sub(weekStart(a)).Hours()/24) / 7
+}
+
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.AddDate(0, 0, -offset).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,108 @@
+package reminders
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence("")
+	if err != nil || r != nil {
+		t.Fatalf("Expected no recurrence, got %v, %v", r, err)
+	}
+
+	r, err = ParseRecurrence("weekly")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r.Freq != FreqWeekly || r.Interval != 1 {
+		t.Fatalf("Expected weekly with interval 1, got %s %d", r.Freq, r.Interval)
+	}
+
+	r, err = ParseRecurrence("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=3")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r.Interval != 2 || r.Count != 3 || len(r.ByDay) != 2 {
+		t.Fatalf("Unexpected recurrence %+v", r)
+	}
+
+	for _, rule := range []string{"hourly", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=MO", "FREQ=DAILY;INTERVAL=0", "INTERVAL=2"} {
+		_, err = ParseRecurrence(rule)
+		if err != ErrInvRecurrence {
+			t.Fatalf("Expected '%v' for '%s', got '%v'", ErrInvRecurrence, rule, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Monday
+	start := time.Date(2018, 7, 2, 9, 0, 0, 0, time.UTC)
+
+	r, _ := ParseRecurrence("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR")
+	expected := []time.Time{
+		time.Date(2018, 7, 6, 9, 0, 0, 0, time.UTC),
+		time.Date(2018, 7, 16, 9, 0, 0, 0, time.UTC),
+		time.Date(2018, 7, 20, 9, 0, 0, 0, time.UTC),
+	}
+	prev := start
+	for i, e := range expected {
+		next, ok := r.Next(start, prev, i+1)
+		if !ok {
+			t.Fatalf("Expected occurrence %d", i+1)
+		}
+		if !next.Equal(e) {
+			t.Fatalf("Expected '%s', got '%s'", e, next)
+		}
+		prev = next
+	}
+
+	r, _ = ParseRecurrence("FREQ=MONTHLY")
+	jan31 := time.Date(2018, 1, 31, 9, 0, 0, 0, time.UTC)
+	next, _ := r.Next(jan31, jan31, 1)
+	next, _ = r.Next(jan31, next, 2)
+	if next.Month() != time.March || next.Day() != 31 {
+		t.Fatalf("Expected March 31, got '%s'", next)
+	}
+
+	r, _ = ParseRecurrence("FREQ=DAILY;COUNT=2")
+	_, ok := r.Next(start, start, 2)
+	if ok {
+		t.Fatal("Expected no more occurrences after count")
+	}
+
+	r, _ = ParseRecurrence("FREQ=DAILY;UNTIL=20180703")
+	_, ok = r.Next(start, start.AddDate(0, 0, 1), 1)
+	if ok {
+		t.Fatal("Expected no more occurrences after until")
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	at := time.Now().UTC().Add(time.Hour)
+	r, err := New("owner", "item", at, "daily", ChannelWebhook, "https://example.com/hook")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// firing 3 days late should skip the missed occurrences
+	firedAt := at.AddDate(0, 0, 3).Add(time.Minute)
+	r.advance(firedAt)
+	if r.Done || r.NextAt == nil || !r.NextAt.Equal(at.AddDate(0, 0, 4)) {
+		t.Fatalf("Expected next occurrence '%s', got %v", at.AddDate(0, 0, 4), r.NextAt)
+	}
+
+	once, err := New("owner", "item", at, "", ChannelEmail, "john@example.com")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	once.advance(at)
+	if !once.Done || once.NextAt != nil {
+		t.Fatal("Expected a one time reminder to be done after firing")
+	}
+
+	_, err = New("owner", "item", at, "", ChannelWebhook, "ftp://example.com")
+	if err != ErrInvTarget {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvTarget, err)
+	}
+}
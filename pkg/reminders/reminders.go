@@ -0,0 +1,265 @@
+// Package reminders handles the reminders of items and fires them when they're due
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	remindersBucket = "reminders"
+	maxDue          = 100
+	// resolveTimeout is the timeout of resolving the host of a webhook target
+	resolveTimeout = time.Second * 5
+)
+
+var (
+	// ErrCreate is returned if there's an error creating a new reminder
+	ErrCreate = errors.New("Sorry, an error occurred while creating the reminder")
+	// ErrNotFound is returned if the reminder does not exist
+	ErrNotFound = errs.NotFound("Sorry, reminder not found")
+	// ErrInvAt is returned if the reminder time is missing or is in the past
+	ErrInvAt = errs.Field("at", "Sorry, the reminder time should be in the future")
+	// ErrInvChannel is returned if the channel is not supported
+	ErrInvChannel = errs.Field("channel", "Sorry, the reminder channel should be webhook or email")
+	// ErrInvTarget is returned if the target is not valid for the channel
+	ErrInvTarget = errs.Field("target", "Sorry, invalid reminder target, it should be a URL for webhook and an email address for email")
+	// ErrInvAddr is returned if the host of a webhook target does not resolve to public addresses
+	ErrInvAddr = errs.Field("target", "Sorry, the webhook URL should resolve to a public address")
+)
+
+// Reminder is a single, optionally recurring, reminder of an item
+type Reminder struct {
+	ID      string `json:"id,omitempty" bson:"id,omitempty"`
+	ItemID  string `json:"itemID,omitempty" bson:"itemID,omitempty"`
+	OwnerID string `json:"-" bson:"ownerID,omitempty"`
+	// At is the time of the first occurrence
+	At time.Time `json:"at" bson:"at"`
+	// NextAt is the time of the next occurrence
+	NextAt *time.Time `json:"nextAt,omitempty" bson:"nextAt,omitempty"`
+	// Recurrence is either of daily, weekly, monthly or an RRULE
+	Recurrence string `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	// Channel is the notifier used to deliver the reminder
+	Channel string `json:"channel" bson:"channel"`
+	// Target is the webhook URL or the email address
+	Target string `json:"target" bson:"target"`
+	// Fired is the number of times the reminder was fired
+	Fired int `json:"fired" bson:"fired"`
+	// LastFiredAt is the time when the reminder was last fired
+	LastFiredAt *time.Time `json:"lastFiredAt,omitempty" bson:"lastFiredAt,omitempty"`
+	// Done is true when there are no more occurrences
+	Done      bool       `json:"done" bson:"done"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+}
+
+func newReminderID() string {
+	return fmt.Sprintf("reminder_%s", uuid.New().String())
+}
+
+func validTarget(channel, target string) error {
+	switch channel {
+	case ChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvTarget
+		}
+	case ChannelEmail:
+		if !strings.Contains(target, "@") || strings.ContainsAny(target, "\r\n") {
+			return ErrInvTarget
+		}
+	default:
+		return ErrInvChannel
+	}
+	return nil
+}
+
+// New returns a new reminder for the item
+func New(ownerID, itemID string, at time.Time, recurrence, channel, target string) (*Reminder, error) {
+	now := time.Now().UTC()
+	var errAt error
+	if at.IsZero() || !at.After(now) {
+		errAt = ErrInvAt
+	}
+
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	target = strings.TrimSpace(target)
+	errTarget := validTarget(channel, target)
+
+	recurrence = strings.TrimSpace(recurrence)
+	_, errRecurrence := ParseRecurrence(recurrence)
+
+	err := errs.Validation(errAt, errTarget, errRecurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	at = at.UTC()
+	return &Reminder{
+		ID:         newReminderID(),
+		ItemID:     itemID,
+		OwnerID:    ownerID,
+		At:         at,
+		NextAt:     &at,
+		Recurrence: recurrence,
+		Channel:    channel,
+		Target:     target,
+		CreatedAt:  &now,
+	}, nil
+}
+
+// advance marks the current occurrence as fired and schedules the next one
+func (r *Reminder) advance(firedAt time.Time) {
+	r.Fired++
+	r.LastFiredAt = &firedAt
+
+	rec, _ := ParseRecurrence(r.Recurrence)
+	if rec == nil || r.NextAt == nil {
+		r.Done = true
+		r.NextAt = nil
+		return
+	}
+
+	prev := *r.NextAt
+	next, ok := rec.Next(r.At, prev, r.Fired)
+	// skipping occurrences missed while the app was down
+	for ok && !next.After(firedAt) {
+		prev = next
+		next, ok = rec.Next(r.At, prev, r.Fired)
+	}
+	if !ok {
+		r.Done = true
+		r.NextAt = nil
+		return
+	}
+	r.NextAt = &next
+}
+
+// Create saves a new reminder. The host of a webhook target should resolve to public addresses.
+func (s *Service) Create(r Reminder) (*Reminder, error) {
+	if r.Channel == ChannelWebhook {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+		err := s.guard.CheckURL(ctx, r.Target)
+		if err != nil {
+			return nil, errs.Validation(ErrInvAddr)
+		}
+	}
+
+	_, err := s.store.Save(remindersBucket, r)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+	return &r, nil
+}
+
+// Read reads a reminder given the reminder ID
+func (s *Service) Read(id string) (*Reminder, error) {
+	r := Reminder{}
+	_, err := s.store.FindOne(
+		remindersBucket,
+		map[string]interface{}{"id": id},
+		nil,
+		nil,
+		&r)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Update saves the reminder
+func (s *Service) Update(r *Reminder) error {
+	err := s.store.Update(
+		remindersBucket,
+		map[string]interface{}{"id": r.ID},
+		r)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// List returns all the reminders of an item
+func (s *Service) List(itemID string) ([]Reminder, error) {
+	out := make([]Reminder, 0)
+	_, err := s.store.Find(
+		remindersBucket,
+		map[string]interface{}{"itemID": itemID},
+		nil,
+		[]string{"at"},
+		0,
+		0,
+		&out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete deletes a reminder given the ID
+func (s *Service) Delete(id string) error {
+	err := s.store.Delete(remindersBucket, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteByItem deletes all the reminders of an item
+func (s *Service) DeleteByItem(itemID string) error {
+	query := map[string]interface{}{"itemID": itemID}
+	for {
+		err := s.store.Delete(remindersBucket, query)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			s.logger.Error(err.Error())
+			return err
+		}
+	}
+}
+
+// Due returns the reminders which are due at the given time
+func (s *Service) Due(now time.Time) ([]Reminder, error) {
+	out := make([]Reminder, 0)
+	_, err := s.store.Find(
+		remindersBucket,
+		map[string]interface{}{
+			"done":   false,
+			"nextAt": map[string]interface{}{"$lte": now},
+		},
+		nil,
+		[]string{"nextAt"},
+		0,
+		maxDue,
+		&out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package reminders
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+const (
+	defaultInterval = time.Second * 15
+	// lockExpiry is how long an instance holds the lock of an occurrence. It should be longer
+	// than the time taken to deliver a reminder
+	lockExpiry = time.Minute * 10
+)
+
+// Scheduler periodically fires all the reminders which are due. Reminders are read from the
+// store, so nothing is lost across restarts, and every occurrence is locked in the cache
+// so that it's fired only once across multiple instances of the app.
+type Scheduler struct {
+	reminders Service
+	items     items.Service
+	cache     cache.Service
+	logger    logger.Service
+	notifiers map[string]Notifier
+	interval  time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewScheduler returns a scheduler which checks for due reminders at every interval
+func NewScheduler(rs Service, is items.Service, cs cache.Service, l logger.Service, interval time.Duration, notifiers map[string]Notifier) *Scheduler {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Scheduler{
+		reminders: rs,
+		items:     is,
+		cache:     cs,
+		logger:    l,
+		notifiers: notifiers,
+		interval:  interval,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start starts checking for due reminders in the background
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.Run(time.Now().UTC())
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for the reminders being fired to complete
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+// lockKey is the cache key used to lock a single occurrence of a reminder
+func lockKey(r *Reminder) string {
+	return fmt.Sprintf("reminders:lock:%s:%d", r.ID, r.NextAt.Unix())
+}
+
+// Run fires all the reminders due at the given time
+func (s *Scheduler) Run(now time.Time) {
+	due, err := s.reminders.Due(now)
+	if err != nil {
+		return
+	}
+
+	for idx := range due {
+		r := &due[idx]
+		if r.NextAt == nil {
+			continue
+		}
+
+		// the lock is released only by the run which took it
+		token := uuid.New().String()
+		locked, err := s.cache.SetNX(lockKey(r), token, lockExpiry)
+		if err != nil {
+			s.logger.Error(err.Error())
+			continue
+		}
+		if !locked {
+			// another instance is firing this occurrence
+			continue
+		}
+
+		err = s.fire(r, now)
+		if err != nil {
+			s.logger.Error("reminder", r.ID, err.Error())
+			// releasing the lock so that it's retried in the next run
+			_, err = s.cache.DeleteIf(lockKey(r), token)
+			if err != nil {
+				s.logger.Error(err.Error())
+			}
+			continue
+		}
+	}
+}
+
+// fire delivers the reminder and schedules the next occurrence
+func (s *Scheduler) fire(r *Reminder, now time.Time) error {
+	notifier, ok := s.notifiers[r.Channel]
+	if !ok {
+		return ErrInvChannel
+	}
+
+	n := Notification{
+		ReminderID: r.ID,
+		ItemID:     r.ItemID,
+		At:         *r.NextAt,
+		FiredAt:    now,
+	}
+
+	item, err := s.items.Read(r.ItemID)
+	if err == nil {
+		n.Title = item.Title
+		n.DueAt = item.DueAt
+	}
+
+	err = notifier.Notify(r.Target, n)
+	if err != nil {
+		return err
+	}
+
+	r.advance(now)
+	return s.reminders.Update(r)
+}
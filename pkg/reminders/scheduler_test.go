@@ -0,0 +1,199 @@
+package reminders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	cachemem "github.com/bnkamalesh/notes/pkg/platform/cache/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/netguard"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+// failing is a notifier which fails until it's fixed
+type failing struct {
+	calls int
+	fixed bool
+}
+
+func (f *failing) Notify(target string, n Notification) error {
+	f.calls++
+	if !f.fixed {
+		return ErrDelivery
+	}
+	return nil
+}
+
+func newScheduler(t *testing.T, notifiers map[string]Notifier) (Service, *Scheduler, *cachemem.Handler) {
+	t.Helper()
+	store := storagemem.New()
+	cs := cachemem.New()
+	l := logger.New(nil)
+	is := items.NewService(store, l)
+	_, err := is.Create(items.Item{ID: "item_1", OwnerID: "owner", Title: "Groceries"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rs := NewService(store, l, Config{})
+	return rs, NewScheduler(rs, is, cs, l, time.Minute, notifiers), cs
+}
+
+// reminder saves a reminder of the item delivered to the memory notifier
+func reminder(t *testing.T, rs Service, at time.Time, recurrence string) *Reminder {
+	t.Helper()
+	r := Reminder{
+		ID:         newReminderID(),
+		ItemID:     "item_1",
+		OwnerID:    "owner",
+		At:         at,
+		NextAt:     &at,
+		Recurrence: recurrence,
+		Channel:    ChannelMemory,
+		Target:     "jane",
+	}
+	_, err := rs.Create(r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return &r
+}
+
+func TestRun(t *testing.T) {
+	mem := NewMemory()
+	rs, s, _ := newScheduler(t, map[string]Notifier{ChannelMemory: mem})
+	at := time.Now().UTC().Truncate(time.Second)
+	daily := reminder(t, rs, at, "daily")
+	once := reminder(t, rs, at, "")
+
+	s.Run(at.Add(-time.Second))
+	if len(mem.Notifications("jane")) != 0 {
+		t.Fatal("Expected no notifications before the reminders are due")
+	}
+
+	s.Run(at)
+	nn := mem.Notifications("jane")
+	if len(nn) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(nn))
+	}
+	for _, n := range nn {
+		if n.ItemID != "item_1" || n.Title != "Groceries" || !n.At.Equal(at) || !n.FiredAt.Equal(at) {
+			t.Fatalf("Unexpected notification %+v", n)
+		}
+	}
+
+	r, _ := rs.Read(daily.ID)
+	if r.Fired != 1 || r.Done || r.NextAt == nil || !r.NextAt.Equal(at.AddDate(0, 0, 1)) {
+		t.Fatalf("Expected the daily reminder to be scheduled for the next day, got %+v", r)
+	}
+	r, _ = rs.Read(once.ID)
+	if r.Fired != 1 || !r.Done || r.NextAt != nil {
+		t.Fatalf("Expected the one time reminder to be done, got %+v", r)
+	}
+
+	// fired once per occurrence
+	s.Run(at.Add(time.Minute))
+	if len(mem.Notifications("jane")) != 2 {
+		t.Fatalf("Expected no more notifications, got %d", len(mem.Notifications("jane")))
+	}
+	s.Run(at.AddDate(0, 0, 1))
+	if len(mem.Notifications("jane")) != 3 {
+		t.Fatalf("Expected the next occurrence to be fired, got %d", len(mem.Notifications("jane")))
+	}
+}
+
+func TestRunLocked(t *testing.T) {
+	mem := NewMemory()
+	rs, s, cs := newScheduler(t, map[string]Notifier{ChannelMemory: mem})
+	at := time.Now().UTC().Truncate(time.Second)
+	r := reminder(t, rs, at, "")
+
+	// another instance is firing the occurrence
+	cs.SetNX(lockKey(r), "other", lockExpiry)
+	s.Run(at)
+	if len(mem.Notifications("jane")) != 0 {
+		t.Fatal("Expected a locked occurrence not to be fired")
+	}
+	saved, _ := rs.Read(r.ID)
+	if saved.Fired != 0 {
+		t.Fatalf("Expected the reminder not to be fired, got %+v", saved)
+	}
+}
+
+func TestRunRetry(t *testing.T) {
+	f := &failing{}
+	rs, s, cs := newScheduler(t, map[string]Notifier{ChannelMemory: f})
+	at := time.Now().UTC().Truncate(time.Second)
+	r := reminder(t, rs, at, "")
+
+	s.Run(at)
+	saved, _ := rs.Read(r.ID)
+	if f.calls != 1 || saved.Fired != 0 || saved.Done {
+		t.Fatalf("Expected the failed reminder to be kept, got %+v", saved)
+	}
+	// the lock is released so that the occurrence is retried
+	locked, _ := cs.SetNX(lockKey(r), "other", lockExpiry)
+	if !locked {
+		t.Fatal("Expected the lock to be released")
+	}
+	cs.Delete(lockKey(r))
+
+	f.fixed = true
+	s.Run(at.Add(time.Minute))
+	saved, _ = rs.Read(r.ID)
+	if f.calls != 2 || saved.Fired != 1 || !saved.Done {
+		t.Fatalf("Expected the reminder to be fired on retry, got %+v", saved)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	_ = m.Notify("jane", Notification{ReminderID: "r1"})
+	_ = m.Notify("john", Notification{ReminderID: "r2"})
+
+	nn := m.Notifications("jane")
+	if len(nn) != 1 || nn[0].ReminderID != "r1" {
+		t.Fatalf("Unexpected notifications %+v", nn)
+	}
+	// the notifications returned are a copy
+	nn[0].ReminderID = "changed"
+	if m.Notifications("jane")[0].ReminderID != "r1" {
+		t.Fatal("Expected the notifications to be copied")
+	}
+	if len(m.Notifications("nobody")) != 0 {
+		t.Fatal("Expected no notifications")
+	}
+}
+
+func TestWebhookPrivateAddress(t *testing.T) {
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received++
+	}))
+	defer server.Close()
+
+	rs := NewService(storagemem.New(), logger.New(nil), Config{})
+	at := time.Now().UTC().Add(time.Hour)
+	for _, target := range []string{server.URL, "http://169.254.169.254/latest", "http://localhost:6379"} {
+		r, err := New("owner", "item_1", at, "", ChannelWebhook, target)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_, err = rs.Create(*r)
+		if err == nil {
+			t.Fatalf("Expected %s to be rejected", target)
+		}
+	}
+
+	err := NewWebhook(time.Second, netguard.Guard{}).Notify(server.URL, Notification{})
+	if err == nil || !strings.Contains(err.Error(), netguard.ErrForbidden.Error()) || received != 0 {
+		t.Fatalf("Expected the private address to be rejected when dialled, got %v", err)
+	}
+	err = NewWebhook(time.Second, netguard.Guard{AllowPrivate: true}).Notify(server.URL, Notification{})
+	if err != nil || received != 1 {
+		t.Fatalf("Expected the notification to be delivered, got %v", err)
+	}
+}
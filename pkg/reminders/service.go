@@ -0,0 +1,49 @@
+package reminders
+
+import (
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/netguard"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+// Service holds all the dependencies of reminders
+type Service struct {
+	store  storage.Service
+	logger logger.Service
+	guard  netguard.Guard
+}
+
+// NewService returns a new instance of Service with all the dependencies initialized
+func NewService(ss storage.Service, l logger.Service, c Config) Service {
+	return Service{
+		store:  ss,
+		logger: l,
+		guard:  netguard.Guard{AllowPrivate: c.AllowPrivate},
+	}
+}
+
+// Config holds all the configurations required for reminders
+type Config struct {
+	// Interval is the duration between successive checks for due reminders
+	Interval time.Duration
+	// WebhookTimeout is the timeout of a single webhook delivery
+	WebhookTimeout time.Duration
+	// SMTP is the configuration of the mail server, email reminders are disabled if Host is empty
+	SMTP SMTPConfig
+	// AllowPrivate allows webhook reminders to loopback and private addresses, e.g. for local
+	// development
+	AllowPrivate bool
+}
+
+// Notifiers returns all the notifiers enabled by the config
+func Notifiers(c Config) map[string]Notifier {
+	notifiers := map[string]Notifier{
+		ChannelWebhook: NewWebhook(c.WebhookTimeout, netguard.Guard{AllowPrivate: c.AllowPrivate}),
+	}
+	if c.SMTP.Host != "" {
+		notifiers[ChannelEmail] = NewSMTP(c.SMTP)
+	}
+	return notifiers
+}
@@ -0,0 +1,265 @@
+// Package search maintains an encrypted full-text index of the user items.
+// Words are never stored in plain text, every word (and its prefixes) is blinded using
+// HMAC-SHA256 keyed with the user's data key before it is written to the index.
+package search
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	indexBucket = "searchIndex"
+
+	// minTermLen is the minimum length of a word for it to be indexed
+	minTermLen = 2
+	// maxTermLen is the maximum length of a word which is indexed, longer words are truncated
+	maxTermLen = 64
+	// maxPrefixLen is the longest prefix of a word which is indexed for prefix matching
+	maxPrefixLen = 16
+
+	// titleWeight is the weight of a word appearing in the title
+	titleWeight = 3
+	// descriptionWeight is the weight of a word appearing in the description
+	descriptionWeight = 1
+
+	// exactPrefix & partialPrefix are used to separate the HMAC domain of whole words and prefixes
+	exactPrefix   = "w:"
+	partialPrefix = "p:"
+
+	maxQueryTerms = 10
+	maxLimit      = 50
+)
+
+var (
+	// ErrEmptyQuery is returned when the search query has no searchable words
+	ErrEmptyQuery = errs.Field("q", "Sorry, the search query should have at least one word")
+	// ErrInvOwnerID is returned if the owner ID is blank or invalid
+	ErrInvOwnerID = errors.New("Sorry, invalid owner ID provided")
+	// ErrIndex is returned if there's an error updating the search index
+	ErrIndex = errors.New("Sorry, an error occurred while updating the search index")
+)
+
+// Term is a single blinded word of an item along with its weight
+type Term struct {
+	// Key is the hex encoded HMAC of the word
+	Key string `json:"key" bson:"key"`
+	// Weight is the sum of weights of all occurrences of the word in the item
+	Weight int `json:"weight" bson:"weight"`
+}
+
+// Entry is the index entry of a single item
+type Entry struct {
+	// OwnerID is the unique identifier of the owner of the item
+	OwnerID string `json:"-" bson:"ownerID,omitempty"`
+	// ItemID is the unique identifier of the indexed item
+	ItemID string `json:"itemID,omitempty" bson:"itemID,omitempty"`
+	// Terms is the list of blinded words and prefixes of the item
+	Terms []Term `json:"-" bson:"terms,omitempty"`
+}
+
+// Result is a single search result
+type Result struct {
+	ItemID string `json:"itemID"`
+	Score  int    `json:"score"`
+}
+
+// queryTerm is a single word from the search query
+type queryTerm struct {
+	exact  string
+	prefix string
+}
+
+// Tokenize splits the given text into lower case words, ignoring punctuation and words shorter
+// than minTermLen
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	out := make([]string, 0, len(words))
+	for _, w := range words {
+		rw := []rune(w)
+		if len(rw) < minTermLen {
+			continue
+		}
+		if len(rw) > maxTermLen {
+			w = string(rw[:maxTermLen])
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// blind returns the hex encoded HMAC of the given word, keyed by the provided key
+func blind(key [32]byte, domain, word string) string {
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(domain + word))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// prefixes returns all the indexable prefixes of the word, excluding the word itself
+func prefixes(word string) []string {
+	rw := []rune(word)
+	max := len(rw) - 1
+	if max > maxPrefixLen {
+		max = maxPrefixLen
+	}
+	out := make([]string, 0, max)
+	for i := minTermLen; i <= max; i++ {
+		out = append(out, string(rw[:i]))
+	}
+	return out
+}
+
+// Terms returns the weighted and blinded terms of an item given its title and description
+func Terms(key [32]byte, title, description string) []Term {
+	weights := make(map[string]int)
+	add := func(text string, weight int) {
+		for _, w := range Tokenize(text) {
+			weights[blind(key, exactPrefix, w)] += weight
+			for _, p := range prefixes(w) {
+				weights[blind(key, partialPrefix, p)] += weight
+			}
+		}
+	}
+	add(title, titleWeight)
+	add(description, descriptionWeight)
+
+	terms := make([]Term, 0, len(weights))
+	for k, w := range weights {
+		terms = append(terms, Term{Key: k, Weight: w})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].Key < terms[j].Key
+	})
+	return terms
+}
+
+// parseQuery returns the blinded terms of a search query. A word in the query matches an
+// indexed word if it's the same word or a prefix of it.
+func parseQuery(key [32]byte, q string) []queryTerm {
+	words := Tokenize(q)
+	if len(words) > maxQueryTerms {
+		words = words[:maxQueryTerms]
+	}
+	out := make([]queryTerm, 0, len(words))
+	for _, w := range words {
+		out = append(out, queryTerm{
+			exact:  blind(key, exactPrefix, w),
+			prefix: blind(key, partialPrefix, w),
+		})
+	}
+	return out
+}
+
+// score computes the rank of an index entry for the given query. Exact word matches are
+// ranked higher than prefix matches.
+func (e *Entry) score(qterms []queryTerm) int {
+	weights := make(map[string]int, len(e.Terms))
+	for _, t := range e.Terms {
+		weights[t.Key] = t.Weight
+	}
+
+	total := 0
+	for _, qt := range qterms {
+		total += weights[qt.exact]*2 + weights[qt.prefix]
+	}
+	return total
+}
+
+// Index adds or replaces the index entry of an item
+func (s *Service) Index(key [32]byte, ownerID, itemID, title, description string) error {
+	ownerID = strings.TrimSpace(ownerID)
+	if ownerID == "" {
+		return ErrInvOwnerID
+	}
+
+	err := s.Remove(itemID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.Save(indexBucket, Entry{
+		OwnerID: ownerID,
+		ItemID:  itemID,
+		Terms:   Terms(key, title, description),
+	})
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrIndex
+	}
+	return nil
+}
+
+// Remove removes the index entry of an item
+func (s *Service) Remove(itemID string) error {
+	err := s.store.Delete(
+		indexBucket,
+		map[string]interface{}{
+			"itemID": itemID,
+		})
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return ErrIndex
+	}
+	return nil
+}
+
+// Search returns the IDs of all the items of the owner matching all the words in the query,
+// ordered by rank
+func (s *Service) Search(key [32]byte, ownerID, q string, limit int) ([]Result, error) {
+	qterms := parseQuery(key, q)
+	if len(qterms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	if limit <= 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	and := make([]interface{}, 0, len(qterms))
+	for _, qt := range qterms {
+		and = append(and, map[string]interface{}{
+			"terms.key": map[string]interface{}{
+				"$in": []string{qt.exact, qt.prefix},
+			},
+		})
+	}
+	query := map[string]interface{}{
+		"ownerID": ownerID,
+		"$and":    and,
+	}
+
+	entries := make([]Entry, 0)
+	_, err := s.store.Find(indexBucket, query, nil, nil, 0, 0, &entries)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(entries))
+	for _, e := range entries {
+		results = append(results, Result{
+			ItemID: e.ItemID,
+			Score:  e.score(qterms),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
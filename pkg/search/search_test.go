@@ -0,0 +1,69 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+var testKey = [32]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+func TestTokenize(t *testing.T) {
+	words := Tokenize("Hello, World! a quick-brown fox; ÉCOLE 42")
+	expected := []string{"hello", "world", "quick", "brown", "fox", "école", "42"}
+	if len(words) != len(expected) {
+		t.Fatalf("Expected %d words, got %d (%v)", len(expected), len(words), words)
+	}
+	for i, w := range expected {
+		if words[i] != w {
+			t.Fatalf("Expected word '%s', got '%s'", w, words[i])
+		}
+	}
+}
+
+func TestTermsAreBlinded(t *testing.T) {
+	terms := Terms(testKey, "Secret plan", "launch codes")
+	if len(terms) == 0 {
+		t.Fatal("Expected terms, got none")
+	}
+	for _, term := range terms {
+		for _, w := range []string{"secret", "plan", "launch", "codes", "se", "la"} {
+			if strings.Contains(term.Key, w) {
+				t.Fatalf("Term '%s' contains plain text word '%s'", term.Key, w)
+			}
+		}
+	}
+
+	otherKey := testKey
+	otherKey[0] = 9
+	other := Terms(otherKey, "Secret plan", "launch codes")
+	for _, a := range terms {
+		for _, b := range other {
+			if a.Key == b.Key {
+				t.Fatal("Expected different keys to produce different terms")
+			}
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	title := Entry{Terms: Terms(testKey, "groceries", "milk and eggs")}
+	description := Entry{Terms: Terms(testKey, "weekend", "buy groceries")}
+	prefix := Entry{Terms: Terms(testKey, "weekend", "grocery store")}
+	missing := Entry{Terms: Terms(testKey, "weekend", "nothing here")}
+
+	q := parseQuery(testKey, "groc")
+	if title.score(q) <= description.score(q) {
+		t.Fatalf("Expected title match to rank higher, got %d <= %d", title.score(q), description.score(q))
+	}
+	if prefix.score(q) == 0 {
+		t.Fatal("Expected prefix match to have a score")
+	}
+	if missing.score(q) != 0 {
+		t.Fatalf("Expected no score, got %d", missing.score(q))
+	}
+
+	q = parseQuery(testKey, "groceries")
+	if description.score(q) <= prefix.score(q) {
+		t.Fatalf("Expected exact match to rank higher than prefix, got %d <= %d", description.score(q), prefix.score(q))
+	}
+}
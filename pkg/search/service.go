@@ -0,0 +1,20 @@
+package search
+
+import (
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+// Service holds all the dependencies of search
+type Service struct {
+	store  storage.Service
+	logger logger.Service
+}
+
+// NewService returns a new instance of Service with all the dependencies initialized
+func NewService(ss storage.Service, l logger.Service) Service {
+	return Service{
+		store:  ss,
+		logger: l,
+	}
+}
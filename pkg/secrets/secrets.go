@@ -0,0 +1,221 @@
+// Package secrets handles one time secrets, which can be read by anyone with the link and are
+// destroyed after a number of views or when they expire. The content is encrypted with a random
+// key which is returned only to the creator, and is never stored. So the server cannot decrypt
+// a secret on its own.
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+)
+
+const (
+	// DefaultMaxViews is the number of times a secret can be read, if not specified
+	DefaultMaxViews = 1
+	// MaxViews is the maximum number of times a secret can be read
+	MaxViews = 100
+	// DefaultTTL is the time after which a secret expires, if not specified
+	DefaultTTL = time.Hour * 24
+	// MaxTTL is the maximum time a secret can be kept
+	MaxTTL = time.Hour * 24 * 7
+	// MaxSize is the maximum size of the content of a secret in bytes
+	MaxSize = 64 * 1024
+
+	cachePrefix = "secrets"
+)
+
+var (
+	// ErrNotFound is returned if the secret does not exist, has expired or has been burnt
+	ErrNotFound = errs.NotFound("Sorry, the secret does not exist or has already been read")
+	// ErrCreate is returned if there's an error saving the secret
+	ErrCreate = errors.New("Sorry, an error occurred while creating the secret")
+	// ErrEmpty is returned if the content of the secret is empty
+	ErrEmpty = errs.Field("content", "Sorry, the secret cannot be empty")
+	// ErrTooLarge is returned if the content of the secret is larger than MaxSize
+	ErrTooLarge = errs.Field("content", fmt.Sprintf("Sorry, the secret cannot be larger than %d bytes", MaxSize))
+	// ErrInvMaxViews is returned if the max views is out of range
+	ErrInvMaxViews = errs.Field("maxViews", fmt.Sprintf("Sorry, max views should be between 1 and %d", MaxViews))
+	// ErrInvTTL is returned if the TTL is out of range
+	ErrInvTTL = errs.Field("ttl", fmt.Sprintf("Sorry, ttl should be between 1 minute and %s", MaxTTL))
+	// ErrInvKey is returned if the key of a secret is malformed
+	ErrInvKey = errs.NotFound("Sorry, invalid secret key")
+)
+
+// Secret is the encrypted content of a one time secret
+type Secret struct {
+	ID string `json:"id,omitempty"`
+	// Ciphertext is the content encrypted with the key of the secret
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+	MaxViews   int    `json:"maxViews,omitempty"`
+	// ViewsLeft is the number of times the secret can be read after the current read
+	ViewsLeft int        `json:"viewsLeft"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// Created is a newly created secret along with its key. The key is not stored anywhere, it's
+// meant to be sent only in the fragment of the URL, which is not sent to the server by browsers
+type Created struct {
+	ID        string     `json:"id"`
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	MaxViews  int        `json:"maxViews"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// newID returns a new random, unguessable ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func dataKey(id string) string {
+	return fmt.Sprintf("%s:%s", cachePrefix, id)
+}
+
+func viewsKey(id string) string {
+	return fmt.Sprintf("%s:%s:views", cachePrefix, id)
+}
+
+// EncodeKey encodes the key of a secret to be used in a URL
+func EncodeKey(key [crypto.KeySize]byte) string {
+	return base64.RawURLEncoding.EncodeToString(key[:])
+}
+
+// DecodeKey decodes the key of a secret encoded by EncodeKey
+func DecodeKey(s string) ([crypto.KeySize]byte, error) {
+	var key [crypto.KeySize]byte
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != crypto.KeySize {
+		return key, ErrInvKey
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+// Decrypt returns the content of the secret using its key
+func (s *Secret) Decrypt(key [crypto.KeySize]byte) (string, error) {
+	b, err := crypto.Open(key, s.Ciphertext)
+	if err != nil {
+		return "", ErrInvKey
+	}
+	return string(b), nil
+}
+
+// Create encrypts the content with a new random key and saves it. The secret can be read
+// maxViews times before the ttl expires. Zero values use the defaults.
+func (s *Service) Create(content string, maxViews int, ttl time.Duration) (*Created, error) {
+	if content == "" {
+		return nil, ErrEmpty
+	}
+	if len(content) > MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	if maxViews == 0 {
+		maxViews = DefaultMaxViews
+	}
+	if maxViews < 1 || maxViews > MaxViews {
+		return nil, ErrInvMaxViews
+	}
+
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	if ttl < time.Minute || ttl > MaxTTL {
+		return nil, ErrInvTTL
+	}
+
+	id, err := newID()
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+
+	key, err := crypto.NewKey()
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+
+	ciphertext, err := crypto.Seal(key, []byte(content))
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+	secret := Secret{
+		ID:         id,
+		Ciphertext: ciphertext,
+		MaxViews:   maxViews,
+		ExpiresAt:  &expiresAt,
+		CreatedAt:  &now,
+	}
+
+	err = s.cache.Set(dataKey(id), secret, ttl)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+
+	encoded := EncodeKey(key)
+	return &Created{
+		ID:        id,
+		Key:       encoded,
+		URL:       fmt.Sprintf("/secrets/%s#%s", id, encoded),
+		MaxViews:  maxViews,
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// Read returns the encrypted secret and counts it as a view. The secret is deleted on its last
+// view. Views are counted atomically, so a secret is never returned more than max views times
+// even with concurrent reads. The views are kept until the secret expires, a read which got the
+// secret before it was deleted is counted after its last view and fails.
+func (s *Service) Read(id string) (*Secret, error) {
+	secret := Secret{}
+	err := s.cache.Get(dataKey(id), &secret)
+	if err != nil || secret.ExpiresAt == nil {
+		return nil, ErrNotFound
+	}
+
+	ttl := time.Until(*secret.ExpiresAt)
+	if ttl <= 0 {
+		return nil, ErrNotFound
+	}
+
+	views, err := s.cache.Increment(viewsKey(id), ttl)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+
+	if views >= int64(secret.MaxViews) {
+		err = s.cache.Delete(dataKey(id))
+		if err != nil {
+			s.logger.Error(err.Error())
+		}
+		if views > int64(secret.MaxViews) {
+			// burnt by a concurrent read
+			return nil, ErrNotFound
+		}
+	}
+
+	secret.ViewsLeft = secret.MaxViews - int(views)
+	return &secret, nil
+}
@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/cache/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+func TestSecret(t *testing.T) {
+	s := NewService(memory.New(), logger.New([]string{"all"}))
+
+	created, err := s.Create("hunter2", 2, time.Hour)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if created.URL != "/secrets/"+created.ID+"#"+created.Key {
+		t.Fatalf("Unexpected URL '%s'", created.URL)
+	}
+
+	key, err := DecodeKey(created.Key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for left := 1; left >= 0; left-- {
+		secret, err := s.Read(created.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if secret.ViewsLeft != left {
+			t.Fatalf("Expected %d views left, got %d", left, secret.ViewsLeft)
+		}
+		content, err := secret.Decrypt(key)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if content != "hunter2" {
+			t.Fatalf("Expected 'hunter2', got '%s'", content)
+		}
+	}
+
+	_, err = s.Read(created.ID)
+	if err != ErrNotFound {
+		t.Fatalf("Expected '%v', got '%v'", ErrNotFound, err)
+	}
+}
+
+func TestSecretConcurrentReads(t *testing.T) {
+	s := NewService(memory.New(), logger.New([]string{"all"}))
+	created, err := s.Create("hunter2", 3, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		reads int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Read(created.ID)
+			if err == nil {
+				mu.Lock()
+				reads++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reads != 3 {
+		t.Fatalf("Expected 3 reads, got %d", reads)
+	}
+}
+
+// interleaved is a cache which runs read before the next view is counted
+type interleaved struct {
+	cache.Service
+	read func()
+}
+
+func (c *interleaved) Increment(key string, expiry time.Duration) (int64, error) {
+	if c.read != nil {
+		read := c.read
+		c.read = nil
+		read()
+	}
+	return c.Service.Increment(key, expiry)
+}
+
+func TestSecretReadAfterLastView(t *testing.T) {
+	c := &interleaved{Service: memory.New()}
+	s := NewService(c, logger.New([]string{"all"}))
+	created, err := s.Create("hunter2", 1, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// another read takes the last view between the read of the secret and the count of its view
+	var other error
+	c.read = func() {
+		_, other = s.Read(created.ID)
+	}
+	_, err = s.Read(created.ID)
+	if other != nil {
+		t.Fatalf("Expected the other read to get the secret, got '%v'", other)
+	}
+	if err != ErrNotFound {
+		t.Fatalf("Expected '%v' after the last view, got '%v'", ErrNotFound, err)
+	}
+}
+
+func TestSecretValidation(t *testing.T) {
+	s := NewService(memory.New(), logger.New([]string{"all"}))
+
+	cases := []struct {
+		content  string
+		maxViews int
+		ttl      time.Duration
+		err      error
+	}{
+		{"", 0, 0, ErrEmpty},
+		{string(make([]byte, MaxSize+1)), 0, 0, ErrTooLarge},
+		{"a", MaxViews + 1, 0, ErrInvMaxViews},
+		{"a", -1, 0, ErrInvMaxViews},
+		{"a", 0, MaxTTL + time.Second, ErrInvTTL},
+		{"a", 0, time.Second, ErrInvTTL},
+	}
+	for _, c := range cases {
+		_, err := s.Create(c.content, c.maxViews, c.ttl)
+		if err != c.err {
+			t.Fatalf("Expected '%v', got '%v'", c.err, err)
+		}
+	}
+
+	_, err := DecodeKey("short")
+	if err != ErrInvKey {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvKey, err)
+	}
+}
@@ -0,0 +1,20 @@
+package secrets
+
+import (
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+)
+
+// Service holds all the dependencies of secrets
+type Service struct {
+	cache  cache.Service
+	logger logger.Service
+}
+
+// NewService returns a new instance of Service with all the dependencies initialized
+func NewService(cs cache.Service, l logger.Service) Service {
+	return Service{
+		cache:  cs,
+		logger: l,
+	}
+}
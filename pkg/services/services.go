@@ -2,26 +2,75 @@
 package services
 
 import (
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/events"
+	"github.com/bnkamalesh/notes/pkg/health"
+	"github.com/bnkamalesh/notes/pkg/importer"
 	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
 	"github.com/bnkamalesh/notes/pkg/platform/cache"
 	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/publications"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+	"github.com/bnkamalesh/notes/pkg/search"
+	"github.com/bnkamalesh/notes/pkg/secrets"
+	"github.com/bnkamalesh/notes/pkg/shares"
 	"github.com/bnkamalesh/notes/pkg/users"
+	"github.com/bnkamalesh/notes/pkg/webhooks"
 )
 
 // Handler holds all the services of the app
 type Handler struct {
-	Items items.Service
-	Users users.Service
+	Items        items.Service
+	Users        users.Service
+	Search       search.Service
+	Reminders    reminders.Service
+	Shares       shares.Service
+	Secrets      secrets.Service
+	Publications publications.Service
+	Attachments  attachments.Service
+	Importer     importer.Service
+	Webhooks     webhooks.Service
+	// Events notifies the changes of the items, it should be started by the app
+	Events events.Service
+	// Scheduler fires the reminders when they're due, it should be started by the app
+	Scheduler *reminders.Scheduler
+	// Dispatcher delivers the events to the webhooks, it should be started by the app
+	Dispatcher *webhooks.Dispatcher
+	// Health checks the store and the cache, to tell if the app is ready to serve requests
+	Health *health.Checker
 }
 
 // New returns a new Service instance with all the internal services initialized
-func New(ss storage.Service, cs cache.Service, l logger.Service) Handler {
+func New(ss storage.Service, cs cache.Service, bs blob.Service, l logger.Service, rc reminders.Config, ac attachments.Config, wc webhooks.Config) Handler {
 	iS := items.NewService(ss, l)
-	uS := users.NewService(ss, cs, l, iS)
+	sS := search.NewService(ss, l)
+	rS := reminders.NewService(ss, l, rc)
+	shS := shares.NewService(ss, l)
+	pS := publications.NewService(ss, l)
+	aS := attachments.NewService(ss, bs, l, ac)
+	eS := events.NewService(cs, l)
+	wS := webhooks.NewService(ss, cs, l, wc)
+	uS := users.NewService(ss, cs, l, iS, sS, rS, shS, pS, aS, eS, wS)
+	hC := health.NewChecker(l)
+	hC.Add("storage", ss.Ping)
+	hC.Add("cache", cs.Ping)
 
 	return Handler{
-		Items: iS,
-		Users: uS,
+		Items:        iS,
+		Users:        uS,
+		Search:       sS,
+		Reminders:    rS,
+		Shares:       shS,
+		Secrets:      secrets.NewService(cs, l),
+		Publications: pS,
+		Attachments:  aS,
+		Importer:     importer.NewService(cs, l),
+		Events:       eS,
+		Webhooks:     wS,
+		Scheduler:    reminders.NewScheduler(rS, iS, cs, l, rc.Interval, reminders.Notifiers(rc)),
+		Dispatcher:   webhooks.NewDispatcher(wS, cs, l, wc),
+		Health:       hC,
 	}
 }
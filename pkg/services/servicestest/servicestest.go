@@ -0,0 +1,16 @@
+// Package servicestest returns the services of the app with the data in memory, for the tests
+// of the packages using them
+package servicestest
+
+import (
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+	"github.com/bnkamalesh/notes/pkg/services"
+	"github.com/bnkamalesh/notes/pkg/testenv"
+	"github.com/bnkamalesh/notes/pkg/webhooks"
+)
+
+// New returns the services with the dependencies of the environment and the default configs
+func New(env *testenv.Env) services.Handler {
+	return services.New(env.Store, env.Cache, env.Blob, env.Logger, reminders.Config{}, attachments.Config{}, webhooks.Config{})
+}
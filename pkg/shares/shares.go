@@ -0,0 +1,182 @@
+// Package shares handles the items shared by their owners with other users. The content key of
+// a shared item is encrypted with the recipient's public key, so only the recipient can read it.
+package shares
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	// PermissionRead allows the recipient to only read the item
+	PermissionRead = "read"
+	// PermissionWrite allows the recipient to read and update the item
+	PermissionWrite = "write"
+
+	sharesBucket = "shares"
+)
+
+var (
+	// ErrCreate is returned if there's an error creating a new share
+	ErrCreate = errors.New("Sorry, an error occurred while sharing")
+	// ErrNotFound is returned if the share does not exist
+	ErrNotFound = errs.NotFound("Sorry, share not found")
+	// ErrInvPermission is returned if the permission is not read or write
+	ErrInvPermission = errs.Field("permission", "Sorry, permission should be read or write")
+)
+
+// Share is an item shared with a single recipient
+type Share struct {
+	ID     string `json:"id,omitempty" bson:"id,omitempty"`
+	ItemID string `json:"itemID,omitempty" bson:"itemID,omitempty"`
+	// OwnerID is the owner ID of the item
+	OwnerID string `json:"-" bson:"ownerID,omitempty"`
+	// RecipientID is the user ID of the recipient
+	RecipientID string `json:"recipientID,omitempty" bson:"recipientID,omitempty"`
+	// RecipientEmail is the email of the recipient, for display
+	RecipientEmail string `json:"recipientEmail,omitempty" bson:"recipientEmail,omitempty"`
+	Permission     string `json:"permission,omitempty" bson:"permission,omitempty"`
+	// Key is the content key of the item, encrypted with the recipient's public key
+	Key        []byte     `json:"-" bson:"key,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty" bson:"modifiedAt,omitempty"`
+}
+
+// CanWrite returns true if the recipient is allowed to update the item
+func (s *Share) CanWrite() bool {
+	return s.Permission == PermissionWrite
+}
+
+func newShareID() string {
+	return fmt.Sprintf("share_%s", uuid.New().String())
+}
+
+// New returns a new share of the item with the recipient
+func New(ownerID, itemID, recipientID, recipientEmail, permission string, key []byte) (*Share, error) {
+	permission = strings.ToLower(strings.TrimSpace(permission))
+	if permission == "" {
+		permission = PermissionRead
+	}
+	if permission != PermissionRead && permission != PermissionWrite {
+		return nil, ErrInvPermission
+	}
+
+	now := time.Now()
+	return &Share{
+		ID:             newShareID(),
+		ItemID:         itemID,
+		OwnerID:        ownerID,
+		RecipientID:    recipientID,
+		RecipientEmail: recipientEmail,
+		Permission:     permission,
+		Key:            key,
+		CreatedAt:      &now,
+		ModifiedAt:     &now,
+	}, nil
+}
+
+// Create saves a new share
+func (s *Service) Create(share Share) (*Share, error) {
+	_, err := s.store.Save(sharesBucket, share)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+	return &share, nil
+}
+
+// Read reads a share given its ID
+func (s *Service) Read(id string) (*Share, error) {
+	return s.findOne(map[string]interface{}{"id": id})
+}
+
+// Find returns the share of the item with the recipient
+func (s *Service) Find(itemID, recipientID string) (*Share, error) {
+	return s.findOne(map[string]interface{}{
+		"itemID":      itemID,
+		"recipientID": recipientID,
+	})
+}
+
+func (s *Service) findOne(query map[string]interface{}) (*Share, error) {
+	share := Share{}
+	_, err := s.store.FindOne(sharesBucket, query, nil, nil, &share)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &share, nil
+}
+
+// Update saves the share
+func (s *Service) Update(share *Share) error {
+	now := time.Now()
+	share.ModifiedAt = &now
+	err := s.store.Update(sharesBucket, map[string]interface{}{"id": share.ID}, share)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ByItem returns all the shares of an item
+func (s *Service) ByItem(itemID string) ([]Share, error) {
+	return s.find(map[string]interface{}{"itemID": itemID})
+}
+
+// ByRecipient returns all the shares with the recipient
+func (s *Service) ByRecipient(recipientID string) ([]Share, error) {
+	return s.find(map[string]interface{}{"recipientID": recipientID})
+}
+
+func (s *Service) find(query map[string]interface{}) ([]Share, error) {
+	out := make([]Share, 0)
+	_, err := s.store.Find(sharesBucket, query, nil, []string{"-createdAt"}, 0, 0, &out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete deletes a share given its ID
+func (s *Service) Delete(id string) error {
+	err := s.store.Delete(sharesBucket, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteByItem deletes all the shares of an item
+func (s *Service) DeleteByItem(itemID string) error {
+	query := map[string]interface{}{"itemID": itemID}
+	for {
+		err := s.store.Delete(sharesBucket, query)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			s.logger.Error(err.Error())
+			return err
+		}
+	}
+}
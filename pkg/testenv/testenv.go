@@ -0,0 +1,38 @@
+// Package testenv has the dependencies of the services with the data in memory, so that the
+// tests run without the database, the cache and the blob store
+package testenv
+
+import (
+	"testing"
+
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	cachemem "github.com/bnkamalesh/notes/pkg/platform/cache/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+// Env is the dependencies of the services. The fields can be replaced before the services are
+// created, e.g. to keep the logs.
+type Env struct {
+	Store  storage.Service
+	Cache  cache.Service
+	Blob   blob.Service
+	Logger logger.Service
+}
+
+// New returns the dependencies with the data in memory, the logs are discarded
+func New(t testing.TB) *Env {
+	t.Helper()
+	bs, err := blob.New(blob.Config{Driver: blob.DriverMemory})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return &Env{
+		Store:  storagemem.New(),
+		Cache:  cachemem.New(),
+		Blob:   bs,
+		Logger: logger.New(nil),
+	}
+}
@@ -0,0 +1,118 @@
+package users
+
+import (
+	"io"
+
+	"github.com/bnkamalesh/notes/pkg/attachments"
+)
+
+// AddAttachment encrypts and attaches everything read from r to an item the user can update.
+// The attachment is counted in the quota of the owner of the item. It's removed if the key of
+// the item was rotated during the upload, since it's wrapped with the previous key.
+func (s *Service) AddAttachment(user *User, itemID, name, contentType string, r io.Reader) (*attachments.Attachment, error) {
+	span := s.trace("AddAttachment")
+	defer span.End()
+	acc, err := s.itemAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.canWrite() {
+		return nil, ErrUnauthorized
+	}
+
+	a, err := s.attachments.Create(acc.item.OwnerID, itemID, name, contentType, acc.key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the attachment is saved before the key is checked, so a rotation that starts after the
+	// check rewraps it as well
+	_, unlock, err := s.lockSeq(acc.item.OwnerID)
+	if err == nil {
+		_, err = s.currentItem(acc)
+		unlock()
+	}
+	if err != nil {
+		derr := s.attachments.Delete(a)
+		if derr != nil {
+			s.logger.Error("attachment", a.ID, derr.Error())
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// Attachments returns all the attachments of an item owned by, or shared with, the user
+func (s *Service) Attachments(user *User, itemID string) ([]attachments.Attachment, error) {
+	span := s.trace("Attachments")
+	defer span.End()
+	_, err := s.itemAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return s.attachments.ByItem(itemID)
+}
+
+// attachment returns an attachment of the item along with the user's access on the item
+func (s *Service) attachment(user *User, itemID, attachmentID string) (*attachments.Attachment, *access, error) {
+	acc, err := s.itemAccess(user, itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a, err := s.attachments.Read(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.ItemID != itemID {
+		return nil, nil, attachments.ErrNotFound
+	}
+	return a, acc, nil
+}
+
+// OpenAttachment returns an attachment of an item owned by, or shared with, the user along
+// with a reader of its decrypted content. The reader should be closed after use.
+func (s *Service) OpenAttachment(user *User, itemID, attachmentID string) (*attachments.Attachment, *attachments.Reader, error) {
+	span := s.trace("OpenAttachment")
+	defer span.End()
+	a, acc, err := s.attachment(user, itemID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s.attachments.Open(a, acc.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, r, nil
+}
+
+// DeleteAttachment deletes an attachment of an item the user can update
+func (s *Service) DeleteAttachment(user *User, itemID, attachmentID string) (*attachments.Attachment, error) {
+	span := s.trace("DeleteAttachment")
+	defer span.End()
+	a, acc, err := s.attachment(user, itemID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.canWrite() {
+		return nil, ErrUnauthorized
+	}
+
+	err = s.attachments.Delete(a)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AttachmentUsage returns the storage used by the attachments of all the items of the user
+func (s *Service) AttachmentUsage(user *User) (*attachments.Usage, error) {
+	span := s.trace("AttachmentUsage")
+	defer span.End()
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+	return s.attachments.Usage(ownerID)
+}
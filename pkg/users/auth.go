@@ -1,8 +1,9 @@
 package users
 
 import (
-	"bytes"
 	"encoding/hex"
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,7 +11,12 @@ import (
 
 // setAuthCache will store the user object in cache
 func (s *Service) setAuthCache(token string, user *User) error {
-	return s.cache.Set(token, user, time.Hour*24)
+	err := s.cache.Set(token, user, authExpiry)
+	if err != nil {
+		return err
+	}
+	activeSessions.add(token, time.Now().Add(authExpiry))
+	return nil
 }
 
 func (s *Service) getAuthCache(token string) (*User, error) {
@@ -22,6 +28,17 @@ func (s *Service) getAuthCache(token string) (*User, error) {
 	return &user, nil
 }
 
+// TokenSalt returns the salt binding the auth tokens to the client making the request, for all
+// the APIs. It's the IP address of the client without the port, since every new connection of
+// the same client has a different port.
+func TokenSalt(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
 // cacheAuthToken will store the authtoken in cache after mixing it with salt
 func cacheAuthToken(token string, salt string) string {
 	return string(hash(token, salt))
@@ -34,15 +51,22 @@ func authToken(user *User) string {
 
 // Authenticate authenticates a user and returns the user instance along with the auth token
 func (s *Service) Authenticate(email, password, tokenSalt string) (*User, error) {
+	span := s.trace("Authenticate")
+	defer span.End()
 	user, err := s.Read(email)
 	if err != nil {
+		if err == ErrUsrNotExists {
+			loginFailures.Inc(loginUnknownUser)
+		}
 		return nil, err
 	}
 
-	pwdHash := hash(password, user.Salt)
-	savedPwdHash := user.Password
-	if !bytes.Equal(pwdHash, savedPwdHash) {
-		return nil, ErrInvPwd
+	dataKey, err := user.checkPassword(password)
+	if err != nil {
+		if err == ErrInvCredentials {
+			loginFailures.Inc(loginWrongPassword)
+		}
+		return nil, err
 	}
 
 	user.AuthToken = authToken(user)
@@ -50,6 +74,20 @@ func (s *Service) Authenticate(email, password, tokenSalt string) (*User, error)
 	if err != nil {
 		return nil, err
 	}
+	if user.KeyVersion != currentKeyVersion {
+		dataKey, err = s.upgradeKeys(user, password, dataKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = user.setDataKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	err = s.ensureKeyPair(user)
+	if err != nil {
+		return nil, err
+	}
 	err = s.setAuthCache(cacheAuthToken(user.AuthToken, tokenSalt), user)
 	if err != nil {
 		return nil, err
@@ -60,5 +98,53 @@ func (s *Service) Authenticate(email, password, tokenSalt string) (*User, error)
 
 // AuthUser returns an authenticated user instance from the auth token
 func (s *Service) AuthUser(authToken string, tokenSalt string) (*User, error) {
+	span := s.trace("AuthUser")
+	defer span.End()
 	return s.getAuthCache(cacheAuthToken(authToken, tokenSalt))
 }
+
+// RefreshToken replaces the auth token with a new one, valid for another 24 hours. The old
+// token stops working once the new one is issued.
+func (s *Service) RefreshToken(token string, tokenSalt string) (*User, error) {
+	span := s.trace("RefreshToken")
+	defer span.End()
+	oldKey := cacheAuthToken(token, tokenSalt)
+	user, err := s.getAuthCache(oldKey)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+	user.AuthToken = token
+
+	password, err := user.passwordStr()
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, ErrNotAuthenticated
+	}
+	dataKey, err := user.dataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	user.AuthToken = authToken(user)
+	err = user.setEncryptedPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	err = user.setDataKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	err = s.setAuthCache(cacheAuthToken(user.AuthToken, tokenSalt), user)
+	if err != nil {
+		return nil, err
+	}
+
+	activeSessions.remove(oldKey)
+	err = s.cache.Delete(oldKey)
+	if err != nil {
+		s.logger.Error("refresh token", user.ID, err.Error())
+	}
+	return user, nil
+}
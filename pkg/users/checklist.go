@@ -0,0 +1,54 @@
+package users
+
+import (
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+// AddChecklistEntry adds a new entry to the checklist of an item owned by the user, at the
+// given position. A negative position adds it at the end.
+func (s *Service) AddChecklistEntry(user *User, itemID string, text string, position int) (*items.Item, error) {
+	span := s.trace("AddChecklistEntry")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		_, err := item.AddEntry(text, position)
+		return err
+	})
+}
+
+// UpdateChecklistEntry updates the text and/or the checked state of a checklist entry
+func (s *Service) UpdateChecklistEntry(user *User, itemID, entryID string, text *string, checked *bool) (*items.Item, error) {
+	span := s.trace("UpdateChecklistEntry")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		_, err := item.UpdateEntry(entryID, text, checked)
+		return err
+	})
+}
+
+// ToggleChecklistEntry flips the checked state of a checklist entry
+func (s *Service) ToggleChecklistEntry(user *User, itemID, entryID string) (*items.Item, error) {
+	span := s.trace("ToggleChecklistEntry")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		_, err := item.ToggleEntry(entryID)
+		return err
+	})
+}
+
+// RemoveChecklistEntry removes an entry from the checklist of an item owned by the user
+func (s *Service) RemoveChecklistEntry(user *User, itemID, entryID string) (*items.Item, error) {
+	span := s.trace("RemoveChecklistEntry")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		return item.RemoveEntry(entryID)
+	})
+}
+
+// ReorderChecklist sets the order of the checklist entries of an item owned by the user
+func (s *Service) ReorderChecklist(user *User, itemID string, entryIDs []string) (*items.Item, error) {
+	span := s.trace("ReorderChecklist")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		return item.ReorderEntries(entryIDs)
+	})
+}
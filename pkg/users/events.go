@@ -0,0 +1,48 @@
+package users
+
+import (
+	"context"
+
+	"github.com/bnkamalesh/notes/pkg/events"
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+// Events returns the events of the items owned by, or shared with, the user until the context is
+// done. If lastID is not 0, the events after it are sent first.
+func (s *Service) Events(ctx context.Context, user *User, lastID int64) (<-chan events.Event, error) {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+	return s.events.Subscribe(ctx, []string{"owner:" + ownerID, "user:" + user.ID}, lastID)
+}
+
+// eventKeys returns the keys of the subscribers to the events of the item, its owner and the
+// users it's shared with
+func (s *Service) eventKeys(item *items.Item) []string {
+	keys := []string{"owner:" + item.OwnerID}
+	shares, err := s.shares.ByItem(item.ID)
+	if err != nil {
+		s.logger.Error("events", item.ID, err.Error())
+		return keys
+	}
+	for _, sh := range shares {
+		keys = append(keys, "user:"+sh.RecipientID)
+	}
+	return keys
+}
+
+// publishEvent notifies the subscribers and the webhooks of the owner of a change of the item.
+// The item should be decrypted, except when it's deleted. The version is the sequence of the
+// change. The change is already saved, so errors are only logged.
+func (s *Service) publishEvent(typ string, item *items.Item, version int64, keys []string) {
+	err := s.events.Publish(typ, item.ID, version, keys...)
+	if err != nil {
+		s.logger.Error("events", item.ID, err.Error())
+	}
+
+	err = s.webhooks.Enqueue(item.OwnerID, typ, item, version)
+	if err != nil {
+		s.logger.Error("webhooks", item.ID, err.Error())
+	}
+}
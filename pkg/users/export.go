@@ -0,0 +1,104 @@
+package users
+
+import (
+	"io"
+
+	"github.com/bnkamalesh/notes/pkg/archive"
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+// exportPageSize is the number of items read from the store at a time while exporting
+const exportPageSize = 50
+
+// Export writes an archive of all the items owned by the user, along with their attachments,
+// to w. The items are read a page at a time, so the whole account is never held in memory. If
+// the passphrase is not empty, the archive is encrypted with it.
+func (s *Service) Export(user *User, w io.Writer, passphrase string) error {
+	span := s.trace("Export")
+	defer span.End()
+	err := s.export(user, w, passphrase)
+	if err != nil {
+		s.logger.Error("export", user.ID, err.Error())
+	}
+	return err
+}
+
+func (s *Service) export(user *User, w io.Writer, passphrase string) error {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := user.dataKey()
+	if err != nil {
+		return err
+	}
+
+	aw, err := archive.NewWriter(w, passphrase)
+	if err != nil {
+		return err
+	}
+
+	opts := items.ListOptions{
+		Sort:  items.SortCreated,
+		Limit: exportPageSize,
+	}
+	for {
+		page, err := s.items.List(ownerID, opts)
+		if err != nil {
+			return err
+		}
+
+		for idx := range page.Items {
+			err = s.exportItem(aw, dataKey, &page.Items[idx])
+			if err != nil {
+				return err
+			}
+		}
+
+		if page.Next == "" {
+			break
+		}
+		opts.After = page.Next
+	}
+
+	return aw.Close()
+}
+
+// exportItem adds a single item and all its attachments to the archive
+func (s *Service) exportItem(aw *archive.Writer, dataKey [32]byte, item *items.Item) error {
+	key, err := itemKey(dataKey, item)
+	if err != nil {
+		return err
+	}
+
+	err = s.decrypt(item, key)
+	if err != nil {
+		return err
+	}
+
+	err = aw.AddItem(item)
+	if err != nil {
+		return err
+	}
+
+	aa, err := s.attachments.ByItem(item.ID)
+	if err != nil {
+		return err
+	}
+
+	for idx := range aa {
+		a := &aa[idx]
+		r, err := s.attachments.Open(a, key)
+		if err != nil {
+			return err
+		}
+
+		err = aw.AddAttachment(a, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package users
+
+import (
+	"github.com/bnkamalesh/notes/pkg/importer"
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+// importProgressEvery is the number of notes imported between progress updates
+const importProgressEvery = 25
+
+// Import creates an item for every note in the source, preserving the original timestamps and
+// tags. Notes which fail are listed in the report and do not stop the import. If dryRun is
+// true, the notes are only parsed and validated. progress, if not nil, is called with the
+// report every few notes.
+func (s *Service) Import(user *User, src importer.Source, format string, dryRun bool, progress func(*importer.Report)) (*importer.Report, error) {
+	span := s.trace("Import")
+	defer span.End()
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+
+	parser, format, err := importer.ParserFor(format, src)
+	if err != nil {
+		return nil, err
+	}
+
+	report := importer.NewReport(format, dryRun)
+	err = parser.Parse(src, func(n importer.Note) error {
+		itemID, err := s.importNote(user, ownerID, n, dryRun)
+		report.Add(n, itemID, err)
+		if progress != nil && report.Total%importProgressEvery == 0 {
+			progress(report)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("import", user.ID, err.Error())
+		return report, err
+	}
+	return report, nil
+}
+
+// importNote creates an item from the note, and returns its ID
+func (s *Service) importNote(user *User, ownerID string, n importer.Note, dryRun bool) (string, error) {
+	if n.Err != nil {
+		return "", n.Err
+	}
+
+	item, err := items.New(map[string]string{"title": n.Title, "description": n.Description}, ownerID)
+	if err != nil {
+		return "", err
+	}
+
+	item.Tags = items.NormalizeTags(n.Tags)
+	for _, e := range n.Checklist {
+		_, err = item.AddEntry(e.Text, -1)
+		if err != nil {
+			// entries without text are dropped
+			continue
+		}
+		item.Checklist[len(item.Checklist)-1].Checked = e.Checked
+	}
+
+	if n.DueAt != nil {
+		dueAt := n.DueAt.UTC()
+		item.DueAt = &dueAt
+	}
+	if n.CreatedAt != nil {
+		createdAt := n.CreatedAt.UTC()
+		item.CreatedAt = &createdAt
+		item.ModifiedAt = &createdAt
+	}
+	if n.ModifiedAt != nil {
+		modifiedAt := n.ModifiedAt.UTC()
+		item.ModifiedAt = &modifiedAt
+	}
+	if item.ModifiedAt.Before(*item.CreatedAt) {
+		item.ModifiedAt = item.CreatedAt
+	}
+
+	if dryRun {
+		return "", nil
+	}
+
+	item, err = s.createItem(user, item)
+	if err != nil {
+		return "", err
+	}
+	return item.ID, nil
+}
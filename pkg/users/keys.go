@@ -0,0 +1,211 @@
+package users
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/subtle"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+)
+
+const (
+	// legacyKeyVersion is the version of the users whose password hash and data key were built
+	// from the password and the salt as is, see legacyHash
+	legacyKeyVersion = 0
+	// currentKeyVersion is the version of the users whose password hash and data key are derived
+	// from a master key, itself derived from the password with PBKDF2
+	currentKeyVersion = 1
+
+	verifierInfo = "notes password verifier"
+	dataKeyInfo  = "notes data key"
+	sessionInfo  = "notes session key"
+)
+
+// masterKey derives the master key of the user from the password. It's slow on purpose, so it's
+// derived only when the user signs up or logs in.
+func masterKey(password, salt string) ([crypto.KeySize]byte, error) {
+	return crypto.PasswordKey(password, []byte(salt))
+}
+
+// subKey derives a key for a single purpose from the key
+func subKey(key []byte, salt, info string) ([crypto.KeySize]byte, error) {
+	var k [crypto.KeySize]byte
+	b, err := hkdf.Key(sha256.New, key, []byte(salt), info, crypto.KeySize)
+	if err != nil {
+		return k, err
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// passwordKeys returns the password hash and the data key of the user, as of the version
+func (u *User) passwordKeys(password string, version int) ([]byte, [crypto.KeySize]byte, error) {
+	if version == legacyKeyVersion {
+		var dataKey [crypto.KeySize]byte
+		copy(dataKey[:], legacyHash(password, u.Salt))
+		return legacyHash(password, u.Salt), dataKey, nil
+	}
+
+	master, err := masterKey(password, u.Salt)
+	if err != nil {
+		return nil, master, err
+	}
+	verifier, err := subKey(master[:], u.Salt, verifierInfo)
+	if err != nil {
+		return nil, master, err
+	}
+	dataKey, err := subKey(master[:], u.Salt, dataKeyInfo)
+	if err != nil {
+		return nil, master, err
+	}
+	return verifier[:], dataKey, nil
+}
+
+// checkPassword returns the data key of the user if the password is correct
+func (u *User) checkPassword(password string) ([crypto.KeySize]byte, error) {
+	verifier, dataKey, err := u.passwordKeys(password, u.KeyVersion)
+	if err != nil {
+		return dataKey, err
+	}
+	if subtle.ConstantTimeCompare(verifier, u.Password) != 1 {
+		return [crypto.KeySize]byte{}, ErrInvCredentials
+	}
+	return dataKey, nil
+}
+
+// sessionKey returns the key encrypting the data key in the session, derived from the auth token
+func (u *User) sessionKey() ([crypto.KeySize]byte, error) {
+	if u.AuthToken == "" {
+		return [crypto.KeySize]byte{}, ErrNotAuthenticated
+	}
+	return subKey([]byte(u.AuthToken), u.Salt, sessionInfo)
+}
+
+// setDataKey encrypts the data key for the session of the auth token
+func (u *User) setDataKey(key [crypto.KeySize]byte) error {
+	sk, err := u.sessionKey()
+	if err != nil {
+		return err
+	}
+	u.EncryptedDataKey, err = crypto.WrapKey(sk, key)
+	return err
+}
+
+// dataKey returns the key used for encrypting and decrypting all the data of the user. It's
+// derived from the password when the user logs in, and kept in the session encrypted with a key
+// derived from the auth token.
+func (u *User) dataKey() ([crypto.KeySize]byte, error) {
+	sk, err := u.sessionKey()
+	if err != nil {
+		return sk, err
+	}
+	key, err := crypto.UnwrapKey(sk, u.EncryptedDataKey)
+	if err != nil {
+		return key, ErrNotAuthenticated
+	}
+	return key, nil
+}
+
+// upgradeKeys moves a user of an older key version to the current one: everything encrypted
+// with the old data key is wrapped with the new one, and the search index, which is keyed by
+// the data key, is rebuilt. Every item is saved on its own and skipped if it's done already, so
+// that it can be done again if it fails midway; the version of the user is saved last.
+func (s *Service) upgradeKeys(user *User, password string, oldKey [crypto.KeySize]byte) ([crypto.KeySize]byte, error) {
+	verifier, newKey, err := user.passwordKeys(password, currentKeyVersion)
+	if err != nil {
+		return newKey, err
+	}
+
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return newKey, err
+	}
+
+	opts := items.ListOptions{
+		Sort:  items.SortCreated,
+		Limit: exportPageSize,
+	}
+	for {
+		page, err := s.items.List(ownerID, opts)
+		if err != nil {
+			return newKey, err
+		}
+
+		for idx := range page.Items {
+			err = s.upgradeItemKey(&page.Items[idx], oldKey, newKey)
+			if err != nil {
+				return newKey, err
+			}
+		}
+
+		if page.Next == "" {
+			break
+		}
+		opts.After = page.Next
+	}
+
+	if len(user.PrivateKey) != 0 {
+		private, err := crypto.Open(oldKey, user.PrivateKey)
+		if err != nil {
+			return newKey, err
+		}
+		user.PrivateKey, err = crypto.Seal(newKey, private)
+		if err != nil {
+			return newKey, err
+		}
+	}
+
+	user.Password = verifier
+	user.KeyVersion = currentKeyVersion
+	err = s.store.Update(userBucket, map[string]interface{}{"id": user.ID}, user)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return newKey, err
+	}
+	return newKey, nil
+}
+
+// upgradeItemKey wraps the content key of the item with the new data key, and indexes the item
+// with it. Items created before content keys were introduced get the old data key as their
+// content key, so their content is not encrypted again.
+func (s *Service) upgradeItemKey(item *items.Item, oldKey, newKey [crypto.KeySize]byte) error {
+	if len(item.Key) != 0 {
+		_, err := crypto.UnwrapKey(newKey, item.Key)
+		if err == nil {
+			return nil
+		}
+	}
+
+	contentKey, err := itemKey(oldKey, item)
+	if err != nil {
+		return err
+	}
+	item.Key, err = crypto.WrapKey(newKey, contentKey)
+	if err != nil {
+		return err
+	}
+	if len(item.PrevKey) != 0 {
+		prevKey, err := crypto.UnwrapKey(oldKey, item.PrevKey)
+		if err != nil {
+			return err
+		}
+		item.PrevKey, err = crypto.WrapKey(newKey, prevKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	encrypted := *item
+	err = s.decrypt(item, contentKey)
+	if err != nil {
+		return err
+	}
+	err = s.search.Index(newKey, item.OwnerID, item.ID, item.Title, item.Text())
+	if err != nil {
+		return err
+	}
+
+	_, err = s.items.Update(item.ID, encrypted)
+	return err
+}
@@ -0,0 +1,113 @@
+package users
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+)
+
+func TestPasswordKeys(t *testing.T) {
+	u, err := New(map[string]string{"email": "jane@example.com", "password": "password"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if u.KeyVersion != currentKeyVersion || bytes.Contains(u.Password, []byte("password")) {
+		t.Fatalf("Expected the password to be hashed, got '%s'", u.Password)
+	}
+
+	dataKey, err := u.checkPassword("password")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if bytes.Contains(dataKey[:], []byte("password")) || bytes.Equal(dataKey[:], u.Password[:crypto.KeySize]) {
+		t.Fatal("Expected the data key to be derived from the password")
+	}
+	_, err = u.checkPassword("wrong")
+	if err != ErrInvCredentials {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvCredentials, err)
+	}
+}
+
+func TestUpgradeKeys(t *testing.T) {
+	s := memService(t)
+	legacy := User{
+		ID:    newUserID(),
+		Email: "jane@example.com",
+		Salt:  "salt",
+	}
+	legacy.Password, _, _ = legacy.passwordKeys("password", legacyKeyVersion)
+	_, err := s.Create(legacy)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// an item encrypted with the legacy data key, and one with a content key wrapped with it
+	var oldKey [crypto.KeySize]byte
+	copy(oldKey[:], legacyHash("password", "salt"))
+	ownerID := hex.EncodeToString(legacyHash(legacy.Email, "password"))
+	contentKey, _ := crypto.NewKey()
+	for _, key := range [][crypto.KeySize]byte{oldKey, contentKey} {
+		item, err := items.New(map[string]string{"title": "Runbook"}, ownerID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if key != oldKey {
+			item.Key, _ = crypto.WrapKey(oldKey, key)
+		}
+		err = s.search.Index(oldKey, ownerID, item.ID, item.Title, item.Text())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		err = item.Encrypt(key)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_, err = s.items.Create(*item)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	_, err = s.Authenticate(legacy.Email, "wrong", "")
+	if err != ErrInvCredentials {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvCredentials, err)
+	}
+	user, err := s.Authenticate(legacy.Email, "password", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	stored, err := s.Read(legacy.Email)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if stored.KeyVersion != currentKeyVersion || bytes.Contains(stored.Password, []byte("password")) {
+		t.Fatalf("Expected the user to be upgraded, got %+v", stored)
+	}
+
+	found, err := s.SearchItems(user, "runbook", 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected the items to be indexed with the new data key, got %d", len(found))
+	}
+	for _, item := range found {
+		read, err := s.Item(user, item.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if read.Title != "Runbook" {
+			t.Fatalf("Expected the item to be kept, got %+v", read)
+		}
+	}
+
+	// the upgraded user logs in again
+	_, err = s.Authenticate(legacy.Email, "password", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,63 @@
+package users
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/metrics"
+)
+
+const (
+	// authExpiry is the duration an auth token is valid for
+	authExpiry = time.Hour * 24
+
+	loginUnknownUser   = "unknownUser"
+	loginWrongPassword = "wrongPassword"
+)
+
+var (
+	loginFailures = metrics.NewCounter(
+		"notes_login_failures_total",
+		"Number of logins which failed, by reason",
+		"reason",
+	)
+
+	activeSessions = &sessions{expiry: make(map[string]time.Time)}
+	_              = metrics.NewGaugeFunc(
+		"notes_sessions_active",
+		"Number of unexpired sessions started on this instance",
+		activeSessions.count,
+	)
+)
+
+// sessions keeps the expiry of the auth tokens issued by the instance. Only the cache keys of
+// the tokens are kept, and not the tokens.
+type sessions struct {
+	mu     sync.Mutex
+	expiry map[string]time.Time
+}
+
+func (s *sessions) add(key string, expiry time.Time) {
+	s.mu.Lock()
+	s.expiry[key] = expiry
+	s.mu.Unlock()
+}
+
+func (s *sessions) remove(key string) {
+	s.mu.Lock()
+	delete(s.expiry, key)
+	s.mu.Unlock()
+}
+
+// count removes the expired sessions and returns the number of sessions left
+func (s *sessions) count() float64 {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, expiry := range s.expiry {
+		if now.After(expiry) {
+			delete(s.expiry, key)
+		}
+	}
+	return float64(len(s.expiry))
+}
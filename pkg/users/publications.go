@@ -0,0 +1,70 @@
+package users
+
+import (
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/publications"
+)
+
+// PublishItem publishes a snapshot of an item owned by the user at a public link. The password
+// and expiry are optional.
+func (s *Service) PublishItem(user *User, itemID, password string, expiresAt *time.Time) (*publications.Published, error) {
+	span := s.trace("PublishItem")
+	defer span.End()
+	acc, err := s.ownerAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := acc.item
+	err = s.decrypt(item, acc.key)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := publications.New(item, password, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.publications.Create(*published.Publication)
+	if err != nil {
+		return nil, err
+	}
+	return published, nil
+}
+
+// Publications returns all the publications of an item owned by the user
+func (s *Service) Publications(user *User, itemID string) ([]publications.Publication, error) {
+	span := s.trace("Publications")
+	defer span.End()
+	_, _, err := s.ownedItem(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return s.publications.ByItem(itemID)
+}
+
+// UnpublishItem removes a publication of an item owned by the user
+func (s *Service) UnpublishItem(user *User, itemID, publicationID string) (*publications.Publication, error) {
+	span := s.trace("UnpublishItem")
+	defer span.End()
+	_, _, err := s.ownedItem(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.publications.Read(publicationID)
+	if err != nil {
+		return nil, err
+	}
+	if p.ItemID != itemID {
+		return nil, publications.ErrNotFound
+	}
+
+	err = s.publications.Delete(publicationID)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,82 @@
+package users
+
+import (
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+)
+
+// ownedItem returns the item if it's owned by the user, along with the owner ID
+func (s *Service) ownedItem(user *User, itemID string) (*items.Item, string, error) {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	item, err := s.items.Read(itemID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if item.OwnerID != ownerID {
+		return nil, "", ErrUnauthorized
+	}
+	return item, ownerID, nil
+}
+
+// AddReminder adds a reminder to an item owned by the user. If the time is not provided, the
+// reminder is set at the due date of the item.
+func (s *Service) AddReminder(user *User, itemID string, at time.Time, recurrence, channel, target string) (*reminders.Reminder, error) {
+	span := s.trace("AddReminder")
+	defer span.End()
+	item, ownerID, err := s.ownedItem(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if at.IsZero() && item.DueAt != nil {
+		at = *item.DueAt
+	}
+
+	r, err := reminders.New(ownerID, itemID, at, recurrence, channel, target)
+	if err != nil {
+		return nil, err
+	}
+	return s.reminders.Create(*r)
+}
+
+// Reminders returns all the reminders of an item owned by the user
+func (s *Service) Reminders(user *User, itemID string) ([]reminders.Reminder, error) {
+	span := s.trace("Reminders")
+	defer span.End()
+	_, _, err := s.ownedItem(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return s.reminders.List(itemID)
+}
+
+// DeleteReminder deletes a reminder of an item owned by the user
+func (s *Service) DeleteReminder(user *User, itemID, reminderID string) (*reminders.Reminder, error) {
+	span := s.trace("DeleteReminder")
+	defer span.End()
+	_, _, err := s.ownedItem(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.reminders.Read(reminderID)
+	if err != nil {
+		return nil, err
+	}
+	if r.ItemID != itemID {
+		return nil, reminders.ErrNotFound
+	}
+
+	err = s.reminders.Delete(reminderID)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
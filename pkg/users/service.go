@@ -1,26 +1,84 @@
 package users
 
 import (
+	"context"
+
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/events"
 	"github.com/bnkamalesh/notes/pkg/items"
 	"github.com/bnkamalesh/notes/pkg/platform/cache"
 	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/platform/tracing"
+	"github.com/bnkamalesh/notes/pkg/publications"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+	"github.com/bnkamalesh/notes/pkg/search"
+	"github.com/bnkamalesh/notes/pkg/shares"
+	"github.com/bnkamalesh/notes/pkg/webhooks"
 )
 
 // Service holds all the dependencies of items
 type Service struct {
-	store  storage.Service
-	cache  cache.Service
-	items  items.Service
-	logger logger.Service
+	store        storage.Service
+	cache        cache.Service
+	items        items.Service
+	logger       logger.Service
+	search       search.Service
+	reminders    reminders.Service
+	shares       shares.Service
+	publications publications.Service
+	attachments  attachments.Service
+	events       events.Service
+	webhooks     webhooks.Service
+	// ctx has the span of the trace the operations are part of
+	ctx context.Context
 }
 
 // NewService returns a new instance of Service with all the dependencies initialized
-func NewService(ss storage.Service, cs cache.Service, l logger.Service, i items.Service) Service {
+func NewService(ss storage.Service, cs cache.Service, l logger.Service, i items.Service, srch search.Service, rs reminders.Service, shs shares.Service, ps publications.Service, as attachments.Service, es events.Service, ws webhooks.Service) Service {
 	return Service{
-		store:  ss,
-		cache:  cs,
-		logger: l,
-		items:  i,
+		store:        ss,
+		cache:        cs,
+		logger:       l,
+		items:        i,
+		search:       srch,
+		reminders:    rs,
+		shares:       shs,
+		publications: ps,
+		attachments:  as,
+		events:       es,
+		webhooks:     ws,
 	}
 }
+
+// WithContext returns a copy of the service tracing its operations as part of the trace in the
+// context, and adding its trace ID to the logs
+func (s Service) WithContext(ctx context.Context) Service {
+	s.ctx = ctx
+	s.store = storage.WithContext(ctx, s.store)
+	s.cache = cache.WithContext(ctx, s.cache)
+	s.items = s.items.WithContext(ctx)
+	s.logger = logger.FromContext(ctx, s.logger)
+	return s
+}
+
+// trace starts the span of the operation. The service is not copied for every operation, so
+// the operations it calls are traced alongside it, under the span the service was created with.
+func (s *Service) trace(operation string) *tracing.Span {
+	_, span := tracing.Start(s.ctx, "users."+operation, tracing.KindInternal)
+	return span
+}
+
+// encrypt encrypts the item with the key, in a span of its own
+func (s *Service) encrypt(item *items.Item, key [32]byte) error {
+	_, span := tracing.Start(s.ctx, "users.encrypt", tracing.KindInternal)
+	defer span.End()
+	return item.Encrypt(key)
+}
+
+// decrypt decrypts the item with the key, in a span of its own
+func (s *Service) decrypt(item *items.Item, key [32]byte) error {
+	_, span := tracing.Start(s.ctx, "users.decrypt", tracing.KindInternal)
+	defer span.End()
+	return item.Decrypt(key)
+}
@@ -0,0 +1,453 @@
+package users
+
+import (
+	"bytes"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	"github.com/bnkamalesh/notes/pkg/shares"
+)
+
+var (
+	// ErrNoPublicKey is returned when sharing with a user who does not have a key pair yet.
+	// Key pairs are generated when the user logs in.
+	ErrNoPublicKey = errs.Invalid("Sorry, the user has to login at least once before items can be shared with them")
+	// ErrShareSelf is returned when the owner tries to share an item with themselves
+	ErrShareSelf = errs.Invalid("Sorry, you cannot share an item with yourself")
+)
+
+// access is the access a user has on an item, along with the item's content key
+type access struct {
+	item *items.Item
+	// key is the content key of the item
+	key [crypto.KeySize]byte
+	// owner is true if the user owns the item
+	owner bool
+	// share is the share of the item with the user, it's nil for the owner
+	share *shares.Share
+}
+
+func (a *access) canWrite() bool {
+	return a.owner || (a.share != nil && a.share.CanWrite())
+}
+
+// privateKey returns the decrypted X25519 private key of the user
+func (u *User) privateKey() ([]byte, error) {
+	key, err := u.dataKey()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Open(key, u.PrivateKey)
+}
+
+// setKeyPair generates a new X25519 key pair for the user. The private key is encrypted with
+// the user's data key
+func (u *User) setKeyPair() error {
+	key, err := u.dataKey()
+	if err != nil {
+		return err
+	}
+
+	public, private, err := crypto.NewKeyPair()
+	if err != nil {
+		return err
+	}
+
+	wrapped, err := crypto.Seal(key, private)
+	if err != nil {
+		return err
+	}
+
+	u.PublicKey = public
+	u.PrivateKey = wrapped
+	return nil
+}
+
+// ensureKeyPair generates and saves a key pair for an authenticated user who does not have one
+func (s *Service) ensureKeyPair(user *User) error {
+	if len(user.PublicKey) != 0 {
+		return nil
+	}
+
+	err := user.setKeyPair()
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Update(userBucket, map[string]interface{}{"id": user.ID}, user)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// readByID reads a user given the user ID
+func (s *Service) readByID(id string) (*User, error) {
+	user := User{}
+	_, err := s.store.FindOne(
+		userBucket,
+		map[string]interface{}{
+			"id": id,
+		},
+		nil,
+		nil,
+		&user)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrUsrNotExists
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &user, nil
+}
+
+// itemKey returns the content key of an item, given the data key of its owner
+func itemKey(dataKey [crypto.KeySize]byte, item *items.Item) ([crypto.KeySize]byte, error) {
+	if len(item.Key) == 0 {
+		return dataKey, nil
+	}
+	return crypto.UnwrapKey(dataKey, item.Key)
+}
+
+// itemAccess returns the access the user has on the item, if the user either owns the item or
+// the item was shared with the user
+func (s *Service) itemAccess(user *User, itemID string) (*access, error) {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.items.Read(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := user.dataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if item.OwnerID == ownerID {
+		key, err := itemKey(dataKey, item)
+		if err != nil {
+			return nil, err
+		}
+		acc := &access{item: item, key: key, owner: true}
+		if len(item.PrevKey) > 0 {
+			// the rotation of the key failed midway, it's finished by the owner
+			err = s.rewrapItemKey(user, acc)
+			if err != nil {
+				s.logger.Warn("item", item.ID, "the rotation of the key could not be finished:", err.Error())
+			}
+		}
+		return acc, nil
+	}
+
+	share, err := s.shares.Find(itemID, user.ID)
+	if err != nil {
+		if err == shares.ErrNotFound {
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	private, err := user.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := crypto.OpenFrom(private, share.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	acc := &access{item: item, owner: false, share: share}
+	copy(acc.key[:], b)
+	return acc, nil
+}
+
+// ownerAccess returns the access on the item only if the user owns it
+func (s *Service) ownerAccess(user *User, itemID string) (*access, error) {
+	acc, err := s.itemAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.owner {
+		return nil, ErrUnauthorized
+	}
+	return acc, nil
+}
+
+// setItemKey encrypts the item with a new content key, and wraps the key for the owner and all
+// the recipients of the item. The item is saved along with its previous key, so that the
+// attachments and the shares not rewrapped yet can still be, if it fails midway. The rotation
+// holds the lock of the sequence and saves the item as a change, so the edits and the uploads
+// started with the previous key fail instead of bringing it back.
+func (s *Service) setItemKey(user *User, acc *access) error {
+	last, unlock, err := s.lockSeq(acc.item.OwnerID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	item, err := s.currentItem(acc)
+	if err != nil {
+		return err
+	}
+
+	err = s.decrypt(item, acc.key)
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := user.dataKey()
+	if err != nil {
+		return err
+	}
+
+	contentKey, err := crypto.NewKey()
+	if err != nil {
+		return err
+	}
+
+	if len(item.PrevKey) == 0 {
+		// the previous key of an unfinished rotation is kept, some attachments may still be
+		// wrapped with it
+		item.PrevKey, err = crypto.WrapKey(dataKey, acc.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	item.Key, err = crypto.WrapKey(dataKey, contentKey)
+	if err != nil {
+		return err
+	}
+
+	err = s.encrypt(item, contentKey)
+	if err != nil {
+		return err
+	}
+
+	item.Seq = last + 1
+	_, err = s.items.Update(item.ID, *item)
+	if err != nil {
+		return err
+	}
+	acc.key = contentKey
+
+	return s.rewrapKeys(user, acc)
+}
+
+// currentItem reads the item again once the lock of the sequence is taken, and returns
+// ErrItemChanged if its key was rotated since the access was checked
+func (s *Service) currentItem(acc *access) (*items.Item, error) {
+	item, err := s.items.Read(acc.item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(item.Key, acc.item.Key) {
+		return nil, ErrItemChanged
+	}
+	acc.item = item
+	return item, nil
+}
+
+// rewrapItemKey finishes the rotation of the key of the item, if it failed midway
+func (s *Service) rewrapItemKey(user *User, acc *access) error {
+	_, unlock, err := s.lockSeq(acc.item.OwnerID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	item, err := s.currentItem(acc)
+	if err != nil {
+		return err
+	}
+	if len(item.PrevKey) == 0 {
+		// finished by another request
+		return nil
+	}
+	return s.rewrapKeys(user, acc)
+}
+
+// rewrapKeys wraps the keys of the attachments and the shares of the item with its current
+// content key, and then drops the previous key of the item. The lock of the sequence should be
+// held by the caller.
+func (s *Service) rewrapKeys(user *User, acc *access) error {
+	item := acc.item
+	dataKey, err := user.dataKey()
+	if err != nil {
+		return err
+	}
+
+	prevKey, err := crypto.UnwrapKey(dataKey, item.PrevKey)
+	if err != nil {
+		return err
+	}
+
+	err = s.attachments.Rekey(item.ID, prevKey, acc.key)
+	if err != nil {
+		return err
+	}
+
+	ss, err := s.shares.ByItem(item.ID)
+	if err != nil {
+		return err
+	}
+
+	for idx := range ss {
+		share := &ss[idx]
+		recipient, err := s.readByID(share.RecipientID)
+		if err != nil {
+			if err == ErrUsrNotExists {
+				s.shares.Delete(share.ID)
+				continue
+			}
+			return err
+		}
+
+		share.Key, err = crypto.SealTo(recipient.PublicKey, acc.key[:])
+		if err != nil {
+			return err
+		}
+
+		err = s.shares.Update(share)
+		if err != nil {
+			return err
+		}
+	}
+
+	item.PrevKey = nil
+	_, err = s.items.Update(item.ID, *item)
+	return err
+}
+
+// ShareItem shares an item owned by the user with another user, identified by email
+func (s *Service) ShareItem(user *User, itemID, email, permission string) (*shares.Share, error) {
+	span := s.trace("ShareItem")
+	defer span.End()
+	acc, err := s.ownerAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient, err := s.Read(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if recipient.ID == user.ID {
+		return nil, ErrShareSelf
+	}
+
+	if len(recipient.PublicKey) == 0 {
+		return nil, ErrNoPublicKey
+	}
+
+	if len(acc.item.Key) == 0 {
+		// items created before content keys were introduced are encrypted with the data key,
+		// which cannot be shared
+		err = s.setItemKey(user, acc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := s.shares.Find(itemID, recipient.ID)
+	if err != nil && err != shares.ErrNotFound {
+		return nil, err
+	}
+
+	key, err := crypto.SealTo(recipient.PublicKey, acc.key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	share, err := shares.New(acc.item.OwnerID, itemID, recipient.ID, recipient.Email, permission, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		// sharing again with the same recipient updates the permission
+		existing.Permission = share.Permission
+		existing.Key = share.Key
+		err = s.shares.Update(existing)
+		if err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	return s.shares.Create(*share)
+}
+
+// ItemShares returns all the shares of an item owned by the user
+func (s *Service) ItemShares(user *User, itemID string) ([]shares.Share, error) {
+	span := s.trace("ItemShares")
+	defer span.End()
+	_, err := s.ownerAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return s.shares.ByItem(itemID)
+}
+
+// RevokeShare removes the access of a recipient to an item owned by the user. The content key
+// of the item is rotated, so the recipient cannot read the item even with a copy of the old key.
+func (s *Service) RevokeShare(user *User, itemID, shareID string) (*shares.Share, error) {
+	span := s.trace("RevokeShare")
+	defer span.End()
+	acc, err := s.ownerAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	share, err := s.shares.Read(shareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.ItemID != itemID {
+		return nil, shares.ErrNotFound
+	}
+
+	err = s.shares.Delete(shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setItemKey(user, acc)
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// SharedItems returns all the items shared with the user
+func (s *Service) SharedItems(user *User) ([]items.Item, error) {
+	span := s.trace("SharedItems")
+	defer span.End()
+	ss, err := s.shares.ByRecipient(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ii := make([]items.Item, 0, len(ss))
+	for _, share := range ss {
+		i, err := s.items.Read(share.ItemID)
+		if err != nil {
+			if err == items.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		ii = append(ii, *i)
+	}
+	return ii, nil
+}
@@ -0,0 +1,364 @@
+package users
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+	"github.com/bnkamalesh/notes/pkg/shares"
+)
+
+// failingStore fails the updates of a bucket while it's set
+type failingStore struct {
+	storage.Service
+	bucket string
+}
+
+func (f *failingStore) Update(bucket string, query interface{}, data interface{}) error {
+	if bucket == f.bucket {
+		return errors.New("update failed")
+	}
+	return f.Service.Update(bucket, query, data)
+}
+
+func TestSetItemKeyFailure(t *testing.T) {
+	for _, bucket := range []string{"attachments", "shares", "items"} {
+		store := &failingStore{Service: storagemem.New()}
+		s := newMemService(t, store)
+		owner := authUser(t, s, "jane@example.com")
+		john := authUser(t, s, "john@example.com")
+		ann := authUser(t, s, "ann@example.com")
+
+		item, err := s.CreateItem(owner, map[string]string{"title": "Runbook"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		a, err := s.AddAttachment(owner, item.ID, "notes.txt", "text/plain", strings.NewReader("attached"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		revoked, err := s.ShareItem(owner, item.ID, "john@example.com", "read")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_, err = s.ShareItem(owner, item.ID, "ann@example.com", "read")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		store.bucket = bucket
+		_, err = s.RevokeShare(owner, item.ID, revoked.ID)
+		if err == nil {
+			t.Fatalf("Expected the revoke to fail when the %s cannot be updated", bucket)
+		}
+		store.bucket = ""
+
+		// the owner finishes the rotation when reading the item
+		read, err := s.Item(owner, item.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if read.Title != "Runbook" {
+			t.Fatalf("Expected the item to be kept, got %+v", read)
+		}
+		stored, err := s.items.Read(item.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(stored.PrevKey) != 0 {
+			t.Fatalf("Expected the rotation to be finished after the %s failed", bucket)
+		}
+
+		_, r, err := s.OpenAttachment(owner, item.ID, a.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(b) != "attached" {
+			t.Fatalf("Expected the attachment to be kept after the %s failed, got '%s' %v", bucket, b, err)
+		}
+
+		read, err = s.Item(ann, item.ID)
+		if err != nil || read.Title != "Runbook" {
+			t.Fatalf("Expected the remaining recipient to read the item after the %s failed, got %v", bucket, err)
+		}
+		_, err = s.Item(john, item.ID)
+		if err != ErrUnauthorized {
+			t.Fatalf("Expected %v, got %v", ErrUnauthorized, err)
+		}
+	}
+}
+
+func TestShareItem(t *testing.T) {
+	s := memService(t)
+	owner := authUser(t, s, "jane@example.com")
+	john := authUser(t, s, "john@example.com")
+
+	item, err := s.CreateItem(owner, map[string]string{"title": "Runbook", "description": "restart the server"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = s.ShareItem(owner, item.ID, "jane@example.com", shares.PermissionRead)
+	if err != ErrShareSelf {
+		t.Fatalf("Expected '%v', got '%v'", ErrShareSelf, err)
+	}
+	_, err = s.ShareItem(john, item.ID, "jane@example.com", shares.PermissionRead)
+	if err != ErrUnauthorized {
+		t.Fatalf("Expected '%v', got '%v'", ErrUnauthorized, err)
+	}
+
+	share, err := s.ShareItem(owner, item.ID, "john@example.com", shares.PermissionRead)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	read, err := s.Item(john, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if read.Description != "restart the server" {
+		t.Fatalf("Expected the recipient to read the item, got %+v", read)
+	}
+	shared, err := s.SharedItems(john)
+	if err != nil || len(shared) != 1 || shared[0].ID != item.ID {
+		t.Fatalf("Expected the item to be shared with the recipient, got %+v %v", shared, err)
+	}
+	_, err = s.UpdateItem(john, item.ID, map[string]string{"title": "Runbook", "description": "reboot"})
+	if err != ErrUnauthorized {
+		t.Fatalf("Expected '%v', got '%v'", ErrUnauthorized, err)
+	}
+
+	// sharing again with the same recipient updates the permission
+	updated, err := s.ShareItem(owner, item.ID, "john@example.com", shares.PermissionWrite)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if updated.ID != share.ID || !updated.CanWrite() {
+		t.Fatalf("Expected the share to be updated, got %+v", updated)
+	}
+	ss, err := s.ItemShares(owner, item.ID)
+	if err != nil || len(ss) != 1 {
+		t.Fatalf("Expected 1 share, got %d %v", len(ss), err)
+	}
+}
+
+func TestUpdateSharedItem(t *testing.T) {
+	s := memService(t)
+	owner := authUser(t, s, "jane@example.com")
+	john := authUser(t, s, "john@example.com")
+
+	item, err := s.CreateItem(owner, map[string]string{"title": "Runbook", "description": "restart the server"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.ShareItem(owner, item.ID, "john@example.com", shares.PermissionWrite)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = s.UpdateItem(john, item.ID, map[string]string{"title": "Playbook", "description": "reboot the cluster"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	read, err := s.Item(owner, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if read.Title != "Playbook" || read.Description != "reboot the cluster" {
+		t.Fatalf("Expected the owner to read the changes of the recipient, got %+v", read)
+	}
+
+	// the changes of the recipient are indexed with the owner's data key
+	found, err := s.SearchItems(owner, "cluster", 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(found) != 1 || found[0].ID != item.ID {
+		t.Fatalf("Expected the item to be found with the words of the recipient, got %+v", found)
+	}
+	found, err = s.SearchItems(owner, "restart", 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(found) != 0 {
+		t.Fatalf("Expected the previous words to be removed from the index, got %+v", found)
+	}
+	stored, err := s.items.Read(item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if stored.Unindexed || stored.Title != "Playbook" {
+		t.Fatalf("Expected the item to be indexed and kept, got %+v", stored)
+	}
+}
+
+func TestRevokeShare(t *testing.T) {
+	s := memService(t)
+	owner := authUser(t, s, "jane@example.com")
+	john := authUser(t, s, "john@example.com")
+	ann := authUser(t, s, "ann@example.com")
+
+	item, err := s.CreateItem(owner, map[string]string{"title": "Runbook", "description": "restart the server"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	revoked, err := s.ShareItem(owner, item.ID, "john@example.com", shares.PermissionRead)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.ShareItem(owner, item.ID, "ann@example.com", shares.PermissionRead)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the recipient keeps a copy of the content key
+	acc, err := s.itemAccess(john, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	oldKey := acc.key
+	before, err := s.items.Read(item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = s.RevokeShare(john, item.ID, revoked.ID)
+	if err != ErrUnauthorized {
+		t.Fatalf("Expected '%v', got '%v'", ErrUnauthorized, err)
+	}
+	_, err = s.RevokeShare(owner, item.ID, revoked.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = s.Item(john, item.ID)
+	if err != ErrUnauthorized {
+		t.Fatalf("Expected '%v', got '%v'", ErrUnauthorized, err)
+	}
+	after, err := s.items.Read(item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if bytes.Equal(after.Key, before.Key) || len(after.PrevKey) != 0 {
+		t.Fatalf("Expected the content key to be rotated, got %+v", after)
+	}
+	err = after.Decrypt(oldKey)
+	if err == nil {
+		t.Fatal("Expected the item not to be decrypted with the revoked key")
+	}
+
+	for _, user := range []*User{owner, ann} {
+		read, err := s.Item(user, item.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if read.Description != "restart the server" {
+			t.Fatalf("Expected %s to read the item after the rotation, got %+v", user.Email, read)
+		}
+	}
+	ss, err := s.ItemShares(owner, item.ID)
+	if err != nil || len(ss) != 1 || ss[0].RecipientID != ann.ID {
+		t.Fatalf("Expected only the remaining share, got %+v %v", ss, err)
+	}
+}
+
+// revokingReader revokes a share the first time it's read
+type revokingReader struct {
+	io.Reader
+	revoke func()
+}
+
+func (r *revokingReader) Read(p []byte) (int, error) {
+	if r.revoke != nil {
+		r.revoke()
+		r.revoke = nil
+	}
+	return r.Reader.Read(p)
+}
+
+func TestRevokeShareDuringSave(t *testing.T) {
+	s := memService(t)
+	owner := authUser(t, s, "jane@example.com")
+	john := authUser(t, s, "john@example.com")
+	ann := authUser(t, s, "ann@example.com")
+
+	item, err := s.CreateItem(owner, map[string]string{"title": "Runbook", "description": "restart the server"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	revoked, err := s.ShareItem(owner, item.ID, "john@example.com", shares.PermissionWrite)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.ShareItem(owner, item.ID, "ann@example.com", shares.PermissionWrite)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	acc, err := s.itemAccess(john, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	oldKey := acc.key
+
+	// the share is revoked after the recipient read the item, but before the edit is saved
+	_, err = s.editItem(john, item.ID, func(i *items.Item) error {
+		_, err := s.RevokeShare(owner, item.ID, revoked.ID)
+		if err != nil {
+			return err
+		}
+		i.Description = "delete the server"
+		return nil
+	})
+	if err != ErrItemChanged {
+		t.Fatalf("Expected '%v', got '%v'", ErrItemChanged, err)
+	}
+
+	stored, err := s.items.Read(item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = stored.Decrypt(oldKey)
+	if err == nil {
+		t.Fatal("Expected the item not to be decrypted with the revoked key")
+	}
+	read, err := s.Item(owner, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if read.Description != "restart the server" {
+		t.Fatalf("Expected the edit not to be saved, got %+v", read)
+	}
+
+	// the upload started before the rotation is wrapped with the revoked key
+	other := authUser(t, s, "tom@example.com")
+	revoked, err = s.ShareItem(owner, item.ID, other.Email, shares.PermissionRead)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r := &revokingReader{
+		Reader: strings.NewReader("attached"),
+		revoke: func() {
+			_, err := s.RevokeShare(owner, item.ID, revoked.ID)
+			if err != nil {
+				t.Error(err.Error())
+			}
+		},
+	}
+	_, err = s.AddAttachment(ann, item.ID, "notes.txt", "text/plain", r)
+	if err != ErrItemChanged {
+		t.Fatalf("Expected '%v', got '%v'", ErrItemChanged, err)
+	}
+	aa, err := s.Attachments(owner, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(aa) != 0 {
+		t.Fatalf("Expected the attachment to be removed, got %+v", aa)
+	}
+}
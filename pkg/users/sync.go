@@ -0,0 +1,365 @@
+package users
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/items"
+)
+
+const (
+	// SyncCreate creates a new item
+	SyncCreate = "create"
+	// SyncUpdate replaces the content of an item
+	SyncUpdate = "update"
+	// SyncDelete deletes an item
+	SyncDelete = "delete"
+
+	// SyncApplied is the status of a change which was saved
+	SyncApplied = "applied"
+	// SyncConflict is the status of a change made on a version of the item which is not the
+	// latest. The item is not changed, an update is saved as a copy of the item instead.
+	SyncConflict = "conflict"
+	// SyncFailed is the status of a change which could not be saved
+	SyncFailed = "failed"
+
+	// maxSyncChanges is the maximum number of changes synced in a single request
+	maxSyncChanges = 100
+	// conflictSuffix is appended to the title of the copies of conflicting items
+	conflictSuffix = " (conflict copy)"
+	// syncFailedMessage is sent instead of the message of internal errors
+	syncFailedMessage = "Sorry, the change could not be saved"
+
+	// seqLockExpiry is the expiry of the lock of the sequence of an owner, in case it's not
+	// released
+	seqLockExpiry = 5 * time.Second
+	// seqLockWait is how long a change waits for the lock of the sequence
+	seqLockWait = 2 * time.Second
+	// anySeq removes an item regardless of its sequence, see deleteItem
+	anySeq = -1
+)
+
+var (
+	// ErrTooManyChanges is returned if too many changes are synced in a single request
+	ErrTooManyChanges = errs.Invalid("Sorry, at most 100 changes can be synced at once")
+	// ErrSyncOp is returned if the operation of a change is not supported
+	ErrSyncOp = errs.Field("op", "Sorry, the operation should be create, update or delete")
+	// ErrItemChanged is returned if an item was changed by another request while being edited
+	ErrItemChanged = errs.Conflict("Sorry, the item was changed by another request, please retry")
+	// ErrSeqLocked is returned if the items of the owner are being changed by other requests
+	// for too long
+	ErrSeqLocked = errs.Conflict("Sorry, the items are being changed by another request, please retry")
+)
+
+// SyncChange is a change of an item made by a client, while it may have been offline
+type SyncChange struct {
+	// Op is create, update or delete
+	Op string `json:"op"`
+	// ID is the ID of the item to update or delete
+	ID string `json:"id,omitempty"`
+	// ClientID is the ID the client gave to an item it created, sent back with the result
+	ClientID string `json:"clientID,omitempty"`
+	// BaseSeq is the sequence of the item the client changed
+	BaseSeq int64 `json:"baseSeq,omitempty"`
+	// Item is the content of the created or updated item, with the title, description,
+	// checklist, tags and due date. The fields which are not provided are cleared.
+	Item map[string]interface{} `json:"item,omitempty"`
+}
+
+// SyncResult is the result of a change made by a client
+type SyncResult struct {
+	Op       string `json:"op"`
+	ID       string `json:"id,omitempty"`
+	ClientID string `json:"clientID,omitempty"`
+	// Status is applied, conflict or failed
+	Status string `json:"status"`
+	// Item is the item saved, the copy of the item if there was a conflict while updating, or
+	// the latest version of the item if there was a conflict while deleting
+	Item *items.Item `json:"item,omitempty"`
+	// ConflictID is the ID of the copy of the item saved if there was a conflict
+	ConflictID string `json:"conflictID,omitempty"`
+	// Error is the reason the change failed
+	Error string `json:"error,omitempty"`
+}
+
+func seqLockKey(ownerID string) string {
+	return "items:seq:lock:" + ownerID
+}
+
+// lockSeq locks the changes of the items of the owner, and returns the sequence of the last
+// change. Changes are saved one at a time, so that the clients never miss a change saved after
+// they synced with a higher sequence. unlock should be called once the change is saved, it
+// releases the lock only if it was not taken by another change after it expired.
+func (s *Service) lockSeq(ownerID string) (int64, func(), error) {
+	key := seqLockKey(ownerID)
+	token := uuid.New().String()
+	deadline := time.Now().Add(seqLockWait)
+	for {
+		locked, err := s.cache.SetNX(key, token, seqLockExpiry)
+		if err != nil {
+			return 0, nil, err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return 0, nil, ErrSeqLocked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	unlock := func() {
+		released, err := s.cache.DeleteIf(key, token)
+		if err != nil {
+			s.logger.Error("sync", ownerID, err.Error())
+			return
+		}
+		if !released {
+			s.logger.Warn("sync", ownerID, "the lock of the sequence expired before it was released")
+		}
+	}
+	last, err := s.items.LastSeq(ownerID)
+	if err != nil {
+		unlock()
+		return 0, nil, err
+	}
+	return last, unlock, nil
+}
+
+// sequenceItems sets the sequences of the items of the owner saved before sequences were
+// introduced, so that they're synced as well
+func (s *Service) sequenceItems(ownerID string) error {
+	unsequenced, err := s.items.Unsequenced(ownerID)
+	if err != nil || len(unsequenced) == 0 {
+		return err
+	}
+
+	last, unlock, err := s.lockSeq(ownerID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for idx := range unsequenced {
+		last++
+		err = s.items.Sequence(&unsequenced[idx], last)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Changes returns the changes of the items owned by the user after the sequence, with the items
+// decrypted. The sequence returned is the one to fetch the next changes from.
+func (s *Service) Changes(user *User, since int64, limit int) (*items.Changes, error) {
+	span := s.trace("Changes")
+	defer span.End()
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := user.dataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.sequenceItems(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.items.Changes(ownerID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes.Changes {
+		if c.Item == nil {
+			continue
+		}
+		key, err := itemKey(dataKey, c.Item)
+		if err != nil {
+			return nil, err
+		}
+		err = s.decrypt(c.Item, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// Sync saves the changes made by a client to the items owned by the user, in order. Changes
+// made on an older version of an item are not applied: an update is saved as a copy of the
+// item, and a deletion is ignored. The result of every change is returned, even if some fail.
+func (s *Service) Sync(user *User, changes []SyncChange) ([]SyncResult, error) {
+	span := s.trace("Sync")
+	defer span.End()
+	if len(changes) > maxSyncChanges {
+		return nil, ErrTooManyChanges
+	}
+
+	results := make([]SyncResult, 0, len(changes))
+	for _, c := range changes {
+		var result *SyncResult
+		var err error
+		switch c.Op {
+		case SyncCreate:
+			result, err = s.syncCreate(user, c)
+		case SyncUpdate:
+			result, err = s.syncUpdate(user, c)
+		case SyncDelete:
+			result, err = s.syncDelete(user, c)
+		default:
+			err = ErrSyncOp
+		}
+
+		if err != nil {
+			message := err.Error()
+			if errs.KindOf(err) == errs.KindInternal {
+				s.logger.Error("sync", user.ID, c.ID, err.Error())
+				message = syncFailedMessage
+			}
+			result = &SyncResult{Status: SyncFailed, Error: message}
+		}
+		result.Op, result.ClientID = c.Op, c.ClientID
+		if result.ID == "" {
+			result.ID = c.ID
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// newSyncItem returns a new item of the user with the content sent by the client
+func (s *Service) newSyncItem(user *User, content map[string]interface{}) (*items.Item, error) {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+	item, err := items.New(map[string]string{}, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	err = item.SetContent(content)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *Service) syncCreate(user *User, c SyncChange) (*SyncResult, error) {
+	item, err := s.newSyncItem(user, c.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	description, checklist, tags := item.Description, item.Checklist, item.Tags
+	item, err = s.createItem(user, item)
+	if err != nil {
+		return nil, err
+	}
+	item.Description, item.Checklist, item.Tags = description, checklist, tags
+	return &SyncResult{Status: SyncApplied, ID: item.ID, Item: item}, nil
+}
+
+// conflictCopy saves the content sent by the client as a new item
+func (s *Service) conflictCopy(user *User, c SyncChange) (*SyncResult, error) {
+	item, err := s.newSyncItem(user, c.Item)
+	if err != nil {
+		return nil, err
+	}
+	item.Title += conflictSuffix
+
+	description, checklist, tags := item.Description, item.Checklist, item.Tags
+	item, err = s.createItem(user, item)
+	if err != nil {
+		return nil, err
+	}
+	item.Description, item.Checklist, item.Tags = description, checklist, tags
+	return &SyncResult{Status: SyncConflict, Item: item, ConflictID: item.ID}, nil
+}
+
+func (s *Service) syncUpdate(user *User, c SyncChange) (*SyncResult, error) {
+	acc, err := s.ownerAccess(user, c.ID)
+	if err == items.ErrNotFound {
+		if !s.deletedItem(user, c.ID) {
+			return nil, err
+		}
+		// the item was deleted while the client was changing it
+		return s.conflictCopy(user, c)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item := acc.item
+	if item.Seq != c.BaseSeq {
+		return s.conflictCopy(user, c)
+	}
+
+	err = s.decrypt(item, acc.key)
+	if err != nil {
+		return nil, err
+	}
+	err = item.SetContent(c.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err = s.saveItem(user, acc, item)
+	if err == ErrItemChanged {
+		return s.conflictCopy(user, c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SyncResult{Status: SyncApplied, Item: item}, nil
+}
+
+// deletedItem returns true if the item was owned by the user and deleted
+func (s *Service) deletedItem(user *User, itemID string) bool {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return false
+	}
+	t, err := s.items.ReadTombstone(itemID)
+	if err != nil {
+		return false
+	}
+	return t.OwnerID == ownerID
+}
+
+func (s *Service) syncDelete(user *User, c SyncChange) (*SyncResult, error) {
+	acc, err := s.ownerAccess(user, c.ID)
+	if err == items.ErrNotFound {
+		// already deleted
+		return &SyncResult{Status: SyncApplied}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.deleteItem(user, c.ID, c.BaseSeq)
+	if err == ErrItemChanged {
+		// the item was changed while the client was deleting it, it's kept and sent back so
+		// that the client restores it
+		acc, err = s.ownerAccess(user, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = s.decrypt(acc.item, acc.key)
+		if err != nil {
+			return nil, err
+		}
+		return &SyncResult{Status: SyncConflict, Item: acc.item}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SyncResult{Status: SyncApplied}, nil
+}
@@ -0,0 +1,94 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+func TestLockSeq(t *testing.T) {
+	s := memService(t)
+	_, unlock, err := s.lockSeq("owner")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, _, err = s.lockSeq("owner")
+	if err != ErrSeqLocked {
+		t.Fatalf("Expected '%v' while locked, got '%v'", ErrSeqLocked, err)
+	}
+
+	// the lock expires while it's held, and another change takes it
+	key := seqLockKey("owner")
+	s.cache.Delete(key)
+	locked, _ := s.cache.SetNX(key, "other", seqLockExpiry)
+	if !locked {
+		t.Fatal("Expected the lock to be taken")
+	}
+	unlock()
+	token := ""
+	err = s.cache.Get(key, &token)
+	if err != nil || token != "other" {
+		t.Fatalf("Expected the lock of the other change to be kept, got '%s' '%v'", token, err)
+	}
+
+	s.cache.Delete(key)
+	_, unlock, err = s.lockSeq("owner")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	unlock()
+	_, unlock, err = s.lockSeq("owner")
+	if err != nil {
+		t.Fatalf("Expected the lock to be released, got '%v'", err)
+	}
+	unlock()
+}
+
+// hookStore calls the hook once, after the next record of the bucket is read
+type hookStore struct {
+	storage.Service
+	bucket string
+	hook   func()
+}
+
+func (h *hookStore) FindOne(bucket string, query, selectFields interface{}, sort []string, result interface{}) (map[string]interface{}, error) {
+	out, err := h.Service.FindOne(bucket, query, selectFields, sort, result)
+	if bucket == h.bucket && h.hook != nil {
+		hook := h.hook
+		h.hook = nil
+		hook()
+	}
+	return out, err
+}
+
+func TestSyncDeleteChanged(t *testing.T) {
+	store := &hookStore{Service: storagemem.New(), bucket: "items"}
+	s := newMemService(t, store)
+	user := authUser(t, s, "jane@example.com")
+
+	item, err := s.CreateItem(user, map[string]string{"title": "Runbook", "description": "restart the server"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the item is updated right after the deletion read it
+	store.hook = func() {
+		_, err := s.UpdateItem(user, item.ID, map[string]string{"title": "Runbook", "description": "restart both servers"})
+		if err != nil {
+			t.Error(err.Error())
+		}
+	}
+	results, err := s.Sync(user, []SyncChange{{Op: SyncDelete, ID: item.ID, BaseSeq: item.Seq}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r := results[0]
+	if r.Status != SyncConflict || r.Item == nil || r.Item.Description != "restart both servers" {
+		t.Fatalf("Expected the updated item to be kept as a conflict, got %+v", r)
+	}
+	_, err = s.Item(user, item.ID)
+	if err != nil {
+		t.Fatalf("Expected the item to be kept, got '%v'", err)
+	}
+}
@@ -2,6 +2,7 @@
 package users
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -15,7 +16,10 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/events"
 	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
 )
 
@@ -24,23 +28,23 @@ const (
 )
 
 var (
-	hasher = sha512.New()
-
 	// ErrEmail is returned when the email address provided is wrong
-	ErrEmail = errors.New("Invalid or no email provided")
+	ErrEmail = errs.Field("email", "Invalid or no email provided")
 	// ErrCreate is returned if there's an error creating new user
 	ErrCreate = errors.New("Sorry, an error occurred while creating new user")
 	// ErrInvPwd is returned if the password is invalid
-	ErrInvPwd = errors.New("Sorry, invalid or no password provided")
+	ErrInvPwd = errs.Field("password", "Sorry, invalid or no password provided")
+	// ErrInvCredentials is returned when trying to login with a wrong password
+	ErrInvCredentials = errs.Unauthorized("Sorry, the email or password is incorrect")
 	// ErrUsrNotExists is returned when trying to login with an non-registered email
-	ErrUsrNotExists = errors.New("Sorry, there's no user registered with that email")
+	ErrUsrNotExists = errs.NotFound("Sorry, there's no user registered with that email")
 	// ErrUsrExists is returned when trying to create a user with the same email
-	ErrUsrExists = errors.New("Sorry, user with that email already exists")
+	ErrUsrExists = errs.Conflict("Sorry, user with that email already exists")
 	// ErrNotAuthenticated is returned when the user is not authenticated and trying to perform
 	// an action which requires authentication
-	ErrNotAuthenticated = errors.New("Sorry, the user is not authenticated")
+	ErrNotAuthenticated = errs.Unauthorized("Sorry, the user is not authenticated")
 	// ErrUnauthorized is returned whenever the user tries to perform an unauthorized action
-	ErrUnauthorized = errors.New("Sorry, you're not authorized to perform this action")
+	ErrUnauthorized = errs.Forbidden("Sorry, you're not authorized to perform this action")
 	// ErrMalformedCipher is returned when the cipher text is invalid and cannot be used
 	ErrMalformedCipher = errors.New("malformed ciphertext")
 )
@@ -53,37 +57,53 @@ func newUserID() string {
 func New(data map[string]string) (*User, error) {
 	email := strings.TrimSpace(data["email"])
 	password := data["password"]
+	var errEmail, errPassword error
 	if email == "" {
-		return nil, ErrEmail
+		errEmail = ErrEmail
 	}
 	if password == "" {
-		return nil, ErrInvPwd
+		errPassword = ErrInvPwd
+	}
+	err := errs.Validation(errEmail, errPassword)
+	if err != nil {
+		return nil, err
 	}
-	hashSalt := uuid.New().String()
 	now := time.Now()
 	user := &User{
 		ID:         newUserID(),
 		Name:       data["name"],
 		Email:      email,
-		Salt:       hashSalt,
-		Password:   hash(password, hashSalt),
+		Salt:       uuid.New().String(),
+		KeyVersion: currentKeyVersion,
 		CreatedAt:  &now,
 		ModifiedAt: nil,
 	}
+	user.Password, _, err = user.passwordKeys(password, currentKeyVersion)
+	if err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
 // User struct holds all the user details
 type User struct {
-	ID                string     `json:"id,omitempty" bson:"id,omitempty"`
-	Name              string     `json:"name,omitempty" bson:"name,omitempty"`
-	Email             string     `json:"email,omitempty" bson:"email,omitempty"`
-	Password          []byte     `json:"-" bson:"password,omitempty"`
-	Salt              string     `json:"-" bson:"salt,omitempty"`
-	AuthToken         string     `bson:"-" json:"authToken,omitempty"`
-	EncryptedPassword []byte     `bson:"-" json:"-"`
-	CreatedAt         *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
-	ModifiedAt        *time.Time `json:"modifiedAt,omitempty" bson:"modifiedAt,omitempty"`
+	ID                string `json:"id,omitempty" bson:"id,omitempty"`
+	Name              string `json:"name,omitempty" bson:"name,omitempty"`
+	Email             string `json:"email,omitempty" bson:"email,omitempty"`
+	Password          []byte `json:"-" bson:"password,omitempty"`
+	Salt              string `json:"-" bson:"salt,omitempty"`
+	AuthToken         string `bson:"-" json:"authToken,omitempty"`
+	EncryptedPassword []byte `bson:"-" json:"-"`
+	// PublicKey is the X25519 public key of the user, used to share items with the user
+	PublicKey []byte `json:"publicKey,omitempty" bson:"publicKey,omitempty"`
+	// PrivateKey is the X25519 private key of the user, encrypted with the user's data key
+	PrivateKey []byte     `json:"-" bson:"privateKey,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty" bson:"modifiedAt,omitempty"`
+	// KeyVersion is how the password hash and the data key are derived from the password
+	KeyVersion int `json:"-" bson:"keyVersion,omitempty"`
+	// EncryptedDataKey is the data key encrypted with a key derived from the auth token
+	EncryptedDataKey []byte `bson:"-" json:"-"`
 }
 
 func (u *User) ownerID() (string, error) {
@@ -91,7 +111,9 @@ func (u *User) ownerID() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return hex.EncodeToString(hash(u.Email, password)), nil
+	// the owner IDs already stored were built from the email and the password as is, see
+	// legacyHash
+	return hex.EncodeToString(legacyHash(u.Email, password)), nil
 }
 
 // passwordStr returns the password of user in clear-text based on the authentication token
@@ -127,7 +149,7 @@ func (u *User) passwordStr() (string, error) {
 		nil,
 	)
 	if err != nil {
-		return "", nil
+		return "", ErrNotAuthenticated
 	}
 	return string(str), nil
 }
@@ -166,13 +188,22 @@ func (u *User) encryptionKey(key, salt string) ([32]byte, error) {
 	return bk, nil
 }
 
-// hash accepts a string and returns a SHA512 hashed string
+// hash returns the SHA-512 digest of the string and the salt
 func hash(str string, salt string) []byte {
-	return hasher.Sum([]byte(str + salt))
+	h := sha512.Sum512([]byte(str + salt))
+	return h[:]
+}
+
+// legacyHash returns the string and the salt followed by the SHA-512 digest of nothing, which
+// is what hash used to return. It's kept only to read the values stored before.
+func legacyHash(str string, salt string) []byte {
+	return sha512.New().Sum([]byte(str + salt))
 }
 
 // Create creates a new user
 func (s *Service) Create(user User) (*User, error) {
+	span := s.trace("Create")
+	defer span.End()
 	email := user.Email
 	_, err := s.Read(email)
 	if err != nil {
@@ -248,6 +279,8 @@ func (s *Service) Delete(user *User) (*User, error) {
 
 // CreateItem adds a new item owned by the user
 func (s *Service) CreateItem(user *User, data map[string]string) (*items.Item, error) {
+	span := s.trace("CreateItem")
+	defer span.End()
 	ownerID, err := user.ownerID()
 	if err != nil {
 		return nil, err
@@ -257,71 +290,164 @@ func (s *Service) CreateItem(user *User, data map[string]string) (*items.Item, e
 	if err != nil {
 		return nil, err
 	}
-	pwd, err := user.passwordStr()
+	return s.createItem(user, item)
+}
+
+// createItem indexes, encrypts and saves a new item owned by the user
+func (s *Service) createItem(user *User, item *items.Item) (*items.Item, error) {
+	ownerID := item.OwnerID
+	key, err := user.dataKey()
 	if err != nil {
 		return nil, err
 	}
 
-	key, err := user.encryptionKey(pwd, user.AuthToken)
+	err = s.search.Index(key, ownerID, item.ID, item.Title, item.Text())
 	if err != nil {
 		return nil, err
 	}
 
-	err = item.Encrypt(key)
+	contentKey, err := crypto.NewKey()
 	if err != nil {
 		return nil, err
 	}
 
-	return s.items.Create(*item)
-}
+	item.Key, err = crypto.WrapKey(key, contentKey)
+	if err != nil {
+		return nil, err
+	}
 
-// UpdateItem updates an item owned by the user
-func (s *Service) UpdateItem(user *User, itemID string, data map[string]string) (*items.Item, error) {
-	ownerID, err := user.ownerID()
+	description, checklist, tags := item.Description, item.Checklist, item.Tags
+	err = s.encrypt(item, contentKey)
 	if err != nil {
 		return nil, err
 	}
 
-	item, err := s.items.Read(itemID)
+	last, unlock, err := s.lockSeq(ownerID)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
 
-	if item.OwnerID != ownerID {
+	item.Seq = last + 1
+	item.CreatedSeq = item.Seq
+	created, err := s.items.Create(*item)
+	if err != nil {
+		return nil, err
+	}
+	plain := *created
+	plain.Description, plain.Checklist, plain.Tags = description, checklist, tags
+	s.publishEvent(events.ItemCreated, &plain, created.Seq, []string{"owner:" + ownerID})
+	return created, nil
+}
+
+// UpdateItem replaces the content of an item owned by, or shared with write permission to,
+// the user
+func (s *Service) UpdateItem(user *User, itemID string, data map[string]string) (*items.Item, error) {
+	span := s.trace("UpdateItem")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		return item.Replace(data)
+	})
+}
+
+// PatchItem applies a JSON Merge Patch on an item owned by, or shared with write permission
+// to, the user. Only the fields present in the patch are updated.
+func (s *Service) PatchItem(user *User, itemID string, patch map[string]interface{}) (*items.Item, error) {
+	span := s.trace("PatchItem")
+	defer span.End()
+	return s.editItem(user, itemID, func(item *items.Item) error {
+		return item.Patch(patch)
+	})
+}
+
+// editItem decrypts an item the user can write to, applies the edit and saves it
+func (s *Service) editItem(user *User, itemID string, edit func(item *items.Item) error) (*items.Item, error) {
+	acc, err := s.itemAccess(user, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !acc.canWrite() {
 		return nil, ErrUnauthorized
 	}
 
-	updatedItem, err := items.New(data, ownerID)
+	item := acc.item
+	err = s.decrypt(item, acc.key)
 	if err != nil {
 		return nil, err
 	}
-	updatedItem.ID = itemID
 
-	pwd, err := user.passwordStr()
+	err = edit(item)
 	if err != nil {
 		return nil, err
 	}
 
-	key, err := user.encryptionKey(pwd, user.AuthToken)
+	return s.saveItem(user, acc, item)
+}
+
+// saveItem encrypts and saves the decrypted item. The search index is keyed by the owner's
+// data key, so the items saved by a recipient are marked as unindexed, and indexed the next
+// time the owner searches.
+func (s *Service) saveItem(user *User, acc *access, item *items.Item) (*items.Item, error) {
+	item.Unindexed = !acc.owner
+	if acc.owner {
+		key, err := user.dataKey()
+		if err != nil {
+			return nil, err
+		}
+		err = s.search.Index(key, item.OwnerID, item.ID, item.Title, item.Text())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	description, checklist, tags := item.Description, item.Checklist, item.Tags
+	err := s.encrypt(item, acc.key)
+	if err != nil {
+		return nil, err
+	}
+
+	last, unlock, err := s.lockSeq(item.OwnerID)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
 
-	err = updatedItem.Encrypt(key)
+	// the item is saved only if it wasn't changed, nor its key rotated, since it was read
+	current, err := s.items.Read(item.ID)
 	if err != nil {
 		return nil, err
 	}
+	if current.Seq != item.Seq || !bytes.Equal(current.Key, item.Key) {
+		return nil, ErrItemChanged
+	}
+	// the previous key is dropped by the rotation without a new sequence
+	item.PrevKey = current.PrevKey
 
-	updatedItem, err = s.items.Update(itemID, *updatedItem)
+	item.Seq = last + 1
+	updated, err := s.items.Update(item.ID, *item)
 	if err != nil {
 		return nil, err
 	}
 
-	return updatedItem, err
+	updated.Description = description
+	updated.Checklist = checklist
+	updated.Tags = tags
+	s.publishEvent(events.ItemUpdated, updated, updated.Seq, s.eventKeys(updated))
+	return updated, nil
 }
 
 // DeleteItem removes an item owned by the user
 func (s *Service) DeleteItem(user *User, itemID string) (*items.Item, error) {
+	span := s.trace("DeleteItem")
+	defer span.End()
+	return s.deleteItem(user, itemID, anySeq)
+}
+
+// deleteItem removes an item owned by the user. Unless seq is anySeq, the item is removed only
+// if it's still at the sequence once the lock of the sequence is taken, otherwise
+// ErrItemChanged is returned.
+func (s *Service) deleteItem(user *User, itemID string, seq int64) (*items.Item, error) {
 	ownerID, err := user.ownerID()
 	if err != nil {
 		return nil, err
@@ -335,44 +461,189 @@ func (s *Service) DeleteItem(user *User, itemID string) (*items.Item, error) {
 	if item.OwnerID != ownerID {
 		return nil, ErrUnauthorized
 	}
+	// the shares are removed with the item, so the recipients are found first
+	keys := s.eventKeys(item)
 
+	last, unlock, err := s.lockSeq(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if seq != anySeq {
+		current, err := s.items.Read(itemID)
+		if err == nil && current.Seq != seq {
+			err = ErrItemChanged
+		}
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+	}
+	// the tombstone is saved first, so that the syncing clients don't miss the deletion
+	tombstone, err := s.items.CreateTombstone(item, last+1)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
 	item, err = s.items.Delete(itemID)
-	return item, err
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.search.Remove(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.reminders.DeleteByItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.shares.DeleteByItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.publications.DeleteByItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.attachments.DeleteByItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(events.ItemDeleted, item, tombstone.Seq, keys)
+	return item, nil
 }
 
-// Items returns list of items the user owns
-func (s *Service) Items(user *User, start, limit int) ([]items.Item, error) {
+// Items returns a page of items the user owns
+func (s *Service) Items(user *User, opts items.ListOptions) (*items.Page, error) {
+	span := s.trace("Items")
+	defer span.End()
 	ownerID, err := user.ownerID()
 	if err != nil {
 		return nil, err
 	}
-	ii, err := s.items.List(ownerID, start, limit)
+	page, err := s.items.List(ownerID, opts)
 	if err != nil {
 		return nil, err
 	}
-	return ii, nil
+	return page, nil
 }
 
-// Item returns a decrypted item
+// Item returns a decrypted item owned by, or shared with, the user
 func (s *Service) Item(user *User, itemID string) (*items.Item, error) {
-	i, err := s.items.Read(itemID)
+	span := s.trace("Item")
+	defer span.End()
+	acc, err := s.itemAccess(user, itemID)
 	if err != nil {
 		return nil, err
 	}
 
-	pwd, err := user.passwordStr()
+	i := acc.item
+	err = s.decrypt(i, acc.key)
 	if err != nil {
 		return nil, err
 	}
+	return i, nil
+}
 
-	key, err := user.encryptionKey(pwd, user.AuthToken)
+// SearchItems returns the items owned by the user matching all the words in the query,
+// ordered by relevance
+func (s *Service) SearchItems(user *User, q string, limit int) ([]items.Item, error) {
+	span := s.trace("SearchItems")
+	defer span.End()
+	ownerID, err := user.ownerID()
 	if err != nil {
 		return nil, err
 	}
 
-	err = i.Decrypt(key)
+	key, err := user.dataKey()
 	if err != nil {
 		return nil, err
 	}
-	return i, nil
+
+	err = s.indexItems(key, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.search.Search(key, ownerID, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ii := make([]items.Item, 0, len(results))
+	for _, r := range results {
+		i, err := s.items.Read(r.ItemID)
+		if err != nil {
+			if err == items.ErrNotFound {
+				// stale index entry, the item was removed without updating the index
+				continue
+			}
+			return nil, err
+		}
+		if i.OwnerID != ownerID {
+			continue
+		}
+		ii = append(ii, *i)
+	}
+	return ii, nil
+}
+
+// indexItems indexes the items of the owner changed by recipients since the owner last searched
+func (s *Service) indexItems(dataKey [crypto.KeySize]byte, ownerID string) error {
+	unindexed, err := s.items.Unindexed(ownerID)
+	if err != nil {
+		return err
+	}
+
+	for idx := range unindexed {
+		err = s.indexItem(dataKey, ownerID, unindexed[idx].ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexItem indexes an item changed by a recipient. It's read again while the sequence is
+// locked, so that a change saved meanwhile is not overwritten.
+func (s *Service) indexItem(dataKey [crypto.KeySize]byte, ownerID, itemID string) error {
+	_, unlock, err := s.lockSeq(ownerID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	item, err := s.items.Read(itemID)
+	if err != nil {
+		if err == items.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	if !item.Unindexed {
+		return nil
+	}
+
+	contentKey, err := itemKey(dataKey, item)
+	if err != nil {
+		return err
+	}
+	plain := *item
+	err = s.decrypt(&plain, contentKey)
+	if err != nil {
+		return err
+	}
+	err = s.search.Index(dataKey, ownerID, item.ID, plain.Title, plain.Text())
+	if err != nil {
+		return err
+	}
+
+	item.Unindexed = false
+	_, err = s.items.Update(item.ID, *item)
+	return err
 }
@@ -3,11 +3,21 @@ package users
 import (
 	"testing"
 
+	"github.com/bnkamalesh/notes/pkg/attachments"
+	"github.com/bnkamalesh/notes/pkg/events"
 	"github.com/bnkamalesh/notes/pkg/items"
 
+	"github.com/bnkamalesh/notes/pkg/platform/blob"
 	"github.com/bnkamalesh/notes/pkg/platform/cache"
 	"github.com/bnkamalesh/notes/pkg/platform/logger"
 	"github.com/bnkamalesh/notes/pkg/platform/storage"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+	"github.com/bnkamalesh/notes/pkg/publications"
+	"github.com/bnkamalesh/notes/pkg/reminders"
+	"github.com/bnkamalesh/notes/pkg/search"
+	"github.com/bnkamalesh/notes/pkg/shares"
+	"github.com/bnkamalesh/notes/pkg/testenv"
+	"github.com/bnkamalesh/notes/pkg/webhooks"
 )
 
 func service() (*Service, error) {
@@ -25,12 +35,68 @@ func service() (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
-	logHandler := logger.New()
+	logHandler := logger.New([]string{"all"})
 	iS := items.NewService(store, logHandler)
-	service := NewService(store, cache, logHandler, iS)
+	sS := search.NewService(store, logHandler)
+	rS := reminders.NewService(store, logHandler, reminders.Config{})
+	shS := shares.NewService(store, logHandler)
+	pS := publications.NewService(store, logHandler)
+	blobStore, err := blob.New(blob.Config{Driver: blob.DriverMemory})
+	if err != nil {
+		return nil, err
+	}
+	aS := attachments.NewService(store, blobStore, logHandler, attachments.Config{})
+	eS := events.NewService(cache, logHandler)
+	wS := webhooks.NewService(store, cache, logHandler, webhooks.Config{})
+	service := NewService(store, cache, logHandler, iS, sS, rS, shS, pS, aS, eS, wS)
 	return &service, nil
 }
 
+// memService returns a service with the data in memory, which does not need the database and
+// the cache to be running
+func memService(t *testing.T) *Service {
+	t.Helper()
+	return newMemService(t, storagemem.New())
+}
+
+func newMemService(t *testing.T, store storage.Service) *Service {
+	t.Helper()
+	env := testenv.New(t)
+	env.Store = store
+	service := NewService(
+		env.Store,
+		env.Cache,
+		env.Logger,
+		items.NewService(env.Store, env.Logger),
+		search.NewService(env.Store, env.Logger),
+		reminders.NewService(env.Store, env.Logger, reminders.Config{}),
+		shares.NewService(env.Store, env.Logger),
+		publications.NewService(env.Store, env.Logger),
+		attachments.NewService(env.Store, env.Blob, env.Logger, attachments.Config{}),
+		events.NewService(env.Cache, env.Logger),
+		webhooks.NewService(env.Store, env.Cache, env.Logger, webhooks.Config{}),
+	)
+	return &service
+}
+
+// authUser signs up and logs in a user with the email
+func authUser(t *testing.T, s *Service, email string) *User {
+	t.Helper()
+	u, err := New(map[string]string{"name": email, "email": email, "password": "password"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.Create(*u)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	user, err := s.Authenticate(email, "password", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return user
+}
+
 func newUser() (*User, map[string]string, error) {
 	payload := map[string]string{
 		"name":     "John Smith",
@@ -66,7 +132,7 @@ func TestCreate(t *testing.T) {
 
 	_, err = s.Delete(createdUsr)
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 }
 func TestRead(t *testing.T) {
@@ -100,7 +166,7 @@ func TestRead(t *testing.T) {
 
 	_, err = s.Delete(createdUsr)
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 }
 
@@ -135,7 +201,7 @@ func TestAuth(t *testing.T) {
 
 	_, err = s.Delete(createdUsr)
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 }
 func TestAddItem(t *testing.T) {
@@ -185,6 +251,33 @@ func TestAddItem(t *testing.T) {
 	}
 	_, err = s.Delete(createdUsr)
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
+	}
+}
+
+func TestUpdateItemChecklist(t *testing.T) {
+	s := memService(t)
+	user := authUser(t, s, "jane@example.com")
+
+	item, err := s.CreateItem(user, map[string]string{"title": "Groceries", "description": "weekly"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = s.AddChecklistEntry(user, item.ID, "milk", -1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// replacing the content of the item keeps its checklist
+	_, err = s.UpdateItem(user, item.ID, map[string]string{"title": "Shopping", "description": "monthly"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	read, err := s.Item(user, item.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if read.Title != "Shopping" || len(read.Checklist) != 1 || read.Checklist[0].Text != "milk" {
+		t.Fatalf("Expected the checklist to be kept, got %+v", read)
 	}
 }
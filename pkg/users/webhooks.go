@@ -0,0 +1,106 @@
+package users
+
+import (
+	"github.com/bnkamalesh/notes/pkg/webhooks"
+)
+
+// AddWebhook registers a webhook for the events of the items owned by the user. The signing
+// secret is returned only here.
+func (s *Service) AddWebhook(user *User, url string, types []string, includeContent bool) (*webhooks.Webhook, error) {
+	span := s.trace("AddWebhook")
+	defer span.End()
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := webhooks.New(ownerID, url, types, includeContent)
+	if err != nil {
+		return nil, err
+	}
+	return s.webhooks.Create(*w)
+}
+
+// Webhooks returns all the webhooks of the user, without their secrets
+func (s *Service) Webhooks(user *User) ([]webhooks.Webhook, error) {
+	span := s.trace("Webhooks")
+	defer span.End()
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+
+	ww, err := s.webhooks.List(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range ww {
+		ww[idx].Secret = ""
+	}
+	return ww, nil
+}
+
+// ownedWebhook returns the webhook if it's owned by the user
+func (s *Service) ownedWebhook(user *User, webhookID string) (*webhooks.Webhook, error) {
+	ownerID, err := user.ownerID()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := s.webhooks.Read(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if w.OwnerID != ownerID {
+		return nil, webhooks.ErrNotFound
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+// DeleteWebhook deletes a webhook of the user, along with its deliveries
+func (s *Service) DeleteWebhook(user *User, webhookID string) (*webhooks.Webhook, error) {
+	span := s.trace("DeleteWebhook")
+	defer span.End()
+	w, err := s.ownedWebhook(user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.webhooks.Delete(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WebhookDeliveries returns the latest deliveries of a webhook of the user, with the response
+// of every attempt
+func (s *Service) WebhookDeliveries(user *User, webhookID string) ([]webhooks.Delivery, error) {
+	span := s.trace("WebhookDeliveries")
+	defer span.End()
+	_, err := s.ownedWebhook(user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	return s.webhooks.Deliveries(webhookID)
+}
+
+// ReplayDelivery queues a delivery of a webhook of the user again
+func (s *Service) ReplayDelivery(user *User, webhookID, deliveryID string) (*webhooks.Delivery, error) {
+	span := s.trace("ReplayDelivery")
+	defer span.End()
+	_, err := s.ownedWebhook(user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := s.webhooks.ReadDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d.WebhookID != webhookID {
+		return nil, webhooks.ErrDeliveryNotFound
+	}
+	return s.webhooks.Replay(d)
+}
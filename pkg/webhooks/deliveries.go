@@ -0,0 +1,367 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/events"
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/crypto"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	deliveriesBucket = "webhookDeliveries"
+
+	// StatusPending is the status of a delivery which is yet to be delivered, or retried
+	StatusPending = "pending"
+	// StatusDelivered is the status of a delivery the webhook responded to with a 2xx status
+	StatusDelivered = "delivered"
+	// StatusFailed is the status of a delivery which failed after all the attempts
+	StatusFailed = "failed"
+
+	// maxAttempts is the number of attempts of a delivery before it fails
+	maxAttempts = 8
+	// retryDelay is the delay before the first retry, it's doubled for every retry after
+	retryDelay = 30 * time.Second
+	// maxRetryDelay is the maximum delay between two attempts
+	maxRetryDelay = time.Hour
+	// maxDue is the maximum number of deliveries attempted in a single run
+	maxDue = 100
+	// maxDeliveries is the maximum number of deliveries listed
+	maxDeliveries = 50
+	// contentExpiry is how long the key of the content of a delivery is kept in the cache. It's
+	// longer than all the attempts of a delivery, the content is delivered without the item
+	// once it expires.
+	contentExpiry = 24 * time.Hour
+)
+
+var (
+	// ErrDeliveryNotFound is returned if the delivery does not exist
+	ErrDeliveryNotFound = errs.NotFound("Sorry, delivery not found")
+)
+
+// Payload is the body of a delivery
+type Payload struct {
+	// ID is the ID of the event, it's the same for the replays of a delivery, so that the
+	// receivers can ignore the events they already received
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	ItemID  string    `json:"itemID"`
+	Version int64     `json:"version,omitempty"`
+	At      time.Time `json:"at"`
+	// Item is the decrypted item, only if the webhook includes the content
+	Item *items.Item `json:"item,omitempty"`
+}
+
+// Attempt is a single attempt of a delivery
+type Attempt struct {
+	At time.Time `json:"at" bson:"at"`
+	// StatusCode is the HTTP status of the response, 0 if there was no response
+	StatusCode int    `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// Delivery is an event to be delivered to a webhook. Deliveries are stored, so that they're
+// retried across restarts, and are kept as the log of the webhook.
+type Delivery struct {
+	ID        string `json:"id" bson:"id"`
+	WebhookID string `json:"webhookID" bson:"webhookID"`
+	OwnerID   string `json:"-" bson:"ownerID"`
+	EventID   string `json:"eventID" bson:"eventID"`
+	Type      string `json:"type" bson:"type"`
+	ItemID    string `json:"itemID" bson:"itemID"`
+	// Payload is the JSON body delivered, without the content of the item
+	Payload []byte `json:"-" bson:"payload"`
+	// Content is the JSON body delivered with the decrypted item, if the webhook includes the
+	// content. It's encrypted with a key of its own which is kept only in the cache, and it's
+	// removed once the delivery is delivered or failed.
+	Content []byte `json:"-" bson:"content,omitempty"`
+	// Status is pending, delivered or failed
+	Status   string    `json:"status" bson:"status"`
+	Attempts []Attempt `json:"attempts" bson:"attempts"`
+	// NextAttemptAt is the time of the next attempt of a pending delivery
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty" bson:"nextAttemptAt,omitempty"`
+	// ReplayOf is the ID of the delivery this delivery replays
+	ReplayOf  string     `json:"replayOf,omitempty" bson:"replayOf,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+}
+
+func newDeliveryID() string {
+	return fmt.Sprintf("delivery_%s", uuid.New().String())
+}
+
+func newEventID() string {
+	return fmt.Sprintf("event_%s", uuid.New().String())
+}
+
+// contentKey is the cache key of the key the content of the delivery is encrypted with
+func contentKey(deliveryID string) string {
+	return "webhooks:content:" + deliveryID
+}
+
+// done returns true if the delivery will not be attempted anymore
+func (d *Delivery) done() bool {
+	return d.Status == StatusDelivered || d.Status == StatusFailed
+}
+
+// record adds the attempt to the delivery, and schedules the next one if it failed
+func (d *Delivery) record(a Attempt) {
+	d.Attempts = append(d.Attempts, a)
+	if a.Error == "" {
+		d.Status = StatusDelivered
+		d.NextAttemptAt = nil
+		return
+	}
+
+	if len(d.Attempts) >= maxAttempts {
+		d.Status = StatusFailed
+		d.NextAttemptAt = nil
+		return
+	}
+
+	delay := retryDelay << uint(len(d.Attempts)-1)
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	next := a.At.Add(delay)
+	d.NextAttemptAt = &next
+}
+
+// Enqueue queues the event of the item for all the webhooks of the owner subscribed to it. The
+// item should be decrypted, its content is delivered to the webhooks which include it.
+func (s *Service) Enqueue(ownerID, typ string, item *items.Item, version int64) error {
+	hooks, err := s.List(ownerID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	payload := Payload{
+		ID:      newEventID(),
+		Type:    typ,
+		ItemID:  item.ID,
+		Version: version,
+		At:      now,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	for _, w := range hooks {
+		if !w.subscribed(typ) {
+			continue
+		}
+
+		d := Delivery{
+			ID:            newDeliveryID(),
+			WebhookID:     w.ID,
+			OwnerID:       ownerID,
+			EventID:       payload.ID,
+			Type:          typ,
+			ItemID:        item.ID,
+			Payload:       body,
+			Status:        StatusPending,
+			Attempts:      []Attempt{},
+			NextAttemptAt: &now,
+			CreatedAt:     &now,
+		}
+		if w.IncludeContent && typ != events.ItemDeleted {
+			if content == nil {
+				p := payload
+				p.Item = item
+				content, err = json.Marshal(p)
+				if err != nil {
+					return err
+				}
+			}
+			d.Content, err = s.sealContent(d.ID, content)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = s.store.Save(deliveriesBucket, d)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// sealContent encrypts the content of the delivery with a new key, which is kept in the cache
+func (s *Service) sealContent(deliveryID string, content []byte) ([]byte, error) {
+	key, err := crypto.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cache.Set(contentKey(deliveryID), key[:], contentExpiry)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return crypto.Seal(key, content)
+}
+
+// body returns the JSON body to be delivered. The content is included only while its key is in
+// the cache.
+func (s *Service) body(d *Delivery) []byte {
+	if len(d.Content) == 0 {
+		return d.Payload
+	}
+
+	b := []byte{}
+	err := s.cache.Get(contentKey(d.ID), &b)
+	if err != nil {
+		if err != cache.ErrNotFound {
+			s.logger.Error(err.Error())
+		}
+		return d.Payload
+	}
+
+	var key [crypto.KeySize]byte
+	copy(key[:], b)
+	content, err := crypto.Open(key, d.Content)
+	if err != nil {
+		s.logger.Error("webhook", d.ID, err.Error())
+		return d.Payload
+	}
+	return content
+}
+
+// ReadDelivery reads a delivery given the delivery ID
+func (s *Service) ReadDelivery(id string) (*Delivery, error) {
+	d := Delivery{}
+	_, err := s.store.FindOne(
+		deliveriesBucket,
+		map[string]interface{}{"id": id},
+		nil,
+		nil,
+		&d)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrDeliveryNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &d, nil
+}
+
+// UpdateDelivery saves the delivery. The content of a delivery which will not be attempted
+// anymore is removed, along with its key.
+func (s *Service) UpdateDelivery(d *Delivery) error {
+	content := len(d.Content) != 0 && d.done()
+	if content {
+		d.Content = nil
+	}
+
+	err := s.store.Update(
+		deliveriesBucket,
+		map[string]interface{}{"id": d.ID},
+		d)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrDeliveryNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+
+	if content {
+		err = s.cache.Delete(contentKey(d.ID))
+		if err != nil {
+			s.logger.Error(err.Error())
+		}
+	}
+	return nil
+}
+
+// Deliveries returns the latest deliveries of the webhook, latest first
+func (s *Service) Deliveries(webhookID string) ([]Delivery, error) {
+	out := make([]Delivery, 0)
+	_, err := s.store.Find(
+		deliveriesBucket,
+		map[string]interface{}{"webhookID": webhookID},
+		nil,
+		[]string{"-createdAt", "-id"},
+		0,
+		maxDeliveries,
+		&out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Due returns the pending deliveries to be attempted at the given time
+func (s *Service) Due(now time.Time) ([]Delivery, error) {
+	out := make([]Delivery, 0)
+	_, err := s.store.Find(
+		deliveriesBucket,
+		map[string]interface{}{
+			"status":        StatusPending,
+			"nextAttemptAt": map[string]interface{}{"$lte": now},
+		},
+		nil,
+		[]string{"nextAttemptAt"},
+		0,
+		maxDue,
+		&out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Replay queues the payload of the delivery again, as a new delivery. The content of the item
+// is not kept after a delivery, so it's not replayed.
+func (s *Service) Replay(d *Delivery) (*Delivery, error) {
+	now := time.Now().UTC()
+	replay := Delivery{
+		ID:            newDeliveryID(),
+		WebhookID:     d.WebhookID,
+		OwnerID:       d.OwnerID,
+		EventID:       d.EventID,
+		Type:          d.Type,
+		ItemID:        d.ItemID,
+		Payload:       d.Payload,
+		Status:        StatusPending,
+		Attempts:      []Attempt{},
+		NextAttemptAt: &now,
+		ReplayOf:      d.ID,
+		CreatedAt:     &now,
+	}
+	_, err := s.store.Save(deliveriesBucket, replay)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &replay, nil
+}
+
+// deleteDeliveries deletes all the deliveries of a webhook
+func (s *Service) deleteDeliveries(webhookID string) error {
+	query := map[string]interface{}{"webhookID": webhookID}
+	for {
+		err := s.store.Delete(deliveriesBucket, query)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			s.logger.Error(err.Error())
+			return err
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/netguard"
+)
+
+const (
+	defaultInterval = time.Second * 5
+	defaultTimeout  = time.Second * 10
+
+	// SignatureHeader is the header with the signature of a delivery
+	SignatureHeader = "X-Notes-Signature"
+	// EventHeader is the header with the type of the event delivered
+	EventHeader = "X-Notes-Event"
+	// DeliveryHeader is the header with the ID of the delivery
+	DeliveryHeader = "X-Notes-Delivery"
+)
+
+var (
+	// ErrSignature is returned if the signature of a delivery is missing or does not match
+	ErrSignature = errors.New("Invalid webhook signature")
+	// ErrSignatureExpired is returned if the signature of a delivery is older than the tolerance
+	ErrSignatureExpired = errors.New("Expired webhook signature")
+	// errWebhookDeleted is recorded for the pending deliveries of a deleted webhook
+	errWebhookDeleted = errors.New("webhook deleted")
+)
+
+// Sign returns the signature of the body delivered at the given time. It's the HMAC-SHA256 of
+// the unix time and the body joined by a '.', with the secret of the webhook as the key.
+func Sign(secret string, at time.Time, body []byte) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify verifies the signature header of a delivery received, and that it was signed within
+// the tolerance, to reject the deliveries replayed by someone else
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var at int64
+	sig := ""
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			at, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			sig = v
+		}
+	}
+	if at == 0 || sig == "" {
+		return ErrSignature
+	}
+
+	expected := Sign(secret, time.Unix(at, 0), body)
+	if !hmac.Equal([]byte(expected), []byte("t="+strconv.FormatInt(at, 10)+",v1="+sig)) {
+		return ErrSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(at, 0)) > tolerance {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+// Dispatcher periodically attempts the pending deliveries. Deliveries are read from the store,
+// so nothing is lost across restarts, and every attempt is locked in the cache so that it's
+// made only once across multiple instances of the app.
+type Dispatcher struct {
+	webhooks Service
+	cache    cache.Service
+	logger   logger.Service
+	client   *http.Client
+	interval time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewDispatcher returns a dispatcher which checks for pending deliveries at every interval
+func NewDispatcher(ws Service, cs cache.Service, l logger.Service, c Config) *Dispatcher {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	// the addresses are checked again when dialled, the host may resolve to another address
+	// since the webhook was created
+	client := ws.guard.Client(timeout)
+	// redirects are not followed, the webhook URL should be updated instead
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Dispatcher{
+		webhooks: ws,
+		cache:    cs,
+		logger:   l,
+		client:   client,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start starts attempting the pending deliveries in the background
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			d.Run(time.Now().UTC())
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the dispatcher and waits for the deliveries being attempted to complete
+func (d *Dispatcher) Stop() {
+	d.once.Do(func() {
+		close(d.stop)
+	})
+	d.wg.Wait()
+}
+
+// lockKey is the cache key used to lock a single attempt of a delivery
+func lockKey(dl *Delivery) string {
+	return fmt.Sprintf("webhooks:lock:%s:%d", dl.ID, len(dl.Attempts))
+}
+
+// Run attempts all the deliveries which are due at the given time
+func (d *Dispatcher) Run(now time.Time) {
+	due, err := d.webhooks.Due(now)
+	if err != nil {
+		return
+	}
+
+	for idx := range due {
+		dl := &due[idx]
+		locked, err := d.cache.SetNX(lockKey(dl), now.Unix(), d.client.Timeout*2)
+		if err != nil {
+			d.logger.Error(err.Error())
+			continue
+		}
+		if !locked {
+			// another instance is attempting this delivery
+			continue
+		}
+
+		err = d.attempt(dl)
+		if err != nil {
+			d.logger.Error("webhook", dl.ID, err.Error())
+		}
+	}
+}
+
+// attempt delivers the payload to the webhook, and records the result
+func (d *Dispatcher) attempt(dl *Delivery) error {
+	a := Attempt{At: time.Now().UTC()}
+	w, err := d.webhooks.Read(dl.WebhookID)
+	switch {
+	case err == ErrNotFound:
+		a.Error = errWebhookDeleted.Error()
+		dl.Status = StatusFailed
+		dl.NextAttemptAt = nil
+		dl.Attempts = append(dl.Attempts, a)
+		return d.webhooks.UpdateDelivery(dl)
+	case err != nil:
+		return err
+	}
+
+	a.StatusCode, err = d.post(w, dl)
+	if err != nil {
+		a.Error = err.Error()
+	}
+	dl.record(a)
+	return d.webhooks.UpdateDelivery(dl)
+}
+
+// post sends the payload of the delivery to the webhook, and returns the status of the response.
+// The errors of the requests are recorded and shown to the users, so the details of the network
+// are left out.
+func (d *Dispatcher) post(w *Webhook, dl *Delivery) (int, error) {
+	body := d.webhooks.body(dl)
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "notes-webhooks")
+	req.Header.Set(EventHeader, dl.Type)
+	req.Header.Set(DeliveryHeader, dl.ID)
+	req.Header.Set(SignatureHeader, Sign(w.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, netguard.Error(err)
+	}
+	// the body is drained so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
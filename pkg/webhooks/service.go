@@ -0,0 +1,40 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/netguard"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+// Service holds all the dependencies of webhooks
+type Service struct {
+	store  storage.Service
+	cache  cache.Service
+	logger logger.Service
+	guard  netguard.Guard
+}
+
+// NewService returns a new instance of Service with all the dependencies initialized
+func NewService(ss storage.Service, cs cache.Service, l logger.Service, c Config) Service {
+	return Service{
+		store:  ss,
+		cache:  cs,
+		logger: l,
+		guard:  netguard.Guard{AllowPrivate: c.AllowPrivate},
+	}
+}
+
+// Config holds all the configurations required for webhooks
+type Config struct {
+	// Interval is the duration between successive checks for pending deliveries
+	Interval time.Duration
+	// Timeout is the timeout of a single delivery
+	Timeout time.Duration
+	// AllowPrivate allows webhooks to loopback and private addresses, e.g. for local
+	// development. They're rejected by default, so that the webhooks cannot reach the network
+	// of the app.
+	AllowPrivate bool
+}
@@ -0,0 +1,225 @@
+// Package webhooks handles the webhooks registered by the users, and delivers the events of
+// their items to them
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bnkamalesh/notes/pkg/errs"
+	"github.com/bnkamalesh/notes/pkg/events"
+	"github.com/bnkamalesh/notes/pkg/platform/storage"
+)
+
+const (
+	webhooksBucket = "webhooks"
+	// maxWebhooks is the maximum number of webhooks of an owner
+	maxWebhooks = 10
+	// secretPrefix is the prefix of the signing secrets, to recognise them
+	secretPrefix = "whsec_"
+	// resolveTimeout is the timeout of resolving the host of a new webhook
+	resolveTimeout = time.Second * 5
+)
+
+var (
+	// ErrCreate is returned if there's an error creating a new webhook
+	ErrCreate = errors.New("Sorry, an error occurred while creating the webhook")
+	// ErrNotFound is returned if the webhook does not exist
+	ErrNotFound = errs.NotFound("Sorry, webhook not found")
+	// ErrInvURL is returned if the URL of the webhook is not an http or https URL
+	ErrInvURL = errs.Field("url", "Sorry, the webhook URL should be an http or https URL")
+	// ErrInvAddr is returned if the host of the webhook does not resolve to public addresses
+	ErrInvAddr = errs.Field("url", "Sorry, the webhook URL should resolve to a public address")
+	// ErrInvEvents is returned if no events or unknown events are provided
+	ErrInvEvents = errs.Field("events", "Sorry, the events should be one or more of item.created, item.updated and item.deleted")
+	// ErrTooMany is returned if the owner already has the maximum number of webhooks
+	ErrTooMany = errs.Invalid("Sorry, at most 10 webhooks can be registered")
+
+	// eventTypes are the events which can be delivered to webhooks
+	eventTypes = map[string]bool{
+		events.ItemCreated: true,
+		events.ItemUpdated: true,
+		events.ItemDeleted: true,
+	}
+)
+
+// Webhook is a URL the events of the items of an owner are delivered to
+type Webhook struct {
+	ID      string `json:"id,omitempty" bson:"id,omitempty"`
+	OwnerID string `json:"-" bson:"ownerID,omitempty"`
+	URL     string `json:"url" bson:"url"`
+	// Events are the types of the events delivered
+	Events []string `json:"events" bson:"events"`
+	// IncludeContent if true, includes the decrypted item in the payloads of the created and
+	// updated events. The content is stored encrypted until the delivery is delivered or
+	// failed, and it's not replayed.
+	IncludeContent bool `json:"includeContent" bson:"includeContent"`
+	// Secret is the key of the signatures of the deliveries, it's returned only when the
+	// webhook is created
+	Secret    string     `json:"secret,omitempty" bson:"secret"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+}
+
+func newWebhookID() string {
+	return fmt.Sprintf("webhook_%s", uuid.New().String())
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+func validURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvURL
+	}
+	return nil
+}
+
+// parseEvents returns the unique event types, in the order provided
+func parseEvents(types []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !eventTypes[t] {
+			return nil, ErrInvEvents
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return nil, ErrInvEvents
+	}
+	return out, nil
+}
+
+// New returns a new webhook of the owner, with a new signing secret
+func New(ownerID, target string, types []string, includeContent bool) (*Webhook, error) {
+	target = strings.TrimSpace(target)
+	errURL := validURL(target)
+	types, errEvents := parseEvents(types)
+	err := errs.Validation(errURL, errEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &Webhook{
+		ID:             newWebhookID(),
+		OwnerID:        ownerID,
+		URL:            target,
+		Events:         types,
+		IncludeContent: includeContent,
+		Secret:         secret,
+		CreatedAt:      &now,
+	}, nil
+}
+
+// subscribed returns true if the event type is delivered to the webhook
+func (w *Webhook) subscribed(typ string) bool {
+	for _, t := range w.Events {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Create saves a new webhook, if its host resolves to public addresses and the owner has not
+// reached the maximum number of webhooks
+func (s *Service) Create(w Webhook) (*Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	err := s.guard.CheckURL(ctx, w.URL)
+	if err != nil {
+		return nil, errs.Validation(ErrInvAddr)
+	}
+
+	count, err := s.store.Count(webhooksBucket, map[string]interface{}{"ownerID": w.OwnerID})
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	if count >= maxWebhooks {
+		return nil, ErrTooMany
+	}
+
+	_, err = s.store.Save(webhooksBucket, w)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrCreate
+	}
+	return &w, nil
+}
+
+// Read reads a webhook given the webhook ID
+func (s *Service) Read(id string) (*Webhook, error) {
+	w := Webhook{}
+	_, err := s.store.FindOne(
+		webhooksBucket,
+		map[string]interface{}{"id": id},
+		nil,
+		nil,
+		&w)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return &w, nil
+}
+
+// List returns all the webhooks of the owner, with their secrets
+func (s *Service) List(ownerID string) ([]Webhook, error) {
+	out := make([]Webhook, 0)
+	_, err := s.store.Find(
+		webhooksBucket,
+		map[string]interface{}{"ownerID": ownerID},
+		nil,
+		[]string{"createdAt"},
+		0,
+		0,
+		&out)
+	if err != nil && err != storage.ErrNotFound {
+		s.logger.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete deletes a webhook and all its deliveries
+func (s *Service) Delete(id string) error {
+	err := s.store.Delete(webhooksBucket, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound
+		}
+		s.logger.Error(err.Error())
+		return err
+	}
+	return s.deleteDeliveries(id)
+}
@@ -0,0 +1,314 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnkamalesh/notes/pkg/events"
+	"github.com/bnkamalesh/notes/pkg/items"
+	"github.com/bnkamalesh/notes/pkg/platform/cache"
+	cachemem "github.com/bnkamalesh/notes/pkg/platform/cache/memory"
+	"github.com/bnkamalesh/notes/pkg/platform/logger"
+	"github.com/bnkamalesh/notes/pkg/platform/netguard"
+	storagemem "github.com/bnkamalesh/notes/pkg/platform/storage/memory"
+)
+
+// receiver is a webhook receiver recording the deliveries, it responds with the status set
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	bodies   [][]byte
+	requests []*http.Request
+}
+
+func (r *receiver) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	r.requests = append(r.requests, req)
+	rw.WriteHeader(r.status)
+}
+
+func (r *receiver) received() ([]*http.Request, [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests, r.bodies
+}
+
+func setup(t *testing.T) (Service, *Dispatcher, *receiver, *httptest.Server) {
+	t.Helper()
+	l := logger.New(nil)
+	cs := cachemem.New()
+	c := Config{AllowPrivate: true}
+	s := NewService(storagemem.New(), cs, l, c)
+	d := NewDispatcher(s, cs, l, c)
+	r := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return s, d, r, server
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("owner", "ftp://example.com", []string{events.ItemCreated}, false)
+	if err == nil {
+		t.Fatal("Expected an error for a URL which is not http")
+	}
+	_, err = New("owner", "https://example.com", []string{"item.moved"}, false)
+	if err == nil {
+		t.Fatal("Expected an error for an unknown event")
+	}
+	w, err := New("owner", "https://example.com/hook", []string{"item.created", " ITEM.CREATED", "item.deleted"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Events) != 2 || len(w.Secret) != len(secretPrefix)+64 {
+		t.Fatalf("Unexpected webhook %+v", w)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	s, d, r, server := setup(t)
+
+	plain, _ := New("owner", server.URL+"/plain", []string{events.ItemCreated, events.ItemDeleted}, false)
+	content, _ := New("owner", server.URL+"/content", []string{events.ItemCreated}, true)
+	for _, w := range []*Webhook{plain, content} {
+		_, err := s.Create(*w)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	item := &items.Item{ID: "item_1", Title: "Groceries", Description: "milk"}
+	err := s.Enqueue("owner", events.ItemCreated, item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// not subscribed by any webhook
+	err = s.Enqueue("owner", events.ItemUpdated, item, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the content is never stored in clear
+	pending, _ := s.Deliveries(content.ID)
+	if len(pending) != 1 || len(pending[0].Content) == 0 {
+		t.Fatalf("Expected a delivery with content, got %+v", pending)
+	}
+	for _, b := range [][]byte{pending[0].Payload, pending[0].Content} {
+		if bytes.Contains(b, []byte("milk")) || bytes.Contains(b, []byte("Groceries")) {
+			t.Fatalf("Expected the content to be encrypted, got %s", b)
+		}
+	}
+	d.Run(time.Now().UTC())
+
+	requests, bodies := r.received()
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(requests))
+	}
+	for i, req := range requests {
+		secret := plain.Secret
+		if req.URL.Path == "/content" {
+			secret = content.Secret
+		}
+		err = Verify(secret, req.Header.Get(SignatureHeader), bodies[i], time.Minute)
+		if err != nil {
+			t.Fatalf("Invalid signature of %s: %v", req.URL.Path, err)
+		}
+		if req.Header.Get(EventHeader) != events.ItemCreated || req.Header.Get(DeliveryHeader) == "" {
+			t.Fatalf("Unexpected headers %v", req.Header)
+		}
+
+		p := Payload{}
+		err = json.Unmarshal(bodies[i], &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ItemID != "item_1" || p.Version != 1 {
+			t.Fatalf("Unexpected payload %+v", p)
+		}
+		if (p.Item != nil) != (req.URL.Path == "/content") {
+			t.Fatalf("The content should be included only if the webhook opted in, %s got %+v", req.URL.Path, p.Item)
+		}
+		if p.Item != nil && p.Item.Description != "milk" {
+			t.Fatalf("Expected the decrypted content, got %+v", p.Item)
+		}
+	}
+	err = Verify(content.Secret, requests[0].Header.Get(SignatureHeader), []byte("{}"), time.Minute)
+	if err != ErrSignature {
+		t.Fatalf("Expected a signature error for a different body, got %v", err)
+	}
+
+	dd, err := s.Deliveries(plain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dd) != 1 || dd[0].Status != StatusDelivered || dd[0].Attempts[0].StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful delivery, got %+v", dd)
+	}
+
+	// the content and its key are removed once delivered
+	dd, _ = s.Deliveries(content.ID)
+	if dd[0].Status != StatusDelivered || len(dd[0].Content) != 0 {
+		t.Fatalf("Expected the content to be removed, got %+v", dd[0])
+	}
+	b := []byte{}
+	err = s.cache.Get(contentKey(dd[0].ID), &b)
+	if err != cache.ErrNotFound {
+		t.Fatalf("Expected the key of the content to be removed, got %v", err)
+	}
+}
+
+func TestContentExpired(t *testing.T) {
+	s, d, r, server := setup(t)
+	w, _ := New("owner", server.URL, []string{events.ItemCreated}, true)
+	_, err := s.Create(*w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Enqueue("owner", events.ItemCreated, &items.Item{ID: "item_1", Description: "milk"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dd, _ := s.Deliveries(w.ID)
+	s.cache.Delete(contentKey(dd[0].ID))
+	d.Run(time.Now().UTC())
+
+	// the event is delivered without the content once its key is gone
+	_, bodies := r.received()
+	p := Payload{}
+	_ = json.Unmarshal(bodies[0], &p)
+	if len(bodies) != 1 || p.ItemID != "item_1" || p.Item != nil {
+		t.Fatalf("Expected the event without the content, got %s", bodies)
+	}
+}
+
+func TestPrivateAddress(t *testing.T) {
+	s, _, r, server := setup(t)
+	l := logger.New(nil)
+	guarded := NewService(s.store, s.cache, l, Config{})
+
+	for _, target := range []string{"http://127.0.0.1:6379", "http://169.254.169.254/latest", server.URL} {
+		w, _ := New("owner", target, []string{events.ItemCreated}, false)
+		_, err := guarded.Create(*w)
+		if err == nil {
+			t.Fatalf("Expected %s to be rejected", target)
+		}
+	}
+
+	// the address is checked again when delivering, the host may resolve to another address
+	w, _ := New("owner", server.URL, []string{events.ItemCreated}, false)
+	_, err := s.Create(*w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Enqueue("owner", events.ItemCreated, &items.Item{ID: "item_1"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewDispatcher(guarded, s.cache, l, Config{}).Run(time.Now().UTC())
+
+	requests, _ := r.received()
+	if len(requests) != 0 {
+		t.Fatalf("Expected no requests to a private address, got %d", len(requests))
+	}
+	dd, _ := s.Deliveries(w.ID)
+	if len(dd[0].Attempts) != 1 || dd[0].Attempts[0].Error != netguard.ErrForbidden.Error() {
+		t.Fatalf("Expected the attempt to fail with a generic error, got %+v", dd[0].Attempts)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s, d, r, server := setup(t)
+	w, _ := New("owner", server.URL, []string{events.ItemDeleted}, true)
+	_, err := s.Create(*w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.status = http.StatusServiceUnavailable
+	err = s.Enqueue("owner", events.ItemDeleted, &items.Item{ID: "item_1", Description: "milk"}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	d.Run(now)
+
+	dd, _ := s.Deliveries(w.ID)
+	dl := dd[0]
+	if dl.Status != StatusPending || len(dl.Attempts) != 1 || dl.Attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected a failed attempt, got %+v", dl)
+	}
+	if dl.NextAttemptAt == nil || dl.NextAttemptAt.Sub(dl.Attempts[0].At) != retryDelay {
+		t.Fatalf("Expected a retry after %s, got %v", retryDelay, dl.NextAttemptAt)
+	}
+
+	// not due yet
+	d.Run(now)
+	requests, bodies := r.received()
+	if len(requests) != 1 {
+		t.Fatalf("Expected a single attempt, got %d", len(requests))
+	}
+	// deleted items never have content
+	p := Payload{}
+	_ = json.Unmarshal(bodies[0], &p)
+	if p.Item != nil {
+		t.Fatalf("Unexpected content in the deletion %+v", p.Item)
+	}
+
+	r.mu.Lock()
+	r.status = http.StatusNoContent
+	r.mu.Unlock()
+	d.Run(now.Add(retryDelay + time.Second))
+	dd, _ = s.Deliveries(w.ID)
+	if dd[0].Status != StatusDelivered || len(dd[0].Attempts) != 2 {
+		t.Fatalf("Expected the retry to be delivered, got %+v", dd[0])
+	}
+
+	replay, err := s.Replay(&dd[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Run(time.Now().UTC())
+	requests, bodies = r.received()
+	if len(requests) != 3 || string(bodies[2]) != string(bodies[0]) {
+		t.Fatalf("Expected the payload to be delivered again, got %d deliveries", len(requests))
+	}
+	replayed, err := s.ReadDelivery(replay.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Status != StatusDelivered || replayed.ReplayOf != dd[0].ID || replayed.EventID != dd[0].EventID {
+		t.Fatalf("Unexpected replay %+v", replayed)
+	}
+
+	err = s.Delete(w.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dd, _ = s.Deliveries(w.ID)
+	if len(dd) != 0 {
+		t.Fatalf("Expected the deliveries to be deleted with the webhook, got %d", len(dd))
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	dl := &Delivery{Status: StatusPending}
+	at := time.Now()
+	for i := 0; i < maxAttempts; i++ {
+		dl.record(Attempt{At: at, Error: "failed"})
+		if i < maxAttempts-1 && dl.Status != StatusPending {
+			t.Fatalf("Expected attempt %d to be retried", i+1)
+		}
+	}
+	if dl.Status != StatusFailed || dl.NextAttemptAt != nil {
+		t.Fatalf("Expected the delivery to fail after %d attempts, got %+v", maxAttempts, dl)
+	}
+}